@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// agentProtocol is the version of the agent API, bumped on incompatible changes
+const agentProtocol = 1
+
+// agentInfo is what the info file tells IDE plugins about a running agent
+type agentInfo struct {
+	Port     int    `json:"port"`
+	Token    string `json:"token"`
+	PID      int    `json:"pid"`
+	Protocol int    `json:"protocol"`
+}
+
+// agentStatus is the response of GET /v1/agent
+type agentStatus struct {
+	Protocol int      `json:"protocol"`
+	PID      int      `json:"pid"`
+	Projects []string `json:"projects"`
+}
+
+// agentGenerateRequest is the body of POST /v1/agent/generate. Unlike
+// /v1/generate, the agent reads the staged changes of the project itself.
+type agentGenerateRequest struct {
+	// Project is the absolute path of the repository, as the IDE opened it
+	Project string `json:"project"`
+	Hint    string `json:"hint,omitempty"`
+	Short   *bool  `json:"short,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Scope   string `json:"scope,omitempty"`
+}
+
+// runAgent serves generations for IDE plugins (e.g. JetBrains) on a
+// loopback port. The port and a fresh token go to an info file that only
+// the user can read, so plugins connect the way they do to Copilot agents.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("commit-gen agent", flag.ExitOnError)
+	port := fs.Int("port", 0, "Port to listen on at 127.0.0.1 (default: any free port)")
+	infoFile := fs.String("info-file", defaultAgentInfoFile(), "File to write the port and auth token to for IDE plugins")
+	idleTimeout := fs.Duration("idle-timeout", 0, "Exit after this long without requests (0 runs until stopped)")
+	warmUp := fs.Bool("warm-up", false, "Send a one-token request when a project is first seen, e.g. to load a local model")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	if *infoFile == "" {
+		fatalf("No user cache directory for the info file, set -info-file")
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(*port)))
+	if err != nil {
+		fatalf("Failed to listen: %v", err)
+	}
+
+	token := make([]byte, 32)
+	rand.Read(token)
+	a := &agent{
+		token:    hex.EncodeToString(token),
+		warmUp:   *warmUp,
+		projects: make(map[string]*agentProject),
+		newOptions: func(dir string) (*generator.Options, []string, error) {
+			cfg, err := config.Load(dir)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load config: %w", err)
+			}
+			opts, err := cfg.Options(dir)
+			if err != nil {
+				return nil, nil, err
+			}
+			providers.apply(opts)
+			return opts, cfg.Files(), nil
+		},
+	}
+	defer a.close()
+
+	info := &agentInfo{
+		Port:     listener.Addr().(*net.TCPAddr).Port,
+		Token:    a.token,
+		PID:      os.Getpid(),
+		Protocol: agentProtocol,
+	}
+	if err := writeAgentInfo(*infoFile, info); err != nil {
+		fatalf("Failed to write the info file: %v", err)
+	}
+	defer removeAgentInfo(*infoFile, info.PID)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	handler := http.Handler(a.mux())
+	if *idleTimeout > 0 {
+		idle := time.AfterFunc(*idleTimeout, stop)
+		handler = resetOnRequest(handler, idle, *idleTimeout)
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go a.warmUpProvider(ctx)
+
+	log.Printf("commit-gen agent listening on %s, info in %s", listener.Addr(), *infoFile)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf("Agent failed: %v", err)
+	}
+}
+
+// agent keeps one generator per project, each with that project's config
+type agent struct {
+	token  string
+	warmUp bool
+	// newOptions loads the options for a project, along with the files
+	// they were read from
+	newOptions func(dir string) (*generator.Options, []string, error)
+
+	mu       sync.Mutex
+	projects map[string]*agentProject
+}
+
+// agentProject is the generator of a project and the stamp of the config
+// files it was built from, to rebuild it when they change
+type agentProject struct {
+	commitGen *generator.CommitGen
+	files     []string
+	stamp     string
+	// requests counts the requests using commitGen, which a replaced
+	// generator waits for before closing
+	requests sync.WaitGroup
+}
+
+// mux wires the agent endpoints, all of which require the token
+func (a *agent) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /v1/agent", a.authorize(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		projects := make([]string, 0, len(a.projects))
+		for dir := range a.projects {
+			projects = append(projects, dir)
+		}
+		a.mu.Unlock()
+		slices.Sort(projects)
+		writeJSON(w, http.StatusOK, &agentStatus{Protocol: agentProtocol, PID: os.Getpid(), Projects: projects})
+	}))
+
+	mux.HandleFunc("POST /v1/agent/generate", a.authorize(func(w http.ResponseWriter, r *http.Request) {
+		var req agentGenerateRequest
+		if status, err := decodeBody(w, r, &req); err != nil {
+			writeJSON(w, status, &generateResponse{Error: err.Error()})
+			return
+		}
+		if !filepath.IsAbs(req.Project) {
+			writeJSON(w, http.StatusBadRequest, &generateResponse{Error: "project must be an absolute path"})
+			return
+		}
+		if stat, err := os.Stat(req.Project); err != nil || !stat.IsDir() {
+			writeJSON(w, http.StatusBadRequest, &generateResponse{Error: "project is not a directory"})
+			return
+		}
+
+		project := filepath.Clean(req.Project)
+		commitGen, release, err := a.commitGen(project)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, &generateResponse{Error: err.Error()})
+			return
+		}
+		defer release()
+		hasChanges, err := commitGen.HasStagedChanges()
+		if err != nil {
+			// Most likely not a repository, so do not keep it around
+			a.forget(project)
+			writeJSON(w, http.StatusBadRequest, &generateResponse{Error: err.Error()})
+			return
+		}
+		if !hasChanges {
+			writeJSON(w, http.StatusConflict, &generateResponse{Error: "no staged changes"})
+			return
+		}
+
+		gitInfo, err := commitGen.GetGitInfo()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, &generateResponse{Error: err.Error()})
+			return
+		}
+		gitInfo.Hint = req.Hint
+		var subjectPrefix string
+		if req.Type != "" {
+			subjectPrefix = generator.SubjectPrefix(req.Type, req.Scope)
+		}
+		result, err := commitGen.GenerateWithConfig(r.Context(), gitInfo, &generator.GenConfig{
+			IsShortCommit: req.Short,
+			SubjectPrefix: subjectPrefix,
+			Scope:         req.Scope,
+		})
+		if err != nil {
+			writeJSON(w, relayStatus(err), &generateResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, newGenerateResponse(result))
+	}))
+
+	return mux
+}
+
+// authorize rejects requests without the agent token. Requests from web
+// pages are refused as well, since any page may reach a loopback port.
+func (a *agent) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeJSON(w, http.StatusForbidden, &generateResponse{Error: "browser requests are not allowed"})
+			return
+		}
+		want := []byte("Bearer " + a.token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeJSON(w, http.StatusUnauthorized, &generateResponse{Error: "invalid or missing token"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// commitGen returns the generator for the project at dir, and a func to
+// call when done with it. The generator is created once and kept until its
+// config files change: checking them costs a stat per file, while loading
+// the config again would cost far more on every request.
+func (a *agent) commitGen(dir string) (*generator.CommitGen, func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	project := a.projects[dir]
+	if project != nil && config.Stamp(project.files) != project.stamp {
+		a.drop(dir, project)
+		project = nil
+	}
+	if project == nil {
+		opts, files, err := a.newOptions(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		commitGen, err := generator.New(opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize commit generator: %w", err)
+		}
+		project = &agentProject{commitGen: commitGen, files: files, stamp: config.Stamp(files)}
+		a.projects[dir] = project
+	}
+	project.requests.Add(1)
+	return project.commitGen, project.requests.Done, nil
+}
+
+// forget releases the generator of the project at dir
+func (a *agent) forget(dir string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if project := a.projects[dir]; project != nil {
+		a.drop(dir, project)
+	}
+}
+
+// drop removes project, closing its generator once the requests still
+// using it are done; a.mu must be held
+func (a *agent) drop(dir string, project *agentProject) {
+	delete(a.projects, dir)
+	go func() {
+		project.requests.Wait()
+		project.commitGen.Close()
+	}()
+}
+
+// close releases every generator
+func (a *agent) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, project := range a.projects {
+		project.commitGen.Close()
+	}
+}
+
+// warmUpProvider connects to the provider configured for the directory
+// the agent started in. Projects mostly share a provider, and generators
+// without custom transport options share keep-alive connections, so the
+// first request of any project skips the handshakes.
+func (a *agent) warmUpProvider(ctx context.Context) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	opts, _, err := a.newOptions(dir)
+	if err != nil {
+		return
+	}
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		return
+	}
+	defer commitGen.Close()
+	warmUpProvider(ctx, commitGen, a.warmUp)
+}
+
+// resetOnRequest restarts the idle timer around every request
+func resetOnRequest(next http.Handler, idle *time.Timer, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idle.Reset(timeout)
+		defer idle.Reset(timeout)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// defaultAgentInfoFile returns where plugins look for the agent by default
+func defaultAgentInfoFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "commitgen", "agent.json")
+}
+
+// writeAgentInfo writes info readable by the user only; it is replaced
+// atomically, so plugins never read a half-written token
+func writeAgentInfo(path string, info *agentInfo) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, info.PID)
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// removeAgentInfo deletes the info file unless a newer agent replaced it
+func removeAgentInfo(path string, pid int) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var info agentInfo
+	if json.Unmarshal(data, &info) == nil && info.PID == pid {
+		os.Remove(path)
+	}
+}
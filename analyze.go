@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// analyzeFormats are the renderings of a ChangeSummary -format accepts
+var analyzeFormats = []string{"commit", "pr", "changelog", "standup", "json"}
+
+// runAnalyze analyzes the staged changes, or a diff on stdin with "-",
+// once and prints the analysis in one or more formats
+func runAnalyze(args []string) {
+	fs := flag.NewFlagSet("commit-gen analyze", flag.ExitOnError)
+	format := fs.String("format", "commit", "Comma-separated outputs: "+strings.Join(analyzeFormats, ", "))
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	formats := strings.Split(*format, ",")
+	for _, f := range formats {
+		if !slices.Contains(analyzeFormats, f) {
+			fail(exitcode.Usage, fmt.Sprintf("Unknown format %q, use %s", f, strings.Join(analyzeFormats, ", ")))
+		}
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	var gitInfo *generator.GitInfo
+	if fs.Arg(0) == "-" {
+		gitInfo = &generator.GitInfo{StagedDiff: readStdin()}
+	}
+	summary, err := commitGen.Analyze(context.Background(), gitInfo)
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges("No changes to analyze.")
+	}
+	if err != nil {
+		failErr(err, "Failed to analyze the change")
+	}
+
+	for i, f := range formats {
+		if len(formats) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s <==\n", f)
+		}
+		switch f {
+		case "commit":
+			fmt.Println(summary.CommitMessage())
+		case "pr":
+			fmt.Print(summary.PullRequest())
+		case "changelog":
+			fmt.Print(summary.Changelog())
+		case "standup":
+			fmt.Print(summary.Standup())
+		case "json":
+			printResponse(summary)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runAudit scores the recent commit history against the commit convention
+func runAudit(args []string) {
+	fs := flag.NewFlagSet("commit-gen audit", flag.ExitOnError)
+	count := fs.Int("n", 50, "Number of recent commits to audit")
+	rate := fs.Bool("llm", false, "Also ask the model how informative each message is")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fatalf("Failed to initialize commit generator: %v", err)
+	}
+	defer commitGen.Close()
+
+	report, err := commitGen.Audit(context.Background(), *count, *rate)
+	if err != nil {
+		fatalf("Failed to audit commit history: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+
+	printAuditReport(report, *rate)
+}
+
+// printAuditReport prints a per-commit table followed by a summary
+func printAuditReport(report *generator.AuditReport, rated bool) {
+	if len(report.Commits) == 0 {
+		fmt.Println("No commits to audit.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "COMMIT\tSCORE\tSUBJECT\tVIOLATIONS"
+	if rated {
+		header = "COMMIT\tSCORE\tINFO\tSUBJECT\tVIOLATIONS"
+	}
+	fmt.Fprintln(w, header)
+	for _, c := range report.Commits {
+		rules := make([]string, len(c.Violations))
+		for i, v := range c.Violations {
+			rules[i] = v.Rule
+		}
+		hash := c.Hash
+		if len(hash) > 8 {
+			hash = hash[:8]
+		}
+		subject := c.Subject
+		if len([]rune(subject)) > 60 {
+			subject = string([]rune(subject)[:57]) + "..."
+		}
+		if rated {
+			fmt.Fprintf(w, "%s\t%.0f\t%d/5\t%s\t%s\n", hash, c.Score, c.Informativeness, subject, strings.Join(rules, ","))
+		} else {
+			fmt.Fprintf(w, "%s\t%.0f\t%s\t%s\n", hash, c.Score, subject, strings.Join(rules, ","))
+		}
+	}
+	w.Flush()
+
+	fmt.Printf("\nAverage score: %.1f/100\n", report.AverageScore)
+	fmt.Printf("Conforming:    %d/%d (%.0f%%)\n", report.Conforming, len(report.Commits),
+		100*float64(report.Conforming)/float64(len(report.Commits)))
+	if top := report.TopViolations(); len(top) > 0 {
+		fmt.Println("Top violations:")
+		for _, rule := range top {
+			fmt.Printf("  %-18s %d\n", rule, report.ViolationCounts[rule])
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runBisectExplain explains the commit git bisect blamed for a regression
+func runBisectExplain(args []string) {
+	fs := flag.NewFlagSet("commit-gen bisect-explain", flag.ExitOnError)
+	symptom := fs.String("symptom", "", "What regressed, e.g. \"login returns 500 for SSO users\"")
+	asJSON := fs.Bool("json", false, "Print the explanation as JSON on stdout and errors as JSON on stderr")
+	providers := registerProviderFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: commit-gen bisect-explain [flags] [commit]")
+		fmt.Fprintln(fs.Output(), "Without a commit, explains the first bad commit of the current git bisect.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+	if fs.NArg() > 1 {
+		fs.Usage()
+		fail(exitcode.Usage, "bisect-explain takes at most one commit")
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	summary, err := commitGen.ExplainBisect(context.Background(), fs.Arg(0), *symptom)
+	if err != nil {
+		failErr(err, "Failed to explain the commit")
+	}
+	printSummary(summary, *asJSON)
+}
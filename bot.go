@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runBot writes the message for an automated commit from a structured
+// description, without a model:
+//
+//	commit-gen bot deps <name> [<from>] <to>
+//	commit-gen bot codegen [<generator>]
+//	commit-gen bot -   (a generator.BotUpdate as JSON on stdin)
+func runBot(args []string) {
+	fs := flag.NewFlagSet("commit-gen bot", flag.ExitOnError)
+	shortCommit := fs.Bool("short", false, "Write the subject line only")
+	dev := fs.Bool("dev", false, "The dependencies are development-only (scope deps-dev)")
+	source := fs.String("source", "", "What the code was generated from, for codegen")
+	commitType := fs.String("type", "", "Pin the commit type (default chore)")
+	scope := fs.String("scope", "", "Pin the commit scope (default deps, deps-dev, or codegen)")
+	asJSON := fs.Bool("json", false, "Print the result as JSON")
+	fs.Parse(args)
+
+	update := &generator.BotUpdate{Kind: fs.Arg(0), Dev: *dev, Source: *source}
+	rest := fs.Args()[min(1, fs.NArg()):]
+	switch update.Kind {
+	case generator.BotDependencies:
+		switch len(rest) {
+		case 2:
+			update.Dependencies = []generator.DependencyUpdate{{Name: rest[0], To: rest[1]}}
+		case 3:
+			update.Dependencies = []generator.DependencyUpdate{{Name: rest[0], From: rest[1], To: rest[2]}}
+		default:
+			fail(exitcode.Usage, "Usage: commit-gen bot deps <name> [<from>] <to>")
+		}
+	case generator.BotCodegen:
+		if len(rest) > 1 {
+			fail(exitcode.Usage, "Usage: commit-gen bot codegen [<generator>]")
+		}
+		if len(rest) == 1 {
+			update.Generator = rest[0]
+		}
+	case "-":
+		update = &generator.BotUpdate{}
+		if err := json.NewDecoder(os.Stdin).Decode(update); err != nil {
+			fail(exitcode.Usage, fmt.Sprintf("Invalid bot update on stdin: %v", err))
+		}
+	default:
+		fail(exitcode.Usage, "Usage: commit-gen bot [flags] deps <name> [<from>] <to> | codegen [<generator>] | -")
+	}
+
+	opts := loadOptions("")
+	opts.IsShortCommit = *shortCommit
+	opts.Type = *commitType
+	opts.Scope = *scope
+	result, err := generator.BotMessage(update, opts)
+	if err != nil {
+		fail(exitcode.Usage, err.Error())
+	}
+
+	if *asJSON {
+		printResponse(newGenerateResponse(result))
+		return
+	}
+	fmt.Println(result)
+}
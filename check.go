@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runCheck verifies the config, git, the API key, and the provider without
+// generating anything, and exits with the code of the first failed check
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("commit-gen check", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the results as JSON")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	opts := loadOptions("")
+	providers.apply(opts)
+	ctx, stop := interruptible()
+	report, err := generator.Check(ctx, opts)
+	stop()
+	if err != nil {
+		failErr(err, "Failed to check the setup")
+	}
+
+	if *asJSON {
+		printResponse(report)
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, check := range report.Checks {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", check.Name, check.Status, check.Detail)
+		}
+		w.Flush()
+	}
+	if !report.OK() {
+		exit(checkExitCode(report))
+	}
+}
+
+// checkExitCode is the exit code generating would fail with: exitcode.Config
+// for a bad config or a missing key, exitcode.Auth for a rejected key, else
+// the code of the error
+func checkExitCode(report *generator.CheckReport) int {
+	for _, check := range report.Checks {
+		if check.Status != generator.CheckFailed {
+			continue
+		}
+		var providerErr *generator.ProviderError
+		switch {
+		case check.Name == generator.CheckConfig:
+			return exitcode.Config
+		case check.Name == generator.CheckAPIKey && errors.As(check.Err, &providerErr):
+			// Gemini rejects a bad key with 400, which Classify cannot tell apart
+			return exitcode.Auth
+		case check.Name == generator.CheckAPIKey:
+			return exitcode.Config
+		}
+		return exitcode.Classify(check.Err)
+	}
+	return exitcode.OK
+}
@@ -0,0 +1,203 @@
+// Command commitgen-nvim is a Neovim plugin host for commit-gen. Neovim
+// starts it as an RPC job:
+//
+//	vim.fn.jobstart({ "commitgen-nvim" }, { rpc = true })
+//
+// It registers :CommitGen, :CommitGenShort, and :CommitGenRefine, which
+// write the generated message into the gitcommit buffer above git's
+// comment lines.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// setupLua registers the user commands; the channel id is passed as ...
+const setupLua = `
+local chan = ...
+local function run(mode)
+  return function(opts)
+    vim.rpcnotify(chan, "generate", mode, vim.api.nvim_get_current_buf(), opts.args)
+  end
+end
+vim.api.nvim_create_user_command("CommitGen", run("full"), { desc = "Generate a commit message" })
+vim.api.nvim_create_user_command("CommitGenShort", run("short"), { desc = "Generate a commit subject line" })
+vim.api.nvim_create_user_command("CommitGenRefine", run("refine"), {
+  nargs = "+",
+  desc = "Revise the commit message, e.g. :CommitGenRefine mention the migration",
+})
+`
+
+func main() {
+	// stdout is the RPC channel, so logs must go to stderr only
+	log.SetOutput(os.Stderr)
+	log.SetFlags(0)
+	log.SetPrefix("commitgen-nvim: ")
+
+	p := &plugin{repos: make(map[string]*generator.CommitGen)}
+	p.nvim = newRPCConn(os.Stdin, os.Stdout)
+	p.nvim.onNotify = p.handle
+	defer p.close()
+
+	go func() {
+		if err := p.register(); err != nil {
+			log.Printf("failed to register commands: %v", err)
+		}
+	}()
+	if err := p.nvim.serve(); err != nil && err != io.EOF {
+		log.Fatalf("RPC connection failed: %v", err)
+	}
+}
+
+// plugin serves the commands of one Neovim instance
+type plugin struct {
+	nvim *rpcConn
+
+	mu sync.Mutex
+	// repos caches a generator per working directory
+	repos map[string]*generator.CommitGen
+}
+
+// register defines the user commands, bound to this job's channel
+func (p *plugin) register() error {
+	info, err := p.nvim.call("nvim_get_api_info")
+	if err != nil {
+		return err
+	}
+	parts, ok := info.([]any)
+	if !ok || len(parts) == 0 {
+		return fmt.Errorf("unexpected nvim_get_api_info result %v", info)
+	}
+	_, err = p.nvim.call("nvim_exec_lua", setupLua, []any{parts[0]})
+	return err
+}
+
+// handle runs a command notification: generate(mode, buffer, args)
+func (p *plugin) handle(method string, args []any) {
+	if method != "generate" || len(args) < 3 {
+		return
+	}
+	mode, _ := args[0].(string)
+	revision, _ := args[2].(string)
+	if err := p.generate(mode, args[1], revision); err != nil {
+		p.echo(err.Error(), "ErrorMsg")
+	}
+}
+
+// generate writes a message into the buffer, replacing the text above
+// git's comment lines. That text is the hint, or the draft to revise in
+// refine mode.
+func (p *plugin) generate(mode string, buffer any, revision string) error {
+	p.echo("commit-gen: generating...", "")
+
+	cwd, err := p.nvim.call("nvim_call_function", "getcwd", []any{})
+	if err != nil {
+		return err
+	}
+	dir, _ := cwd.(string)
+	commitGen, err := p.commitGen(dir)
+	if err != nil {
+		return err
+	}
+
+	raw, err := p.nvim.call("nvim_buf_get_lines", buffer, 0, -1, false)
+	if err != nil {
+		return err
+	}
+	items, _ := raw.([]any)
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i], _ = item.(string)
+	}
+	draftLines := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			draftLines = i
+			break
+		}
+	}
+	draft := strings.TrimSpace(strings.Join(lines[:draftLines], "\n"))
+
+	gitInfo, err := commitGen.GetGitInfo()
+	if err != nil {
+		return fmt.Errorf("commit-gen: %w", err)
+	}
+	if mode == "refine" {
+		if draft == "" {
+			return fmt.Errorf("commit-gen: there is no message to refine yet, run :CommitGen first")
+		}
+		gitInfo.Draft = draft
+		gitInfo.Revision = revision
+	} else {
+		gitInfo.Hint = draft
+	}
+	short := mode == "short"
+	result, err := commitGen.GenerateWithConfig(context.Background(), gitInfo, &generator.GenConfig{IsShortCommit: &short})
+	if err != nil {
+		return fmt.Errorf("commit-gen: %w", err)
+	}
+
+	message := strings.Split(result.String(), "\n")
+	if draftLines < len(lines) {
+		// Keep a blank line before git's comments
+		message = append(message, "")
+	}
+	if _, err := p.nvim.call("nvim_buf_set_lines", buffer, 0, draftLines, false, message); err != nil {
+		return err
+	}
+	p.echo(fmt.Sprintf("commit-gen: message by %s", result.Model), "")
+	return nil
+}
+
+// commitGen returns the generator for the repository at dir, creating it once
+func (p *plugin) commitGen(dir string) (*generator.CommitGen, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if commitGen := p.repos[dir]; commitGen != nil {
+		return commitGen, nil
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("commit-gen: failed to load config: %w", err)
+	}
+	opts, err := cfg.Options(dir)
+	if err != nil {
+		return nil, fmt.Errorf("commit-gen: %w", err)
+	}
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		return nil, fmt.Errorf("commit-gen: failed to initialize commit generator: %w", err)
+	}
+	p.repos[dir] = commitGen
+	return commitGen, nil
+}
+
+// echo shows a message in Neovim's command line
+func (p *plugin) echo(message, highlight string) {
+	chunk := []any{message}
+	if highlight != "" {
+		chunk = append(chunk, highlight)
+	}
+	if _, err := p.nvim.call("nvim_echo", []any{chunk}, highlight != "", map[string]any{}); err != nil {
+		log.Printf("failed to echo: %v", err)
+	}
+}
+
+// close releases every generator
+func (p *plugin) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, commitGen := range p.repos {
+		commitGen.Close()
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// msgpackExt is a MessagePack extension value; Neovim sends buffer, window,
+// and tabpage handles as extension types 0, 1, and 2
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// encodeMsgpack appends the MessagePack encoding of v to buf. Only the
+// types the Neovim API needs are supported.
+func encodeMsgpack(buf []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int:
+		return encodeInt(buf, int64(v)), nil
+	case int64:
+		return encodeInt(buf, v), nil
+	case uint32:
+		return encodeInt(buf, int64(v)), nil
+	case float64:
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case string:
+		return append(encodeLength(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb), v...), nil
+	case []byte:
+		return append(encodeLength(buf, len(v), 0, 0, 0xc4, 0xc5, 0xc6), v...), nil
+	case []string:
+		buf = encodeLength(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, s := range v {
+			buf, _ = encodeMsgpack(buf, s)
+		}
+		return buf, nil
+	case []any:
+		buf = encodeLength(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		var err error
+		for _, item := range v {
+			if buf, err = encodeMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		buf = encodeLength(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		var err error
+		for key, item := range v {
+			buf, _ = encodeMsgpack(buf, key)
+			if buf, err = encodeMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case msgpackExt:
+		buf = encodeLength(buf, len(v.Data), 0, 0, 0xc7, 0xc8, 0xc9)
+		return append(append(buf, byte(v.Type)), v.Data...), nil
+	}
+	return nil, fmt.Errorf("msgpack: cannot encode %T", v)
+}
+
+func encodeInt(buf []byte, v int64) []byte {
+	if v >= -32 && v <= 127 {
+		return append(buf, byte(v))
+	}
+	buf = append(buf, 0xd3)
+	return binary.BigEndian.AppendUint64(buf, uint64(v))
+}
+
+// encodeLength writes a length header: the fix form (fix|n) when n < fixMax,
+// otherwise the 8, 16, or 32 bit form; a zero code skips that form
+func encodeLength(buf []byte, n int, fix byte, fixMax int, code8, code16, code32 byte) []byte {
+	switch {
+	case fixMax > 0 && n < fixMax:
+		return append(buf, fix|byte(n))
+	case code8 != 0 && n <= math.MaxUint8:
+		return append(buf, code8, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, code16), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(buf, code32), uint32(n))
+}
+
+// decodeMsgpack reads one value. Integers decode to int64, maps to
+// map[string]any, and extension values to msgpackExt.
+func decodeMsgpack(r *bufio.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return readString(r, int(code&0x1f))
+	case code&0xf0 == 0x90:
+		return readArray(r, int(code&0x0f))
+	case code&0xf0 == 0x80:
+		return readMap(r, int(code&0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readUint(r, 1<<(code-0xcc))
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		n, err := readUint(r, size)
+		// Sign-extend from the encoded width
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xca:
+		n, err := readUint(r, 4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := readUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := readUint(r, 1<<(code-0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return readString(r, int(n))
+	case 0xc4, 0xc5, 0xc6:
+		n, err := readUint(r, 1<<(code-0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return readBytes(r, int(n))
+	case 0xdc, 0xdd:
+		n, err := readUint(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return readArray(r, int(n))
+	case 0xde, 0xdf:
+		n, err := readUint(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+		return readMap(r, int(n))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return readExt(r, 1<<(code-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := readUint(r, 1<<(code-0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return readExt(r, int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unknown type code 0x%x", code)
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	b, err := readBytes(r, size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func readBytes(r *bufio.Reader, n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func readString(r *bufio.Reader, n int) (string, error) {
+	b, err := readBytes(r, n)
+	return string(b), err
+}
+
+func readArray(r *bufio.Reader, n int) ([]any, error) {
+	items := make([]any, n)
+	for i := range items {
+		item, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func readMap(r *bufio.Reader, n int) (map[string]any, error) {
+	m := make(map[string]any, n)
+	for range n {
+		key, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeMsgpack(r)
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(key)] = value
+	}
+	return m, nil
+}
+
+func readExt(r *bufio.Reader, n int) (msgpackExt, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return msgpackExt{}, err
+	}
+	data, err := readBytes(r, n)
+	return msgpackExt{Type: int8(t), Data: data}, err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MessagePack-RPC message types
+const (
+	rpcRequest      = 0
+	rpcResponse     = 1
+	rpcNotification = 2
+)
+
+// rpcResult is the outcome of a call
+type rpcResult struct {
+	value any
+	err   error
+}
+
+// rpcConn is a MessagePack-RPC connection to Neovim. Calls may be made
+// from any goroutine while serve reads the connection.
+type rpcConn struct {
+	r *bufio.Reader
+
+	writeMu sync.Mutex
+	w       io.Writer
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcResult
+
+	// onNotify handles notifications in their own goroutine, so handlers
+	// can call back into Neovim
+	onNotify func(method string, args []any)
+}
+
+func newRPCConn(r io.Reader, w io.Writer) *rpcConn {
+	return &rpcConn{
+		r:       bufio.NewReader(r),
+		w:       w,
+		pending: make(map[int64]chan rpcResult),
+	}
+}
+
+// call invokes a Neovim API method and waits for its result
+func (c *rpcConn) call(method string, args ...any) (any, error) {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	done := make(chan rpcResult, 1)
+	c.pending[id] = done
+	c.mu.Unlock()
+
+	if args == nil {
+		args = []any{}
+	}
+	if err := c.send([]any{rpcRequest, id, method, args}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, err
+	}
+	result := <-done
+	return result.value, result.err
+}
+
+// send writes one message
+func (c *rpcConn) send(msg []any) error {
+	buf, err := encodeMsgpack(nil, msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.w.Write(buf)
+	return err
+}
+
+// serve reads messages until the connection closes
+func (c *rpcConn) serve() error {
+	defer c.failPending()
+	for {
+		value, err := decodeMsgpack(c.r)
+		if err != nil {
+			return err
+		}
+		msg, ok := value.([]any)
+		if !ok || len(msg) < 3 {
+			return fmt.Errorf("invalid RPC message %v", value)
+		}
+
+		kind, _ := msg[0].(int64)
+		switch {
+		case kind == rpcResponse && len(msg) == 4:
+			c.resolve(msg[1], msg[2], msg[3])
+		case kind == rpcNotification:
+			method, _ := msg[1].(string)
+			args, _ := msg[2].([]any)
+			if c.onNotify != nil {
+				go c.onNotify(method, args)
+			}
+		case kind == rpcRequest && len(msg) == 4:
+			// No request handlers are registered, every command is a notification
+			method, _ := msg[2].(string)
+			c.send([]any{rpcResponse, msg[1], "unknown method " + method, nil})
+		}
+	}
+}
+
+// resolve delivers a response to the waiting call
+func (c *rpcConn) resolve(rawID, rpcErr, value any) {
+	id, _ := rawID.(int64)
+	c.mu.Lock()
+	done := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+	if done == nil {
+		return
+	}
+
+	if rpcErr != nil {
+		// Neovim errors are [type, message]
+		if parts, ok := rpcErr.([]any); ok && len(parts) == 2 {
+			rpcErr = parts[1]
+		}
+		done <- rpcResult{err: fmt.Errorf("nvim: %v", rpcErr)}
+		return
+	}
+	done <- rpcResult{value: value}
+}
+
+// failPending unblocks every waiting call once the connection is gone
+func (c *rpcConn) failPending() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, done := range c.pending {
+		done <- rpcResult{err: io.ErrClosedPipe}
+		delete(c.pending, id)
+	}
+}
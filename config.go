@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+)
+
+// runConfig inspects and checks the config files
+func runConfig(args []string) {
+	if len(args) == 0 {
+		fail(exitcode.Usage, "Usage: commit-gen config get|set|unset|validate|show|schema")
+	}
+	switch args[0] {
+	case "get":
+		runConfigGet(args[1:])
+	case "set":
+		runConfigSet(args[1:])
+	case "unset":
+		runConfigUnset(args[1:])
+	case "validate":
+		runConfigValidate(args[1:])
+	case "show":
+		runConfigShow(args[1:])
+	case "schema":
+		runConfigSchema(args[1:])
+	default:
+		fail(exitcode.Usage, fmt.Sprintf("Unknown config command %q, use get, set, unset, validate, show, or schema", args[0]))
+	}
+}
+
+// configScope holds the -global and -local flags that pick the file a
+// config command reads or edits, like git config
+type configScope struct {
+	global *bool
+	local  *bool
+}
+
+// registerScopeFlags adds -global and -local to fs
+func registerScopeFlags(fs *flag.FlagSet) configScope {
+	return configScope{
+		global: fs.Bool("global", false, "Use the user-wide config file"),
+		local:  fs.Bool("local", false, "Use the repository config file, "+config.RepoFileName),
+	}
+}
+
+// path returns the file the flags pick, the repository one by default,
+// or "" for none when neither flag is set and fallback is false
+func (s configScope) path(fallback bool) string {
+	if *s.global && *s.local {
+		fail(exitcode.Usage, "-global and -local cannot be combined")
+	}
+	if *s.global {
+		path, err := config.GlobalPath()
+		if err != nil {
+			fail(exitcode.Config, err.Error())
+		}
+		return path
+	}
+	if !*s.local && !fallback {
+		return ""
+	}
+	root, err := config.RepoRoot("")
+	if err != nil {
+		fail(exitcode.Usage, fmt.Sprintf("%v, use -global for the user-wide config", err))
+	}
+	return filepath.Join(root, config.RepoFileName)
+}
+
+// runConfigGet prints the value of a setting: the one in effect, or with
+// -global or -local the one in that file. It exits with code 1, printing
+// nothing, when the setting is not set.
+func runConfigGet(args []string) {
+	fs := flag.NewFlagSet("commit-gen config get", flag.ExitOnError)
+	scope := registerScopeFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fail(exitcode.Usage, "Usage: commit-gen config get [-global | -local] <key>")
+	}
+	key := fs.Arg(0)
+
+	var value any
+	var ok bool
+	var err error
+	if path := scope.path(false); path != "" {
+		value, ok, err = config.Get(path, key)
+	} else {
+		var cfg *config.Config
+		if cfg, err = config.Load(""); err == nil {
+			value, ok, err = cfg.Lookup(key)
+		}
+	}
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	if !ok {
+		exit(exitcode.Failure)
+	}
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			fmt.Println(item)
+		}
+	case map[string]any:
+		encoder := toml.NewEncoder(os.Stdout)
+		encoder.Indent = ""
+		encoder.Encode(v)
+	default:
+		fmt.Println(v)
+	}
+}
+
+// runConfigSet sets a setting in the repository config, or with -global in
+// the user-wide one. List settings take every remaining argument.
+func runConfigSet(args []string) {
+	fs := flag.NewFlagSet("commit-gen config set", flag.ExitOnError)
+	scope := registerScopeFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fail(exitcode.Usage, "Usage: commit-gen config set [-global | -local] <key> <value>...")
+	}
+	if err := config.Set(scope.path(true), fs.Arg(0), fs.Args()[1:]); err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+}
+
+// runConfigUnset removes a setting from the repository config, or with
+// -global from the user-wide one
+func runConfigUnset(args []string) {
+	fs := flag.NewFlagSet("commit-gen config unset", flag.ExitOnError)
+	scope := registerScopeFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fail(exitcode.Usage, "Usage: commit-gen config unset [-global | -local] <key>")
+	}
+	if err := config.Unset(scope.path(true), fs.Arg(0)); err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+}
+
+// runConfigValidate checks config files against the schema and reports
+// every problem, by default in the files commit-gen would load
+func runConfigValidate(args []string) {
+	fs := flag.NewFlagSet("commit-gen config validate", flag.ExitOnError)
+	fs.Parse(args)
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = configFiles()
+		if len(paths) == 0 {
+			fmt.Println("No config files, nothing to validate.")
+			return
+		}
+	}
+
+	valid := true
+	for _, path := range paths {
+		err := config.ValidateFile(path)
+		var invalid *config.ValidationError
+		switch {
+		case errors.As(err, &invalid):
+			valid = false
+			for _, problem := range invalid.Problems {
+				fmt.Printf("%s: %s\n", path, problem.Message)
+			}
+		case err != nil:
+			valid = false
+			fmt.Printf("%s: %v\n", path, err)
+		default:
+			fmt.Printf("%s: ok\n", path)
+		}
+	}
+	// The shared config, if any, is only known once the files are merged
+	if valid && fs.NArg() == 0 {
+		if _, err := config.Load(""); err != nil {
+			fail(exitcode.Config, err.Error())
+		}
+	}
+	if !valid {
+		exit(exitcode.Config)
+	}
+}
+
+// runConfigShow prints the config files, or with -effective the settings
+// they add up to
+func runConfigShow(args []string) {
+	fs := flag.NewFlagSet("commit-gen config show", flag.ExitOnError)
+	effective := fs.Bool("effective", false, "Print the merged settings of all layers, shared config included, instead of each file")
+	fs.Parse(args)
+
+	if !*effective {
+		for i, path := range configFiles() {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				fatalf("Failed to read config: %v", err)
+			}
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("# %s\n%s", path, data)
+		}
+		return
+	}
+
+	cfg, err := config.Load("")
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to load config: %v", err))
+	}
+	values, err := cfg.Values()
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	encoder := toml.NewEncoder(os.Stdout)
+	encoder.Indent = ""
+	if err := encoder.Encode(values); err != nil {
+		fatalf("Failed to print config: %v", err)
+	}
+}
+
+// runConfigSchema prints the JSON Schema of the config file
+func runConfigSchema(args []string) {
+	fs := flag.NewFlagSet("commit-gen config schema", flag.ExitOnError)
+	fs.Parse(args)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(config.Schema())
+}
+
+// configFiles returns the config files commit-gen loads that exist, the
+// global one first
+func configFiles() []string {
+	var paths []string
+	if global, err := config.GlobalPath(); err == nil {
+		paths = append(paths, global)
+	}
+	paths = append(paths, config.RepoPath(""))
+
+	var existing []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+			existing = append(existing, path)
+		}
+	}
+	return existing
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// canAsk reports whether a person can answer a question on the terminal
+func canAsk() bool {
+	return !jsonErrors && porcelain == "" && isTerminal(os.Stdin) && isTerminal(os.Stderr)
+}
+
+// stdin reads the answers to questions. It is shared, so that input typed
+// ahead for a later question is not lost with the reader of an earlier one.
+var stdin = bufio.NewReader(os.Stdin)
+
+// askYes asks a yes/no question on the terminal; anything but yes is no
+func askYes(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := stdin.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// formatCommands lists commands one per line, indented
+func formatCommands(commands []generator.Command) string {
+	var b strings.Builder
+	for _, command := range commands {
+		fmt.Fprintf(&b, "  %s\n", command)
+	}
+	return b.String()
+}
+
+// confirmCommands shows the commands about to change the repository and
+// asks whether to run them, after intro if there is one. Without a
+// terminal to ask, the answer is no.
+func confirmCommands(intro string, commands []generator.Command) bool {
+	if !canAsk() {
+		return false
+	}
+	if intro != "" {
+		fmt.Fprintln(os.Stderr, intro)
+	}
+	fmt.Fprintf(os.Stderr, "About to run:\n%s", formatCommands(commands))
+	return askYes("Run these commands?")
+}
+
+// requireConfirmation exits unless the user agrees to the commands about
+// to change the repository, or yes (from -yes) agrees for them. Without a
+// terminal to ask, -yes is required, so that a misconfigured editor
+// keybinding or script never changes the repository by surprise.
+func requireConfirmation(commands []generator.Command, yes bool) {
+	if yes {
+		return
+	}
+	if !canAsk() {
+		fail(exitcode.Usage, "Not changing the repository without confirmation; pass -yes to run:\n"+strings.TrimSuffix(formatCommands(commands), "\n"))
+	}
+	if !confirmCommands("", commands) {
+		fail(exitcode.Interrupted, "Cancelled, the repository was not changed")
+	}
+}
@@ -0,0 +1,131 @@
+;;; commit-gen.el --- Generate commit messages with commit-gen -*- lexical-binding: t; -*-
+
+;; URL: https://github.com/nguyenanhhao221/commit-gen
+;; Package-Requires: ((emacs "28.1") (transient "0.4"))
+
+;;; Commentary:
+
+;; Generates a commit message for the staged changes into the Magit
+;; (git-commit) message buffer.  Text already typed above the comment
+;; lines is sent to commit-gen as a hint and replaced by the result.
+;;
+;;   (with-eval-after-load 'git-commit
+;;     (require 'commit-gen)
+;;     (define-key git-commit-mode-map (kbd "C-c C-g") #'commit-gen))
+;;
+;; `commit-gen' is a transient for picking options; `commit-gen-insert'
+;; generates right away.  commit-gen runs with -emacs, so it prints the
+;; result, or the error, as a plist that `read' can parse.
+
+;;; Code:
+
+(require 'subr-x)
+(require 'transient)
+
+(declare-function magit-toplevel "magit-git")
+
+(defgroup commit-gen nil
+  "Generate commit messages with commit-gen."
+  :group 'tools)
+
+(defcustom commit-gen-executable "commit-gen"
+  "The commit-gen program."
+  :type 'string)
+
+(defcustom commit-gen-arguments nil
+  "Extra arguments for every run, e.g. (\"-provider\" \"ollama\")."
+  :type '(repeat string))
+
+(defun commit-gen--draft-end ()
+  "Return the position where git's comment lines start."
+  (save-excursion
+    (goto-char (point-min))
+    (if (re-search-forward
+         (concat "^" (regexp-quote (string-trim (or comment-start "#"))))
+         nil t)
+        (line-beginning-position)
+      (point-max))))
+
+(defun commit-gen--directory ()
+  "Return the work tree to run in; the message buffer lives in .git."
+  (or (and (fboundp 'magit-toplevel) (magit-toplevel))
+      default-directory))
+
+(defun commit-gen--read (buffer)
+  "Read the plist printed into BUFFER, or nil when there is none."
+  (with-current-buffer buffer
+    (goto-char (point-min))
+    (ignore-errors (read (current-buffer)))))
+
+;;;###autoload
+(defun commit-gen-insert (&optional args)
+  "Generate a commit message into the current buffer.
+ARGS are extra commit-gen arguments, e.g. from the `commit-gen' transient."
+  (interactive (list (transient-args 'commit-gen)))
+  (let* ((target (current-buffer))
+         (draft (string-trim
+                 (buffer-substring-no-properties (point-min) (commit-gen--draft-end))))
+         (default-directory (commit-gen--directory))
+         (stdout (generate-new-buffer " *commit-gen*"))
+         (stderr (generate-new-buffer " *commit-gen-errors*"))
+         ;; The buffer already shows commit.template, so it becomes the hint
+         (command `(,commit-gen-executable "-emacs" "-no-template"
+                    ,@(unless (string-empty-p draft) (list "-hint" draft))
+                    ,@commit-gen-arguments ,@args)))
+    (message "commit-gen: generating...")
+    (make-process
+     :name "commit-gen"
+     :buffer stdout
+     :stderr stderr
+     :command command
+     :connection-type 'pipe
+     :noquery t
+     :sentinel
+     (lambda (process _event)
+       (unless (process-live-p process)
+         (unwind-protect
+             (if (= (process-exit-status process) 0)
+                 (commit-gen--insert target (commit-gen--read stdout))
+               (let ((pipe (get-buffer-process stderr)))
+                 ;; The error may still be on its way through the stderr pipe
+                 (while (and pipe (accept-process-output pipe 0.1))))
+               (let ((err (commit-gen--read stderr)))
+                 (message "commit-gen: %s"
+                          (if (and (consp err) (keywordp (car err)))
+                              (plist-get err :message)
+                            (string-trim (with-current-buffer stderr (buffer-string)))))))
+           (kill-buffer stdout)
+           (kill-buffer stderr)))))))
+
+(defun commit-gen--insert (buffer result)
+  "Replace the message above the comment lines of BUFFER with RESULT."
+  (when (buffer-live-p buffer)
+    (with-current-buffer buffer
+      (let* ((end (commit-gen--draft-end))
+             (comments (< end (point-max))))
+        (save-excursion
+          (delete-region (point-min) end)
+          (goto-char (point-min))
+          (insert (plist-get result :message))
+          ;; Keep a blank line before git's comments
+          (when comments
+            (insert "\n\n")))
+        (goto-char (point-min))
+        (message "commit-gen: message by %s" (plist-get result :model))))))
+
+;;;###autoload (autoload 'commit-gen "commit-gen" nil t)
+(transient-define-prefix commit-gen ()
+  "Generate a commit message for the staged changes."
+  ["Arguments"
+   ("-s" "Subject line only" "-short")
+   ("-b" "Show blame context" "-blame")
+   ("-r" "Show related files" "-related")
+   ("-t" "Pin the type" "-type=")
+   ("-c" "Pin the scope" "-scope=")
+   ("-p" "Provider" "-provider=" :choices ("gemini" "ollama" "relay"))]
+  ["Generate"
+   ("g" "Into the message buffer" commit-gen-insert)])
+
+(provide 'commit-gen)
+
+;;; commit-gen.el ends here
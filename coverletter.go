@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// Placeholders git format-patch --cover-letter leaves in the cover letter
+const (
+	coverSubjectPlaceholder = "*** SUBJECT HERE ***"
+	coverBlurbPlaceholder   = "*** BLURB HERE ***"
+)
+
+// runCoverLetter writes the cover letter of the patch series on the current branch
+func runCoverLetter(args []string) {
+	fs := flag.NewFlagSet("commit-gen cover-letter", flag.ExitOnError)
+	base := fs.String("base", "@{upstream}", "Revision the series is based on")
+	patch := fs.String("patch", "", "Fill in a 0000-cover-letter.patch from git format-patch --cover-letter")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fatalf("Failed to initialize commit generator: %v", err)
+	}
+	defer commitGen.Close()
+
+	result, err := commitGen.CoverLetter(context.Background(), *base)
+	if err != nil {
+		failErr(err, "Failed to write cover letter")
+	}
+
+	if *patch == "" {
+		fmt.Println(result.Message)
+		return
+	}
+
+	data, err := os.ReadFile(*patch)
+	if err != nil {
+		fatalf("Failed to read cover letter: %v", err)
+	}
+	text := string(data)
+	if !strings.Contains(text, coverSubjectPlaceholder) || !strings.Contains(text, coverBlurbPlaceholder) {
+		fatalf("%s has no %s/%s placeholders to fill in", *patch, coverSubjectPlaceholder, coverBlurbPlaceholder)
+	}
+	text = strings.Replace(text, coverSubjectPlaceholder, result.Message.Header, 1)
+	text = strings.Replace(text, coverBlurbPlaceholder, result.Message.Body, 1)
+	if err := atomicfile.WriteFile(*patch, []byte(text), 0o644); err != nil {
+		fatalf("Failed to write cover letter: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// slackHeaderMaxLen is the longest text Slack accepts in a header block
+const slackHeaderMaxLen = 150
+
+// runDigest writes a team digest of recent commits, grouped by author and
+// area, for a scheduled CI job or cron to print or post to a chat
+func runDigest(args []string) {
+	fs := flag.NewFlagSet("commit-gen digest", flag.ExitOnError)
+	since := fs.String("since", "1 week ago", "Git date to start from")
+	output := fs.String("output", "markdown", "Output format: markdown, slack (Block Kit JSON), or json")
+	post := fs.String("post", "", "Post the digest to this Slack or Teams incoming webhook URL instead of printing it")
+	var repos listFlag
+	fs.Var(&repos, "repo", "Repository to include (repeatable; default: repos in the config, or the current one)")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	var render func(*generator.Digest) any
+	switch *output {
+	case "markdown":
+		render = func(d *generator.Digest) any { return map[string]string{"text": renderDigestMarkdown(d)} }
+	case "slack":
+		render = func(d *generator.Digest) any { return renderDigestSlack(d) }
+	case "json":
+		jsonErrors = true
+		render = func(d *generator.Digest) any { return d }
+	default:
+		fail(exitcode.Usage, fmt.Sprintf("Unknown -output %q, want markdown, slack, or json", *output))
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	digest, err := commitGen.Digest(context.Background(), &generator.DigestOptions{
+		Repos: repos,
+		Since: *since,
+	})
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges(fmt.Sprintf("No commits since %s.", *since))
+	}
+	if err != nil {
+		failErr(err, "Failed to write the digest")
+	}
+
+	if *post != "" {
+		if err := postWebhook(*post, render(digest)); err != nil {
+			fatalf("Failed to post the digest: %v", err)
+		}
+		warnf("Posted the digest of %d commits", digest.Commits)
+		return
+	}
+	if *output == "markdown" {
+		fmt.Print(renderDigestMarkdown(digest))
+		return
+	}
+	printResponse(render(digest))
+}
+
+// renderDigestMarkdown writes a digest as a markdown section per author
+func renderDigestMarkdown(digest *generator.Digest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Team digest since %s\n\n", digest.Since)
+	if digest.Headline != "" {
+		fmt.Fprintf(&b, "%s\n\n", digest.Headline)
+	}
+	for _, author := range digest.Authors {
+		fmt.Fprintf(&b, "### %s (%s)\n\n", author.Name, plural(author.Commits, "commit"))
+		for _, area := range author.Areas {
+			fmt.Fprintf(&b, "- **%s**: %s\n", area.Area, area.Summary)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "_%s by %s_\n", plural(digest.Commits, "commit"), plural(len(digest.Authors), "author"))
+	return b.String()
+}
+
+// slackBlock is a Slack Block Kit block; only the fields the digest uses
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []*slackText `json:"elements,omitempty"`
+}
+
+// slackText is a Block Kit text object
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackMessage is an incoming webhook payload; text is the notification
+// fallback for clients that do not show blocks
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// renderDigestSlack writes a digest as Block Kit blocks: a header, a
+// section per author, and a footer
+func renderDigestSlack(digest *generator.Digest) *slackMessage {
+	title := "Team digest since " + digest.Since
+	msg := &slackMessage{Text: title}
+	if digest.Headline != "" {
+		msg.Text = digest.Headline
+	}
+
+	msg.Blocks = append(msg.Blocks, slackBlock{
+		Type: "header",
+		Text: &slackText{Type: "plain_text", Text: truncateRunes(title, slackHeaderMaxLen)},
+	})
+	if digest.Headline != "" {
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: slackEscape(digest.Headline)},
+		})
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "divider"})
+	for _, author := range digest.Authors {
+		var b strings.Builder
+		fmt.Fprintf(&b, "*%s* (%s)", slackEscape(author.Name), plural(author.Commits, "commit"))
+		for _, area := range author.Areas {
+			fmt.Fprintf(&b, "\n• *%s*: %s", slackEscape(area.Area), slackEscape(area.Summary))
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: b.String()},
+		})
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{
+		Type: "context",
+		Elements: []*slackText{{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("%s by %s · %s", plural(digest.Commits, "commit"), plural(len(digest.Authors), "author"), digest.Model),
+		}},
+	})
+	return msg
+}
+
+// slackEscape escapes the characters Slack treats as markup
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+
+// truncateRunes cuts s to at most n runes, ending with an ellipsis when cut
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// plural returns "1 commit" or "3 commits"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// postWebhook posts payload as JSON to an incoming webhook
+func postWebhook(url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
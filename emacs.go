@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// emacsOutput prints results and errors as Emacs Lisp plists instead of JSON
+var emacsOutput bool
+
+// printResponse prints v on stdout as JSON, or as a plist with -emacs
+func printResponse(v any) {
+	if emacsOutput {
+		writeSexp(os.Stdout, v)
+		return
+	}
+	json.NewEncoder(os.Stdout).Encode(v)
+}
+
+// writeSexp writes v, which must encode to JSON, as one line of Emacs Lisp
+// that read can parse: objects become plists with :kebab-case keys, arrays
+// become lists, null and false become nil, and true becomes t
+func writeSexp(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var b strings.Builder
+	if err := sexpValue(&b, dec); err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// sexpValue converts the next JSON value of dec, keeping the field order
+func sexpValue(b *strings.Builder, dec *json.Decoder) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case json.Delim:
+		isObject := t == '{'
+		b.WriteByte('(')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if isObject {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(b, ":%s ", strings.ReplaceAll(fmt.Sprint(key), "_", "-"))
+			}
+			if err := sexpValue(b, dec); err != nil {
+				return err
+			}
+		}
+		// The closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		b.WriteByte(')')
+	case string:
+		b.WriteString(sexpString(t))
+	case json.Number:
+		b.WriteString(t.String())
+	case bool:
+		if t {
+			b.WriteString("t")
+		} else {
+			b.WriteString("nil")
+		}
+	case nil:
+		b.WriteString("nil")
+	}
+	return nil
+}
+
+// sexpString quotes s as an Emacs Lisp string; newlines may stay literal
+func sexpString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// feedbackTimeout bounds sending feedback in passing, after a generation
+const feedbackTimeout = 3 * time.Second
+
+// runFeedback prints the feedback signals waiting to be sent, exactly as
+// they would be sent, and with -send sends them
+func runFeedback(args []string) {
+	fs := flag.NewFlagSet("commit-gen feedback", flag.ExitOnError)
+	send := fs.Bool("send", false, "Send the pending signals now")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	if !*send {
+		// Showing what would be sent needs no opt-in and no provider
+		signals, err := gitRepository().PendingFeedback()
+		if err != nil {
+			fatalf("Failed to read the feedback: %v", err)
+		}
+		if !opts.Feedback {
+			warnf("Sending feedback is off; opt in with feedback.enabled = true in the user-wide config")
+		}
+		printResponse(&generator.FeedbackBatch{Signals: signals})
+		return
+	}
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	sent, err := commitGen.SendFeedback(context.Background())
+	if errors.Is(err, generator.ErrFeedbackDisabled) {
+		fail(exitcode.Usage, "Sending feedback is off; opt in with feedback.enabled = true in the user-wide config")
+	}
+	if err != nil {
+		failErr(err, "Failed to send feedback")
+	}
+	fmt.Fprintf(os.Stderr, "Sent %s to %s\n", plural(sent, "signal"), commitGen.FeedbackURL())
+}
+
+// sendFeedback sends the pending feedback in passing when the user opted
+// in. Failures are ignored: the signals stay pending for the next run or
+// for commit-gen feedback -send to report.
+func sendFeedback(commitGen *generator.CommitGen) {
+	ctx, cancel := context.WithTimeout(context.Background(), feedbackTimeout)
+	defer cancel()
+	commitGen.SendFeedback(ctx)
+}
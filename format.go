@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// formatFields are what -format templates can use: the parsed message
+// fields, e.g. {{.Type}} or {{.Subject}}, and a few result details
+type formatFields struct {
+	generator.CommitMessage
+	// Message is the full commit message
+	Message string
+	Model   string
+	Tokens  generator.Usage
+	Cached  bool
+}
+
+func newFormatFields(result *generator.Result) *formatFields {
+	return &formatFields{
+		CommitMessage: result.Message,
+		Message:       result.String(),
+		Model:         result.Model,
+		Tokens:        result.Tokens,
+		Cached:        result.Cached,
+	}
+}
+
+// parseFormat parses a -format template. It is tried on an empty result
+// right away, so a misspelled field fails before the model is asked.
+func parseFormat(text string) *template.Template {
+	tmpl, err := template.New("format").Parse(text)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, &formatFields{})
+	}
+	if err != nil {
+		fail(exitcode.Usage, fmt.Sprintf("Invalid -format template: %v", err))
+	}
+	return tmpl
+}
+
+// printFormatted prints result through the -format template, ending
+// with a newline like git log --format does
+func printFormatted(tmpl *template.Template, result *generator.Result) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, newFormatFields(result)); err != nil {
+		fail(exitcode.Usage, fmt.Sprintf("Invalid -format template: %v", err))
+	}
+	output := b.String()
+	if !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+	fmt.Print(output)
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+const (
+	// githubWebhookPath receives GitHub webhook deliveries
+	githubWebhookPath = "/github/webhook"
+	// maxWebhookBytes bounds a webhook delivery; GitHub caps them at 25 MB
+	maxWebhookBytes = 25 << 20
+	// maxPullRequestDiffBytes bounds the diff fetched for a pull request
+	maxPullRequestDiffBytes = 10 << 20
+	// pullRequestTimeout bounds the work on one pull request
+	pullRequestTimeout = 5 * time.Minute
+)
+
+// githubClient calls the GitHub REST API
+type githubClient struct {
+	// apiURL is https://api.github.com or a GitHub Enterprise /api/v3 URL
+	apiURL string
+	token  string
+	client *http.Client
+}
+
+// githubBot comments a summary and a squash-merge message on pull requests
+// as they are opened, answering GitHub webhooks
+type githubBot struct {
+	*githubClient
+	commitGen *generator.CommitGen
+	secret    []byte
+}
+
+// pullRequestEvent is the part of a pull_request webhook the bot reads
+type pullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		Draft bool   `json:"draft"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// githubCommit is an entry of the pull request commits API
+type githubCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// ServeHTTP handles a webhook delivery. Pull requests are worked on in the
+// background, since GitHub gives up on a delivery after 10 seconds.
+func (b *githubBot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		http.Error(w, "failed to read the delivery", http.StatusBadRequest)
+		return
+	}
+	if !b.validSignature(payload, r.Header.Get("X-Hub-Signature-256")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case "pull_request":
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var event pullRequestEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Drafts are summarized once they are ready for review
+	opened := event.Action == "opened" && !event.PullRequest.Draft
+	if !opened && event.Action != "ready_for_review" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), pullRequestTimeout)
+		defer cancel()
+		if err := b.commentOn(ctx, &event); err != nil {
+			log.Printf("Pull request %s#%d: %v", event.Repository.FullName, event.Number, err)
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks the HMAC-SHA256 that GitHub signs deliveries with
+func (b *githubBot) validSignature(payload []byte, signature string) bool {
+	digest, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write(payload)
+	return hmac.Equal(digest, mac.Sum(nil))
+}
+
+// commentOn fetches the pull request of event and comments on it
+func (b *githubBot) commentOn(ctx context.Context, event *pullRequestEvent) error {
+	base := fmt.Sprintf("%s/repos/%s", b.apiURL, event.Repository.FullName)
+	pr := &generator.PullRequest{Title: event.PullRequest.Title, Body: event.PullRequest.Body}
+
+	diff, err := b.get(ctx, fmt.Sprintf("%s/pulls/%d", base, event.Number), "application/vnd.github.diff", maxPullRequestDiffBytes)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the diff: %w", err)
+	}
+	pr.Diff = string(diff)
+
+	data, err := b.get(ctx, fmt.Sprintf("%s/pulls/%d/commits?per_page=100", base, event.Number), "application/vnd.github+json", maxWebhookBytes)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the commits: %w", err)
+	}
+	var commits []githubCommit
+	if err := json.Unmarshal(data, &commits); err != nil {
+		return fmt.Errorf("failed to decode the commits: %w", err)
+	}
+	pr.Commits = toCommits(commits)
+
+	summary, err := b.commitGen.SummarizePullRequest(ctx, pr)
+	if errors.Is(err, generator.ErrNoChanges) || errors.Is(err, generator.ErrDisabled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return b.post(ctx, fmt.Sprintf("%s/issues/%d/comments", base, event.Number), map[string]string{
+		"body": pullRequestComment(summary),
+	})
+}
+
+// compareBranch describes branch of repo ("owner/name") as a pull request
+// into the default branch would
+func (c *githubClient) compareBranch(ctx context.Context, repo, branch string) (*generator.PullRequest, error) {
+	base := fmt.Sprintf("%s/repos/%s", c.apiURL, repo)
+	data, err := c.get(ctx, base, "application/vnd.github+json", 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", repo, err)
+	}
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.Unmarshal(data, &repository); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", repo, err)
+	}
+
+	compare := fmt.Sprintf("%s/compare/%s...%s", base, escapeRef(repository.DefaultBranch), escapeRef(branch))
+	diff, err := c.get(ctx, compare, "application/vnd.github.diff", maxPullRequestDiffBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", branch, repository.DefaultBranch, err)
+	}
+	data, err = c.get(ctx, compare+"?per_page=100", "application/vnd.github+json", maxWebhookBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare %s with %s: %w", branch, repository.DefaultBranch, err)
+	}
+	var comparison struct {
+		Commits []githubCommit `json:"commits"`
+	}
+	if err := json.Unmarshal(data, &comparison); err != nil {
+		return nil, fmt.Errorf("failed to decode the comparison: %w", err)
+	}
+	return &generator.PullRequest{Title: branch, Commits: toCommits(comparison.Commits), Diff: string(diff)}, nil
+}
+
+// escapeRef escapes a branch name for a URL path, keeping its slashes
+func escapeRef(ref string) string {
+	return strings.ReplaceAll(url.PathEscape(ref), "%2F", "/")
+}
+
+// toCommits converts commits of the GitHub API
+func toCommits(commits []githubCommit) []generator.Commit {
+	var converted []generator.Commit
+	for _, c := range commits {
+		converted = append(converted, generator.Commit{
+			Hash:        c.SHA,
+			AuthorName:  c.Commit.Author.Name,
+			AuthorEmail: c.Commit.Author.Email,
+			Date:        c.Commit.Author.Date,
+			Message:     strings.TrimSpace(c.Commit.Message),
+		})
+	}
+	return converted
+}
+
+// pullRequestComment renders the comment posted on a pull request
+func pullRequestComment(summary *generator.PullRequestSummary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Summary\n\n%s\n\n", summary.Summary.Text)
+	fmt.Fprintf(&b, "### Suggested squash commit\n\n```text\n%s\n```\n\n", summary.SquashMessage)
+	fmt.Fprintf(&b, "<sub>Written by commit-gen with %s</sub>\n", summary.SquashMessage.Model)
+	return b.String()
+}
+
+// get reads a GitHub API resource in the given media type
+func (c *githubClient) get(ctx context.Context, url, accept string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	return c.do(req, limit)
+}
+
+// post sends payload to a GitHub API endpoint
+func (c *githubClient) post(ctx context.Context, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	_, err = c.do(req, 1<<20)
+	return err
+}
+
+// do authenticates and sends req, returning the response body
+func (c *githubClient) do(req *http.Request, limit int64) ([]byte, error) {
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GitHub returned %s: %s", resp.Status, strings.TrimSpace(string(body[:min(len(body), 512)])))
+	}
+	return body, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+const (
+	// hookMarker identifies hook scripts written by "commit-gen hook install"
+	hookMarker = "# Installed by commit-gen"
+	// fillNotice tops the commit message file while an async hook works
+	// on a suggestion, telling the user to reload it
+	fillNotice = "# commit-gen is writing a suggestion. Reload this file in a few seconds (:e in Vim)."
+)
+
+// runHook installs the prepare-commit-msg hook, or runs it as
+// "commit-gen hook run <message-file> [source [commit]]"
+func runHook(args []string) {
+	if len(args) > 0 && args[0] == "install" {
+		runHookInstall(args[1:])
+		return
+	}
+	if len(args) == 0 || args[0] != "run" {
+		fail(exitcode.Usage, "Usage: commit-gen hook install [-force] | commit-gen hook run <message-file> [source [commit]]")
+	}
+
+	fs := flag.NewFlagSet("commit-gen hook run", flag.ExitOnError)
+	shortCommit := fs.Bool("short", false, "Generate a short commit title")
+	timeout := fs.Duration("timeout", 0, "Give up on a suggestion after this long (default: [hook] timeout, or 3s)")
+	async := fs.Bool("async", false, "Open the editor right away and fill in the suggestion in the background")
+	fill := fs.Bool("fill", false, "Run as the background half of -async (internal)")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args[1:])
+
+	path, source := fs.Arg(0), fs.Arg(1)
+	if path == "" {
+		fail(exitcode.Usage, "commit-gen hook run needs the commit message file")
+	}
+	// A hook must never stand in the way of a commit: with the kill switch
+	// on, or when the message comes from -m, -F, -c, --amend, or a squash,
+	// it leaves the message alone and succeeds
+	if *providers.noAI || generator.Disabled() {
+		exit(exitcode.OK)
+	}
+	switch source {
+	case "", "template", "merge":
+	default:
+		exit(exitcode.OK)
+	}
+
+	opts := loadHookOptions()
+	opts.IsShortCommit = *shortCommit
+	providers.apply(opts)
+	if *timeout > 0 {
+		opts.HookTimeout = *timeout
+	}
+	if opts.HookTimeout <= 0 {
+		opts.HookTimeout = generator.DefaultHookTimeout
+	}
+	if (*async || opts.HookAsync) && !*fill {
+		startFill(args[1:], path)
+		return
+	}
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		skipHook("Failed to initialize commit generator: %v", err)
+	}
+	defer commitGen.Close()
+
+	if *fill {
+		fillHook(commitGen, path)
+		return
+	}
+
+	// Ctrl-C cancels the request; on timeout the hook stops waiting rather
+	// than stopping it, and exiting abandons the request
+	ctx, stop := interruptible()
+	defer stop()
+	type generated struct {
+		result *generator.Result
+		err    error
+	}
+	done := make(chan generated, 1)
+	go func() {
+		result, err := commitGen.GenerateContext(ctx)
+		done <- generated{result, err}
+	}()
+
+	var result *generator.Result
+	select {
+	case g := <-done:
+		result, err = g.result, g.err
+	case <-time.After(opts.HookTimeout):
+		skipHook("No message suggested within %s", opts.HookTimeout)
+	}
+	if errors.Is(err, generator.ErrNoChanges) || errors.Is(err, generator.ErrDisabled) {
+		exit(exitcode.OK)
+	}
+	if err != nil {
+		skipHook("No message suggested: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		skipHook("Failed to read %s: %v", path, err)
+	}
+	if err := atomicfile.WriteFile(path, []byte(withComments(result.String(), string(data))), 0o644); err != nil {
+		skipHook("Failed to write %s: %v", path, err)
+	}
+}
+
+// startFill puts the fill-in notice at the top of the commit message file
+// and hands generation to a background "hook run -fill" process, so that
+// git opens the editor right away
+func startFill(args []string, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		skipHook("Failed to read %s: %v", path, err)
+	}
+	if err := atomicfile.WriteFile(path, []byte(fillNotice+"\n"+string(data)), 0o644); err != nil {
+		skipHook("Failed to write %s: %v", path, err)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		skipHook("Failed to start the background suggestion: %v", err)
+	}
+	// The child gets the arguments of this run, message file included
+	cmd := exec.Command(binary, append([]string{"hook", "run", "-fill"}, args...)...)
+	// No standard streams: git waits for the hook's output to close
+	if err := cmd.Start(); err != nil {
+		atomicfile.WriteFile(path, data, 0o644)
+		skipHook("Failed to start the background suggestion: %v", err)
+	}
+	cmd.Process.Release()
+}
+
+// fillHook is the background half of an async hook. It writes the
+// suggestion into the commit message file only if the file still holds
+// what startFill left there, so a message the user already saved wins.
+func fillHook(commitGen *generator.CommitGen, path string) {
+	// Outlive the terminal and the editor's Ctrl-C
+	signal.Ignore(os.Interrupt, syscall.SIGHUP)
+
+	before, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(before), fillNotice+"\n") {
+		return
+	}
+	original := strings.TrimPrefix(string(before), fillNotice+"\n")
+
+	result, err := commitGen.Generate()
+	if errors.Is(err, generator.ErrNoChanges) || errors.Is(err, generator.ErrDisabled) {
+		replaceIfUnchanged(path, before, original)
+		return
+	}
+	if err != nil {
+		// Nobody reads the output of a background process; the notice
+		// says the suggestion failed instead
+		message := "# commit-gen: no message suggested: " + strings.ReplaceAll(err.Error(), "\n", " ")
+		replaceIfUnchanged(path, before, message+"\n"+original)
+		return
+	}
+	replaceIfUnchanged(path, before, withComments(result.String(), original))
+}
+
+// replaceIfUnchanged writes content to path unless the file no longer
+// holds before, i.e. the editor saved it in the meantime
+func replaceIfUnchanged(path string, before []byte, content string) {
+	if now, err := os.ReadFile(path); err != nil || !bytes.Equal(now, before) {
+		return
+	}
+	atomicfile.WriteFile(path, []byte(content), 0o644)
+}
+
+// loadHookOptions is loadOptions for the hook, which reports a broken
+// config without failing the commit
+func loadHookOptions() *generator.Options {
+	cfg, err := config.Load("")
+	if err != nil {
+		skipHook("Failed to load config: %v", err)
+	}
+	opts, err := cfg.Options("")
+	if err != nil {
+		skipHook("%v", err)
+	}
+	return opts
+}
+
+// withComments replaces the text above git's comment lines in a commit
+// message file with message, keeping the comments (and any diff shown by
+// git commit -v) below it
+func withComments(message, file string) string {
+	lines := strings.Split(file, "\n")
+	draftLines := draftLineCount(file)
+	if draftLines == len(lines) {
+		return message + "\n"
+	}
+	return message + "\n\n" + strings.Join(lines[draftLines:], "\n")
+}
+
+// skipHook reports why the hook made no suggestion and lets the commit
+// go ahead with git's own message
+func skipHook(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "commit-gen: "+format+"\n", args...)
+	exit(exitcode.OK)
+}
+
+// runHookInstall writes a prepare-commit-msg hook that runs this binary
+func runHookInstall(args []string) {
+	fs := flag.NewFlagSet("commit-gen hook install", flag.ExitOnError)
+	force := fs.Bool("force", false, "Replace an existing prepare-commit-msg hook")
+	fs.Parse(args)
+
+	repo := gitRepository()
+	if err := repo.CheckWritable("install the hook"); err != nil {
+		failErr(err, "Failed to install the hook")
+	}
+	// --git-path honors core.hooksPath and linked worktrees
+	path, err := repo.HookPath("prepare-commit-msg")
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if data, err := os.ReadFile(path); err == nil && !strings.Contains(string(data), hookMarker) && !*force {
+		fatalf("%s already exists; add \"commit-gen hook run \\\"$@\\\"\" to it, or use -force to replace it", path)
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		binary = "commit-gen"
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+%s
+# Suggests a commit message. Set COMMITGEN_DISABLE=1 to skip it.
+[ -x %s ] || exit 0
+exec %s hook run "$@"
+`, hookMarker, shellQuote(binary), shellQuote(binary))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fatalf("Failed to create hooks directory: %v", err)
+	}
+	if err := atomicfile.WriteFile(path, []byte(script), 0o755); err != nil {
+		fatalf("Failed to install hook: %v", err)
+	}
+	warnf("Installed %s", path)
+}
+
+// shellQuote quotes s as a single POSIX shell word, in which nothing is
+// expanded
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	for _, s := range []string{
+		"/usr/local/bin/commit-gen",
+		"/home/o'brien/bin/commit-gen",
+		`/tmp/$(touch pwned)/"x"/` + "`id`/commit-gen",
+		`/tmp/back\slash/$HOME/commit-gen`,
+	} {
+		output, err := exec.Command("sh", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil {
+			t.Fatalf("sh -c with %q: %v", s, err)
+		}
+		if string(output) != s {
+			t.Errorf("sh read shellQuote(%q) as %q", s, output)
+		}
+	}
+}
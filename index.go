@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runIndex builds the repository caches ahead of time, instead of on the
+// first search
+func runIndex(args []string) {
+	fs := flag.NewFlagSet("commit-gen index", flag.ExitOnError)
+	rebuild := fs.Bool("rebuild", false, "Embed the whole history again instead of only new commits")
+	embeddingModel := fs.String("embedding-model", "", "Embedding model (default: the provider's embedding model)")
+	asJSON := fs.Bool("json", false, "Print the index stats as JSON on stdout and errors as JSON on stderr")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	opts := loadOptions("")
+	providers.apply(opts)
+	if *embeddingModel != "" {
+		opts.EmbeddingModel = *embeddingModel
+	}
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	stats, err := commitGen.Index(context.Background(), *rebuild)
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges("No commits to index.")
+	}
+	if err != nil {
+		failErr(err, "Failed to index commits")
+	}
+
+	if *asJSON {
+		printResponse(stats)
+		return
+	}
+	fmt.Printf("Indexed %s with %s (%d new), %s\n",
+		plural(stats.Commits, "commit"), stats.Model, stats.Embedded, plural(stats.Modules, "module"))
+}
+
+// runCache reports on or clears the repository cache
+func runCache(args []string) {
+	if len(args) == 0 || (args[0] != "stats" && args[0] != "clear") {
+		fail(exitcode.Usage, "Usage: commit-gen cache stats [-json] | commit-gen cache clear")
+	}
+	fs := flag.NewFlagSet("commit-gen cache "+args[0], flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the cache stats as JSON on stdout and errors as JSON on stderr")
+	fs.Parse(args[1:])
+	jsonErrors = jsonErrors || *asJSON
+
+	// The cache is plain files, no provider is needed
+	repo := gitRepository()
+	if args[0] == "clear" {
+		if err := repo.ClearCache(); err != nil {
+			failErr(err, "Failed to clear the cache")
+		}
+		warnf("Cleared the commit-gen cache")
+		return
+	}
+
+	stats, err := repo.CacheStats()
+	if err != nil {
+		fatalf("%v", err)
+	}
+	if *asJSON {
+		printResponse(stats)
+		return
+	}
+	fmt.Println(stats.Dir)
+	for _, file := range stats.Files {
+		fmt.Printf("  %-16s %8s", file.Name, formatBytes(file.Size))
+		if file.Entries > 0 {
+			fmt.Printf("  %d entries", file.Entries)
+		}
+		if file.Model != "" {
+			fmt.Printf(" (%s)", file.Model)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("  %-16s %8s\n", "total", formatBytes(stats.Size))
+}
+
+// formatBytes prints a size in B, KiB, or MiB
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,123 @@
+// Package atomicfile replaces files so that readers, and anyone looking
+// after a crash or Ctrl-C, see either the old content or the new one, never
+// half of it. Commit message files, plans, and configs are written this way.
+package atomicfile
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// interrupted is the exit status after a signal, exitcode.Interrupted
+const interrupted = 130
+
+// pending are the temporary files being written, for Cleanup, and signals
+// is the channel of the signal handler that runs while any is pending
+var (
+	mu      sync.Mutex
+	pending = make(map[string]bool)
+	signals chan os.Signal
+)
+
+// exit and testHookCreated are replaced by the tests
+var (
+	exit            = os.Exit
+	testHookCreated = func(string) {}
+)
+
+// WriteFile writes data to a temporary file next to path and renames it
+// over path. An existing file keeps its permissions, a new one gets perm. A
+// symlink is followed, so the file it points to is replaced rather than
+// the link.
+func WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	track(tmp.Name(), true)
+	defer track(tmp.Name(), false)
+	testHookCreated(tmp.Name())
+
+	if err := write(tmp, data, perm); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// write fills and closes tmp, flushing it to disk before the rename makes
+// it visible
+func write(tmp *os.File, data []byte, perm fs.FileMode) error {
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(tmp.Name(), perm)
+}
+
+// track adds or removes a temporary file from the pending set. While the
+// set is not empty, SIGINT and SIGTERM remove the files and exit, so that
+// Ctrl-C in the middle of a write leaves no temporary file behind.
+func track(name string, add bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	if add {
+		pending[name] = true
+	} else {
+		delete(pending, name)
+	}
+	switch {
+	case len(pending) > 0 && signals == nil:
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go handle(signals)
+	case len(pending) == 0 && signals != nil:
+		signal.Stop(signals)
+		close(signals)
+		signals = nil
+	}
+}
+
+// handle removes the pending temporary files and exits on the first signal
+// received on ch, and returns once ch is closed
+func handle(ch chan os.Signal) {
+	if _, ok := <-ch; ok {
+		Cleanup()
+		exit(interrupted)
+	}
+}
+
+// Cleanup removes the temporary files of writes still in progress. The
+// signal handler of track calls it, and so should other paths that exit
+// the process, which skip the deferred removals of WriteFile.
+func Cleanup() {
+	mu.Lock()
+	defer mu.Unlock()
+	for name := range pending {
+		os.Remove(name)
+		delete(pending, name)
+	}
+}
@@ -0,0 +1,61 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send SIGINT on Windows")
+	}
+	codes := make(chan int, 1)
+	exit = func(code int) { codes <- code }
+	var tmp string
+	testHookCreated = func(name string) {
+		tmp = name
+		process, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := process.Signal(os.Interrupt); err != nil {
+			t.Fatal(err)
+		}
+		if code := <-codes; code != interrupted {
+			t.Errorf("exit code = %d, want %d", code, interrupted)
+		}
+	}
+	t.Cleanup(func() {
+		exit = os.Exit
+		testHookCreated = func(string) {}
+	})
+
+	path := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	if err := WriteFile(path, []byte("feat: x\n"), 0o644); err == nil {
+		t.Error("WriteFile() succeeded after the temporary file was removed")
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("the temporary file %s is left behind: %v", tmp, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("the directory has %d entries after the interrupted write, want none", len(entries))
+	}
+}
+
+func TestWriteFileStopsHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.md")
+	if err := WriteFile(path, []byte("plan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if signals != nil || len(pending) != 0 {
+		t.Errorf("after WriteFile, %d writes are pending and the handler is installed: %t", len(pending), signals != nil)
+	}
+}
@@ -0,0 +1,523 @@
+// Package config loads commit-gen settings from TOML config files
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// RepoFileName is the name of the per-repository config file
+const RepoFileName = ".commitgen.toml"
+
+// Config holds settings read from config files. Empty values mean "not set"
+// so that later layers and command-line flags can override earlier ones.
+type Config struct {
+	Provider         string `toml:"provider"`
+	Model            string `toml:"model"`
+	DraftModel       string `toml:"draft_model"`
+	OllamaURL        string `toml:"ollama_url"`
+	FallbackProvider string `toml:"fallback_provider"`
+	FallbackModel    string `toml:"fallback_model"`
+	// Timeout bounds each request to the provider, a duration like "30s"
+	// (default: 10s)
+	Timeout string `toml:"timeout"`
+	// EmbeddingModel embeds commit history for search
+	EmbeddingModel string `toml:"embedding_model"`
+	// RelayURL is the commit-gen relay used by provider = "relay"
+	RelayURL string `toml:"relay_url"`
+	// RelayTokenCommand prints the token for the relay, e.g. an SSO helper
+	RelayTokenCommand string `toml:"relay_token_command"`
+	// SystemPrompt is a path to a file that fully replaces the built-in prompt
+	SystemPrompt string `toml:"system_prompt"`
+	// PromptFragments are paths to files with extra prompt rules. Unlike other
+	// settings they accumulate across layers: global (org) first, then repo.
+	PromptFragments []string `toml:"prompt_fragments"`
+	// PromptRules are extra prompt rules given inline, appended after the
+	// fragments; unlike fragments they can come from a shared config
+	PromptRules []string `toml:"prompt_rules"`
+	// SharedConfig is an HTTPS URL of a team-wide config layered beneath
+	// the global and repository configs
+	SharedConfig string `toml:"shared_config"`
+	// SharedConfigKey is the base64 ed25519 public key that SharedConfig
+	// must be signed with
+	SharedConfigKey string `toml:"shared_config_key"`
+	// NoHistory ignores the git log, e.g. in repos with poor historical messages
+	NoHistory *bool `toml:"no_history"`
+	// StyleSource is history, convention, or examples-file
+	StyleSource string `toml:"style_source"`
+	// ExamplesFile is a path to curated example messages for examples-file
+	ExamplesFile string `toml:"examples_file"`
+	// ScopesFile is a YAML scope glossary (default: scopes.yaml in the
+	// repository root)
+	ScopesFile string `toml:"scopes_file"`
+	// BlameContext adds the commits that last touched the modified lines
+	BlameContext *bool `toml:"blame_context"`
+	// RelatedFiles adds files that historically change with the staged files
+	RelatedFiles *bool `toml:"related_files"`
+	// ASCIIOnly keeps emoji and non-ASCII characters out of messages
+	ASCIIOnly *bool `toml:"ascii_only"`
+	// CloseIssues adds "Fixes: #123" to fix commits on issue branches
+	CloseIssues *bool `toml:"close_issues"`
+	// Deterministic fixes the sampling temperature and seed, for CI
+	Deterministic *bool `toml:"deterministic"`
+	// Quality is fast, balanced, or max
+	Quality string `toml:"quality"`
+	// VerifyClaims checks messages for claims the diff does not support
+	VerifyClaims *bool `toml:"verify_claims"`
+	// Grounded keeps only body bullets that cite the diff
+	Grounded *bool `toml:"grounded"`
+	// DedupeSubjects is the number of recent commit subjects a new subject
+	// must not nearly repeat (0 disables the check)
+	DedupeSubjects int `toml:"dedupe_subjects"`
+	// DedupeScope scopes subjects that still repeat a recent one
+	DedupeScope *bool `toml:"dedupe_scope"`
+	// SemanticRelease enforces what semantic-release can parse
+	SemanticRelease *bool `toml:"semantic_release"`
+	// IssueKeyword is the closing keyword, e.g. Fixes, Closes, or Resolves
+	IssueKeyword string `toml:"issue_keyword"`
+	// IssuePosition is footer or body
+	IssuePosition string `toml:"issue_position"`
+	// NoMerges leaves merge commits out of the history
+	NoMerges *bool `toml:"no_merges"`
+	// Provenance adds an "Assisted-by: commitgen (model)" trailer
+	Provenance *bool `toml:"provenance"`
+	// ProvenanceTrailer overrides the provenance trailer key
+	ProvenanceTrailer string `toml:"provenance_trailer"`
+	// PolicyPaths are globs of paths where AI generation is forbidden. Like
+	// prompt fragments they add up across layers, so a repository policy
+	// cannot be lifted by a user's global config.
+	PolicyPaths []string `toml:"policy_paths"`
+	// PolicyAction is "refuse" (default) or "offline" for changes touching PolicyPaths
+	PolicyAction string `toml:"policy_action"`
+	// AuditLog is a JSON lines file recording every generation, provider
+	// call, and change to the repository
+	AuditLog string `toml:"audit_log"`
+	// ReadOnly forbids every change to the repository: staging,
+	// committing, notes, caches, and hook installation
+	ReadOnly *bool `toml:"read_only"`
+	// EnvFile is a dotenv file to load, e.g. holding COMMITGEN_API_KEY
+	EnvFile string `toml:"env_file"`
+	// VCS is git, jj, hg, or auto
+	VCS string `toml:"vcs"`
+	// Convention is conventional or kernel
+	Convention string `toml:"convention"`
+	// Platform is auto, none, github, gitlab, gerrit, or bitbucket
+	Platform string `toml:"platform"`
+	// Repos are the repositories that commands spanning several
+	// repositories, like standup, look at. A later layer replaces the list.
+	Repos []string `toml:"repos"`
+	// CostLimit is the most a commit message may cost, in US dollars,
+	// before commit-gen asks (or, without a terminal, refuses)
+	CostLimit float64 `toml:"cost_limit"`
+	// Prices are model prices in US dollars per million tokens, for models
+	// commit-gen does not know ([prices] section). Later layers add to them.
+	Prices map[string]Price `toml:"prices"`
+	// Anonymize maps sensitive terms to the placeholders the provider sees
+	// instead ([anonymize] section). Later layers add to them.
+	Anonymize map[string]string `toml:"anonymize"`
+	// MaxOutputTokens caps the response length of each kind of request
+	// ([max_output_tokens] section)
+	MaxOutputTokens OutputTokens `toml:"max_output_tokens"`
+	// Hook configures the prepare-commit-msg hook ([hook] section)
+	Hook HookConfig `toml:"hook"`
+	// WIP configures checkpoint commits ([wip] section)
+	WIP WIPConfig `toml:"wip"`
+	// Feedback configures anonymized quality feedback ([feedback] section)
+	Feedback FeedbackConfig `toml:"feedback"`
+
+	// sources are the config files Load looked at, present or not
+	sources []string
+}
+
+// Price is what a model costs per million input and output tokens
+type Price struct {
+	Input  float64 `toml:"input"`
+	Output float64 `toml:"output"`
+}
+
+// OutputTokens holds the output token limit of each kind of request; zero
+// keeps the built-in limit
+type OutputTokens struct {
+	Short   int `toml:"short"`
+	Full    int `toml:"full"`
+	Summary int `toml:"summary"`
+	Report  int `toml:"report"`
+}
+
+// HookConfig holds the settings that only apply in hook mode, where any
+// delay is felt on every git commit
+type HookConfig struct {
+	// Timeout is how long the hook waits for a suggestion, e.g. "3s",
+	// before it lets the commit go ahead without one
+	Timeout string `toml:"timeout"`
+	// Async opens the editor right away and fills in the suggestion from
+	// the background when it is ready
+	Async *bool `toml:"async"`
+}
+
+// WIPConfig holds the settings of the wip and reword-wip commands
+type WIPConfig struct {
+	// Prefix starts the subject of checkpoint commits, e.g. "wip: "
+	Prefix string `toml:"prefix"`
+}
+
+// FeedbackConfig holds the settings of anonymized quality feedback
+type FeedbackConfig struct {
+	// Enabled opts in to sending accept, edit, and reject signals. It is
+	// only read from the user-wide config: sending is the user's choice.
+	Enabled *bool `toml:"enabled"`
+	// URL is the endpoint (default: the feedback endpoint of relay_url)
+	URL string `toml:"url"`
+}
+
+// GlobalPath returns the user-wide config file location
+func GlobalPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "commitgen", "config.toml"), nil
+}
+
+// RepoPath returns the repository config file location for workingDir
+// If workingDir is not inside a git repository, workingDir itself is used
+func RepoPath(workingDir string) string {
+	root, err := RepoRoot(workingDir)
+	if err != nil {
+		root = workingDir
+	}
+	return filepath.Join(root, RepoFileName)
+}
+
+// RepoRoot returns the top directory of the git repository containing
+// workingDir
+func RepoRoot(workingDir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	if workingDir != "" {
+		cmd.Dir = workingDir
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.New("not inside a git repository")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Load reads the global config and then the repository config for
+// workingDir, with repository values taking precedence. A shared config
+// they point to is layered beneath both.
+func Load(workingDir string) (*Config, error) {
+	cfg := &Config{}
+
+	var sources []string
+	globalPath, err := GlobalPath()
+	if err == nil {
+		if err := cfg.mergeFile(globalPath); err != nil {
+			return nil, err
+		}
+		sources = append(sources, globalPath)
+	}
+
+	global := *cfg
+	repoPath := RepoPath(workingDir)
+	if err := cfg.mergeFile(repoPath); err != nil {
+		return nil, err
+	}
+	sources = append(sources, repoPath)
+	cfg.keepGlobalOnly(&global)
+
+	if cfg.SharedConfig != "" {
+		shared, err := loadShared(&global)
+		if err != nil {
+			return nil, err
+		}
+		shared.merge(cfg)
+		cfg = shared
+	}
+
+	cfg.sources = sources
+	return cfg, nil
+}
+
+// keepGlobalOnly undoes what the repository config set of the settings only
+// the user may choose. A repository's config is whatever its author wrote,
+// so it must not run commands, send the diff or feedback elsewhere, or name
+// local files to read into prompts or write to.
+func (c *Config) keepGlobalOnly(global *Config) {
+	c.Provider = global.Provider
+	c.FallbackProvider = global.FallbackProvider
+	c.OllamaURL = global.OllamaURL
+	c.DraftModel = global.DraftModel
+	c.RelayURL = global.RelayURL
+	c.SharedConfig = global.SharedConfig
+	c.SharedConfigKey = global.SharedConfigKey
+	c.RelayTokenCommand = global.RelayTokenCommand
+	c.SystemPrompt = global.SystemPrompt
+	c.ExamplesFile = global.ExamplesFile
+	c.ScopesFile = global.ScopesFile
+	c.EnvFile = global.EnvFile
+	c.AuditLog = global.AuditLog
+	c.PromptFragments = global.PromptFragments
+	c.Repos = global.Repos
+	c.Feedback.Enabled = global.Feedback.Enabled
+	c.Feedback.URL = global.Feedback.URL
+}
+
+// Sources returns the config files Load looked at, whether they exist or
+// not, in the order they were layered
+func (c *Config) Sources() []string {
+	return slices.Clone(c.sources)
+}
+
+// Files returns the local files the config was built from: the config
+// files, whether they exist or not, and the files they point to. A shared
+// config is left out; it is refreshed on its own schedule.
+func (c *Config) Files() []string {
+	files := slices.Clone(c.sources)
+	for _, path := range append([]string{c.SystemPrompt, c.ExamplesFile, c.ScopesFile, c.EnvFile}, c.PromptFragments...) {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// Values returns the settings of the config keyed as in the config file,
+// e.g. to hand them to a plugin. Settings that are not set are left out.
+func (c *Config) Values() (map[string]any, error) {
+	var b bytes.Buffer
+	if err := toml.NewEncoder(&b).Encode(c); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	values := make(map[string]any)
+	if _, err := toml.Decode(b.String(), &values); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	pruneUnset(values)
+	return values, nil
+}
+
+// pruneUnset removes the empty values of a decoded config, which mean
+// "not set", and the tables left empty
+func pruneUnset(values map[string]any) {
+	for key, value := range values {
+		switch v := value.(type) {
+		case map[string]any:
+			pruneUnset(v)
+			if len(v) == 0 {
+				delete(values, key)
+			}
+		case []any:
+			if len(v) == 0 {
+				delete(values, key)
+			}
+		case string:
+			if v == "" {
+				delete(values, key)
+			}
+		case int64:
+			if v == 0 {
+				delete(values, key)
+			}
+		case float64:
+			if v == 0 {
+				delete(values, key)
+			}
+		}
+	}
+}
+
+// Stamp fingerprints files by size and modification time, so long-running
+// processes can tell cheaply when a config they loaded has changed
+func Stamp(files []string) string {
+	var b strings.Builder
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(&b, "%s:-\n", path)
+		}
+	}
+	return b.String()
+}
+
+// mergeFile reads path, if it exists, and overrides cfg with its non-empty values
+func (c *Config) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	layer, err := decodeLayer(string(data), path)
+	if err != nil {
+		return err
+	}
+
+	// Paths are relative to the file that declares them
+	layer.SystemPrompt = resolvePath(filepath.Dir(path), layer.SystemPrompt)
+	layer.ExamplesFile = resolvePath(filepath.Dir(path), layer.ExamplesFile)
+	layer.ScopesFile = resolvePath(filepath.Dir(path), layer.ScopesFile)
+	layer.EnvFile = resolvePath(filepath.Dir(path), layer.EnvFile)
+	layer.AuditLog = resolvePath(filepath.Dir(path), layer.AuditLog)
+	for i, fragment := range layer.PromptFragments {
+		layer.PromptFragments[i] = resolvePath(filepath.Dir(path), fragment)
+	}
+	for i, repo := range layer.Repos {
+		layer.Repos[i] = resolvePath(filepath.Dir(path), repo)
+	}
+
+	c.merge(layer)
+	return nil
+}
+
+// merge overrides cfg with the non-empty values of other
+func (c *Config) merge(other *Config) {
+	override(&c.Provider, other.Provider)
+	override(&c.Model, other.Model)
+	override(&c.DraftModel, other.DraftModel)
+	override(&c.OllamaURL, other.OllamaURL)
+	override(&c.FallbackProvider, other.FallbackProvider)
+	override(&c.FallbackModel, other.FallbackModel)
+	override(&c.EmbeddingModel, other.EmbeddingModel)
+	override(&c.SystemPrompt, other.SystemPrompt)
+	override(&c.StyleSource, other.StyleSource)
+	override(&c.ExamplesFile, other.ExamplesFile)
+	override(&c.ScopesFile, other.ScopesFile)
+	override(&c.IssueKeyword, other.IssueKeyword)
+	override(&c.IssuePosition, other.IssuePosition)
+	override(&c.Convention, other.Convention)
+	override(&c.Platform, other.Platform)
+	override(&c.VCS, other.VCS)
+	override(&c.EnvFile, other.EnvFile)
+	override(&c.AuditLog, other.AuditLog)
+	override(&c.ProvenanceTrailer, other.ProvenanceTrailer)
+	override(&c.PolicyAction, other.PolicyAction)
+	override(&c.RelayURL, other.RelayURL)
+	override(&c.RelayTokenCommand, other.RelayTokenCommand)
+	override(&c.SharedConfig, other.SharedConfig)
+	override(&c.SharedConfigKey, other.SharedConfigKey)
+	override(&c.Timeout, other.Timeout)
+	override(&c.Hook.Timeout, other.Hook.Timeout)
+	override(&c.WIP.Prefix, other.WIP.Prefix)
+	override(&c.Feedback.URL, other.Feedback.URL)
+	overrideInt(&c.MaxOutputTokens.Short, other.MaxOutputTokens.Short)
+	overrideInt(&c.MaxOutputTokens.Full, other.MaxOutputTokens.Full)
+	overrideInt(&c.MaxOutputTokens.Summary, other.MaxOutputTokens.Summary)
+	overrideInt(&c.MaxOutputTokens.Report, other.MaxOutputTokens.Report)
+	c.PromptFragments = append(c.PromptFragments, other.PromptFragments...)
+	c.PromptRules = append(c.PromptRules, other.PromptRules...)
+	c.PolicyPaths = append(c.PolicyPaths, other.PolicyPaths...)
+	if len(other.Repos) > 0 {
+		c.Repos = other.Repos
+	}
+	if other.NoHistory != nil {
+		c.NoHistory = other.NoHistory
+	}
+	if other.ReadOnly != nil {
+		c.ReadOnly = other.ReadOnly
+	}
+	if other.BlameContext != nil {
+		c.BlameContext = other.BlameContext
+	}
+	if other.RelatedFiles != nil {
+		c.RelatedFiles = other.RelatedFiles
+	}
+	if other.ASCIIOnly != nil {
+		c.ASCIIOnly = other.ASCIIOnly
+	}
+	if other.Provenance != nil {
+		c.Provenance = other.Provenance
+	}
+	if other.NoMerges != nil {
+		c.NoMerges = other.NoMerges
+	}
+	if other.CloseIssues != nil {
+		c.CloseIssues = other.CloseIssues
+	}
+	if other.Deterministic != nil {
+		c.Deterministic = other.Deterministic
+	}
+	override(&c.Quality, other.Quality)
+	if other.VerifyClaims != nil {
+		c.VerifyClaims = other.VerifyClaims
+	}
+	if other.Grounded != nil {
+		c.Grounded = other.Grounded
+	}
+	if other.DedupeSubjects != 0 {
+		c.DedupeSubjects = other.DedupeSubjects
+	}
+	if other.DedupeScope != nil {
+		c.DedupeScope = other.DedupeScope
+	}
+	if other.SemanticRelease != nil {
+		c.SemanticRelease = other.SemanticRelease
+	}
+	if other.CostLimit != 0 {
+		c.CostLimit = other.CostLimit
+	}
+	for model, price := range other.Prices {
+		if c.Prices == nil {
+			c.Prices = make(map[string]Price)
+		}
+		c.Prices[model] = price
+	}
+	for term, placeholder := range other.Anonymize {
+		if c.Anonymize == nil {
+			c.Anonymize = make(map[string]string)
+		}
+		c.Anonymize[term] = placeholder
+	}
+	if other.Hook.Async != nil {
+		c.Hook.Async = other.Hook.Async
+	}
+	if other.Feedback.Enabled != nil {
+		c.Feedback.Enabled = other.Feedback.Enabled
+	}
+}
+
+// Bool returns the value of an optional boolean setting
+func Bool(value *bool) bool {
+	return value != nil && *value
+}
+
+// override sets *dst to value when value is not empty
+func override(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// overrideInt sets *dst to value when value is set
+func overrideInt(dst *int, value int) {
+	if value != 0 {
+		*dst = value
+	}
+}
+
+// resolvePath makes path absolute relative to dir, expanding a leading ~
+func resolvePath(dir, path string) string {
+	if path == "" {
+		return ""
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
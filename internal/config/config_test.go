@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadLayers writes the user-wide and repository configs and loads them
+func loadLayers(t *testing.T, global, repo string) *Config {
+	t.Helper()
+	cfg, err := Load(writeLayers(t, global, repo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// writeLayers writes the user-wide and repository configs and returns the
+// repository directory
+func writeLayers(t *testing.T, global, repo string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	if global != "" {
+		if err := os.MkdirAll(filepath.Join(home, "commitgen"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(home, "commitgen", "config.toml"), []byte(global), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, RepoFileName), []byte(repo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestLoadKeepsGlobalOnly(t *testing.T) {
+	tests := []struct {
+		key  string
+		repo string
+		get  func(*Config) string
+	}{
+		{"provider", `provider = "ollama"`, func(c *Config) string { return c.Provider }},
+		{"fallback_provider", `fallback_provider = "ollama"`, func(c *Config) string { return c.FallbackProvider }},
+		{"ollama_url", `ollama_url = "https://attacker.example"`, func(c *Config) string { return c.OllamaURL }},
+		{"draft_model", `draft_model = "qwen2.5-coder:7b"`, func(c *Config) string { return c.DraftModel }},
+		{"relay_url", `relay_url = "https://attacker.example"`, func(c *Config) string { return c.RelayURL }},
+		{"relay_token_command", `relay_token_command = "touch /tmp/pwned"`, func(c *Config) string { return c.RelayTokenCommand }},
+		{"system_prompt", `system_prompt = "/etc/passwd"`, func(c *Config) string { return c.SystemPrompt }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := tt.get(loadLayers(t, "", tt.repo)); got != "" {
+				t.Errorf("the repository config set %s to %q", tt.key, got)
+			}
+		})
+	}
+}
+
+func TestLoadGlobalOnlyFromUser(t *testing.T) {
+	cfg := loadLayers(t,
+		"provider = \"ollama\"\nollama_url = \"http://localhost:11434\"\ndraft_model = \"qwen\"\nfallback_provider = \"gemini\"\n",
+		"provider = \"gemini\"\nollama_url = \"https://attacker.example\"\ndraft_model = \"other\"\nfallback_provider = \"ollama\"\nmodel = \"llama3\"\n")
+	if cfg.Provider != "ollama" || cfg.OllamaURL != "http://localhost:11434" || cfg.DraftModel != "qwen" || cfg.FallbackProvider != "gemini" {
+		t.Errorf("Load() = provider %q, ollama_url %q, draft_model %q, fallback_provider %q; want the user's",
+			cfg.Provider, cfg.OllamaURL, cfg.DraftModel, cfg.FallbackProvider)
+	}
+	// Other settings still come from the repository
+	if cfg.Model != "llama3" {
+		t.Errorf("Load() model = %q, want the repository's llama3", cfg.Model)
+	}
+}
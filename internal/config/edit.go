@@ -0,0 +1,348 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+)
+
+// tableHeader matches a "[table]" line, not an array of tables
+var tableHeader = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*(#.*)?$`)
+
+// keyLine matches the start of a "key = value" line
+var keyLine = regexp.MustCompile(`^\s*("[^"]*"|'[^']*'|[A-Za-z0-9_.-]+)\s*=`)
+
+// Get returns the value of the dotted key in the config file at path, and
+// whether it is set there
+func Get(path, key string) (any, bool, error) {
+	values, err := readValues(path)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := lookup(values, strings.Split(key, "."))
+	return value, ok, nil
+}
+
+// Lookup returns the value of the dotted key in the settings of the config,
+// and whether it is set
+func (c *Config) Lookup(key string) (any, bool, error) {
+	values, err := c.Values()
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := lookup(values, strings.Split(key, "."))
+	return value, ok, nil
+}
+
+// lookup walks the decoded tables of a config along key
+func lookup(values map[string]any, key []string) (any, bool) {
+	value, ok := values[key[0]]
+	if !ok || len(key) == 1 {
+		return value, ok
+	}
+	table, ok := value.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return lookup(table, key[1:])
+}
+
+// Set sets the dotted key in the config file at path, creating the file
+// if needed. A list key takes every value, any other key exactly one.
+// Comments and the rest of the file are kept as they are; the edit is
+// only written when the file still parses, passes validation, and differs
+// from before in that key alone.
+func Set(path, key string, values []string) error {
+	field, err := fieldType(key)
+	if err != nil {
+		return err
+	}
+	value, err := parseValue(key, field, values)
+	if err != nil {
+		return err
+	}
+
+	var line bytes.Buffer
+	encoder := toml.NewEncoder(&line)
+	if err := encoder.Encode(map[string]any{"x": value}); err != nil {
+		return fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	_, encoded, _ := strings.Cut(strings.TrimSpace(line.String()), "=")
+	return editFile(path, key, "= "+strings.TrimSpace(encoded))
+}
+
+// Unset removes the dotted key from the config file at path
+func Unset(path, key string) error {
+	if _, err := fieldType(key); err != nil {
+		return err
+	}
+	return editFile(path, key, "")
+}
+
+// fieldType returns the type of the config setting at the dotted key,
+// which must be a setting rather than a table
+func fieldType(key string) (reflect.Type, error) {
+	parts := strings.Split(key, ".")
+	t := reflect.TypeOf(Config{})
+	for i, name := range parts {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByTag(t, name)
+			if !ok {
+				message := fmt.Sprintf("unknown key %q", key)
+				if suggestion := suggest(name, keysAt(parts[:i])); suggestion != "" {
+					message += fmt.Sprintf(", did you mean %q?", strings.Join(append(parts[:i:i], suggestion), "."))
+				}
+				return nil, errors.New(message)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("%s is not a table", strings.Join(parts[:i], "."))
+		}
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case len(parts) > 2 || (len(parts) == 2 && (t.Kind() == reflect.Struct || t.Kind() == reflect.Map)):
+		return nil, fmt.Errorf("%s is too deeply nested to set from the command line, edit the file instead", key)
+	case t.Kind() == reflect.Struct || t.Kind() == reflect.Map:
+		return nil, fmt.Errorf("%s is a table, set one of its keys instead, e.g. %s.<key>", key, key)
+	}
+	return t, nil
+}
+
+// parseValue converts the command-line values of a setting to its type
+func parseValue(key string, t reflect.Type, values []string) (any, error) {
+	if t.Kind() == reflect.Slice {
+		return values, nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%s takes one value", key)
+	}
+	value := values[0]
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false, not %q", key, value)
+		}
+		return b, nil
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a whole number, not %q", key, value)
+		}
+		return n, nil
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, not %q", key, value)
+		}
+		return f, nil
+	}
+	return value, nil
+}
+
+// editFile replaces the line of the dotted key in the config file at path
+// with "key <assignment>", adds it when it is missing, or removes it when
+// assignment is empty, then checks and writes the result
+func editFile(path, key, assignment string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	before, err := decodeValues(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	table, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		table, name = key[:i], key[i+1:]
+	}
+	if strings.ContainsAny(name, " \"'") || !keyLine.MatchString(name+" =") {
+		name = strconv.Quote(name)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	current, found, prefix, comment := "", -1, "", ""
+	insertAt, firstTable, tableFound := -1, len(lines), false
+	for i, line := range lines {
+		if m := tableHeader.FindStringSubmatch(line); m != nil {
+			current = unquoteKey(m[1])
+			firstTable = min(firstTable, i)
+			if current == table {
+				tableFound, insertAt = true, i+1
+			}
+			continue
+		}
+		loc := keyLine.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		k := unquoteKey(line[loc[2]:loc[3]])
+		if current != "" {
+			k = current + "." + k
+		}
+		if k == key {
+			// Keep the key as written, e.g. a dotted top-level key
+			found, prefix = i, strings.TrimRight(line[:loc[1]-1], " \t")
+			comment = trailingComment(line[loc[1]:])
+		}
+		if current == table {
+			insertAt = i + 1
+		}
+	}
+
+	newLine := name + " " + assignment
+	switch {
+	case found >= 0 && assignment == "":
+		lines = slices.Delete(lines, found, found+1)
+	case found >= 0:
+		lines[found] = prefix + " " + assignment + comment
+	case assignment == "":
+		return nil
+	case table == "":
+		// Top-level keys go after the others, before the first table
+		if insertAt < 0 {
+			insertAt = firstTable
+		}
+		lines = slices.Insert(lines, insertAt, newLine)
+	case tableFound:
+		lines = slices.Insert(lines, insertAt, newLine)
+	default:
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+table+"]", newLine)
+	}
+	text := strings.Join(lines, "\n") + "\n"
+
+	// The edit must change exactly the key, and leave a valid config
+	after, err := decodeValues(text)
+	if err != nil {
+		return fmt.Errorf("cannot edit %s in %s safely, edit the file instead: %w", key, path, err)
+	}
+	expected := before
+	if assignment == "" {
+		deleteKey(expected, strings.Split(key, "."))
+	} else {
+		snippet, err := decodeValues("x " + assignment)
+		if err != nil {
+			return err
+		}
+		setKey(expected, strings.Split(key, "."), snippet["x"])
+	}
+	if !reflect.DeepEqual(after, expected) {
+		return fmt.Errorf("cannot edit %s in %s safely, edit the file instead", key, path)
+	}
+	if _, err := decodeLayer(text, path); err != nil {
+		return err
+	}
+	return writeConfig(path, text)
+}
+
+// trailingComment returns the comment after the value of a key line, with
+// the space before it, or "" for none. A "#" inside a string is not one.
+func trailingComment(value string) string {
+	for i, c := range value {
+		if c != '#' {
+			continue
+		}
+		if _, err := decodeValues("x =" + value[:i]); err == nil {
+			return " " + strings.TrimSpace(value[i:])
+		}
+	}
+	return ""
+}
+
+// unquoteKey returns a bare, quoted, or dotted TOML key as plain text
+func unquoteKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil {
+			part = unquoted
+		} else {
+			part = strings.Trim(part, "'")
+		}
+		parts[i] = part
+	}
+	return strings.Join(parts, ".")
+}
+
+// decodeValues decodes config text into its tables, without the schema
+func decodeValues(text string) (map[string]any, error) {
+	values := make(map[string]any)
+	if _, err := toml.Decode(text, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// readValues decodes the config file at path; a missing file has no values
+func readValues(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	values, err := decodeValues(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// setKey sets the dotted key in decoded tables, creating tables as needed
+func setKey(values map[string]any, key []string, value any) {
+	if len(key) == 1 {
+		values[key[0]] = value
+		return
+	}
+	table, ok := values[key[0]].(map[string]any)
+	if !ok {
+		table = make(map[string]any)
+		values[key[0]] = table
+	}
+	setKey(table, key[1:], value)
+}
+
+// deleteKey removes the dotted key from decoded tables
+func deleteKey(values map[string]any, key []string) {
+	if len(key) == 1 {
+		delete(values, key[0])
+		return
+	}
+	if table, ok := values[key[0]].(map[string]any); ok {
+		deleteKey(table, key[1:])
+	}
+}
+
+// writeConfig replaces the config file at path with text, through a
+// temporary file so that a failed write never leaves half a config
+func writeConfig(path, text string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return atomicfile.WriteFile(path, []byte(text), 0o644)
+}
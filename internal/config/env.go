@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// Environment variables that override the config files. Flags override them
+// in turn. COMMITGEN_API_KEY and ANTHROPIC_API_KEY are read by the
+// generator itself, see generator.APIKeyEnv.
+const (
+	EnvProvider = "COMMITGEN_PROVIDER"
+	EnvModel    = "COMMITGEN_MODEL"
+	EnvTimeout  = "COMMITGEN_TIMEOUT"
+	// EnvRelayToken is the relay token, used instead of relay_token_command
+	EnvRelayToken = "COMMITGEN_RELAY_TOKEN"
+)
+
+// ApplyEnv overrides opts with the COMMITGEN_* environment variables that
+// are set. Options applies them already; call it again after loading
+// another env file.
+func ApplyEnv(opts *generator.Options) error {
+	if provider := os.Getenv(EnvProvider); provider != "" {
+		if !slices.Contains(providers, provider) {
+			message := fmt.Sprintf("%s must be one of %s, not %q", EnvProvider, strings.Join(providers, ", "), provider)
+			if suggestion := suggest(provider, providers); suggestion != "" {
+				message += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			return errors.New(message)
+		}
+		opts.Provider = provider
+	}
+	if model := os.Getenv(EnvModel); model != "" {
+		opts.Model = model
+	}
+	if value := os.Getenv(EnvTimeout); value != "" {
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvTimeout, err)
+		}
+		opts.Timeout = timeout
+	}
+	return nil
+}
+
+// parseTimeout parses a request timeout, a duration like "30s" or a whole
+// number of seconds
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("%q is not a duration like \"30s\"", value)
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("%q must be positive", value)
+	}
+	return timeout, nil
+}
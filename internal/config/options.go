@@ -0,0 +1,161 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// ErrRelayToken is returned when relay_token_command fails
+var ErrRelayToken = generator.ErrRelayToken
+
+// Options builds generator options for workingDir from the config. It loads
+// the env file, applies the COMMITGEN_* environment variables, reads the
+// prompt fragments, and sets up the relay token, so every commit-gen binary
+// configures the generator the same way. relay_token_command only runs once
+// a request goes to the relay.
+func (c *Config) Options(workingDir string) (*generator.Options, error) {
+	if err := LoadEnvFile(c.EnvFile); err != nil {
+		return nil, err
+	}
+
+	fragments := make([]string, 0, len(c.PromptFragments))
+	for _, path := range c.PromptFragments {
+		fragment, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt fragment: %w", err)
+		}
+		fragments = append(fragments, string(fragment))
+	}
+	fragments = append(fragments, c.PromptRules...)
+
+	limits := c.MaxOutputTokens
+	if min(limits.Short, limits.Full, limits.Summary, limits.Report) < 0 {
+		return nil, errors.New("max_output_tokens limits must be positive")
+	}
+
+	if c.CostLimit < 0 {
+		return nil, errors.New("cost_limit must be positive")
+	}
+	if c.DedupeSubjects < 0 {
+		return nil, errors.New("dedupe_subjects must be positive")
+	}
+	var prices map[string]generator.ModelPrice
+	for model, price := range c.Prices {
+		if prices == nil {
+			prices = make(map[string]generator.ModelPrice)
+		}
+		prices[model] = generator.ModelPrice{Input: price.Input, Output: price.Output}
+	}
+
+	var timeout time.Duration
+	if c.Timeout != "" {
+		var err error
+		if timeout, err = parseTimeout(c.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+
+	var hookTimeout time.Duration
+	if c.Hook.Timeout != "" {
+		var err error
+		if hookTimeout, err = time.ParseDuration(c.Hook.Timeout); err != nil || hookTimeout <= 0 {
+			return nil, fmt.Errorf("invalid hook timeout %q, use a duration like \"3s\"", c.Hook.Timeout)
+		}
+	}
+
+	opts := &generator.Options{
+		WorkingDir:        workingDir,
+		Provider:          c.Provider,
+		Model:             c.Model,
+		DraftModel:        c.DraftModel,
+		OllamaURL:         c.OllamaURL,
+		RelayURL:          c.RelayURL,
+		RelayToken:        os.Getenv(EnvRelayToken),
+		FallbackProvider:  c.FallbackProvider,
+		FallbackModel:     c.FallbackModel,
+		EmbeddingModel:    c.EmbeddingModel,
+		SystemPromptFile:  c.SystemPrompt,
+		PromptFragments:   fragments,
+		NoHistory:         Bool(c.NoHistory),
+		StyleSource:       c.StyleSource,
+		ExamplesFile:      c.ExamplesFile,
+		ScopesFile:        c.ScopesFile,
+		BlameContext:      Bool(c.BlameContext),
+		RelatedFiles:      Bool(c.RelatedFiles),
+		ASCIIOnly:         Bool(c.ASCIIOnly),
+		CloseIssues:       Bool(c.CloseIssues),
+		IssueKeyword:      c.IssueKeyword,
+		IssuePosition:     c.IssuePosition,
+		Convention:        c.Convention,
+		Platform:          c.Platform,
+		VCS:               c.VCS,
+		NoMerges:          Bool(c.NoMerges),
+		Provenance:        Bool(c.Provenance),
+		ProvenanceTrailer: c.ProvenanceTrailer,
+		PolicyPaths:       c.PolicyPaths,
+		PolicyAction:      c.PolicyAction,
+		Repos:             c.Repos,
+		MaxOutputTokens: generator.OutputBudget{
+			Short:   limits.Short,
+			Full:    limits.Full,
+			Summary: limits.Summary,
+			Report:  limits.Report,
+		},
+		CostLimit:       c.CostLimit,
+		Prices:          prices,
+		Anonymize:       c.Anonymize,
+		Deterministic:   Bool(c.Deterministic),
+		Quality:         c.Quality,
+		VerifyClaims:    Bool(c.VerifyClaims),
+		Grounded:        Bool(c.Grounded),
+		DedupeSubjects:  c.DedupeSubjects,
+		DedupeScope:     Bool(c.DedupeScope),
+		SemanticRelease: Bool(c.SemanticRelease),
+		Timeout:         timeout,
+		HookTimeout:     hookTimeout,
+		HookAsync:       Bool(c.Hook.Async),
+		WIPPrefix:       c.WIP.Prefix,
+		Feedback:        Bool(c.Feedback.Enabled),
+		FeedbackURL:     c.Feedback.URL,
+		ReadOnly:        Bool(c.ReadOnly),
+		AuditLog:        c.AuditLog,
+	}
+	if opts.RelayToken == "" && c.RelayTokenCommand != "" {
+		command := c.RelayTokenCommand
+		opts.RelayTokenSource = func() (string, error) { return relayToken(command) }
+	}
+	if err := ApplyEnv(opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// LoadEnvFile loads a dotenv file into the environment without overriding
+// variables that are already set. Nothing is loaded implicitly: the file
+// must be named with -env-file or env_file in the config.
+func LoadEnvFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := godotenv.Load(path); err != nil {
+		return fmt.Errorf("failed to load env file: %w", err)
+	}
+	return nil
+}
+
+// relayToken returns the output of the configured token command (e.g. an
+// SSO helper), for when COMMITGEN_RELAY_TOKEN is not set
+func relayToken(command string) (string, error) {
+	output, err := exec.Command("sh", "-c", command).Output()
+	if err != nil {
+		return "", fmt.Errorf("%w from %q: %v", ErrRelayToken, command, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
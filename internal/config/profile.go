@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+)
+
+// ProfileVersion is the format version of exported profiles
+const ProfileVersion = 1
+
+// Profile is a portable copy of the user-wide config and the prompt and
+// example files it points to, to carry between machines or hand to new
+// team members
+type Profile struct {
+	Version int `json:"version"`
+	// Config is the TOML of the user-wide config, with its paths relative
+	// to the config file
+	Config string `json:"config"`
+	// Files holds the contents of the files the config points to, by path
+	// relative to the config file
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// ExportProfile packs the user-wide config and the files it points to.
+// The env file is left out, since it usually holds API keys.
+func ExportProfile() (*Profile, error) {
+	path, err := GlobalPath()
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if _, err := toml.DecodeFile(path, &raw); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("there is no config at %s to export", path)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	delete(raw, "env_file")
+
+	dir := filepath.Dir(path)
+	profile := &Profile{Version: ProfileVersion, Files: make(map[string]string)}
+	pack := func(file string) (string, error) {
+		abs := resolvePath(dir, file)
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", abs, err)
+		}
+		name := profileFileName(dir, abs, profile.Files)
+		profile.Files[name] = string(data)
+		return name, nil
+	}
+
+	for _, key := range []string{"system_prompt", "examples_file", "scopes_file"} {
+		if file, ok := raw[key].(string); ok && file != "" {
+			if raw[key], err = pack(file); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if fragments, ok := raw["prompt_fragments"].([]any); ok {
+		for i, fragment := range fragments {
+			if file, ok := fragment.(string); ok && file != "" {
+				if fragments[i], err = pack(file); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	encoder := toml.NewEncoder(&b)
+	encoder.Indent = ""
+	if err := encoder.Encode(raw); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	profile.Config = b.String()
+	return profile, nil
+}
+
+// profileFileName names a packed file: by its path below the config
+// directory, or under files/ when it lives elsewhere
+func profileFileName(dir, file string, taken map[string]string) string {
+	if rel, err := filepath.Rel(dir, file); err == nil && filepath.IsLocal(rel) {
+		return filepath.ToSlash(rel)
+	}
+	name := "files/" + filepath.Base(file)
+	ext := filepath.Ext(name)
+	for i := 2; ; i++ {
+		if _, ok := taken[name]; !ok {
+			return name
+		}
+		name = fmt.Sprintf("files/%s-%d%s", strings.TrimSuffix(filepath.Base(file), ext), i, ext)
+	}
+}
+
+// ImportProfile installs profile as the user-wide config, next to the
+// files it carries, and returns the config path. An existing config is
+// only replaced with force.
+func ImportProfile(profile *Profile, force bool) (string, error) {
+	if profile.Version != ProfileVersion {
+		return "", fmt.Errorf("unsupported profile version %d, upgrade commit-gen", profile.Version)
+	}
+	if _, err := decodeLayer(profile.Config, "in the profile"); err != nil {
+		return "", err
+	}
+	path, err := GlobalPath()
+	if err != nil {
+		return "", err
+	}
+	for name := range profile.Files {
+		// A profile may come from anyone, so it must not write elsewhere
+		if !filepath.IsLocal(filepath.FromSlash(name)) || name == filepath.Base(path) {
+			return "", fmt.Errorf("the profile has an unsafe file name %q", name)
+		}
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s already exists, use -force to replace it", path)
+	}
+
+	dir := filepath.Dir(path)
+	for name, content := range profile.Files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create %s: %w", filepath.Dir(file), err)
+		}
+		if err := atomicfile.WriteFile(file, []byte(content), 0o644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := atomicfile.WriteFile(path, []byte(profile.Config), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// providers are the values provider and fallback_provider accept
+var providers = []string{generator.ProviderGemini, generator.ProviderAnthropic, generator.ProviderOllama, generator.ProviderRelay}
+
+// enums are the values of the keys that accept only a few
+var enums = map[string][]string{
+	"provider":          providers,
+	"fallback_provider": providers,
+	"style_source":      {generator.StyleHistory, generator.StyleConvention, generator.StyleExamples},
+	"quality":           {generator.QualityFast, generator.QualityBalanced, generator.QualityMax},
+	"issue_position":    {generator.IssueFooter, generator.IssueBody},
+	"policy_action":     {generator.PolicyRefuse, generator.PolicyOffline},
+	"vcs":               {generator.VCSGit, generator.VCSJujutsu, generator.VCSMercurial, generator.VCSAuto},
+	"convention":        {generator.ConventionConventional, generator.ConventionKernel},
+	"platform":          {generator.PlatformAuto, generator.PlatformNone, generator.PlatformGitHub, generator.PlatformGitLab, generator.PlatformGerrit, generator.PlatformBitbucket},
+}
+
+// Problem is a mistake in a config file
+type Problem struct {
+	// Key is the dotted key the problem is about, e.g. "hook.timeout"
+	Key     string
+	Message string
+}
+
+// ValidationError lists the problems of a config file
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Message
+	}
+	return fmt.Sprintf("invalid config %s: %s", e.Path, strings.Join(messages, "; "))
+}
+
+// decodeLayer parses the config file data read from path and checks it
+// against the schema: unknown keys, usually typos, and values outside the
+// few a key accepts are reported with a suggestion
+func decodeLayer(data, path string) (*Config, error) {
+	var layer Config
+	meta, err := toml.Decode(data, &layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	var problems []Problem
+	unknown := make(map[string]bool)
+	for _, key := range meta.Undecoded() {
+		unknown[key.String()] = true
+	}
+	for _, key := range meta.Undecoded() {
+		// Only report the outermost unknown key, not every key below it
+		if len(key) > 1 && unknown[key[:len(key)-1].String()] {
+			continue
+		}
+		message := fmt.Sprintf("unknown key %q", key.String())
+		if suggestion := suggest(key[len(key)-1], keysAt(key[:len(key)-1])); suggestion != "" {
+			message += fmt.Sprintf(", did you mean %q?", append(slices.Clone(key[:len(key)-1]), suggestion).String())
+		}
+		problems = append(problems, Problem{Key: key.String(), Message: message})
+	}
+
+	values := reflect.ValueOf(layer)
+	for _, key := range slices.Sorted(maps.Keys(enums)) {
+		value := fieldByKey(values, key).String()
+		if value == "" || slices.Contains(enums[key], value) {
+			continue
+		}
+		message := fmt.Sprintf("%s must be one of %s, not %q", key, strings.Join(enums[key], ", "), value)
+		if suggestion := suggest(value, enums[key]); suggestion != "" {
+			message += fmt.Sprintf(", did you mean %q?", suggestion)
+		}
+		problems = append(problems, Problem{Key: key, Message: message})
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+	return &layer, nil
+}
+
+// ValidateFile checks the config file at path against the schema. It
+// returns a *ValidationError listing every problem found.
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+	_, err = decodeLayer(string(data), path)
+	return err
+}
+
+// keysAt returns the keys the table at the dotted path parent accepts
+func keysAt(parent toml.Key) []string {
+	t := reflect.TypeOf(Config{})
+	for _, name := range parent {
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByTag(t, name)
+			if !ok {
+				return nil
+			}
+			t = field.Type
+		case reflect.Map:
+			// Any name is a key of a map, e.g. a model under [prices]
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	var keys []string
+	for i := range t.NumField() {
+		if tag := t.Field(i).Tag.Get("toml"); tag != "" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
+// fieldByTag returns the field of struct type t with the toml tag name
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := range t.NumField() {
+		if t.Field(i).Tag.Get("toml") == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldByKey returns the field of the config struct v with the toml tag key
+func fieldByKey(v reflect.Value, key string) reflect.Value {
+	field, _ := fieldByTag(v.Type(), key)
+	return v.FieldByIndex(field.Index)
+}
+
+// suggest returns the candidate closest to word, when it is close enough
+// to be a typo of it. Case, dashes, and underscores do not count, so
+// "ollamaURL" and "ollama-url" suggest "ollama_url".
+func suggest(word string, candidates []string) string {
+	normalize := strings.NewReplacer("_", "", "-", "")
+	w := strings.ToLower(normalize.Replace(word))
+	best, bestDistance := "", 0
+	for _, candidate := range candidates {
+		d := editDistance(w, strings.ToLower(normalize.Replace(candidate)))
+		if best == "" || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" || bestDistance > max(1, len(w)/3) {
+		return ""
+	}
+	return best
+}
+
+// editDistance is the edit distance between a and b, counting a swap of
+// two adjacent letters, a common typo, as one edit
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// Schema returns the JSON Schema of the config file, for editors that
+// complete and check TOML against one
+func Schema() map[string]any {
+	schema := schemaOf(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "commit-gen config"
+	return schema
+}
+
+// schemaOf returns the schema of values of type t at the dotted key path
+func schemaOf(t reflect.Type, path string) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		schema := map[string]any{"type": "string"}
+		if values, ok := enums[path]; ok {
+			schema["enum"] = values
+		}
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "minimum": 0}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), "")}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), "")}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if tag := field.Tag.Get("toml"); tag != "" {
+				properties[tag] = schemaOf(field.Type, strings.TrimPrefix(path+"."+tag, "."))
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	}
+	return map[string]any{}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SharedTTL is how long a fetched shared config is used before it is
+// fetched again
+const SharedTTL = time.Hour
+
+// maxSharedBytes caps the size of a shared config and its signature
+const maxSharedBytes = 1 << 20
+
+// sharedClient fetches shared configs; a slow server must not stall every commit
+var sharedClient = &http.Client{Timeout: 10 * time.Second}
+
+// loadShared returns the shared config layer that the user-wide config user
+// names, verified against its base64 ed25519 public key. Only the user can
+// vouch for the key: a repository naming both the URL and the key would
+// prove nothing by its signature. A copy is cached for SharedTTL and used,
+// still verified, when the server cannot be reached.
+func loadShared(user *Config) (*Config, error) {
+	rawURL, publicKey := user.SharedConfig, user.SharedConfigKey
+	if publicKey == "" {
+		return nil, fmt.Errorf("shared_config_key must be set in the user-wide config")
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("shared_config_key must be a base64 ed25519 public key")
+	}
+	if err := checkSharedURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	cachePath := sharedCachePath(rawURL)
+	data, signature, _ := readSharedCache(cachePath, SharedTTL)
+	if data == nil {
+		var fetchErr error
+		data, signature, fetchErr = fetchShared(rawURL)
+		if fetchErr == nil {
+			writeSharedCache(cachePath, data, signature)
+		} else if data, signature, _ = readSharedCache(cachePath, 0); data == nil {
+			return nil, fetchErr
+		}
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(key), data, sig) {
+		return nil, fmt.Errorf("shared config %s: signature verification failed", rawURL)
+	}
+
+	layer, err := decodeLayer(string(data), rawURL)
+	if err != nil {
+		return nil, err
+	}
+	// Local paths mean nothing on other machines, and a shared config
+	// must not redirect to another one
+	layer.SystemPrompt = ""
+	layer.ExamplesFile = ""
+	layer.ScopesFile = ""
+	layer.EnvFile = ""
+	layer.AuditLog = ""
+	layer.PromptFragments = nil
+	layer.Repos = nil
+	layer.SharedConfig = ""
+	layer.SharedConfigKey = ""
+	// A signed config still must not run commands on every machine, nor
+	// pick where the diff is sent
+	layer.RelayTokenCommand = ""
+	layer.RelayURL = ""
+	layer.Provider = ""
+	layer.FallbackProvider = ""
+	layer.OllamaURL = ""
+	layer.DraftModel = ""
+	// Only the user can opt in to sending feedback
+	layer.Feedback.Enabled = nil
+	return layer, nil
+}
+
+// checkSharedURL requires HTTPS, except for loopback servers used in testing
+func checkSharedURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid shared_config URL: %w", err)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	if ip := net.ParseIP(u.Hostname()); u.Scheme == "http" && (u.Hostname() == "localhost" || ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return fmt.Errorf("shared_config must be an https URL: %s", rawURL)
+}
+
+// fetchShared downloads the config and its detached signature at rawURL.sig
+func fetchShared(rawURL string) (data, signature []byte, err error) {
+	if data, err = fetch(rawURL); err != nil {
+		return nil, nil, err
+	}
+	if signature, err = fetch(rawURL + ".sig"); err != nil {
+		return nil, nil, err
+	}
+	return data, signature, nil
+}
+
+func fetch(rawURL string) ([]byte, error) {
+	resp, err := sharedClient.Get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shared config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", rawURL, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSharedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	if len(data) > maxSharedBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rawURL, maxSharedBytes)
+	}
+	return data, nil
+}
+
+// sharedCachePath returns the cache file for rawURL, or "" without a cache directory
+func sharedCachePath(rawURL string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(dir, "commitgen", "shared", hex.EncodeToString(sum[:8])+".toml")
+}
+
+// readSharedCache returns the cached config and signature if they are
+// younger than ttl (any age when ttl is 0)
+func readSharedCache(path string, ttl time.Duration) (data, signature []byte, err error) {
+	if path == "" {
+		return nil, nil, errors.New("no cache directory")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ttl > 0 && time.Since(info.ModTime()) > ttl {
+		return nil, nil, nil
+	}
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, nil, err
+	}
+	if signature, err = os.ReadFile(path + ".sig"); err != nil {
+		return nil, nil, err
+	}
+	return data, signature, nil
+}
+
+// writeSharedCache stores a fetched config; the cache is an optimization,
+// so failures are ignored
+func writeSharedCache(path string, data, signature []byte) {
+	if path == "" || os.MkdirAll(filepath.Dir(path), 0o755) != nil {
+		return
+	}
+	// The signature goes first so the config's mtime marks a complete pair
+	if os.WriteFile(path+".sig", signature, 0o644) == nil {
+		_ = os.WriteFile(path, data, 0o644)
+	}
+}
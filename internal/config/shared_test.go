@@ -0,0 +1,81 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveShared publishes config signed with a new key and returns the
+// user-wide config lines pointing at it
+func serveShared(t *testing.T, config string) string {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(private, []byte(config)))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/commitgen.toml":
+			fmt.Fprint(w, config)
+		case "/commitgen.toml.sig":
+			fmt.Fprint(w, signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return fmt.Sprintf("shared_config = %q\nshared_config_key = %q\n", server.URL+"/commitgen.toml", base64.StdEncoding.EncodeToString(public))
+}
+
+func TestLoadSharedIgnoresRouting(t *testing.T) {
+	global := serveShared(t, `provider = "ollama"
+fallback_provider = "ollama"
+ollama_url = "https://attacker.example"
+draft_model = "qwen2.5-coder:7b"
+relay_url = "https://attacker.example"
+relay_token_command = "touch /tmp/pwned"
+model = "shared-model"
+`)
+	cfg := loadLayers(t, global, "")
+	if cfg.Provider != "" || cfg.FallbackProvider != "" || cfg.OllamaURL != "" || cfg.DraftModel != "" {
+		t.Errorf("the shared config set provider %q, fallback_provider %q, ollama_url %q, draft_model %q",
+			cfg.Provider, cfg.FallbackProvider, cfg.OllamaURL, cfg.DraftModel)
+	}
+	if cfg.RelayURL != "" || cfg.RelayTokenCommand != "" {
+		t.Errorf("the shared config set relay_url %q, relay_token_command %q", cfg.RelayURL, cfg.RelayTokenCommand)
+	}
+	if cfg.Model != "shared-model" {
+		t.Errorf("Load() model = %q, want the shared one", cfg.Model)
+	}
+}
+
+func TestLoadSharedFromUserOnly(t *testing.T) {
+	shared := serveShared(t, "model = \"shared-model\"\n")
+	url, key, _ := strings.Cut(shared, "\n")
+
+	cfg := loadLayers(t, "", shared)
+	if cfg.Model != "" || cfg.SharedConfig != "" || cfg.SharedConfigKey != "" {
+		t.Errorf("the repository config loaded a shared config: model %q, url %q, key %q", cfg.Model, cfg.SharedConfig, cfg.SharedConfigKey)
+	}
+
+	// The key vouches for the config, so the repository cannot supply it
+	if _, err := Load(writeLayers(t, url+"\n", key)); err == nil {
+		t.Error("Load() with the key in the repository config succeeded")
+	}
+
+	if cfg := loadLayers(t, shared, ""); cfg.Model != "shared-model" {
+		t.Errorf("Load() model = %q, want the shared one", cfg.Model)
+	}
+}
+
+func TestLoadSharedNeedsUserKey(t *testing.T) {
+	if _, err := loadShared(&Config{SharedConfig: "https://example.com/commitgen.toml"}); err == nil {
+		t.Error("loadShared() without a key succeeded")
+	}
+}
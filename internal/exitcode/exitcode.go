@@ -0,0 +1,147 @@
+// Package exitcode defines the exit codes and machine-readable errors of the
+// commit-gen CLI. They are a stable contract for wrapper scripts and editor
+// plugins: codes are only ever added, never renumbered or repurposed.
+package exitcode
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// Exit codes of the commit-gen CLI
+const (
+	// OK means a message was generated (or the command succeeded)
+	OK = 0
+	// Failure is any error not covered by a more specific code
+	Failure = 1
+	// Usage means invalid flags or arguments
+	Usage = 2
+	// NoChanges means there was nothing to describe, e.g. nothing staged
+	NoChanges = 3
+	// Config means a config, prompt, or examples file is missing or invalid
+	Config = 4
+	// Auth means the provider rejected the credentials
+	Auth = 5
+	// Quota means the provider's rate limit or quota is exhausted
+	Quota = 6
+	// Unavailable means the provider could not be reached or failed
+	Unavailable = 7
+	// Timeout means the provider did not answer in time
+	Timeout = 8
+	// Blocked means the provider refused to answer (safety filters)
+	Blocked = 9
+	// Validation means every generated message broke the commit rules
+	Validation = 10
+	// EmptyResponse means the model kept answering with no text
+	EmptyResponse = 11
+	// Policy means the repository policy forbids AI generation for the change
+	Policy = 12
+	// Cost means the request would cost more than the configured limit
+	Cost = 13
+	// ReadOnly means read-only mode forbids the change to the repository
+	ReadOnly = 14
+	// Interrupted means the user cancelled, e.g. with Ctrl-C
+	Interrupted = 130
+)
+
+// names are the stable identifiers of the exit codes used in JSON errors
+var names = map[int]string{
+	OK:            "ok",
+	Failure:       "failure",
+	Usage:         "usage",
+	NoChanges:     "no_changes",
+	Config:        "config",
+	Auth:          "auth",
+	Quota:         "quota_exceeded",
+	Unavailable:   "provider_unavailable",
+	Timeout:       "timeout",
+	Blocked:       "blocked",
+	Validation:    "validation_failed",
+	EmptyResponse: "empty_response",
+	Policy:        "policy",
+	Cost:          "cost_limit",
+	ReadOnly:      "read_only",
+	Interrupted:   "interrupted",
+}
+
+// Name returns the stable identifier of code
+func Name(code int) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return names[Failure]
+}
+
+// Classify returns the exit code for err
+func Classify(err error) int {
+	var providerErr *generator.ProviderError
+	var blockedErr *generator.BlockedError
+	var validationErr *generator.ValidationError
+	var policyErr *generator.PolicyError
+	var costErr *generator.CostError
+	var netErr net.Error
+
+	switch {
+	case err == nil, errors.Is(err, generator.ErrDisabled):
+		// Switching generation off is a choice, not a failure
+		return OK
+	case errors.Is(err, context.Canceled):
+		return Interrupted
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout
+	case errors.Is(err, generator.ErrNoChanges):
+		return NoChanges
+	case errors.Is(err, generator.ErrEmptyResponse):
+		return EmptyResponse
+	case errors.As(err, &blockedErr):
+		return Blocked
+	case errors.As(err, &validationErr):
+		return Validation
+	case errors.As(err, &policyErr):
+		return Policy
+	case errors.As(err, &costErr):
+		return Cost
+	case errors.Is(err, generator.ErrReadOnly):
+		return ReadOnly
+	case errors.Is(err, generator.ErrRelayToken):
+		return Auth
+	case errors.As(err, &providerErr):
+		switch {
+		case providerErr.StatusCode == http.StatusUnauthorized || providerErr.StatusCode == http.StatusForbidden:
+			return Auth
+		case providerErr.StatusCode == http.StatusTooManyRequests:
+			return Quota
+		case providerErr.StatusCode == http.StatusRequestTimeout || providerErr.StatusCode == http.StatusGatewayTimeout:
+			return Timeout
+		case providerErr.StatusCode >= 500:
+			return Unavailable
+		}
+		return Failure
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return Timeout
+		}
+		return Unavailable
+	}
+	return Failure
+}
+
+// Error is the JSON object written to stderr when machine-readable errors
+// are requested
+type Error struct {
+	// Code is the process exit code
+	Code int `json:"code"`
+	// Error is the stable identifier of Code, e.g. "quota_exceeded"
+	Error string `json:"error"`
+	// Message is the human readable description
+	Message string `json:"message"`
+}
+
+// NewError builds the JSON error for err exiting with code
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Error: Name(code), Message: message}
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// anonymizedProvider hides sensitive terms from the wrapped provider: it
+// replaces them with placeholders in everything sent, and puts them back in
+// the text that comes back
+type anonymizedProvider struct {
+	Provider
+	hide    *strings.Replacer
+	restore *strings.Replacer
+}
+
+// anonymize wraps provider so that it never sees the terms of dictionary,
+// which maps each term to its placeholder
+func anonymize(provider Provider, dictionary map[string]string) Provider {
+	if len(dictionary) == 0 {
+		return provider
+	}
+	return &anonymizedProvider{
+		Provider: provider,
+		hide:     newTermReplacer(dictionary),
+		restore:  newTermReplacer(invert(dictionary)),
+	}
+}
+
+// GenerateText sends the request with the terms hidden and restores them
+// in the response
+func (p *anonymizedProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	hidden := *req
+	hidden.SystemPrompt = p.hide.Replace(req.SystemPrompt)
+	hidden.Prompt = p.hide.Replace(req.Prompt)
+	resp, err := p.Provider.GenerateText(ctx, &hidden)
+	if err != nil {
+		return nil, err
+	}
+	restored := *resp
+	restored.Text = p.restore.Replace(resp.Text)
+	return &restored, nil
+}
+
+// Embed embeds the texts with the terms hidden; queries and the history
+// index are hidden alike, so they still match
+func (p *anonymizedProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	hidden := *req
+	hidden.Texts = make([]string, len(req.Texts))
+	for i, text := range req.Texts {
+		hidden.Texts[i] = p.hide.Replace(text)
+	}
+	return embed(ctx, p.Provider, &hidden)
+}
+
+// Ping forwards health checks when the wrapped provider supports them
+func (p *anonymizedProvider) Ping(ctx context.Context, model string) error {
+	if checker, ok := p.Provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
+
+// failover returns the failover provider beneath the anonymizer, or nil
+// without a fallback provider
+func (g *CommitMessageGenerator) failover() *failoverProvider {
+	provider := g.provider
+	if p, ok := provider.(*anonymizedProvider); ok {
+		provider = p.Provider
+	}
+	fp, _ := provider.(*failoverProvider)
+	return fp
+}
+
+// newTermReplacer replaces the keys of pairs with their values, trying
+// longer keys first so that a term containing another one wins
+func newTermReplacer(pairs map[string]string) *strings.Replacer {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Or(len(b)-len(a), strings.Compare(a, b))
+	})
+	oldnew := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		oldnew = append(oldnew, key, pairs[key])
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// invert swaps the keys and values of dictionary
+func invert(dictionary map[string]string) map[string]string {
+	inverted := make(map[string]string, len(dictionary))
+	for term, placeholder := range dictionary {
+		inverted[placeholder] = term
+	}
+	return inverted
+}
+
+// validateDictionary checks that every term has its own placeholder, so
+// that the terms can be restored, and that no placeholder is itself a term
+func validateDictionary(dictionary map[string]string) error {
+	terms := make(map[string]string, len(dictionary))
+	for term, placeholder := range dictionary {
+		switch {
+		case strings.TrimSpace(term) == "":
+			return errors.New("the anonymize dictionary has an empty term")
+		case strings.TrimSpace(placeholder) == "":
+			return fmt.Errorf("the anonymize term %q has no placeholder", term)
+		case terms[placeholder] != "":
+			return fmt.Errorf("the anonymize terms %q and %q share the placeholder %q", terms[placeholder], term, placeholder)
+		}
+		if _, ok := dictionary[placeholder]; ok {
+			return fmt.Errorf("the anonymize placeholder %q is also a term", placeholder)
+		}
+		terms[placeholder] = term
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProviderAnthropic uses the Anthropic Messages API and its Claude models
+const ProviderAnthropic = "anthropic"
+
+// AnthropicAPIKeyEnv holds the Anthropic API key. With provider =
+// "anthropic", Options.APIKey and APIKeyEnv take precedence over it.
+const AnthropicAPIKeyEnv = "ANTHROPIC_API_KEY"
+
+// DefaultAnthropicURL is used when TransportOptions.BaseURL is not set
+const DefaultAnthropicURL = "https://api.anthropic.com"
+
+// anthropicVersion is the API version sent with every request
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens is the output limit of requests without one, as the
+// Messages API requires a limit
+const anthropicMaxTokens = 1024
+
+// anthropicProvider talks to the Anthropic Messages API
+type anthropicProvider struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// newAnthropicProvider creates an Anthropic backed Provider; an empty
+// baseURL is DefaultAnthropicURL
+func newAnthropicProvider(apiKey, baseURL string, httpClient *http.Client) *anthropicProvider {
+	if baseURL == "" {
+		baseURL = DefaultAnthropicURL
+	}
+	return &anthropicProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}
+}
+
+// anthropicMessage is one turn of the conversation
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// anthropicThinking enables extended thinking
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int32  `json:"budget_tokens"`
+}
+
+// anthropicRequest is the body of POST /v1/messages
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Temperature *float32           `json:"temperature,omitempty"`
+	Thinking    *anthropicThinking `json:"thinking,omitempty"`
+}
+
+// anthropicResponse is the reply of POST /v1/messages
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Name returns the provider identifier
+func (p *anthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// GenerateText sends the request to the Messages API. The API has no
+// sampling seed, so TextRequest.Seed is ignored.
+func (p *anthropicProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	body := &anthropicRequest{
+		Model:       req.Model,
+		MaxTokens:   req.MaxOutputTokens,
+		System:      req.SystemPrompt,
+		Messages:    []anthropicMessage{{Role: "user", Content: req.Prompt}},
+		Temperature: req.Temperature,
+	}
+	if body.MaxTokens <= 0 {
+		body.MaxTokens = anthropicMaxTokens
+	}
+	if req.ThinkingBudget != nil && *req.ThinkingBudget > 0 {
+		// Thinking counts against max_tokens, which is sized for the
+		// answer, and only runs at the default temperature
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: *req.ThinkingBudget}
+		body.MaxTokens += int(*req.ThinkingBudget)
+		body.Temperature = nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode anthropic request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create anthropic request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	p.authorize(httpReq)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+
+	var out anthropicResponse
+	decodeErr := json.Unmarshal(data, &out)
+	if resp.StatusCode != http.StatusOK || out.Error != nil {
+		return nil, anthropicError(resp.StatusCode, &out, decodeErr, data)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode anthropic response: %w", decodeErr)
+	}
+
+	// Thinking blocks come first and are left out of the answer
+	var text strings.Builder
+	for _, block := range out.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	response := &TextResponse{
+		Text:         text.String(),
+		Model:        out.Model,
+		FinishReason: anthropicFinishReason(out.StopReason),
+		Usage: Usage{
+			PromptTokens: out.Usage.InputTokens,
+			OutputTokens: out.Usage.OutputTokens,
+			TotalTokens:  out.Usage.InputTokens + out.Usage.OutputTokens,
+		},
+	}
+	if response.FinishReason == FinishReasonSafety {
+		response.BlockReason = out.StopReason
+	}
+	return response, nil
+}
+
+// anthropicFinishReason maps an Anthropic stop reason to a FinishReason
+// constant
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "":
+		return FinishReasonStop
+	case "max_tokens":
+		return FinishReasonLength
+	case "refusal":
+		return FinishReasonSafety
+	default:
+		return FinishReasonOther
+	}
+}
+
+// anthropicError turns an error reply into a ProviderError
+func anthropicError(status int, out *anthropicResponse, decodeErr error, data []byte) error {
+	var message string
+	switch {
+	case decodeErr != nil:
+		// Proxies answer with plain text or HTML
+		message = strings.TrimSpace(truncateBytes(string(data), 200))
+	case out.Error != nil:
+		message = out.Error.Message
+	}
+	return &ProviderError{Provider: ProviderAnthropic, StatusCode: status, Message: message}
+}
+
+// authorize adds the API key and version headers
+func (p *anthropicProvider) authorize(req *http.Request) {
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+}
+
+// Ping verifies the API key and model by fetching the model metadata
+func (p *anthropicProvider) Ping(ctx context.Context, model string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/v1/models/"+url.PathEscape(model), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create anthropic request: %w", err)
+	}
+	p.authorize(httpReq)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach anthropic: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read anthropic response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var out anthropicResponse
+		return anthropicError(resp.StatusCode, &out, json.Unmarshal(data, &out), data)
+	}
+	return nil
+}
+
+// Close cleans up resources
+func (p *anthropicProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// asciiReplacements maps common typographic characters to ASCII equivalents
+var asciiReplacements = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '′': "'",
+	'“': `"`, '”': `"`, '„': `"`, '″': `"`,
+	'–': "-", '—': "--", '―': "--", '‐': "-", '‑': "-", '−': "-",
+	'…': "...", '•': "*", '·': "*",
+	'→': "->", '←': "<-", '⇒': "=>", '≤': "<=", '≥': ">=", '≠': "!=", '×': "x",
+	' ': " ", ' ': " ", ' ': " ",
+	'©': "(c)", '®': "(R)", '™': "(TM)",
+}
+
+// asciiFolds strips the diacritics of common Latin letters
+var asciiFolds = map[rune]rune{}
+
+func init() {
+	for ascii, accented := range map[rune]string{
+		'a': "àáâãäåā", 'A': "ÀÁÂÃÄÅĀ", 'c': "çćč", 'C': "ÇĆČ",
+		'e': "èéêëēę", 'E': "ÈÉÊËĒĘ", 'i': "ìíîïī", 'I': "ÌÍÎÏĪ",
+		'n': "ñń", 'N': "ÑŃ", 'o': "òóôõöøō", 'O': "ÒÓÔÕÖØŌ",
+		'u': "ùúûüū", 'U': "ÙÚÛÜŪ", 'y': "ýÿ", 'Y': "Ý",
+		's': "śš", 'S': "ŚŠ", 'z': "źżž", 'Z': "ŹŻŽ", 'l': "ł", 'L': "Ł",
+		'd': "đ", 'D': "Đ",
+	} {
+		for _, r := range accented {
+			asciiFolds[r] = ascii
+		}
+	}
+}
+
+// ToASCII rewrites text using only ASCII characters: typographic punctuation
+// is replaced, accents are stripped, and anything else (emoji, symbols) is
+// removed
+func ToASCII(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r <= unicode.MaxASCII:
+			b.WriteRune(r)
+		case asciiReplacements[r] != "":
+			b.WriteString(asciiReplacements[r])
+		case asciiFolds[r] != 0:
+			b.WriteRune(asciiFolds[r])
+		}
+	}
+
+	// Removed emoji leave doubled or trailing spaces behind
+	lines := strings.Split(b.String(), "\n")
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		lines[i] = line[:indent] + strings.Join(strings.Fields(line[indent:]), " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// violationPenalty is the score lost for each broken rule
+const violationPenalty = 25
+
+// CommitScore is the audit result of a single commit
+type CommitScore struct {
+	Hash       string      `json:"hash"`
+	Subject    string      `json:"subject"`
+	Violations []Violation `json:"violations,omitempty"`
+	// Informativeness is the model's 1-5 rating (0 when not rated)
+	Informativeness int `json:"informativeness,omitempty"`
+	// Score is 0-100, combining rule conformance and informativeness
+	Score float64 `json:"score"`
+}
+
+// AuditReport summarizes how well a history follows the convention
+type AuditReport struct {
+	Commits []CommitScore `json:"commits"`
+	// AverageScore is the mean commit score
+	AverageScore float64 `json:"average_score"`
+	// Conforming is the number of commits without violations
+	Conforming int `json:"conforming"`
+	// ViolationCounts counts commits breaking each rule
+	ViolationCounts map[string]int `json:"violation_counts"`
+}
+
+// AuditCommits scores commits against rules without calling a model
+func AuditCommits(commits []Commit, rules *ValidationRules) *AuditReport {
+	report := &AuditReport{ViolationCounts: make(map[string]int)}
+
+	for _, commit := range commits {
+		msg := ParseCommitMessage(commit.Message)
+		violations := Validate(msg, rules)
+
+		report.Commits = append(report.Commits, CommitScore{
+			Hash:       commit.Hash,
+			Subject:    msg.Header,
+			Violations: violations,
+			Score:      float64(max(0, 100-violationPenalty*len(violations))),
+		})
+
+		if len(violations) == 0 {
+			report.Conforming++
+		}
+		seen := make(map[string]bool)
+		for _, v := range violations {
+			if !seen[v.Rule] {
+				report.ViolationCounts[v.Rule]++
+				seen[v.Rule] = true
+			}
+		}
+	}
+
+	report.updateAverage()
+	return report
+}
+
+// updateAverage recomputes the average score
+func (r *AuditReport) updateAverage() {
+	if len(r.Commits) == 0 {
+		r.AverageScore = 0
+		return
+	}
+	var total float64
+	for _, c := range r.Commits {
+		total += c.Score
+	}
+	r.AverageScore = total / float64(len(r.Commits))
+}
+
+// TopViolations returns the violated rules, most frequent first
+func (r *AuditReport) TopViolations() []string {
+	rules := make([]string, 0, len(r.ViolationCounts))
+	for rule := range r.ViolationCounts {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if r.ViolationCounts[rules[i]] != r.ViolationCounts[rules[j]] {
+			return r.ViolationCounts[rules[i]] > r.ViolationCounts[rules[j]]
+		}
+		return rules[i] < rules[j]
+	})
+	return rules
+}
+
+// informativenessRating is one entry of the model's rating response
+type informativenessRating struct {
+	Index int `json:"index"`
+	Score int `json:"score"`
+}
+
+// Audit scores the last count commits against the configured convention.
+// With rateWithModel, the model also rates how informative each message is
+// and the rating is blended into the score.
+func (c *CommitGen) Audit(ctx context.Context, count int, rateWithModel bool) (*AuditReport, error) {
+	commits, err := c.repo.GetCommits(fmt.Sprintf("-%d", count))
+	if err != nil {
+		return nil, err
+	}
+
+	report := AuditCommits(commits, c.generator.rulesFor(false))
+	if !rateWithModel || len(commits) == 0 {
+		return report, nil
+	}
+
+	ratings, err := c.generator.rateInformativeness(ctx, commits)
+	if err != nil {
+		return nil, err
+	}
+	for _, rating := range ratings {
+		if rating.Index < 0 || rating.Index >= len(report.Commits) || rating.Score < 1 || rating.Score > 5 {
+			continue
+		}
+		entry := &report.Commits[rating.Index]
+		entry.Informativeness = rating.Score
+		entry.Score = (entry.Score + float64(rating.Score)/5*100) / 2
+	}
+	report.updateAverage()
+
+	return report, nil
+}
+
+// rateInformativeness asks the model to rate every commit message from 1 to 5
+func (g *CommitMessageGenerator) rateInformativeness(ctx context.Context, commits []Commit) ([]informativenessRating, error) {
+	var b strings.Builder
+	for i, commit := range commits {
+		fmt.Fprintf(&b, "--- commit %d ---\n%s\n\n", i, commit.Message)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    prompts.Template(prompts.Audit),
+		Prompt:          b.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Report,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rate commit messages: %w", err)
+	}
+
+	var ratings []informativenessRating
+	if err := decodeJSONResponse(resp.Text, &ratings); err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events of the audit log
+const (
+	// AuditGeneration is a commit message generated, or refused by the
+	// repository policy
+	AuditGeneration = "generation"
+	// AuditProviderCall is a request to a provider, for any feature
+	AuditProviderCall = "provider_call"
+	// AuditGit is a command that changed the repository
+	AuditGit = "git"
+)
+
+// AuditEntry is one line of the audit log, see Options.AuditLog
+type AuditEntry struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`
+	// Repo is the top directory of the repository
+	Repo string `json:"repo,omitempty"`
+	// DiffSHA256 identifies the change a generation described without
+	// revealing it
+	DiffSHA256 string `json:"diff_sha256,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Tokens     *Usage `json:"tokens,omitempty"`
+	// Redacted is true when Anonymize hid terms from the provider
+	Redacted bool `json:"redacted"`
+	// Policy is the policy action taken when the change touched a
+	// forbidden path
+	Policy string `json:"policy,omitempty"`
+	// Command is the command that changed the repository
+	Command string `json:"command,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// auditLog appends AuditEntry lines to a file. A nil *auditLog records
+// nothing.
+type auditLog struct {
+	path       string
+	workingDir string
+	mu         sync.Mutex
+	repoOnce   sync.Once
+	repo       string
+}
+
+// newAuditLog opens the audit log at path for the repository in
+// workingDir, or returns nil when path is empty. It fails right away when
+// the file cannot be written, rather than after a request went unrecorded.
+func newAuditLog(path, workingDir string) (*auditLog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	f.Close()
+	return &auditLog{path: path, workingDir: workingDir}, nil
+}
+
+// record stamps entry with the time and repository and appends it
+func (l *auditLog) record(entry AuditEntry) error {
+	if l == nil {
+		return nil
+	}
+	l.repoOnce.Do(func() {
+		if root, err := runIn(l.workingDir, "git", "rev-parse", "--show-toplevel"); err == nil {
+			l.repo = strings.TrimSpace(root)
+		} else if abs, err := filepath.Abs(l.workingDir); err == nil {
+			// jj and hg repositories, or none at all
+			l.repo = abs
+		}
+	})
+	entry.Time = time.Now().UTC()
+	entry.Repo = l.repo
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// generation records the outcome of a commit message generation
+func (l *auditLog) generation(gitInfo *GitInfo, dictionary map[string]string, policy string, result *Result, err error) error {
+	if l == nil {
+		return nil
+	}
+	entry := AuditEntry{Event: AuditGeneration, Policy: policy}
+	if gitInfo != nil {
+		sum := sha256.Sum256([]byte(gitInfo.StagedDiff))
+		entry.DiffSHA256 = hex.EncodeToString(sum[:])
+		// The offline fallback sends nothing to hide terms from
+		entry.Redacted = policy == "" && containsTerm(dictionary, gitInfo.StagedDiff, gitInfo.Hint, gitInfo.Notes)
+	}
+	if result != nil {
+		entry.Model = result.Model
+		entry.Tokens = &result.Tokens
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return l.record(entry)
+}
+
+// containsTerm reports whether any term of dictionary occurs in texts
+func containsTerm(dictionary map[string]string, texts ...string) bool {
+	for term := range dictionary {
+		for _, text := range texts {
+			if strings.Contains(text, term) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// auditedProvider records every call of the wrapped provider in the audit
+// log. A call that cannot be recorded fails.
+type auditedProvider struct {
+	Provider
+	log *auditLog
+	// dictionary holds the terms the wrapped provider hides, if any
+	dictionary map[string]string
+}
+
+// audit wraps provider so that its calls are recorded in log, if any
+func audit(provider Provider, log *auditLog, dictionary map[string]string) Provider {
+	if log == nil {
+		return provider
+	}
+	return &auditedProvider{Provider: provider, log: log, dictionary: dictionary}
+}
+
+// GenerateText forwards the request and records it
+func (p *auditedProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	resp, err := p.Provider.GenerateText(ctx, req)
+	entry := AuditEntry{
+		Event:    AuditProviderCall,
+		Provider: p.Provider.Name(),
+		Model:    req.Model,
+		Redacted: containsTerm(p.dictionary, req.SystemPrompt, req.Prompt),
+	}
+	if resp != nil {
+		entry.Tokens = &resp.Usage
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := p.log.record(entry); logErr != nil && err == nil {
+		return nil, logErr
+	}
+	return resp, err
+}
+
+// Embed forwards the request and records it
+func (p *auditedProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	vectors, err := embed(ctx, p.Provider, req)
+	entry := AuditEntry{
+		Event:    AuditProviderCall,
+		Provider: p.Provider.Name(),
+		Model:    req.Model,
+		Redacted: containsTerm(p.dictionary, req.Texts...),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := p.log.record(entry); logErr != nil && err == nil {
+		return nil, logErr
+	}
+	return vectors, err
+}
+
+// Ping forwards health checks when the wrapped provider supports them
+func (p *auditedProvider) Ping(ctx context.Context, model string) error {
+	if checker, ok := p.Provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
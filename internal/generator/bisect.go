@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// maxBisectDiffBytes caps the diff of the first bad commit in the prompt
+const maxBisectDiffBytes = 48 << 10
+
+// firstBadCommit matches the line git bisect writes to BISECT_LOG when done
+var firstBadCommit = regexp.MustCompile(`(?m)^# first bad commit: \[([0-9a-f]+)\]`)
+
+// BisectResult returns the first bad commit of the bisect in progress, once
+// git bisect has found it
+func (g *GitRepository) BisectResult() (string, error) {
+	logPath, err := g.run("rev-parse", "--git-path", "BISECT_LOG")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	logPath = strings.TrimSpace(logPath)
+	if !filepath.IsAbs(logPath) && g.workingDir != "" {
+		logPath = filepath.Join(g.workingDir, logPath)
+	}
+	data, err := os.ReadFile(logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errors.New("no git bisect in progress, name the commit to explain")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read the bisect log: %w", err)
+	}
+	match := firstBadCommit.FindSubmatch(data)
+	if match == nil {
+		return "", errors.New("git bisect has not found the first bad commit yet, name the commit to explain")
+	}
+	return string(match[1]), nil
+}
+
+// ExplainBisect explains in plain language what the commit rev changed and
+// why it plausibly caused the regression described by symptom (optional),
+// for pasting into a bug report. An empty rev means the first bad commit of
+// the current git bisect.
+func (c *CommitGen) ExplainBisect(ctx context.Context, rev, symptom string) (summary *Summary, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.ExplainBisect")
+	defer func() { endSpan(span, err) }()
+
+	if rev == "" {
+		if rev, err = c.repo.BisectResult(); err != nil {
+			return nil, err
+		}
+	}
+	commits, err := c.repo.GetCommits("-1", rev+"^{commit}", "--")
+	if err != nil || len(commits) == 0 {
+		return nil, fmt.Errorf("unknown commit %s", rev)
+	}
+	commit := commits[0]
+
+	// --first-parent shows a merge the way it landed on the branch
+	diff, err := c.repo.run("show", "--format=", "--stat", "--patch", "--first-parent", commit.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the commit diff: %w", err)
+	}
+	if forbidden := ForbiddenPaths(c.policyPaths, ParseDiff(diff)); len(forbidden) > 0 {
+		return nil, &PolicyError{Paths: forbidden}
+	}
+	if truncated := truncateBytes(diff, maxBisectDiffBytes); len(truncated) < len(diff) {
+		diff = truncated + "\n[... diff truncated]\n"
+	}
+
+	var prompt strings.Builder
+	if symptom = strings.TrimSpace(symptom); symptom != "" {
+		fmt.Fprintf(&prompt, "Regression:\n%s\n\n", symptom)
+	}
+	fmt.Fprintf(&prompt, "First bad commit:\n%s", formatCommitLog(commits, ""))
+	fmt.Fprintf(&prompt, "Diff:\n%s", diff)
+
+	summary, err = c.generator.summarize(ctx, prompts.Template(prompts.Bisect), prompt.String(), 1)
+	if err != nil {
+		return nil, err
+	}
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	summary.Text = fmt.Sprintf("First bad commit: %s (%s)\n\n%s", commit.Hash[:min(len(commit.Hash), 12)], subject, summary.Text)
+	return summary, nil
+}
@@ -0,0 +1,98 @@
+package generator
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxBlameCommits caps how many blamed commits are added to the prompt
+const maxBlameCommits = 10
+
+// blamedCommit is a commit that last touched some of the modified lines
+type blamedCommit struct {
+	hash    string
+	subject string
+	lines   int
+}
+
+// GetBlameContext lists the commits that last touched the lines modified by
+// diff, most involved first, as "<short hash> <subject>" lines
+func (g *GitRepository) GetBlameContext(diff string) (string, error) {
+	commits := make(map[string]*blamedCommit)
+
+	for _, file := range ParseDiff(diff) {
+		// Added files have no history to blame
+		if file.OldPath == "" {
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			// Pure insertions only have context lines on the old side
+			if hunk.OldLines == 0 || hunk.OldStart < 1 {
+				continue
+			}
+			output, err := g.run("blame", "--porcelain", "-L",
+				fmt.Sprintf("%d,+%d", hunk.OldStart, hunk.OldLines), "HEAD", "--", file.OldPath)
+			if err != nil {
+				return "", fmt.Errorf("failed to blame %s: %w", file.OldPath, err)
+			}
+			parseBlame(output, commits)
+		}
+	}
+
+	ranked := make([]*blamedCommit, 0, len(commits))
+	for _, commit := range commits {
+		ranked = append(ranked, commit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].lines != ranked[j].lines {
+			return ranked[i].lines > ranked[j].lines
+		}
+		return ranked[i].hash < ranked[j].hash
+	})
+	if len(ranked) > maxBlameCommits {
+		ranked = ranked[:maxBlameCommits]
+	}
+
+	var b strings.Builder
+	for _, commit := range ranked {
+		fmt.Fprintf(&b, "%s %s\n", commit.hash[:min(7, len(commit.hash))], commit.subject)
+	}
+	return b.String(), nil
+}
+
+// parseBlame accumulates the commits of git blame --porcelain output
+func parseBlame(output string, commits map[string]*blamedCommit) {
+	var current *blamedCommit
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\t") {
+			// The content line ends the entry of a blamed line
+			if current != nil {
+				current.lines++
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && len(fields[0]) == 40 {
+			// Uncommitted lines are blamed on the all-zero hash
+			if strings.Trim(fields[0], "0") == "" {
+				current = nil
+				continue
+			}
+			current = commits[fields[0]]
+			if current == nil {
+				current = &blamedCommit{hash: fields[0]}
+				commits[fields[0]] = current
+			}
+			continue
+		}
+
+		if subject, ok := strings.CutPrefix(line, "summary "); ok && current != nil {
+			current.subject = subject
+		}
+	}
+}
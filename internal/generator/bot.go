@@ -0,0 +1,155 @@
+package generator
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BotModel is the Result.Model of messages written by BotMessage
+const BotModel = "bot"
+
+// Kinds of automated change for BotUpdate
+const (
+	// BotDependencies is a dependency version bump, renovate-style
+	BotDependencies = "deps"
+	// BotCodegen is a refresh of generated code
+	BotCodegen = "codegen"
+)
+
+// DependencyUpdate is one dependency moved from one version to another
+type DependencyUpdate struct {
+	Name string `json:"name"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to"`
+}
+
+// String describes the update, e.g. "bump cobra from v1.7.0 to v1.8.0"
+func (u DependencyUpdate) String() string {
+	if u.From == "" {
+		return fmt.Sprintf("bump %s to %s", u.Name, u.To)
+	}
+	return fmt.Sprintf("bump %s from %s to %s", u.Name, u.From, u.To)
+}
+
+// BotUpdate is the structured description of an automated change that a
+// dependency-update or codegen bot hands to BotMessage
+type BotUpdate struct {
+	// Kind is BotDependencies or BotCodegen
+	Kind string `json:"kind"`
+	// Dependencies are the bumped dependencies, for BotDependencies
+	Dependencies []DependencyUpdate `json:"dependencies,omitempty"`
+	// Dev marks development-only dependencies, scoped deps-dev
+	Dev bool `json:"dev,omitempty"`
+	// Generator names the code generator, e.g. "protoc", for BotCodegen
+	Generator string `json:"generator,omitempty"`
+	// Source is what the code was generated from, e.g. "api/v1/service.proto"
+	Source string `json:"source,omitempty"`
+}
+
+// botScope is the scope of the message for an update
+func botScope(update *BotUpdate) string {
+	switch {
+	case update.Kind == BotCodegen:
+		return "codegen"
+	case update.Dev:
+		return "deps-dev"
+	}
+	return "deps"
+}
+
+// botSubjects returns the subjects for update, most specific first; the
+// first that fits the subject limit is used
+func botSubjects(update *BotUpdate) ([]string, error) {
+	switch update.Kind {
+	case BotDependencies:
+		if len(update.Dependencies) == 0 {
+			return nil, errors.New("a dependency update needs at least one dependency")
+		}
+		for _, dep := range update.Dependencies {
+			if dep.Name == "" || dep.To == "" {
+				return nil, errors.New("every dependency needs a name and the version it moves to")
+			}
+		}
+		if len(update.Dependencies) > 1 {
+			return []string{fmt.Sprintf("bump %d dependencies", len(update.Dependencies))}, nil
+		}
+		dep := update.Dependencies[0]
+		return []string{dep.String(), "bump " + dep.Name, "bump dependency"}, nil
+	case BotCodegen:
+		if update.Generator == "" {
+			return []string{"regenerate code"}, nil
+		}
+		return []string{fmt.Sprintf("regenerate %s output", update.Generator), "regenerate code"}, nil
+	}
+	return nil, fmt.Errorf("unknown bot update kind %q, use %s or %s", update.Kind, BotDependencies, BotCodegen)
+}
+
+// BotMessage writes the message for an automated change from its
+// structured description alone, without a model or an API key, so the same
+// update always gets the same message. The convention, validation subject
+// limit, and pinned type and scope of opts apply; the body lists what
+// changed unless the message is short.
+func BotMessage(update *BotUpdate, opts *Options) (*Result, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	subjects, err := botSubjects(update)
+	if err != nil {
+		return nil, err
+	}
+	prefix := SubjectPrefix("chore", botScope(update))
+	rules := DefaultValidationRules(opts.IsShortCommit)
+	if opts.Convention == ConventionKernel {
+		prefix = botScope(update) + ": "
+		rules = kernelValidationRules(opts.IsShortCommit, opts.SeriesLength)
+	}
+	if opts.Validation != nil {
+		rules = opts.Validation
+	}
+
+	header := prefix + subjects[len(subjects)-1]
+	for _, subject := range subjects {
+		if rules.MaxSubjectLength <= 0 || len(prefix+subject) <= rules.MaxSubjectLength {
+			header = prefix + subject
+			break
+		}
+	}
+
+	var body strings.Builder
+	switch update.Kind {
+	case BotDependencies:
+		// A single bump whose versions fit the subject needs no body
+		if len(update.Dependencies) > 1 || !strings.HasSuffix(header, update.Dependencies[0].String()) {
+			for _, dep := range update.Dependencies {
+				fmt.Fprintf(&body, "- %s\n", dep)
+			}
+		}
+	case BotCodegen:
+		if update.Source != "" {
+			fmt.Fprintf(&body, "Generated from %s.\n", update.Source)
+		}
+	}
+
+	message := header
+	if !opts.IsShortCommit && body.Len() > 0 {
+		message += "\n\n" + strings.TrimSuffix(body.String(), "\n")
+	}
+
+	subjectPrefix, scope := opts.SubjectPrefix, ""
+	switch {
+	case subjectPrefix != "":
+	case opts.Type != "":
+		// A pinned type keeps the scope of the update unless that is pinned too
+		subjectPrefix = SubjectPrefix(opts.Type, cmp.Or(opts.Scope, botScope(update)))
+	default:
+		scope = opts.Scope
+	}
+	return &Result{
+		Message:      applyPins(ParseCommitMessage(message), subjectPrefix, scope),
+		Model:        BotModel,
+		Attempts:     1,
+		FinishReason: FinishReasonStop,
+	}, nil
+}
@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// responseCache is a small in-memory LRU cache of generated results keyed by
+// everything that influences the output (model, prompts)
+type responseCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// cacheEntry is a single cached result
+type cacheEntry struct {
+	key    string
+	result *Result
+}
+
+// newResponseCache creates a cache holding at most size messages
+func newResponseCache(size int) *responseCache {
+	return &responseCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// cacheKey hashes the parts of a request into a cache key
+func cacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the cached result for key, if any
+// The returned result must not be modified
+func (c *responseCache) get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).result, true
+}
+
+// put stores a copy of result, evicting the least recently used one when full
+func (c *responseCache) put(key string, result *Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := *result
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*cacheEntry).result = &stored
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: &stored})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,149 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IndexStats describes the history index after Index
+type IndexStats struct {
+	Model string `json:"model"`
+	// Commits is the number of commits of the current branch in the index
+	Commits int `json:"commits"`
+	// Embedded is the number of commits embedded by this run
+	Embedded int `json:"embedded"`
+	// Modules is the number of modules in the module map
+	Modules int `json:"modules"`
+}
+
+// Index brings the repository caches up to date ahead of use: it embeds
+// the commits missing from the history index that Search uses, and rebuilds
+// the module map if a manifest changed. With rebuild, the history index is
+// embedded again from scratch, e.g. after a model upgrade.
+func (c *CommitGen) Index(ctx context.Context, rebuild bool) (stats *IndexStats, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Index")
+	defer func() { endSpan(span, err) }()
+
+	model := c.generator.config.EmbeddingModel
+	if c.generator.config.Disabled {
+		return nil, ErrDisabled
+	}
+	if rebuild {
+		if err := c.repo.removeCacheFile(embeddingsCacheFile); err != nil {
+			return nil, err
+		}
+	}
+	before := c.repo.loadIndex(model)
+	commits, index, err := c.updateIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats = &IndexStats{Model: model, Embedded: len(index.Vectors) - len(before.Vectors)}
+	for _, commit := range commits {
+		if _, ok := index.Vectors[commit.Hash]; ok {
+			stats.Commits++
+		}
+	}
+
+	modules, err := c.repo.GetModuleMap()
+	if err != nil {
+		return nil, err
+	}
+	stats.Modules = len(modules.Modules)
+	return stats, nil
+}
+
+// CacheFile describes a file of the repository cache
+type CacheFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Entries is the number of commits, modules, or messages it holds
+	Entries int `json:"entries"`
+	// Model is the embedding model of the history index
+	Model string `json:"model,omitempty"`
+}
+
+// CacheStats describes the repository cache
+type CacheStats struct {
+	Dir   string      `json:"dir"`
+	Files []CacheFile `json:"files"`
+	Size  int64       `json:"size"`
+}
+
+// CacheStats reports what the commit-gen cache of the repository holds
+func (g *GitRepository) CacheStats() (*CacheStats, error) {
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	stats := &CacheStats{Dir: cacheDir, Files: []CacheFile{}}
+	entries, err := os.ReadDir(cacheDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cache: %w", err)
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		file := CacheFile{Name: entry.Name(), Size: info.Size()}
+		data, _ := os.ReadFile(filepath.Join(cacheDir, entry.Name()))
+		switch entry.Name() {
+		case embeddingsCacheFile:
+			var index historyIndex
+			if json.Unmarshal(data, &index) == nil {
+				file.Entries, file.Model = len(index.Vectors), index.Model
+			}
+		case moduleCacheFile:
+			var modules ModuleMap
+			if json.Unmarshal(data, &modules) == nil {
+				file.Entries = len(modules.Modules)
+			}
+		case generationsCacheFile:
+			file.Entries = bytes.Count(data, []byte("\n"))
+		}
+		stats.Files = append(stats.Files, file)
+		stats.Size += file.Size
+	}
+	return stats, nil
+}
+
+// ClearCache deletes the commit-gen cache of the repository; everything
+// in it is rebuilt on demand
+func (g *GitRepository) ClearCache() error {
+	if err := checkWritable(g.readOnly, "clear the cache"); err != nil {
+		return err
+	}
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return fmt.Errorf("failed to clear the cache: %w", err)
+	}
+	return nil
+}
+
+// removeCacheFile deletes one file of the repository cache
+func (g *GitRepository) removeCacheFile(name string) error {
+	if err := checkWritable(g.readOnly, "change the cache"); err != nil {
+		return err
+	}
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(cacheDir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s from the cache: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// Change is one logical part of a change: what it does and the files that
+// do it
+type Change struct {
+	Files []string `json:"files"`
+	// Kind is a Conventional Commits type from DefaultTypes
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Breaking    bool   `json:"breaking,omitempty"`
+}
+
+// ChangeSummary is a structured analysis of a change, made once by the
+// model and then rendered as a commit message, a pull request body, a
+// changelog entry, or a standup update without calling the model again
+type ChangeSummary struct {
+	// Title is a short imperative description of the change as a whole
+	Title string `json:"title"`
+	Scope string `json:"scope,omitempty"`
+	// Changes are ordered by importance, the primary change first
+	Changes []Change `json:"changes"`
+	Model   string   `json:"model"`
+	Tokens  Usage    `json:"tokens"`
+}
+
+// Analyze asks the model for a ChangeSummary of the change in gitInfo, or
+// of the staged changes when gitInfo is nil. Paths the repository policy
+// keeps away from models are refused, as there is no heuristic summary.
+func (c *CommitGen) Analyze(ctx context.Context, gitInfo *GitInfo) (summary *ChangeSummary, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Analyze")
+	defer func() { endSpan(span, err) }()
+
+	if gitInfo == nil {
+		if gitInfo, err = c.changeContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+	files := changedFiles(gitInfo)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: the diff is empty", ErrNoChanges)
+	}
+	if forbidden := ForbiddenPaths(c.policyPaths, files); len(forbidden) > 0 {
+		return nil, &PolicyError{Paths: forbidden}
+	}
+
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path()
+	}
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Changed files:\n%s\n\n", strings.Join(paths, "\n"))
+	if gitInfo.Hint != "" {
+		fmt.Fprintf(&prompt, "The author says: %s\n\n", gitInfo.Hint)
+	}
+	fmt.Fprintf(&prompt, "Git diff:\n%s", gitInfo.StagedDiff)
+
+	g := c.generator
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    prompts.Template(prompts.Analyze),
+		Prompt:          prompt.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Report,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze the change: %w", err)
+	}
+
+	summary = &ChangeSummary{}
+	if err := decodeJSONResponse(resp.Text, summary); err != nil {
+		return nil, err
+	}
+	if err := completeChangeSummary(summary, paths); err != nil {
+		return nil, err
+	}
+	summary.Model = g.config.Model
+	summary.Tokens = resp.Usage
+	return summary, nil
+}
+
+// completeChangeSummary drops the files of the model's summary that did not
+// change and the changes without a description, and turns unknown kinds
+// into chore
+func completeChangeSummary(summary *ChangeSummary, paths []string) error {
+	summary.Title = strings.TrimSuffix(strings.TrimSpace(summary.Title), ".")
+	summary.Scope = strings.TrimSpace(summary.Scope)
+
+	var changes []Change
+	for _, change := range summary.Changes {
+		change.Description = strings.TrimSuffix(strings.TrimSpace(change.Description), ".")
+		if change.Description == "" {
+			continue
+		}
+		change.Kind = strings.ToLower(strings.TrimSpace(change.Kind))
+		if !slices.Contains(DefaultTypes, change.Kind) {
+			change.Kind = "chore"
+		}
+		kept := []string{}
+		for _, path := range change.Files {
+			if slices.Contains(paths, path) && !slices.Contains(kept, path) {
+				kept = append(kept, path)
+			}
+		}
+		change.Files = kept
+		changes = append(changes, change)
+	}
+	if len(changes) == 0 {
+		return errors.New("the model described no changes")
+	}
+	summary.Changes = changes
+	if summary.Title == "" {
+		summary.Title = changes[0].Description
+	}
+	return nil
+}
+
+// Kind returns the kind of the primary change
+func (s *ChangeSummary) Kind() string {
+	return s.Changes[0].Kind
+}
+
+// Breaking reports whether any change breaks compatibility
+func (s *ChangeSummary) Breaking() bool {
+	return slices.ContainsFunc(s.Changes, func(change Change) bool { return change.Breaking })
+}
+
+// CommitMessage renders the summary as a Conventional Commits message: the
+// header from the primary change and the title, one body bullet per change
+// when there are several, and a BREAKING CHANGE trailer per breaking change
+func (s *ChangeSummary) CommitMessage() CommitMessage {
+	prefix := SubjectPrefix(s.Kind(), s.Scope)
+	if s.Breaking() {
+		prefix = strings.TrimSuffix(prefix, ": ") + "!: "
+	}
+	msg := CommitMessage{Header: prefix + lowerFirst(s.Title)}
+
+	if len(s.Changes) > 1 {
+		var body strings.Builder
+		for _, change := range s.Changes {
+			fmt.Fprintf(&body, "- %s\n", upperFirst(change.Description))
+		}
+		msg.Body = Reflow(strings.TrimSuffix(body.String(), "\n"), DefaultWrapWidth)
+	}
+	for _, change := range s.Changes {
+		if change.Breaking {
+			msg.AddTrailer("BREAKING CHANGE", upperFirst(change.Description))
+		}
+	}
+	return msg
+}
+
+// PullRequest renders the summary as a Markdown pull request description
+func (s *ChangeSummary) PullRequest() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", upperFirst(s.Title))
+	for _, change := range s.Changes {
+		fmt.Fprintf(&b, "- **%s**: %s", change.Kind, upperFirst(change.Description))
+		if len(change.Files) > 0 {
+			fmt.Fprintf(&b, " (`%s`)", strings.Join(change.Files, "`, `"))
+		}
+		b.WriteString("\n")
+	}
+	if s.Breaking() {
+		b.WriteString("\n### Breaking changes\n\n")
+		for _, change := range s.Changes {
+			if change.Breaking {
+				fmt.Fprintf(&b, "- %s\n", upperFirst(change.Description))
+			}
+		}
+	}
+	return b.String()
+}
+
+// changelogSections maps the kinds of changes users notice to the Keep a
+// Changelog sections they go in; other kinds are left out of changelogs
+var changelogSections = []struct {
+	name  string
+	kinds []string
+}{
+	{"Added", []string{"feat"}},
+	{"Changed", []string{"perf", "revert"}},
+	{"Fixed", []string{"fix"}},
+}
+
+// Changelog renders the changes users notice as Keep a Changelog sections,
+// breaking changes of any kind first. It is empty when every change is
+// internal, e.g. a refactor or a test.
+func (s *ChangeSummary) Changelog() string {
+	var b strings.Builder
+	section := func(name string, include func(Change) bool) {
+		var items []string
+		for _, change := range s.Changes {
+			if include(change) {
+				items = append(items, "- "+upperFirst(change.Description)+"\n")
+			}
+		}
+		if len(items) == 0 {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "### %s\n\n%s", name, strings.Join(items, ""))
+	}
+
+	section("Breaking", func(change Change) bool { return change.Breaking })
+	for _, group := range changelogSections {
+		section(group.name, func(change Change) bool { return !change.Breaking && slices.Contains(group.kinds, change.Kind) })
+	}
+	return b.String()
+}
+
+// Standup renders the summary as one line for a standup update, with the
+// changes in parentheses when there are several
+func (s *ChangeSummary) Standup() string {
+	line := "- " + upperFirst(s.Title)
+	if s.Scope != "" {
+		line += " in " + s.Scope
+	}
+	if len(s.Changes) > 1 {
+		descriptions := make([]string, len(s.Changes))
+		for i, change := range s.Changes {
+			descriptions[i] = change.Description
+		}
+		line += " (" + strings.Join(descriptions, "; ") + ")"
+	}
+	return line + "\n"
+}
+
+// upperFirst capitalizes the first letter of s
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// lowerFirst lowercases the first letter of s unless it starts an acronym
+// or identifier, e.g. "API" or "README"
+func lowerFirst(s string) string {
+	if len(s) < 2 || strings.ToUpper(s[:2]) == s[:2] {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
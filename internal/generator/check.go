@@ -0,0 +1,307 @@
+package generator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+)
+
+// Setup checks, the names of the results of Check
+const (
+	// CheckConfig is the options and the files they name: quality level,
+	// prompt files, anonymization dictionary, ...
+	CheckConfig = "config"
+	// CheckVCS is the version control tool, e.g. git, being installed
+	CheckVCS = "vcs"
+	// CheckRepository is the working directory being a repository; its
+	// detail is the repository root
+	CheckRepository = "repository"
+	// CheckAPIKey is the API key being set and accepted
+	CheckAPIKey = "api_key"
+	// CheckProvider is the provider answering
+	CheckProvider = "provider"
+	// CheckModel is the model existing on the provider
+	CheckModel = "model"
+	// CheckFallback is the fallback provider and model answering
+	CheckFallback = "fallback"
+)
+
+// Statuses of a CheckResult
+const (
+	CheckOK      = "ok"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// CheckResult is the outcome of one setup check
+type CheckResult struct {
+	// Name is one of CheckConfig, CheckVCS, ...
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Detail is what was found, e.g. the git version, why the check failed,
+	// or why it was skipped
+	Detail string `json:"detail,omitempty"`
+	// Err is the error of a failed check, for errors.Is and errors.As
+	Err error `json:"-"`
+}
+
+// CheckReport is the result of Check, one result per check in a fixed
+// order: config, vcs, repository, api_key, provider, model, and fallback
+// when one is configured
+type CheckReport struct {
+	Provider string        `json:"provider"`
+	Model    string        `json:"model"`
+	Checks   []CheckResult `json:"checks"`
+}
+
+// OK reports whether no check failed
+func (r *CheckReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err returns the error of the first failed check, or nil
+func (r *CheckReport) Err() error {
+	for _, check := range r.Checks {
+		if check.Status == CheckFailed {
+			return check.Err
+		}
+	}
+	return nil
+}
+
+// Check returns the named result
+func (r *CheckReport) Check(name string) (CheckResult, bool) {
+	for _, check := range r.Checks {
+		if check.Name == name {
+			return check, true
+		}
+	}
+	return CheckResult{}, false
+}
+
+func (r *CheckReport) ok(name, detail string) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: CheckOK, Detail: detail})
+}
+
+func (r *CheckReport) failed(name string, err error) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: CheckFailed, Detail: err.Error(), Err: err})
+}
+
+func (r *CheckReport) skipped(name, reason string) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: CheckSkipped, Detail: reason})
+}
+
+// Check verifies the setup New(opts) would get without generating anything:
+// the options, the version control tool and repository, the API key, and
+// that the provider answers and has the model. It costs no tokens, so that
+// editor plugins can show the setup status up front. Problems are reported
+// in the results; the error is only for a cancelled ctx.
+func Check(ctx context.Context, opts *Options) (*CheckReport, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	report := &CheckReport{Provider: cmp.Or(opts.Provider, ProviderGemini)}
+
+	keyErr := missingAPIKey(opts)
+	needsKey := needsAPIKey(opts.Provider) || (opts.FallbackProvider != "" && needsAPIKey(opts.FallbackProvider))
+	var commitGen *CommitGen
+	if keyErr != nil {
+		report.skipped(CheckConfig, "needs an API key")
+	} else {
+		var err error
+		if commitGen, err = New(opts); err != nil {
+			report.failed(CheckConfig, err)
+		} else {
+			defer commitGen.Close()
+			report.ok(CheckConfig, "")
+			report.Model = commitGen.generator.config.Model
+		}
+	}
+
+	checkVCS(report, opts)
+
+	switch {
+	case !needsKey:
+		report.skipped(CheckAPIKey, fmt.Sprintf("%s needs no API key", report.Provider))
+	case keyErr != nil:
+		report.failed(CheckAPIKey, keyErr)
+	}
+
+	if commitGen == nil || commitGen.generator.config.Disabled {
+		reason := "the config is invalid"
+		switch {
+		case commitGen != nil:
+			reason = ErrDisabled.Error()
+		case keyErr != nil:
+			reason = "needs an API key"
+		}
+		if needsKey && keyErr == nil {
+			report.skipped(CheckAPIKey, reason)
+		}
+		report.skipped(CheckProvider, reason)
+		report.skipped(CheckModel, reason)
+		if opts.FallbackProvider != "" {
+			report.skipped(CheckFallback, reason)
+		}
+		return report, ctx.Err()
+	}
+
+	config := commitGen.generator.config
+	httpClient, err := newHTTPClient(config.Transport)
+	if err != nil {
+		// New has built the same client already
+		return nil, err
+	}
+	checkProvider(ctx, report, config, httpClient, needsKey)
+	if config.FallbackProvider != "" {
+		checkFallback(ctx, report, config, httpClient)
+	}
+	return report, ctx.Err()
+}
+
+// checkVCS checks that the version control tool runs and that the working
+// directory is one of its repositories
+func checkVCS(report *CheckReport, opts *Options) {
+	vcs, err := NewVCS(opts.VCS, opts.WorkingDir)
+	if err != nil {
+		report.failed(CheckVCS, err)
+		report.skipped(CheckRepository, "no version control tool")
+		return
+	}
+	output, err := exec.Command(vcs.Name(), "--version").Output()
+	if err != nil {
+		report.failed(CheckVCS, fmt.Errorf("failed to run %s: %w", vcs.Name(), err))
+		report.skipped(CheckRepository, fmt.Sprintf("%s is not available", vcs.Name()))
+		return
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	report.ok(CheckVCS, version)
+
+	root := exec.Command(vcs.Name(), vcsRootArgs[vcs.Name()]...)
+	root.Dir = opts.WorkingDir
+	output, err = root.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+		// e.g. "fatal: not a git repository ..."
+		message, _, _ := strings.Cut(strings.TrimSpace(string(exitErr.Stderr)), "\n")
+		err = errors.New(message)
+	}
+	if err != nil {
+		report.failed(CheckRepository, err)
+		return
+	}
+	report.ok(CheckRepository, strings.TrimSpace(string(output)))
+}
+
+// vcsRootArgs print the root of the repository, for each VCS
+var vcsRootArgs = map[string][]string{
+	VCSGit:       {"rev-parse", "--show-toplevel"},
+	VCSJujutsu:   {"root"},
+	VCSMercurial: {"root"},
+}
+
+// checkProvider pings the provider, telling a rejected key and a missing
+// model apart from a provider that does not answer
+func checkProvider(ctx context.Context, report *CheckReport, config *GeneratorConfig, httpClient *http.Client, needsKey bool) {
+	keyless := !needsAPIKey(config.Provider)
+	if needsKey && keyless {
+		// Only the fallback uses the key, see CheckFallback
+		report.skipped(CheckAPIKey, fmt.Sprintf("%s needs no API key, the fallback does", report.Provider))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	provider, err := newProvider(ctx, config.Provider, config, httpClient)
+	if err == nil {
+		defer provider.Close()
+		if checker, ok := provider.(HealthChecker); ok {
+			err = checker.Ping(ctx, config.Model)
+		}
+	}
+
+	var providerErr *ProviderError
+	errors.As(err, &providerErr)
+	switch {
+	case err == nil:
+	case providerErr != nil && isKeyRejected(providerErr) && !keyless:
+		report.failed(CheckAPIKey, err)
+		report.skipped(CheckProvider, "the API key was rejected")
+		report.skipped(CheckModel, "the API key was rejected")
+		return
+	case providerErr != nil && providerErr.StatusCode == http.StatusNotFound && !keyless:
+		// The provider has answered, it only does not know the model
+		report.ok(CheckAPIKey, "")
+		report.ok(CheckProvider, "")
+		report.failed(CheckModel, fmt.Errorf("model %q not found: %w", config.Model, err))
+		return
+	default:
+		if !keyless {
+			report.skipped(CheckAPIKey, "the provider did not answer")
+		}
+		report.failed(CheckProvider, err)
+		report.skipped(CheckModel, "the provider did not answer")
+		return
+	}
+
+	if !keyless {
+		report.ok(CheckAPIKey, "")
+	}
+	report.ok(CheckProvider, "")
+	switch p := provider.(type) {
+	case *geminiProvider, *anthropicProvider:
+		// Ping has fetched the model
+		report.ok(CheckModel, config.Model)
+	case *ollamaProvider:
+		pulled, err := p.hasModel(ctx, config.Model)
+		switch {
+		case err != nil:
+			report.failed(CheckModel, err)
+		case !pulled:
+			report.failed(CheckModel, fmt.Errorf("model %q is not pulled, run: ollama pull %s", config.Model, config.Model))
+		default:
+			report.ok(CheckModel, config.Model)
+		}
+	default:
+		report.skipped(CheckModel, fmt.Sprintf("%s picks the model", report.Provider))
+	}
+}
+
+// checkFallback pings the fallback provider with the fallback model
+func checkFallback(ctx context.Context, report *CheckReport, config *GeneratorConfig, httpClient *http.Client) {
+	ctx, cancel := context.WithTimeout(ctx, config.Timeout)
+	defer cancel()
+	provider, err := newProvider(ctx, config.FallbackProvider, config, httpClient)
+	if err == nil {
+		defer provider.Close()
+		if checker, ok := provider.(HealthChecker); ok {
+			err = checker.Ping(ctx, config.FallbackModel)
+		}
+	}
+	if p, ok := provider.(*ollamaProvider); ok && err == nil {
+		var pulled bool
+		if pulled, err = p.hasModel(ctx, config.FallbackModel); err == nil && !pulled {
+			err = fmt.Errorf("model %q is not pulled, run: ollama pull %s", config.FallbackModel, config.FallbackModel)
+		}
+	}
+	if err != nil {
+		report.failed(CheckFallback, fmt.Errorf("%s: %w", config.FallbackProvider, err))
+		return
+	}
+	report.ok(CheckFallback, config.FallbackProvider+" "+config.FallbackModel)
+}
+
+// isKeyRejected reports whether the provider refused the API key. Gemini
+// answers an invalid key with 400 rather than 401.
+func isKeyRejected(err *ProviderError) bool {
+	switch err.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	case http.StatusBadRequest:
+		return strings.Contains(err.Message, "API key")
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// claimRule is a kind of claim messages make that only some files can back,
+// e.g. "add tests" needs a changed test file
+type claimRule struct {
+	pattern *regexp.Regexp
+	backs   func(file string) bool
+	problem string
+}
+
+// claimRules are the claims UnsupportedClaims checks
+var claimRules = []claimRule{
+	{
+		pattern: regexp.MustCompile(`(?i)\b(add|adds|added|adding|write|writes|wrote|extend|extends|extended|update|updates|updated|cover|covers|covered)\b[^.\n]{0,40}\b(tests?|test cases|specs?|test coverage)\b`),
+		backs:   isTestFile,
+		problem: "the message mentions tests, but no test file changed",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\b(add|adds|added|update|updates|updated|write|writes|wrote|extend|extends|extended)\b[^.\n]{0,30}\b(docs|documentation|readme)\b`),
+		backs:   isDocFile,
+		problem: "the message mentions documentation, but no documentation file changed",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\bchange ?log\b`),
+		backs:   func(file string) bool { return strings.Contains(strings.ToLower(file), "changelog") },
+		problem: "the message mentions the changelog, but it did not change",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\b(add|adds|added|write|writes|wrote|include|includes|included)\b[^.\n]{0,30}\bmigrations?\b`),
+		backs:   func(file string) bool { return strings.Contains(strings.ToLower(file), "migrat") },
+		problem: "the message mentions a migration, but no migration file changed",
+	},
+	{
+		pattern: regexp.MustCompile(`(?i)\b(bump|bumps|bumped|upgrade|upgrades|upgraded|update|updates|updated)\b[^.\n]{0,30}\bdependenc(y|ies)\b`),
+		backs:   isManifestFile,
+		problem: "the message mentions dependencies, but no dependency manifest changed",
+	},
+}
+
+// negation matches a negation just before a claim, as in "does not add tests"
+var negation = regexp.MustCompile(`(?i)\b(no|not|without|never)\s+(\w+\s+)?$|n't\s+(\w+\s+)?$`)
+
+// UnsupportedClaims returns the claims of msg that no file of the change
+// backs, like "add tests" when no test file changed. It only knows a few
+// kinds of claims; the model judges the rest when VerifyClaims is set.
+func UnsupportedClaims(msg CommitMessage, files []DiffFile) []Violation {
+	text := msg.Header + "\n" + msg.Body
+	var violations []Violation
+	for _, rule := range claimRules {
+		if !makesClaim(text, rule.pattern) {
+			continue
+		}
+		backed := false
+		for _, file := range files {
+			if rule.backs(file.Path()) {
+				backed = true
+				break
+			}
+		}
+		if !backed {
+			violations = append(violations, Violation{Rule: "unsupported-claim", Message: rule.problem})
+		}
+	}
+	return violations
+}
+
+// makesClaim reports whether text matches pattern other than in a negation
+func makesClaim(text string, pattern *regexp.Regexp) bool {
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		if !negation.MatchString(text[max(0, loc[0]-24):loc[0]]) {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestFiles are the dependency manifests and lock files of common
+// package managers
+var manifestFiles = map[string]bool{
+	"go.mod": true, "go.sum": true, "package.json": true, "package-lock.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "cargo.toml": true, "cargo.lock": true,
+	"requirements.txt": true, "pyproject.toml": true, "poetry.lock": true, "pipfile": true,
+	"pipfile.lock": true, "gemfile": true, "gemfile.lock": true, "pom.xml": true,
+	"build.gradle": true, "build.gradle.kts": true, "composer.json": true, "composer.lock": true,
+	"mix.exs": true, "mix.lock": true, "pubspec.yaml": true, "pubspec.lock": true,
+}
+
+// isManifestFile reports whether file declares or locks dependencies
+func isManifestFile(file string) bool {
+	return manifestFiles[strings.ToLower(path.Base(file))]
+}
+
+// judgeClaims asks the model for statements of msg that the change in
+// prompt does not support. In two-tier mode the local draft model judges,
+// so the diff stays local. A verdict that is not valid JSON counts as none.
+func (g *CommitMessageGenerator) judgeClaims(parent context.Context, call *GenConfig, prompt string, msg CommitMessage) ([]Violation, Usage, error) {
+	provider, model := g.provider, call.Model
+	if g.draftProvider != nil {
+		provider, model = g.draftProvider, g.config.DraftModel
+	}
+
+	ctx, cancel := context.WithTimeout(parent, g.config.Timeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "claims.judge")
+	defer span.End()
+
+	resp, err := g.callProvider(ctx, provider, &TextRequest{
+		Model:           model,
+		SystemPrompt:    prompts.Template(prompts.ClaimsJudge),
+		Prompt:          fmt.Sprintf("%s\nCommit message to check:\n%s\n", prompt, msg),
+		MaxOutputTokens: g.config.MaxOutputTokens.Full,
+	})
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to check the claims of the commit message: %w", err)
+	}
+
+	var verdict struct {
+		Unsupported []string `json:"unsupported"`
+	}
+	if err := decodeJSONResponse(resp.Text, &verdict); err != nil {
+		return nil, resp.Usage, nil
+	}
+	var violations []Violation
+	for _, claim := range verdict.Unsupported {
+		if claim = strings.TrimSpace(claim); claim != "" {
+			violations = append(violations, Violation{
+				Rule:    "unsupported-claim",
+				Message: fmt.Sprintf("the diff does not support %q, remove or correct it", claim),
+			})
+		}
+	}
+	return violations, resp.Usage, nil
+}
+
+// verifyClaims returns the claims of msg the change does not support,
+// checking the known kinds of claims against the changed files before
+// asking the model
+func (g *CommitMessageGenerator) verifyClaims(ctx context.Context, call *GenConfig, prompt string, gitInfo *GitInfo, msg CommitMessage) ([]Violation, Usage, error) {
+	if violations := UnsupportedClaims(msg, changedFiles(gitInfo)); len(violations) > 0 {
+		return violations, Usage{}, nil
+	}
+	return g.judgeClaims(ctx, call, prompt, msg)
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// coChangeCommits is how far back co-change analysis looks
+	coChangeCommits = 300
+	// maxRelatedFiles caps how many related files are added to the prompt
+	maxRelatedFiles = 10
+	// maxCoChangeCommitSize skips sweeping commits (renames, formatting runs)
+	// that would make every file look related to every other
+	maxCoChangeCommitSize = 50
+)
+
+// RelatedFile is a file that historically changes together with the staged files
+type RelatedFile struct {
+	Path string
+	// Commits is how many recent commits changed it together with a staged file
+	Commits int
+}
+
+// GetRelatedFiles returns the files that most often changed in the same
+// commit as any of files, excluding files themselves
+func (g *GitRepository) GetRelatedFiles(files []string) ([]RelatedFile, error) {
+	output, err := g.run("log", fmt.Sprintf("-%d", coChangeCommits), "--no-merges", "--name-only", "--format=%x1e")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file history: %w", err)
+	}
+
+	staged := make(map[string]bool, len(files))
+	for _, file := range files {
+		staged[file] = true
+	}
+
+	counts := make(map[string]int)
+	for _, record := range strings.Split(output, "\x1e") {
+		var changed []string
+		for _, line := range strings.Split(record, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				changed = append(changed, line)
+			}
+		}
+		if len(changed) > maxCoChangeCommitSize {
+			continue
+		}
+
+		touchesStaged := false
+		for _, file := range changed {
+			if staged[file] {
+				touchesStaged = true
+				break
+			}
+		}
+		if !touchesStaged {
+			continue
+		}
+		for _, file := range changed {
+			if !staged[file] {
+				counts[file]++
+			}
+		}
+	}
+
+	related := make([]RelatedFile, 0, len(counts))
+	for path, n := range counts {
+		// A single shared commit is a coincidence, not a relationship
+		if n >= 2 {
+			related = append(related, RelatedFile{Path: path, Commits: n})
+		}
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Commits != related[j].Commits {
+			return related[i].Commits > related[j].Commits
+		}
+		return related[i].Path < related[j].Path
+	})
+	if len(related) > maxRelatedFiles {
+		related = related[:maxRelatedFiles]
+	}
+	return related, nil
+}
@@ -0,0 +1,130 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Limits on how much of each conflict resolution reaches the prompt
+const (
+	maxConflictDiffBytes  = 8 << 10
+	maxConflictTotalBytes = 32 << 10
+)
+
+// theirsHeads are the refs git leaves naming the other side of a merge,
+// cherry-pick, or revert in progress, in order of preference
+var theirsHeads = []string{"MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD"}
+
+// ConflictedFiles returns the files that had conflicts in the merge,
+// cherry-pick, or revert being concluded: those git listed in MERGE_MSG and
+// those rerere recorded. It returns nothing outside such an operation.
+func (g *GitRepository) ConflictedFiles() ([]string, error) {
+	path, err := g.run("rev-parse", "--path-format=absolute", "--git-path", "MERGE_MSG")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	var files []string
+	if data, err := os.ReadFile(strings.TrimSpace(path)); err == nil {
+		files = parseMergeConflicts(string(data))
+	}
+	// rerere is off unless rerere.enabled is set, so errors mean no record
+	if output, err := g.run("rerere", "status"); err == nil {
+		for _, file := range strings.Split(output, "\n") {
+			if file = strings.TrimSpace(file); file != "" && !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// parseMergeConflicts reads the "Conflicts:" list git writes to MERGE_MSG,
+// commented out ("# Conflicts:" and "#\tpath") by current versions of git
+func parseMergeConflicts(msg string) []string {
+	var files []string
+	inList := false
+	for _, line := range strings.Split(msg, "\n") {
+		line = strings.TrimPrefix(line, "#")
+		switch {
+		case strings.TrimSpace(line) == "Conflicts:":
+			inList = true
+		case inList && strings.HasPrefix(line, "\t"):
+			files = append(files, strings.TrimSpace(line))
+		case inList && strings.TrimSpace(line) == "":
+			// git separates the heading from the list with a bare "#"
+			if len(files) > 0 {
+				inList = false
+			}
+		default:
+			inList = false
+		}
+	}
+	return files
+}
+
+// GetConflictResolutions shows how each conflicted file was resolved: the
+// staged result compared with our side (HEAD) and with their side, capped
+// in size
+func (g *GitRepository) GetConflictResolutions(files []string) string {
+	theirs := ""
+	for _, head := range theirsHeads {
+		if _, err := g.run("rev-parse", "--verify", "--quiet", head); err == nil {
+			theirs = head
+			break
+		}
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		if b.Len() >= maxConflictTotalBytes {
+			fmt.Fprintf(&b, "=== %s ===\n(omitted)\n", file)
+			continue
+		}
+		fmt.Fprintf(&b, "=== %s ===\n", file)
+		sides := []string{"HEAD"}
+		if theirs != "" {
+			sides = append(sides, theirs)
+		}
+		for _, side := range sides {
+			diff, err := g.run("diff", "--cached", side, "--", file)
+			if err != nil {
+				continue
+			}
+			if truncated := truncateBytes(diff, maxConflictDiffBytes); len(truncated) < len(diff) {
+				diff = truncated + "\n[... diff truncated]\n"
+			}
+			if strings.TrimSpace(diff) == "" {
+				fmt.Fprintf(&b, "Resolution is identical to %s\n", side)
+			} else {
+				fmt.Fprintf(&b, "Resolution compared with %s:\n%s", side, diff)
+			}
+		}
+	}
+	return b.String()
+}
+
+// listConflicts adds a "Conflicts:" paragraph naming the conflicted files
+// the body does not mention yet, so that reviewers always see them
+func listConflicts(msg CommitMessage, files []string) CommitMessage {
+	var missing []string
+	for _, file := range files {
+		if !strings.Contains(msg.Body, file) {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) == 0 {
+		return msg
+	}
+
+	text, trailers := splitTrailers(msg.Body)
+	list := "Conflicts:\n- " + strings.Join(missing, "\n- ")
+	if text == "" {
+		text = list
+	} else {
+		text += "\n\n" + list
+	}
+	msg.Body = joinTrailers(text, trailers)
+	return msg
+}
@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"context"
+	"strings"
+)
+
+// Continuation is the rest of a commit message the author started typing,
+// for editors to offer as ghost text after the cursor
+type Continuation struct {
+	// Text follows the typed prefix; it is empty when the model would not
+	// keep the prefix, since a suggestion that rewrites what the author
+	// typed cannot be shown inline
+	Text string `json:"text"`
+	// Result is the whole message, prefix included
+	Result *Result `json:"result"`
+}
+
+// Continue completes the message prefix the author typed for the change
+// in gitInfo, e.g. "fix(ui): han" may continue with "dle nil menu" and a
+// body. Unlike generation with a Hint, the typed text is kept verbatim.
+func (c *CommitGen) Continue(ctx context.Context, gitInfo *GitInfo, prefix string) (continuation *Continuation, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Continue")
+	defer func() { endSpan(span, err) }()
+
+	info := *gitInfo
+	info.Prefix = prefix
+	result, err := c.generateAllowed(ctx, &info, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Continuation{Text: continueAfter(result.String(), prefix), Result: result}, nil
+}
+
+// continueAfter returns what message adds after prefix, or nothing when
+// the message does not start with it. Spaces at the end of the prefix are
+// forgiven, since the author may have typed the one before the next word.
+func continueAfter(message, prefix string) string {
+	if rest, ok := strings.CutPrefix(message, prefix); ok {
+		return rest
+	}
+	trimmed := strings.TrimRight(prefix, " \t")
+	rest, ok := strings.CutPrefix(message, trimmed)
+	if ok && trimmed != prefix && (rest == "" || strings.ContainsRune(" \t\n", rune(rest[0]))) {
+		return strings.TrimLeft(rest, " \t")
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// ModelPrice is what a model costs, in US dollars per million tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// defaultPrices are the list prices of the Gemini and Claude models,
+// matched by prefix so that dated and preview versions share them. Local
+// providers cost nothing.
+var defaultPrices = map[string]ModelPrice{
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+	"claude-haiku-4-5":      {Input: 1, Output: 5},
+	"claude-sonnet-4-5":     {Input: 3, Output: 15},
+	"claude-opus-4-1":       {Input: 15, Output: 75},
+}
+
+// CostEstimate is the expected cost of an action, worked out before its
+// requests are sent
+type CostEstimate struct {
+	Model string `json:"model"`
+	// Requests is the most requests the action sends to the model: one per
+	// candidate and attempt, plus the critique, claims, and grounding passes
+	Requests int `json:"requests"`
+	// InputTokens is estimated from the prompt size, for all requests
+	InputTokens int `json:"input_tokens"`
+	// OutputTokens is the output budget of all requests, so an upper bound
+	OutputTokens int `json:"output_tokens"`
+	// Cost is in US dollars, valid only when Priced is set
+	Cost   float64 `json:"cost"`
+	Priced bool    `json:"priced"`
+}
+
+// String describes the estimate for a confirmation prompt
+func (e *CostEstimate) String() string {
+	tokens := fmt.Sprintf("~%d input and up to %d output tokens", e.InputTokens, e.OutputTokens)
+	if e.Requests > 1 {
+		tokens = fmt.Sprintf("up to %d requests, %s", e.Requests, tokens)
+	}
+	if !e.Priced {
+		return fmt.Sprintf("%s for %s, whose price is unknown", tokens, e.Model)
+	}
+	if e.Cost == 0 {
+		return fmt.Sprintf("%s for %s, which is free", tokens, e.Model)
+	}
+	return fmt.Sprintf("%s for %s, about $%.4f", tokens, e.Model, e.Cost)
+}
+
+// CostError is returned when a request is estimated to cost more than
+// Options.CostLimit and nobody confirmed it
+type CostError struct {
+	Estimate *CostEstimate
+	Limit    float64
+}
+
+// Error implements the error interface
+func (e *CostError) Error() string {
+	if e.Limit <= 0 {
+		return fmt.Sprintf("the request was not confirmed: %s", e.Estimate)
+	}
+	return fmt.Sprintf("estimated cost exceeds the $%.4f limit: %s", e.Limit, e.Estimate)
+}
+
+// estimateTokens approximates the token count of text, at about four
+// bytes per token for English and code
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// priceOf returns the price of model on the primary provider, looking at
+// the configured prices before the built-in ones
+func (g *CommitMessageGenerator) priceOf(model string) (ModelPrice, bool) {
+	if price, ok := g.config.Prices[model]; ok {
+		return price, true
+	}
+	if g.config.Provider == ProviderOllama {
+		return ModelPrice{}, true
+	}
+	var best string
+	for prefix := range defaultPrices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	price, ok := defaultPrices[best]
+	return price, ok
+}
+
+// requestsPerMessage is the most requests to the primary model that
+// generating one message sends: every attempt, a self-critique and the
+// revision it asks for, a claims check per attempt, and grounding. In the
+// two-tier pipeline the local draft model runs the checks, for free.
+func (g *CommitMessageGenerator) requestsPerMessage() int {
+	attempts := g.config.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
+	if g.config.SelfCritique {
+		attempts++
+	}
+	if g.draftProvider != nil {
+		return attempts
+	}
+	requests := attempts
+	if g.config.SelfCritique {
+		requests++
+	}
+	if g.config.VerifyClaims {
+		requests += attempts
+	}
+	if g.config.Grounded {
+		requests++
+	}
+	return requests
+}
+
+// estimateCost estimates the cost of generating messages from prompt, each
+// taking up to requestsPerMessage requests of about the size of the first.
+// In the two-tier pipeline the cloud model only sees the draft, at most
+// the output budget, while the local draft is free.
+func (g *CommitMessageGenerator) estimateCost(call *GenConfig, prompt string, messages int) *CostEstimate {
+	outputTokens := g.config.MaxOutputTokens.commitTokens(*call.IsShortCommit)
+	inputTokens := estimateTokens(call.SystemPrompt) + estimateTokens(prompt)
+	if g.draftProvider != nil {
+		inputTokens = estimateTokens(prompts.Polish(*call.IsShortCommit, g.config.Convention)) + outputTokens
+	}
+
+	requests := messages * g.requestsPerMessage()
+	estimate := &CostEstimate{
+		Model:        call.Model,
+		Requests:     requests,
+		InputTokens:  requests * inputTokens,
+		OutputTokens: requests * outputTokens,
+	}
+	if price, ok := g.priceOf(call.Model); ok {
+		estimate.Priced = true
+		estimate.Cost = (float64(estimate.InputTokens)*price.Input + float64(estimate.OutputTokens)*price.Output) / 1e6
+	}
+	return estimate
+}
+
+type costCheckedKey struct{}
+
+// withCostChecked marks ctx as belonging to an action whose cost was
+// already checked, so that its generations do not ask again
+func withCostChecked(ctx context.Context) context.Context {
+	return context.WithValue(ctx, costCheckedKey{}, true)
+}
+
+// costChecked reports whether the cost of the action of ctx was checked
+func costChecked(ctx context.Context) bool {
+	checked, _ := ctx.Value(costCheckedKey{}).(bool)
+	return checked
+}
+
+// checkCost asks ConfirmCost before an expensive action generating
+// messages messages. Actions within CostLimit go ahead; without a limit,
+// ConfirmCost sees every action. It is asked at most once per action.
+func (g *CommitMessageGenerator) checkCost(ctx context.Context, call *GenConfig, prompt string, messages int) error {
+	limit, confirm := g.config.CostLimit, g.config.ConfirmCost
+	if (limit <= 0 && confirm == nil) || costChecked(ctx) {
+		return nil
+	}
+	estimate := g.estimateCost(call, prompt, messages)
+	// An unknown price cannot be held against the limit
+	if limit > 0 && (!estimate.Priced || estimate.Cost <= limit) {
+		return nil
+	}
+	if confirm != nil && confirm(estimate) {
+		return nil
+	}
+	return &CostError{Estimate: estimate, Limit: limit}
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// critique asks the model to review msg against the change described in
+// prompt and returns the problems it found, none when msg is fine. In
+// two-tier mode the local draft model reviews, so the diff stays local.
+// A review that is not valid JSON counts as no problems.
+func (g *CommitMessageGenerator) critique(parent context.Context, call *GenConfig, prompt string, msg CommitMessage) ([]string, Usage, error) {
+	provider, model := g.provider, call.Model
+	if g.draftProvider != nil {
+		provider, model = g.draftProvider, g.config.DraftModel
+	}
+
+	ctx, cancel := context.WithTimeout(parent, g.config.Timeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "critique")
+	defer span.End()
+
+	resp, err := g.callProvider(ctx, provider, &TextRequest{
+		Model:           model,
+		SystemPrompt:    prompts.Template(prompts.Critique),
+		Prompt:          fmt.Sprintf("%s\nCommit message to review:\n%s\n", prompt, msg),
+		MaxOutputTokens: g.config.MaxOutputTokens.Full,
+	})
+	if err != nil {
+		return nil, Usage{}, fmt.Errorf("failed to review commit message: %w", err)
+	}
+
+	var review struct {
+		Problems []string `json:"problems"`
+	}
+	if err := decodeJSONResponse(resp.Text, &review); err != nil {
+		return nil, resp.Usage, nil
+	}
+	var problems []string
+	for _, problem := range review.Problems {
+		if problem = strings.TrimSpace(problem); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	return problems, resp.Usage, nil
+}
+
+// revisionPrompt extends prompt with the reviewed message and the problems
+// the review found
+func revisionPrompt(prompt string, reviewed CommitMessage, problems []string) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\nYour previous commit message was:\n")
+	b.WriteString(reviewed.String())
+	b.WriteString("\n\nA review against the diff found:\n")
+	for _, problem := range problems {
+		b.WriteString("- ")
+		b.WriteString(problem)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nWrite a revised commit message that fixes these problems and keeps what was right.\n")
+	return b.String()
+}
@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// duplicateThreshold is the share of words two headers must have in
+// common to count as the same subject
+const duplicateThreshold = 0.8
+
+// RecentSubjects returns the subjects of the last count commits, newest
+// first, leaving out merges
+func (g *GitRepository) RecentSubjects(count int) ([]string, error) {
+	output, err := g.run("log", "-n", strconv.Itoa(count), "--no-merges", "--format=%s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recent subjects: %w", err)
+	}
+	return strings.FieldsFunc(output, func(r rune) bool { return r == '\n' }), nil
+}
+
+// NearDuplicate returns the first of subjects that header nearly repeats,
+// or "". Headers repeat each other when their type and scope are the same
+// and most words of their descriptions are, so "fix(api): handle nil" and
+// "fix(ui): handle nil" differ while "fix: Handle nil." and "fix: handle
+// nil" do not.
+func NearDuplicate(header string, subjects []string) string {
+	msg := ParseCommitMessage(header)
+	words := headerWords(msg.Subject)
+	if len(words) == 0 {
+		return ""
+	}
+	for _, subject := range subjects {
+		other := ParseCommitMessage(subject)
+		if !strings.EqualFold(msg.Type, other.Type) || !strings.EqualFold(msg.Scope, other.Scope) {
+			continue
+		}
+		if similarity(words, headerWords(other.Subject)) >= duplicateThreshold {
+			return subject
+		}
+	}
+	return ""
+}
+
+// headerWords returns the set of lower-cased words of text
+func headerWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// similarity is the Jaccard index of two word sets
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// duplicateViolation asks the model to set a message apart from the
+// recent commit whose subject it repeats
+func duplicateViolation(duplicate string) Violation {
+	return Violation{
+		Rule:    "duplicate-subject",
+		Message: fmt.Sprintf("the subject nearly repeats the recent commit %q; say what sets this change apart from it", duplicate),
+	}
+}
+
+// differentiate adds the scope of the staged files to a conventional
+// header without one, to tell it apart from the subject it repeats
+func differentiate(msg CommitMessage, scope string) CommitMessage {
+	if msg.Scope != "" || scope == "" {
+		return msg
+	}
+	return applyPins(msg, "", scope)
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DiffFile is one file section of a unified diff
+type DiffFile struct {
+	// OldPath is empty for added files, NewPath is empty for deleted files
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// Path returns the path of the file after the change, or before it for deletions
+func (f DiffFile) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// Hunk is the line range header of a diff hunk
+type Hunk struct {
+	OldStart int `json:"old_start"`
+	OldLines int `json:"old_lines"`
+	NewStart int `json:"new_start"`
+	NewLines int `json:"new_lines"`
+}
+
+// ParseDiff extracts the files and hunk ranges of a unified git diff.
+// Lines it does not understand are skipped, so malformed input yields
+// partial results instead of an error.
+func ParseDiff(diff string) []DiffFile {
+	var files []DiffFile
+	var current *DiffFile
+
+	for _, line := range strings.Split(diff, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			// Binary and mode-only changes have no ---/+++ lines, so start
+			// from the paths in the header
+			oldPath, newPath := parseGitHeader(line[len("diff --git "):])
+			files = append(files, DiffFile{OldPath: oldPath, NewPath: newPath})
+			current = &files[len(files)-1]
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "new file mode "):
+			current.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode "):
+			current.NewPath = ""
+		case strings.HasPrefix(line, "--- "):
+			current.OldPath = diffPath(line[4:], "a/")
+		case strings.HasPrefix(line, "+++ "):
+			current.NewPath = diffPath(line[4:], "b/")
+		case strings.HasPrefix(line, "@@ "):
+			if hunk, ok := parseHunkHeader(line); ok {
+				current.Hunks = append(current.Hunks, hunk)
+			}
+		}
+	}
+
+	return files
+}
+
+// parseGitHeader splits "a/old b/new" from a diff --git header. Paths
+// containing " b/" are ambiguous; they are resolved when the ---/+++ lines
+// follow.
+func parseGitHeader(paths string) (string, string) {
+	oldPath, newPath, ok := strings.Cut(paths, " b/")
+	if !ok || !strings.HasPrefix(oldPath, "a/") {
+		return "", ""
+	}
+	return oldPath[2:], newPath
+}
+
+// diffPath strips the a/ or b/ prefix from a ---/+++ path ("" for /dev/null)
+func diffPath(path, prefix string) string {
+	// Paths with spaces are followed by a tab
+	path, _, _ = strings.Cut(path, "\t")
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// parseHunkHeader parses "@@ -a,b +c,d @@ ..."
+func parseHunkHeader(line string) (Hunk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return Hunk{}, false
+	}
+
+	oldStart, oldLines, ok1 := parseRange(fields[1], "-")
+	newStart, newLines, ok2 := parseRange(fields[2], "+")
+	if !ok1 || !ok2 {
+		return Hunk{}, false
+	}
+	return Hunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines}, true
+}
+
+// parseRange parses "-start,count" where the count defaults to 1
+func parseRange(field, sign string) (start, count int, ok bool) {
+	field, ok = strings.CutPrefix(field, sign)
+	if !ok {
+		return 0, 0, false
+	}
+
+	startText, countText, hasCount := strings.Cut(field, ",")
+	start, err := strconv.Atoi(startText)
+	// Line numbers are 1-based, 0 only appears for empty ranges
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countText); err != nil || count < 0 {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\r\n" +
+		"--- a/main.go\r\n" +
+		"+++ b/main.go\r\n" +
+		"@@ -1,2 +1,3 @@\r\n" +
+		" package main\r\n" +
+		"+\r\n" +
+		"diff --git a/logo.png b/logo.png\n" +
+		"new file mode 100644\n" +
+		"Binary files /dev/null and b/logo.png differ\n" +
+		"diff --git a/old.go b/old.go\n" +
+		"deleted file mode 100644\n" +
+		"--- a/old.go\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"@@ malformed @@\n"
+	want := []DiffFile{
+		{OldPath: "main.go", NewPath: "main.go", Hunks: []Hunk{{OldStart: 1, OldLines: 2, NewStart: 1, NewLines: 3}}},
+		{NewPath: "logo.png"},
+		{OldPath: "old.go", Hunks: []Hunk{{OldStart: 1, OldLines: 1}}},
+	}
+	if got := ParseDiff(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff() = %+v, want %+v", got, want)
+	}
+}
+
+func FuzzParseDiff(f *testing.F) {
+	f.Add("diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,3 @@\n package main\n+\n")
+	f.Add("diff --git a/a b/a\r\nnew file mode 100644\r\nBinary files /dev/null and b/a differ\r\n")
+	f.Add("diff --git a/x b/y b/z\n--- a/x b/y\n+++ b/x b/y\n@@ -0,0 +1 @@\n")
+	f.Add("diff --git \n@@ -a,b +c,d @@\n@@ -1,-1 +99999999999999999999 @@\n\x00\xff")
+	f.Fuzz(func(t *testing.T, diff string) {
+		for _, file := range ParseDiff(diff) {
+			for _, hunk := range file.Hunks {
+				if hunk.OldStart < 0 || hunk.OldLines < 0 || hunk.NewStart < 0 || hunk.NewLines < 0 {
+					t.Errorf("ParseDiff(%q) has a negative hunk range %+v", diff, hunk)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// Digest is a team digest of the commits in a period, grouped by author
+// and then by area
+type Digest struct {
+	// Since is the start of the period, as given
+	Since string `json:"since"`
+	// Headline sums up the period in one sentence
+	Headline string         `json:"headline"`
+	Authors  []DigestAuthor `json:"authors"`
+	Commits  int            `json:"commits"`
+	Model    string         `json:"model"`
+	Tokens   Usage          `json:"tokens"`
+}
+
+// DigestAuthor is the work of one author, busiest areas first
+type DigestAuthor struct {
+	Name    string       `json:"name"`
+	Commits int          `json:"commits"`
+	Areas   []DigestArea `json:"areas"`
+}
+
+// DigestArea is what an author did in one area: a Conventional Commits
+// scope, or else the top-level directory the commit touched
+type DigestArea struct {
+	Area    string `json:"area"`
+	Commits int    `json:"commits"`
+	Summary string `json:"summary"`
+	// messages are the commit messages the summary is written from
+	messages []string
+}
+
+// DigestOptions selects the commits of a Digest
+type DigestOptions struct {
+	// Repos are the repository directories to look at (default:
+	// Options.Repos, or else the working directory)
+	Repos []string
+	// Since is a git date, e.g. "1 week ago"
+	Since string
+}
+
+// digestSummaries is the model's answer for a digest
+type digestSummaries struct {
+	Headline string `json:"headline"`
+	Groups   []struct {
+		Index   int    `json:"index"`
+		Summary string `json:"summary"`
+	} `json:"groups"`
+}
+
+// Digest summarizes everyone's commits on the checked-out branch of each
+// repository since opts.Since, e.g. for a weekly team update from CI
+// running on the main branch
+func (c *CommitGen) Digest(ctx context.Context, opts *DigestOptions) (digest *Digest, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Digest")
+	defer func() { endSpan(span, err) }()
+
+	digest = &Digest{Since: opts.Since}
+	authors := make(map[string]*DigestAuthor)
+	for _, dir := range c.reposOr(opts.Repos) {
+		repo := NewGitRepository(dir)
+		commits, err := repo.GetCommits("--reverse", "--no-merges", "--since="+opts.Since)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.name(), err)
+		}
+		for _, commit := range commits {
+			author := authors[commit.AuthorName]
+			if author == nil {
+				author = &DigestAuthor{Name: commit.AuthorName}
+				authors[commit.AuthorName] = author
+			}
+			area := repo.commitArea(commit)
+			i := slices.IndexFunc(author.Areas, func(a DigestArea) bool { return a.Area == area })
+			if i < 0 {
+				author.Areas = append(author.Areas, DigestArea{Area: area})
+				i = len(author.Areas) - 1
+			}
+			author.Areas[i].Commits++
+			author.Areas[i].messages = append(author.Areas[i].messages, commit.Message)
+			author.Commits++
+			digest.Commits++
+		}
+	}
+	if digest.Commits == 0 {
+		return nil, fmt.Errorf("%w: no commits since %s", ErrNoChanges, opts.Since)
+	}
+
+	for _, author := range authors {
+		slices.SortStableFunc(author.Areas, func(a, b DigestArea) int { return b.Commits - a.Commits })
+		digest.Authors = append(digest.Authors, *author)
+	}
+	slices.SortFunc(digest.Authors, func(a, b DigestAuthor) int {
+		return cmp.Or(b.Commits-a.Commits, strings.Compare(a.Name, b.Name))
+	})
+
+	if err := c.generator.summarizeDigest(ctx, digest); err != nil {
+		return nil, err
+	}
+	return digest, nil
+}
+
+// commitArea returns the scope of a Conventional Commits header, or else
+// the top-level directory of the first file the commit changed
+func (g *GitRepository) commitArea(commit Commit) string {
+	if msg := ParseCommitMessage(commit.Message); msg.Scope != "" {
+		return msg.Scope
+	}
+	output, err := g.run("diff-tree", "--no-commit-id", "--name-only", "-r", "--root", commit.Hash)
+	path, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	if err != nil || path == "" {
+		return "other"
+	}
+	if dir, _, ok := strings.Cut(path, "/"); ok {
+		return dir
+	}
+	return "root"
+}
+
+// summarizeDigest has the model write the headline and a one-line summary
+// for every author and area; areas it skips get their commit subjects
+func (g *CommitMessageGenerator) summarizeDigest(ctx context.Context, digest *Digest) error {
+	var areas []*DigestArea
+	var b strings.Builder
+	for i := range digest.Authors {
+		author := &digest.Authors[i]
+		for j := range author.Areas {
+			area := &author.Areas[j]
+			fmt.Fprintf(&b, "=== group %d: %s, area %s ===\n", len(areas), author.Name, area.Area)
+			for _, message := range area.messages {
+				fmt.Fprintf(&b, "%s\n\n", message)
+			}
+			areas = append(areas, area)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    prompts.Template(prompts.Digest),
+		Prompt:          b.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Report,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to summarize commits: %w", err)
+	}
+
+	var summaries digestSummaries
+	if err := decodeJSONResponse(resp.Text, &summaries); err != nil {
+		return err
+	}
+	digest.Headline = strings.TrimSpace(summaries.Headline)
+	for _, group := range summaries.Groups {
+		if group.Index >= 0 && group.Index < len(areas) {
+			areas[group.Index].Summary = strings.TrimSpace(group.Summary)
+		}
+	}
+	for _, area := range areas {
+		if area.Summary == "" {
+			subjects := make([]string, len(area.messages))
+			for i, message := range area.messages {
+				subjects[i] = ParseCommitMessage(message).Subject
+			}
+			area.Summary = strings.Join(subjects, "; ")
+		}
+	}
+
+	digest.Model = g.config.Model
+	if resp.Model != "" {
+		digest.Model = resp.Model
+	}
+	digest.Tokens = resp.Usage
+	return nil
+}
@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrEmptyResponse is returned when the model keeps answering with no text
+var ErrEmptyResponse = errors.New("model returned an empty response")
+
+// ErrNoChanges is returned when there is no change to describe
+var ErrNoChanges = errors.New("no changes found")
+
+// ErrDisabled is returned instead of calling a provider while AI generation
+// is switched off with Options.Disabled or DisableEnv
+var ErrDisabled = errors.New("AI generation is disabled")
+
+// ErrRelayToken is returned when Options.RelayTokenSource fails to get the
+// relay token
+var ErrRelayToken = errors.New("failed to get relay token")
+
+// ErrFeedbackDisabled is returned by SendFeedback unless the user opted in
+// to sending feedback with Options.Feedback
+var ErrFeedbackDisabled = errors.New("sending feedback is not enabled")
+
+// DisableEnv is the kill switch: when set to 1 (or true, yes, on), no
+// provider is ever called, e.g. so that commits stay instant while the
+// network or the provider is down
+const DisableEnv = "COMMITGEN_DISABLE"
+
+// Disabled reports whether DisableEnv switches AI generation off
+func Disabled() bool {
+	return envEnabled(DisableEnv)
+}
+
+// envEnabled reports whether the switch environment variable name is set
+// to 1, true, yes, or on
+func envEnabled(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// ProviderError is an error response from a provider's API
+type ProviderError struct {
+	Provider string
+	// StatusCode is the HTTP status, e.g. 401 for a bad key or 429 when the
+	// quota is exhausted
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *ProviderError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s returned status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// BlockedError is returned when the provider refused to answer, e.g. because
+// its safety filters flagged the prompt or the response
+type BlockedError struct {
+	// Reason is the provider's block reason (e.g. "SAFETY", "PROHIBITED_CONTENT")
+	Reason string
+}
+
+// Error implements the error interface
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("response blocked by the provider: %s", e.Reason)
+}
@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FailoverPolicy controls when requests move from the primary provider to
+// the secondary one and when they move back
+type FailoverPolicy struct {
+	// Window is the number of recent primary calls used to compute health
+	Window int
+	// MinSamples is the number of calls required before the error rate is trusted
+	MinSamples int
+	// MaxErrorRate fails over once the primary error rate exceeds it
+	MaxErrorRate float64
+	// MaxLatency fails over once the primary average latency exceeds it
+	MaxLatency time.Duration
+	// RecoverProbes is the number of consecutive healthy probes required
+	// before switching back; this hysteresis prevents flapping
+	RecoverProbes int
+	// ProbeInterval is how often the background probe checks the primary
+	ProbeInterval time.Duration
+}
+
+// DefaultFailoverPolicy returns a conservative failover policy
+func DefaultFailoverPolicy() *FailoverPolicy {
+	return &FailoverPolicy{
+		Window:        20,
+		MinSamples:    3,
+		MaxErrorRate:  0.5,
+		MaxLatency:    8 * time.Second,
+		RecoverProbes: 3,
+		ProbeInterval: 30 * time.Second,
+	}
+}
+
+// callSample is a single observed primary call
+type callSample struct {
+	failed  bool
+	latency time.Duration
+}
+
+// failoverProvider routes requests to a primary provider and switches to a
+// secondary one when the primary becomes unhealthy
+type failoverProvider struct {
+	primary        Provider
+	secondary      Provider
+	secondaryModel string
+	policy         *FailoverPolicy
+
+	mu             sync.Mutex
+	samples        []callSample
+	failedOver     bool
+	healthyProbes  int
+	lastTransition time.Time
+}
+
+// newFailoverProvider wraps primary and secondary providers with the given policy
+func newFailoverProvider(primary, secondary Provider, secondaryModel string, policy *FailoverPolicy) *failoverProvider {
+	if policy == nil {
+		policy = DefaultFailoverPolicy()
+	}
+	return &failoverProvider{
+		primary:        primary,
+		secondary:      secondary,
+		secondaryModel: secondaryModel,
+		policy:         policy,
+	}
+}
+
+// Name returns the provider identifier
+func (p *failoverProvider) Name() string {
+	return p.primary.Name() + "+" + p.secondary.Name()
+}
+
+// GenerateText sends the request to the primary provider unless it is
+// currently failed over, falling back to the secondary on error
+func (p *failoverProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	if !p.isFailedOver() {
+		start := time.Now()
+		resp, err := p.primary.GenerateText(ctx, req)
+		p.record(callSample{failed: err != nil, latency: time.Since(start)})
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		resp, secondaryErr := p.generateSecondary(ctx, req)
+		if secondaryErr != nil {
+			return nil, fmt.Errorf("primary provider failed: %w (secondary also failed: %v)", err, secondaryErr)
+		}
+		return resp, nil
+	}
+
+	return p.generateSecondary(ctx, req)
+}
+
+// generateSecondary sends the request to the secondary provider with its own model
+func (p *failoverProvider) generateSecondary(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	secondaryReq := *req
+	secondaryReq.Model = p.secondaryModel
+	return p.secondary.GenerateText(ctx, &secondaryReq)
+}
+
+// isFailedOver reports whether requests currently go to the secondary provider
+func (p *failoverProvider) isFailedOver() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.failedOver
+}
+
+// record stores a primary call and fails over when thresholds are exceeded
+func (p *failoverProvider) record(sample callSample) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.samples = append(p.samples, sample)
+	if len(p.samples) > p.policy.Window {
+		p.samples = p.samples[len(p.samples)-p.policy.Window:]
+	}
+
+	if !p.failedOver && p.unhealthyLocked() {
+		p.failedOver = true
+		p.healthyProbes = 0
+		p.lastTransition = time.Now()
+	}
+}
+
+// unhealthyLocked reports whether the recorded samples exceed the policy thresholds
+func (p *failoverProvider) unhealthyLocked() bool {
+	if len(p.samples) < p.policy.MinSamples {
+		return false
+	}
+
+	var failures int
+	var total time.Duration
+	for _, s := range p.samples {
+		if s.failed {
+			failures++
+		}
+		total += s.latency
+	}
+
+	errorRate := float64(failures) / float64(len(p.samples))
+	avgLatency := total / time.Duration(len(p.samples))
+	return errorRate > p.policy.MaxErrorRate ||
+		(p.policy.MaxLatency > 0 && avgLatency > p.policy.MaxLatency)
+}
+
+// probe checks the primary once and switches back after enough healthy probes
+func (p *failoverProvider) probe(ctx context.Context, model string) {
+	checker, ok := p.primary.(HealthChecker)
+	if !ok {
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, p.policy.ProbeInterval)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Ping(probeCtx, model)
+	latency := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.failedOver {
+		return
+	}
+
+	// Require a comfortably fast response to recover, not just a passing one
+	healthy := err == nil && (p.policy.MaxLatency == 0 || latency < p.policy.MaxLatency/2)
+	if !healthy {
+		p.healthyProbes = 0
+		return
+	}
+
+	p.healthyProbes++
+	if p.healthyProbes >= p.policy.RecoverProbes {
+		p.failedOver = false
+		p.healthyProbes = 0
+		p.samples = nil
+		p.lastTransition = time.Now()
+	}
+}
+
+// runHealthProbe probes the primary provider until ctx is cancelled
+func (p *failoverProvider) runHealthProbe(ctx context.Context, model string) {
+	ticker := time.NewTicker(p.policy.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.probe(ctx, model)
+		}
+	}
+}
+
+// ProviderStatus describes the failover state of the generator
+type ProviderStatus struct {
+	Primary        string    `json:"primary"`
+	Secondary      string    `json:"secondary,omitempty"`
+	Active         string    `json:"active"`
+	FailedOver     bool      `json:"failed_over"`
+	LastTransition time.Time `json:"last_transition,omitzero"`
+}
+
+// status returns a snapshot of the failover state
+func (p *failoverProvider) status() ProviderStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	active := p.primary.Name()
+	if p.failedOver {
+		active = p.secondary.Name()
+	}
+	return ProviderStatus{
+		Primary:        p.primary.Name(),
+		Secondary:      p.secondary.Name(),
+		Active:         active,
+		FailedOver:     p.failedOver,
+		LastTransition: p.lastTransition,
+	}
+}
+
+// Embed always uses the primary provider: vectors from different models
+// cannot be compared, so an index must not mix them
+func (p *failoverProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	return embed(ctx, p.primary, req)
+}
+
+// Ping checks the provider currently serving requests
+func (p *failoverProvider) Ping(ctx context.Context, model string) error {
+	provider := p.primary
+	if p.isFailedOver() {
+		provider, model = p.secondary, p.secondaryModel
+	}
+	if checker, ok := provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
+
+// Close cleans up both providers
+func (p *failoverProvider) Close() error {
+	if err := p.secondary.Close(); err != nil {
+		return err
+	}
+	return p.primary.Close()
+}
@@ -0,0 +1,273 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FeedbackPath is the feedback endpoint of a relay, relative to the relay URL
+const FeedbackPath = "/v1/feedback"
+
+// Feedback signals, what became of a generated message
+const (
+	// FeedbackAccept is a message committed as generated
+	FeedbackAccept = "accept"
+	// FeedbackEdit is a message whose header was edited before committing
+	FeedbackEdit = "edit"
+	// FeedbackReject is a message that was regenerated, or not committed
+	// within FeedbackRejectAfter
+	FeedbackReject = "reject"
+)
+
+// FeedbackRejectAfter is how long a generated message may wait to be
+// committed before it counts as rejected
+const FeedbackRejectAfter = 7 * 24 * time.Hour
+
+// feedbackSentFile holds the generations already sent as feedback
+const feedbackSentFile = "feedback-sent.json"
+
+// feedbackCommits is how many recent commits are searched for the commit
+// of a generated message
+const feedbackCommits = 500
+
+// FeedbackSignal is what became of one generated message. It carries no
+// diff, message, path, or repository name: only the signal, the parts of
+// the header that were edited, the model, and the generated type when it
+// is a standard one.
+type FeedbackSignal struct {
+	Signal string `json:"signal"`
+	// Edited lists what an edit changed: type, scope, or subject
+	Edited []string `json:"edited,omitempty"`
+	Model  string   `json:"model,omitempty"`
+	// Type is the generated Conventional Commits type, "other" when it is
+	// not one of DefaultTypes
+	Type string `json:"type,omitempty"`
+	// Date is the day the message was generated, in UTC
+	Date string `json:"date"`
+
+	// key identifies the generation the signal is about
+	key string
+}
+
+// FeedbackBatch is the body of POST /v1/feedback
+type FeedbackBatch struct {
+	Signals []FeedbackSignal `json:"signals"`
+}
+
+// key identifies a generation in the sent list
+func (r generationRecord) key() string {
+	return r.Tree + "@" + r.Time.Format(time.RFC3339Nano)
+}
+
+// PendingFeedback returns the signals of the generated messages whose fate
+// is known and that were not sent yet, oldest first
+func (g *GitRepository) PendingFeedback() ([]FeedbackSignal, error) {
+	records := g.loadGenerations()
+	if len(records) == 0 {
+		return nil, nil
+	}
+	commits, err := g.recentTrees(feedbackCommits)
+	if err != nil {
+		return nil, err
+	}
+	committed := make(map[string]string)
+	// Oldest last, so the first commit of a tree wins
+	for _, commit := range slices.Backward(commits) {
+		if _, ok := committed[commit.Tree]; !ok {
+			committed[commit.Tree] = commit.Header
+		}
+	}
+	latest := latestGenerations(records)
+	sent := g.loadFeedbackSent()
+
+	var signals []FeedbackSignal
+	for _, record := range records {
+		if sent[record.key()] {
+			continue
+		}
+		signal := FeedbackSignal{
+			Model: record.Model,
+			Type:  feedbackType(record.Header),
+			Date:  record.Time.UTC().Format(time.DateOnly),
+			key:   record.key(),
+		}
+		header, ok := committed[record.Tree]
+		switch {
+		case latest[record.Tree].key() != record.key():
+			// Regenerated for the same change
+			signal.Signal = FeedbackReject
+		case ok && header == record.Header:
+			signal.Signal = FeedbackAccept
+		case ok:
+			signal.Signal = FeedbackEdit
+			signal.Edited = editedParts(record.Header, header)
+		case time.Since(record.Time) > FeedbackRejectAfter:
+			signal.Signal = FeedbackReject
+		default:
+			// Not committed yet
+			continue
+		}
+		signals = append(signals, signal)
+	}
+	return signals, nil
+}
+
+// feedbackType is the type of a generated header as it may be shared
+func feedbackType(header string) string {
+	msg := ParseCommitMessage(header)
+	switch {
+	case msg.Type == "":
+		return ""
+	case slices.Contains(DefaultTypes, msg.Type):
+		return msg.Type
+	default:
+		return "other"
+	}
+}
+
+// editedParts names the parts of the header that differ
+func editedParts(generated, committed string) []string {
+	was, is := ParseCommitMessage(generated), ParseCommitMessage(committed)
+	var parts []string
+	if was.Type != is.Type {
+		parts = append(parts, "type")
+	}
+	if was.Scope != is.Scope {
+		parts = append(parts, "scope")
+	}
+	if was.Subject != is.Subject {
+		parts = append(parts, "subject")
+	}
+	return parts
+}
+
+// loadFeedbackSent reads the keys of the generations already sent
+func (g *GitRepository) loadFeedbackSent() map[string]bool {
+	sent := make(map[string]bool)
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return sent
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, feedbackSentFile))
+	if err != nil {
+		return sent
+	}
+	var keys []string
+	if json.Unmarshal(data, &keys) == nil {
+		for _, key := range keys {
+			sent[key] = true
+		}
+	}
+	return sent
+}
+
+// markFeedbackSent adds signals to the sent list, dropping generations
+// that are no longer in the generations log
+func (g *GitRepository) markFeedbackSent(signals []FeedbackSignal) error {
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return err
+	}
+	sent := g.loadFeedbackSent()
+	for _, signal := range signals {
+		sent[signal.key] = true
+	}
+	var keys []string
+	for _, record := range g.loadGenerations() {
+		if sent[record.key()] {
+			keys = append(keys, record.key())
+		}
+	}
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to record sent feedback: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, feedbackSentFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to record sent feedback: %w", err)
+	}
+	return nil
+}
+
+// FeedbackURL is where SendFeedback posts to: Options.FeedbackURL, or
+// else the relay's FeedbackPath, or "" when there is neither
+func (c *CommitGen) FeedbackURL() string {
+	if c.feedbackURL != "" {
+		return c.feedbackURL
+	}
+	if relayURL := c.generator.config.RelayURL; relayURL != "" {
+		return strings.TrimRight(relayURL, "/") + FeedbackPath
+	}
+	return ""
+}
+
+// SendFeedback posts the pending feedback signals, when the user opted in
+// with Options.Feedback, and returns how many were sent
+func (c *CommitGen) SendFeedback(ctx context.Context) (int, error) {
+	if !c.feedback {
+		return 0, ErrFeedbackDisabled
+	}
+	if c.vcs.Name() != VCSGit {
+		return 0, nil
+	}
+	if err := checkWritable(c.repo.readOnly, "record sent feedback"); err != nil {
+		return 0, err
+	}
+	url := c.FeedbackURL()
+	if url == "" {
+		return 0, fmt.Errorf("no feedback URL: set feedback.url or relay_url")
+	}
+
+	signals, err := c.repo.PendingFeedback()
+	if err != nil || len(signals) == 0 {
+		return 0, err
+	}
+	body, err := json.Marshal(&FeedbackBatch{Signals: signals})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode feedback: %w", err)
+	}
+
+	httpClient, err := newHTTPClient(c.generator.config.Transport)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.generator.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create feedback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The relay token is only ever asked for when the relay is in use
+	if config := c.generator.config; config.Provider == ProviderRelay || config.FallbackProvider == ProviderRelay {
+		token, err := config.relayToken()
+		if err != nil {
+			return 0, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send feedback: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("failed to send feedback: %s answered %s", url, resp.Status)
+	}
+
+	return len(signals), c.repo.markFeedbackSent(signals)
+}
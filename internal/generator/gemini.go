@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"google.golang.org/genai"
+)
+
+// geminiProvider talks to the Google Gemini API
+type geminiProvider struct {
+	client *genai.Client
+}
+
+// newGeminiProvider creates a Gemini backed Provider
+func newGeminiProvider(ctx context.Context, apiKey, baseURL string, httpClient *http.Client) (*geminiProvider, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:     apiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: httpClient,
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL: baseURL,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+
+	return &geminiProvider{client: client}, nil
+}
+
+// Name returns the provider identifier
+func (p *geminiProvider) Name() string {
+	return "gemini"
+}
+
+// GenerateText sends the request to Gemini
+func (p *geminiProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	// Configure the AI request
+	genConfig := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(req.SystemPrompt, genai.RoleUser),
+		ThinkingConfig: &genai.ThinkingConfig{
+			IncludeThoughts: false,
+			ThinkingBudget:  func() *int32 { v := int32(0); return &v }(), // Disable thinking
+		},
+		Temperature:     req.Temperature,
+		Seed:            req.Seed,
+		MaxOutputTokens: int32(req.MaxOutputTokens),
+	}
+	if req.ThinkingBudget != nil && *req.ThinkingBudget > 0 {
+		genConfig.ThinkingConfig.ThinkingBudget = req.ThinkingBudget
+		// Thoughts count against the output limit, which is sized for the answer
+		if genConfig.MaxOutputTokens > 0 {
+			genConfig.MaxOutputTokens += *req.ThinkingBudget
+		}
+	}
+
+	result, err := p.client.Models.GenerateContent(
+		ctx,
+		req.Model,
+		genai.Text(req.Prompt),
+		genConfig,
+	)
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return nil, &ProviderError{Provider: ProviderGemini, StatusCode: apiErr.Code, Message: apiErr.Message}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TextResponse{
+		Text:  result.Text(),
+		Model: req.Model,
+	}
+	if len(result.Candidates) > 0 {
+		response.FinishReason = geminiFinishReason(result.Candidates[0].FinishReason)
+		if response.FinishReason == FinishReasonSafety {
+			response.BlockReason = string(result.Candidates[0].FinishReason)
+		}
+	}
+	if result.PromptFeedback != nil && result.PromptFeedback.BlockReason != "" {
+		response.FinishReason = FinishReasonSafety
+		response.BlockReason = string(result.PromptFeedback.BlockReason)
+	}
+	if result.UsageMetadata != nil {
+		response.Usage = Usage{
+			PromptTokens: int(result.UsageMetadata.PromptTokenCount),
+			OutputTokens: int(result.UsageMetadata.CandidatesTokenCount),
+			TotalTokens:  int(result.UsageMetadata.TotalTokenCount),
+		}
+	}
+
+	return response, nil
+}
+
+// geminiFinishReason maps a Gemini finish reason to a FinishReason constant
+func geminiFinishReason(reason genai.FinishReason) string {
+	switch reason {
+	case genai.FinishReasonStop, "":
+		return FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return FinishReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonBlocklist, genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII, genai.FinishReasonRecitation:
+		return FinishReasonSafety
+	default:
+		return FinishReasonOther
+	}
+}
+
+// Embed embeds the texts in a single batch request
+func (p *geminiProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	contents := make([]*genai.Content, len(req.Texts))
+	for i, text := range req.Texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	config := &genai.EmbedContentConfig{TaskType: "RETRIEVAL_DOCUMENT"}
+	if req.Query {
+		config.TaskType = "RETRIEVAL_QUERY"
+	}
+
+	result, err := p.client.Models.EmbedContent(ctx, req.Model, contents, config)
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return nil, &ProviderError{Provider: ProviderGemini, StatusCode: apiErr.Code, Message: apiErr.Message}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Embeddings) != len(req.Texts) {
+		return nil, fmt.Errorf("gemini returned %d embeddings for %d texts", len(result.Embeddings), len(req.Texts))
+	}
+
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, embedding := range result.Embeddings {
+		vectors[i] = embedding.Values
+	}
+	return vectors, nil
+}
+
+// Ping verifies the API key and model by fetching the model metadata
+func (p *geminiProvider) Ping(ctx context.Context, model string) error {
+	_, err := p.client.Models.Get(ctx, model, nil)
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return &ProviderError{Provider: ProviderGemini, StatusCode: apiErr.Code, Message: apiErr.Message}
+	}
+	return err
+}
+
+// Close cleans up resources
+func (p *geminiProvider) Close() error {
+	return nil
+}
@@ -2,23 +2,50 @@
 package generator
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"google.golang.org/genai"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
 )
 
 // CommitGen provides a high-level interface for commit message generation
+//
+// A CommitGen is safe for concurrent use by multiple goroutines and is meant
+// to be long-lived: provider clients and their HTTP connections are reused
+// across calls. Per-call differences go through GenerateWithConfig.
 type CommitGen struct {
 	generator *CommitMessageGenerator
-	repo      *GitRepository
+	// vcs supplies the change to describe; repo serves git-only features
+	// such as audits, cover letters, and commit templates
+	vcs            VCS
+	repo           *GitRepository
+	contextOptions *ContextOptions
+	hint           string
+	issue          string
+	noTemplate     bool
+	autoStage      bool
+	dedupeSubjects int
+	policyPaths    []string
+	policyAction   string
+	repos          []string
+	feedback       bool
+	feedbackURL    string
 }
 
 // Options contains configuration options for CommitGen
 type Options struct {
-	// WorkingDir is the git repository directory (empty for current dir)
+	// WorkingDir is the git repository directory (empty for current dir); a
+	// relative path is resolved against the current directory once, in New
 	WorkingDir string
 	// APIKey for the AI service
 	APIKey string
@@ -26,8 +53,229 @@ type Options struct {
 	Model string
 	// Use short commit format
 	IsShortCommit bool
+	// DraftModel enables the two-tier pipeline: a local Ollama model drafts
+	// the message from the full diff and the cloud model only polishes the
+	// draft, so the diff never leaves the machine (empty disables it)
+	DraftModel string
+	// OllamaURL is the local Ollama server (defaults to OLLAMA_HOST or localhost)
+	OllamaURL string
+	// RelayURL is the commit-gen relay used by ProviderRelay
+	RelayURL string
+	// RelayToken authenticates with the relay, e.g. an SSO token
+	RelayToken string
+	// RelayTokenSource gets the relay token when RelayToken is empty, e.g.
+	// from an SSO helper. It runs once, when the first request goes to the
+	// relay, and never when no relay is used.
+	RelayTokenSource func() (string, error)
+	// Provider selects the AI backend: "gemini" (default), "anthropic",
+	// "ollama", or "relay"
+	Provider string
+	// FallbackProvider is used when the primary provider is unhealthy (empty disables failover)
+	FallbackProvider string
+	// FallbackModel is the model used on the fallback provider (optional)
+	FallbackModel string
+	// Failover tunes the failover thresholds (optional, uses DefaultFailoverPolicy if nil)
+	Failover *FailoverPolicy
+	// Transport configures proxies, extra headers, and mTLS for provider requests (optional)
+	Transport *TransportOptions
+	// CacheSize enables an in-memory cache of the last N generated messages (0 disables it)
+	CacheSize int
+	// Observer receives provider and cache events, e.g. for metrics (optional)
+	Observer Observer
+	// SystemPromptFile is a file whose content fully replaces the built-in system prompt (optional)
+	SystemPromptFile string
+	// PromptFragments are extra rules appended, in order, after the base
+	// system prompt (e.g. org-level rules followed by repo-level rules)
+	PromptFragments []string
+	// Validation overrides the rules generated messages must follow (optional)
+	Validation *ValidationRules
+	// MaxAttempts is how many times a message failing validation is
+	// regenerated with feedback before giving up (0 uses DefaultMaxAttempts)
+	MaxAttempts int
+	// NoHistory skips git log collection and style matching, basing the
+	// message purely on the diff and the configured convention. It is a
+	// shorthand for StyleSource = StyleConvention.
+	NoHistory bool
+	// StyleSource selects what the model imitates: StyleHistory (default),
+	// StyleConvention, or StyleExamples
+	StyleSource string
+	// ExamplesFile holds curated example messages used with StyleExamples
+	ExamplesFile string
+	// ScopesFile is a YAML scope glossary, mapping each scope to the part of
+	// the codebase it stands for (default: DefaultScopesFile in the
+	// repository root, when present)
+	ScopesFile string
+	// BlameContext adds the commits that last touched the modified lines to
+	// the prompt, so the model can reference the work being changed
+	BlameContext bool
+	// RelatedFiles adds the files that historically change together with the
+	// staged files to the prompt, helping the model pick the scope
+	RelatedFiles bool
+	// Hint is the user's summary of the change (e.g. "moving auth to
+	// middleware") used to steer the type and subject
+	Hint string
+	// Type and Scope pin those parts of the header so the model only writes
+	// the description and body. Scope may be pinned on its own.
+	Type  string
+	Scope string
+	// SubjectPrefix pins the start of the header verbatim, e.g. "fix(parser): "
+	// or "JIRA-123: ", for tools that already know it. It overrides Type and Scope.
+	SubjectPrefix string
+	// NoTemplate ignores the repository's commit.template instead of merging
+	// the generated message into it
+	NoTemplate bool
+	// ASCIIOnly keeps emoji and other non-ASCII characters out of the
+	// message, for legacy tooling and mail-based workflows
+	ASCIIOnly bool
+	// NoReflow keeps the body exactly as the model wrapped it instead of
+	// hard-wrapping it to the body line limit (72 columns by default)
+	NoReflow bool
+	// CloseIssues adds a closing keyword (e.g. "Fixes: #123") to fix commits
+	// when an issue is detected from the branch name or given in Issue
+	CloseIssues bool
+	// Issue overrides the detected issue number
+	Issue string
+	// IssueKeyword and IssuePosition (IssueFooter or IssueBody) control how
+	// the issue is referenced
+	IssueKeyword  string
+	IssuePosition string
+	// NoContent describes the change from the staged file names only,
+	// without reading file contents. Partial clones fall back to this
+	// automatically when the diff cannot be computed.
+	NoContent bool
+	// NoMerges leaves merge commits out of the history shown to the model
+	NoMerges bool
+	// Provenance adds a trailer disclosing that the message was AI-assisted,
+	// e.g. "Assisted-by: commitgen (gemini-2.5-flash-lite)"
+	Provenance bool
+	// ProvenanceTrailer overrides the trailer key (default DefaultProvenanceTrailer)
+	ProvenanceTrailer string
+	// PolicyPaths are globs of paths where AI generation is forbidden, e.g.
+	// "crypto/" or "*.pem"; see ForbiddenPaths for the syntax
+	PolicyPaths []string
+	// PolicyAction is PolicyRefuse (default) or PolicyOffline when the change
+	// touches PolicyPaths
+	PolicyAction string
+	// AutoStage stages all modified tracked files when nothing is staged,
+	// like git commit -a, for one-click commit flows (git only)
+	AutoStage bool
+	// VCS is VCSGit (default), VCSJujutsu, VCSMercurial, or VCSAuto to
+	// detect it from the working directory
+	VCS string
+	// Convention is ConventionConventional (default) or ConventionKernel for
+	// mailing-list patch workflows
+	Convention string
+	// SeriesLength is the number of patches in the series being prepared;
+	// with ConventionKernel the subject limit leaves room for the
+	// "[PATCH n/m] " prefix
+	SeriesLength int
+	// DedupeSubjects re-asks the model, within MaxAttempts, when the subject
+	// nearly repeats one of the last DedupeSubjects commit subjects, as
+	// happens when committing incremental work (git only, 0 disables it).
+	// A subject still repeating then is kept and reported in the Result.
+	DedupeSubjects int
+	// DedupeScope adds the scope of the staged files to a repeated subject
+	// without one, to set it apart
+	DedupeScope bool
+	// Platform picks subject and body limits that render well on a code
+	// hosting platform: PlatformAuto (default) detects it from the remote,
+	// PlatformNone keeps the built-in limits. Ignored with
+	// ConventionKernel and Validation.
+	Platform string
+	// Repos are the repository directories that commands spanning several
+	// repositories, like Standup, look at (default: WorkingDir only)
+	Repos []string
+	// Feedback opts in to SendFeedback: anonymized accept, edit, and reject
+	// signals about generated messages, never diffs or messages
+	Feedback bool
+	// FeedbackURL is where feedback is sent (default: FeedbackPath of
+	// RelayURL)
+	FeedbackURL string
+	// MaxOutputTokens caps the response length of each kind of request;
+	// zero fields keep DefaultOutputBudget
+	MaxOutputTokens OutputBudget
+	// CostLimit is the most generating may cost, in US dollars, before
+	// ConfirmCost must approve it; without ConfirmCost, such requests fail
+	// with a *CostError. The estimate covers all candidates, retries, and
+	// checks of a generation. Models of unknown price are not limited.
+	CostLimit float64
+	// ConfirmCost is asked about generations over CostLimit, or about every
+	// generation when there is no limit, and sends their requests when it
+	// returns true. It is asked once for all candidates.
+	ConfirmCost func(*CostEstimate) bool
+	// Prices add to or override the built-in model prices, by model name
+	Prices map[string]ModelPrice
+	// SemanticRelease guarantees messages that semantic-release parses: only
+	// its types, and breaking changes in a BREAKING CHANGE footer rather
+	// than a "!" in the header. Not available with ConventionKernel.
+	SemanticRelease bool
+	// Deterministic samples at temperature 0 with a fixed seed, where the
+	// provider supports one, so that retries of automated commits produce
+	// the same message. Candidates keep their spread of temperatures.
+	Deterministic bool
+	// Quality is QualityFast (the default), QualityBalanced, or QualityMax,
+	// choosing the model and thinking budget; an explicit Model wins
+	Quality string
+	// VerifyClaims checks each message for claims the diff does not support,
+	// like "add tests" when no test changed, first against the changed files
+	// and then with the model as the judge. Messages are regenerated with the
+	// claims as feedback; claims that remain are flagged in the Result.
+	VerifyClaims bool
+	// Grounded asks for a bullet body and maps each bullet to the hunks it
+	// describes, dropping bullets that no hunk backs; Result.Grounding has
+	// the mapping for review tools
+	Grounded bool
+	// Anonymize maps sensitive terms, like project codenames, internal
+	// hostnames, or names, to placeholders that the provider sees instead;
+	// the terms are restored in what it returns
+	Anonymize map[string]string
+	// Timeout bounds each request to the provider (default: 10s); analysis
+	// requests like Audit get three times as long
+	Timeout time.Duration
+	// HookTimeout bounds the wait for a suggestion in the prepare-commit-msg
+	// hook (default: DefaultHookTimeout)
+	HookTimeout time.Duration
+	// HookAsync makes the hook return at once and fill in the suggestion
+	// from a background process
+	HookAsync bool
+	// WIPPrefix starts the subject of checkpoint commits, see WIPMessage
+	// and RewordWIP (default: DefaultWIPPrefix)
+	WIPPrefix string
+	// EmbeddingModel embeds the commit history for Search (empty uses the
+	// provider's default embedding model)
+	EmbeddingModel string
+	// Disabled switches AI generation off like DisableEnv: every call that
+	// would reach a provider returns ErrDisabled instead
+	Disabled bool
+	// AuditLog is a file that every generation, provider call, and command
+	// changing the repository is appended to as a JSON line, see AuditEntry.
+	// Diffs and messages are not recorded, only a hash of the diff.
+	AuditLog string
+	// ReadOnly guarantees that the CommitGen never changes the repository,
+	// like ReadOnlyEnv: no staging, even with AutoStage, no commits or
+	// descriptions, and no notes or cache files in the git directory.
+	// Calls that would change it return ErrReadOnly.
+	ReadOnly bool
 }
 
+// Style sources accepted by Options.StyleSource
+const (
+	// StyleHistory imitates the repository's recent commits
+	StyleHistory = prompts.StyleHistory
+	// StyleConvention follows only the configured convention
+	StyleConvention = prompts.StyleConvention
+	// StyleExamples imitates a curated examples file
+	StyleExamples = prompts.StyleExamples
+)
+
+// APIKeyEnv holds the API key of the primary provider when Options.APIKey
+// is empty. For Gemini, LegacyAPIKeyEnv, read when it is not set, keeps
+// older setups working; Anthropic reads AnthropicAPIKeyEnv.
+const (
+	APIKeyEnv       = "COMMITGEN_API_KEY"
+	LegacyAPIKeyEnv = "GOOGLE_API_KEY"
+)
+
 // New creates a new CommitGen instance
 func New(opts *Options) (*CommitGen, error) {
 	if opts == nil {
@@ -35,19 +283,172 @@ func New(opts *Options) (*CommitGen, error) {
 	}
 
 	// Get API key from options or environment
-	apiKey := opts.APIKey
-	if apiKey == "" {
-		apiKey = os.Getenv("GOOGLE_API_KEY")
-	}
-	if apiKey == "" {
-		return nil, fmt.Errorf("API key not provided in options or GOOGLE_API_KEY environment variable")
+	if err := missingAPIKey(opts); err != nil {
+		return nil, err
 	}
+	apiKey, anthropicKey := apiKeys(opts)
 
 	// Set up generator config
 	config := DefaultConfig()
 	config.APIKey = apiKey
-	if opts.Model != "" {
-		config.Model = opts.Model
+	config.AnthropicAPIKey = anthropicKey
+	config.Provider = opts.Provider
+	if opts.Timeout > 0 {
+		config.Timeout = opts.Timeout
+	}
+	quality, err := Quality(opts.Quality)
+	if err != nil {
+		return nil, err
+	}
+	config.Model = cmp.Or(opts.Model, quality.model(opts.Provider), DefaultModel(opts.Provider))
+	config.ThinkingBudget = quality.ThinkingBudget
+	config.SelfCritique = quality.SelfCritique
+	config.VerifyClaims = opts.VerifyClaims
+	config.Grounded = opts.Grounded
+	config.DraftModel = opts.DraftModel
+	config.OllamaURL = opts.OllamaURL
+	config.RelayURL = opts.RelayURL
+	config.RelayToken = opts.RelayToken
+	if opts.RelayTokenSource != nil {
+		config.RelayTokenSource = sync.OnceValues(opts.RelayTokenSource)
+	}
+	config.FallbackProvider = opts.FallbackProvider
+	config.FallbackModel = opts.FallbackModel
+	if config.FallbackModel == "" {
+		config.FallbackModel = cmp.Or(quality.model(opts.FallbackProvider), DefaultModel(opts.FallbackProvider))
+	}
+	config.Disabled = opts.Disabled || Disabled()
+	config.EmbeddingModel = opts.EmbeddingModel
+	config.MaxOutputTokens = opts.MaxOutputTokens.withDefaults()
+	config.CostLimit = opts.CostLimit
+	config.ConfirmCost = opts.ConfirmCost
+	config.Prices = opts.Prices
+	if err := validateDictionary(opts.Anonymize); err != nil {
+		return nil, err
+	}
+	config.Anonymize = opts.Anonymize
+	config.Deterministic = opts.Deterministic
+	if config.EmbeddingModel == "" {
+		config.EmbeddingModel = DefaultEmbeddingModel(opts.Provider)
+	}
+	config.Failover = opts.Failover
+	config.Transport = opts.Transport
+	config.CacheSize = opts.CacheSize
+	config.Observer = opts.Observer
+
+	if opts.SystemPromptFile != "" {
+		prompt, err := os.ReadFile(opts.SystemPromptFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read system prompt file: %w", err)
+		}
+		if strings.TrimSpace(string(prompt)) == "" {
+			return nil, fmt.Errorf("system prompt file %s is empty", opts.SystemPromptFile)
+		}
+		config.SystemPrompt = string(prompt)
+	}
+	config.PromptFragments = opts.PromptFragments
+	config.Validation = opts.Validation
+	config.MaxAttempts = opts.MaxAttempts
+
+	config.ASCIIOnly = opts.ASCIIOnly
+	config.NoReflow = opts.NoReflow
+	config.SeriesLength = opts.SeriesLength
+	config.DedupeScope = opts.DedupeScope
+	if opts.Provenance {
+		config.ProvenanceTrailer = opts.ProvenanceTrailer
+		if config.ProvenanceTrailer == "" {
+			config.ProvenanceTrailer = DefaultProvenanceTrailer
+		}
+	}
+	config.Convention = opts.Convention
+	switch config.Convention {
+	case "":
+		config.Convention = ConventionConventional
+	case ConventionConventional, ConventionKernel:
+	default:
+		return nil, fmt.Errorf("unknown convention %q", config.Convention)
+	}
+	if opts.SemanticRelease && config.Convention == ConventionKernel {
+		return nil, errors.New("semantic-release needs the conventional convention")
+	}
+	config.SemanticRelease = opts.SemanticRelease
+	if opts.CloseIssues {
+		switch opts.IssuePosition {
+		case "", IssueFooter, IssueBody:
+		default:
+			return nil, fmt.Errorf("unknown issue position %q", opts.IssuePosition)
+		}
+		config.Issues = &IssueOptions{Keyword: opts.IssueKeyword, Position: opts.IssuePosition}
+	}
+	switch opts.PolicyAction {
+	case "", PolicyRefuse, PolicyOffline:
+	default:
+		return nil, fmt.Errorf("unknown policy action %q", opts.PolicyAction)
+	}
+	config.SubjectPrefix = opts.SubjectPrefix
+	switch {
+	case config.SubjectPrefix != "":
+	case opts.Type != "":
+		config.SubjectPrefix = SubjectPrefix(opts.Type, opts.Scope)
+	default:
+		config.Scope = opts.Scope
+	}
+
+	config.StyleSource = opts.StyleSource
+	if config.StyleSource == "" {
+		config.StyleSource = StyleHistory
+	}
+	if opts.NoHistory {
+		config.StyleSource = StyleConvention
+	}
+	switch config.StyleSource {
+	case StyleHistory, StyleConvention:
+	case StyleExamples:
+		if opts.ExamplesFile == "" {
+			return nil, fmt.Errorf("style source %q requires an examples file", StyleExamples)
+		}
+		examples, err := os.ReadFile(opts.ExamplesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read examples file: %w", err)
+		}
+		config.Examples = string(examples)
+	default:
+		return nil, fmt.Errorf("unknown style source %q", config.StyleSource)
+	}
+
+	workingDir, err := resolveWorkingDir(opts.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+	auditLog, err := newAuditLog(opts.AuditLog, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	config.audit = auditLog
+	guard := writeGuard{readOnly: opts.ReadOnly, audit: auditLog}
+
+	vcs, err := NewVCS(opts.VCS, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	switch v := vcs.(type) {
+	case *GitRepository:
+		v.writeGuard = guard
+	case *JujutsuRepository:
+		v.writeGuard = guard
+	}
+
+	// Create git repository handler
+	repo := NewGitRepository(workingDir)
+	repo.writeGuard = guard
+
+	config.Platform, err = resolvePlatform(opts.Platform, repo)
+	if err != nil {
+		return nil, err
+	}
+	config.Scopes, err = repo.scopeGlossary(opts.ScopesFile)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create generator
@@ -56,53 +457,332 @@ func New(opts *Options) (*CommitGen, error) {
 		return nil, fmt.Errorf("failed to create generator: %w", err)
 	}
 
-	// Create git repository handler
-	repo := NewGitRepository(opts.WorkingDir)
-
 	return &CommitGen{
 		generator: generator,
+		vcs:       vcs,
 		repo:      repo,
+		contextOptions: &ContextOptions{
+			// Only the history style needs the git log
+			NoHistory:    config.StyleSource != StyleHistory,
+			Blame:        opts.BlameContext,
+			RelatedFiles: opts.RelatedFiles,
+			NoContent:    opts.NoContent,
+			NoMerges:     opts.NoMerges,
+		},
+		hint:           strings.TrimSpace(opts.Hint),
+		issue:          opts.Issue,
+		noTemplate:     opts.NoTemplate,
+		autoStage:      opts.AutoStage,
+		dedupeSubjects: opts.DedupeSubjects,
+		policyPaths:    opts.PolicyPaths,
+		policyAction:   opts.PolicyAction,
+		repos:          opts.Repos,
+		feedback:       opts.Feedback,
+		feedbackURL:    opts.FeedbackURL,
 	}, nil
 }
 
 // Generate creates a commit message for the current staged changes
-func (c *CommitGen) Generate() (string, error) {
-	// Get git context
-	gitInfo, err := c.repo.GetCommitContext()
+func (c *CommitGen) Generate() (*Result, error) {
+	return c.GenerateContext(context.Background())
+}
+
+// GenerateContext is Generate with a context. Cancelling it, e.g. on
+// Ctrl-C, aborts the provider request in flight.
+func (c *CommitGen) GenerateContext(ctx context.Context) (result *Result, err error) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "commitgen.Generate")
+	defer func() { endSpan(span, err) }()
+	ctx, m := withMeter(ctx)
+	defer func() { err = m.interrupted(ctx, err) }()
+
+	gitInfo, err := c.changeContext(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Generate commit message
-	message, err := c.generator.GenerateCommitMessage(gitInfo)
+	result, err = c.generateAllowed(ctx, gitInfo, nil)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if err := c.applyTemplate(result); err != nil {
+		return nil, err
+	}
+	result.Latency = time.Since(start)
+	if c.vcs.Name() == VCSGit {
+		c.repo.recordGeneration(result)
+	}
+
+	return result, nil
+}
+
+// Candidates generates up to n alternative messages for the staged changes,
+// for the user to pick from. Each is sampled at a different temperature so
+// they differ; duplicates are dropped. Failed attempts are skipped unless
+// all of them fail.
+func (c *CommitGen) Candidates(ctx context.Context, n int) (results []*Result, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Candidates")
+	defer func() { endSpan(span, err) }()
+	ctx, m := withMeter(ctx)
+	defer func() { err = m.interrupted(ctx, err) }()
+
+	gitInfo, err := c.changeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The candidates are generated at once, so their cost is checked up
+	// front, all together, unless the policy keeps the change local
+	if len(ForbiddenPaths(c.policyPaths, changedFiles(gitInfo))) == 0 {
+		g := c.generator
+		call := g.resolve(nil)
+		if err := g.checkCost(ctx, call, g.callPrompt(ctx, gitInfo, call), n); err != nil {
+			return nil, err
+		}
+		ctx = withCostChecked(ctx)
+	}
+
+	all := make([]*Result, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			temperature := candidateTemperature(i)
+			result, err := c.generateAllowed(ctx, gitInfo, &GenConfig{Temperature: &temperature})
+			if err == nil {
+				err = c.applyTemplate(result)
+			}
+			all[i], errs[i] = result, err
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for i, result := range all {
+		if errs[i] != nil || seen[result.String()] {
+			continue
+		}
+		seen[result.String()] = true
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, errs[0]
+	}
+	return results, nil
+}
+
+// candidateTemperature spreads candidates from focused to varied
+func candidateTemperature(i int) float32 {
+	return min(0.3+0.3*float32(i), 1.5)
+}
+
+// changeContext collects the change to describe, staging modified files
+// first when AutoStage is set
+func (c *CommitGen) changeContext(ctx context.Context) (*GitInfo, error) {
+	if c.autoStage && c.vcs.Name() == VCSGit {
+		if err := c.repo.stageIfEmpty(); err != nil {
+			return nil, err
+		}
 	}
 
-	return message, nil
+	_, gitSpan := tracer.Start(ctx, "git.collect")
+	gitInfo, err := c.vcs.ChangeContext(c.contextOptions)
+	endSpan(gitSpan, err)
+	if err != nil {
+		return nil, err
+	}
+	gitInfo.Hint = c.hint
+	if c.issue != "" {
+		gitInfo.Issue = c.issue
+	}
+	if c.dedupeSubjects > 0 && c.vcs.Name() == VCSGit {
+		// Before the first commit there is nothing to repeat
+		gitInfo.RecentSubjects, _ = c.repo.RecentSubjects(c.dedupeSubjects)
+	}
+	return gitInfo, nil
+}
+
+// applyTemplate keeps the sections of a corporate commit.template intact
+func (c *CommitGen) applyTemplate(result *Result) error {
+	if c.vcs.Name() != VCSGit || c.noTemplate {
+		return nil
+	}
+	template, err := c.repo.GetCommitTemplate()
+	if err != nil {
+		return err
+	}
+	if template != nil {
+		result.Message = ParseCommitMessage(template.Merge(result.Message.String(), false))
+	}
+	return nil
 }
 
 // GenerateFromDiff creates a commit message from provided diff and optional history
 // This is useful for applications that want to provide their own git data
-func (c *CommitGen) GenerateFromDiff(diff, history string) (string, error) {
+func (c *CommitGen) GenerateFromDiff(diff, history string) (*Result, error) {
+	return c.GenerateFromDiffContext(context.Background(), diff, history)
+}
+
+// GenerateFromDiffContext is GenerateFromDiff with a context. Cancelling it,
+// e.g. on Ctrl-C, aborts the provider request in flight.
+func (c *CommitGen) GenerateFromDiffContext(ctx context.Context, diff, history string) (result *Result, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.GenerateFromDiff")
+	defer func() { endSpan(span, err) }()
+	ctx, m := withMeter(ctx)
+	defer func() { err = m.interrupted(ctx, err) }()
+
 	gitInfo := &GitInfo{
 		StagedDiff:    diff,
 		RecentCommits: history,
 		HasHistory:    history != "",
+		Hint:          c.hint,
+		Issue:         c.issue,
 	}
+	return c.generateAllowed(ctx, gitInfo, nil)
+}
 
-	return c.generator.GenerateCommitMessage(gitInfo)
+// GenerateWithConfig creates a commit message from the provided git
+// information with per-call overrides of model, temperature, and prompt
+func (c *CommitGen) GenerateWithConfig(ctx context.Context, gitInfo *GitInfo, cfg *GenConfig) (*Result, error) {
+	ctx, span := tracer.Start(ctx, "commitgen.GenerateWithConfig")
+	result, err := c.generateAllowed(ctx, gitInfo, cfg)
+	endSpan(span, err)
+
+	return result, err
+}
+
+// generateAllowed generates the message unless the change touches paths
+// the repository policy keeps away from models, in which case it refuses
+// or falls back to HeuristicMessage
+func (c *CommitGen) generateAllowed(ctx context.Context, gitInfo *GitInfo, cfg *GenConfig) (result *Result, err error) {
+	config := c.generator.config
+	policy := ""
+	defer func() {
+		if logErr := config.audit.generation(gitInfo, config.Anonymize, policy, result, err); logErr != nil && err == nil {
+			result, err = nil, logErr
+		}
+	}()
+
+	forbidden := ForbiddenPaths(c.policyPaths, changedFiles(gitInfo))
+	if len(forbidden) == 0 {
+		return c.generator.GenerateWithConfig(ctx, gitInfo, cfg)
+	}
+	policy = cmp.Or(c.policyAction, PolicyRefuse)
+	if c.policyAction != PolicyOffline {
+		return nil, &PolicyError{Paths: forbidden}
+	}
+
+	isShortCommit := c.generator.isShortCommit
+	if cfg != nil && cfg.IsShortCommit != nil {
+		isShortCommit = *cfg.IsShortCommit
+	}
+	message := applyPins(HeuristicMessage(gitInfo, isShortCommit), config.SubjectPrefix, config.Scope)
+	return &Result{
+		Message:      linkIssue(message, gitInfo.Issue, config.Issues),
+		Model:        HeuristicModel,
+		Attempts:     1,
+		FinishReason: FinishReasonStop,
+	}, nil
 }
 
 // HasStagedChanges checks if there are staged changes in the repository
+// For jj and hg, which have no staging area, it checks the working copy
 func (c *CommitGen) HasStagedChanges() (bool, error) {
-	return c.repo.HasStagedChanges()
+	return c.vcs.HasChanges()
+}
+
+// StageAll stages all modified tracked files (git only)
+func (c *CommitGen) StageAll() error {
+	return c.repo.StageAll()
+}
+
+// StageAllCommand returns the command StageAll runs, to show it first
+func (c *CommitGen) StageAllCommand() Command {
+	return c.repo.StageAllCommand()
+}
+
+// ReadOnly reports whether read-only mode forbids changing the repository
+func (c *CommitGen) ReadOnly() bool {
+	return !cacheWritable(c.repo.readOnly)
+}
+
+// HasUnstagedChanges reports whether tracked files have unstaged modifications
+func (c *CommitGen) HasUnstagedChanges() (bool, error) {
+	return c.repo.HasUnstagedChanges()
+}
+
+// VCS returns the version control system the generator describes changes of
+func (c *CommitGen) VCS() VCS {
+	return c.vcs
 }
 
 // GetGitInfo returns the git information that would be used for generation
 // This is useful for debugging or for applications that want to preview the data
 func (c *CommitGen) GetGitInfo() (*GitInfo, error) {
-	return c.repo.GetCommitContext()
+	return c.vcs.ChangeContext(c.contextOptions)
+}
+
+// StartHealthProbe probes the primary provider in the background until ctx is
+// cancelled, switching back from the fallback once it is healthy again.
+// It is a no-op when no fallback provider is configured.
+func (c *CommitGen) StartHealthProbe(ctx context.Context) {
+	if c.generator.config.Disabled {
+		return
+	}
+	if fp := c.generator.failover(); fp != nil {
+		go fp.runHealthProbe(ctx, c.generator.config.Model)
+	}
+}
+
+// WarmUp connects to the provider ahead of the first generation, so that
+// it does not pay for the TLS and auth handshakes; the connection is kept
+// alive for later calls. With generate set it also sends a one-token
+// completion, which loads a local model into memory. The draft provider of
+// the two-tier pipeline is warmed up as well.
+func (c *CommitGen) WarmUp(ctx context.Context, generate bool) (err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.WarmUp")
+	defer func() { endSpan(span, err) }()
+
+	g := c.generator
+	if g.config.Disabled {
+		return ErrDisabled
+	}
+	type target struct {
+		provider Provider
+		model    string
+		timeout  time.Duration
+	}
+	targets := []target{{g.provider, g.config.Model, g.config.Timeout}}
+	if g.draftProvider != nil {
+		targets = append(targets, target{g.draftProvider, g.config.DraftModel, g.config.DraftTimeout})
+	}
+
+	for _, t := range targets {
+		warmCtx, cancel := context.WithTimeout(ctx, t.timeout)
+		if generate {
+			_, err = t.provider.GenerateText(warmCtx, &TextRequest{Model: t.model, Prompt: "Reply with OK.", MaxOutputTokens: 1})
+		} else if checker, ok := t.provider.(HealthChecker); ok {
+			err = checker.Ping(warmCtx, t.model)
+		}
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to warm up %s: %w", t.provider.Name(), err)
+		}
+	}
+	return nil
+}
+
+// ProviderStatus reports which provider is currently serving requests
+func (c *CommitGen) ProviderStatus() ProviderStatus {
+	if fp := c.generator.failover(); fp != nil {
+		return fp.status()
+	}
+	name := c.generator.provider.Name()
+	return ProviderStatus{Primary: name, Active: name}
 }
 
 // Close cleans up resources
@@ -121,7 +801,11 @@ func QuickGenerate(apiKey string) (string, error) {
 	}
 	defer commitGen.Close()
 
-	return commitGen.Generate()
+	result, err := commitGen.Generate()
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
 }
 
 // QuickGenerateShort is a convenience function for generating short commit messages
@@ -135,7 +819,11 @@ func QuickGenerateShort(apiKey string) (string, error) {
 	}
 	defer commitGen.Close()
 
-	return commitGen.Generate()
+	result, err := commitGen.Generate()
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
 }
 
 // QuickGenerateWithOptions is like QuickGenerate but with more options
@@ -146,15 +834,74 @@ func QuickGenerateWithOptions(opts *Options) (string, error) {
 	}
 	defer commitGen.Close()
 
-	return commitGen.Generate()
+	result, err := commitGen.Generate()
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
 }
 
 // CommitMessageGenerator handles AI-powered commit message generation
+//
+// All fields are read-only after construction (the cache and failover state
+// guard themselves), so a generator is safe for concurrent use. Calls made
+// after Close return ErrClosed.
 type CommitMessageGenerator struct {
-	client        *genai.Client
+	provider      Provider
+	draftProvider Provider // local drafting backend, nil unless two-tier mode is on
+	cache         *responseCache
 	config        *GeneratorConfig
 	systemPrompt  string
 	isShortCommit bool
+
+	closed    atomic.Bool
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// ErrClosed is returned when generating with a closed generator
+var ErrClosed = errors.New("generator is closed")
+
+// OutputBudget is the maximum number of output tokens for each kind of
+// request. Tight limits keep costs predictable; a truncated response is
+// still retried once with twice the budget.
+type OutputBudget struct {
+	// Short is for one-line commit titles
+	Short int
+	// Full is for commit messages with a body
+	Full int
+	// Summary is for prose about several commits: push summaries, standups,
+	// bisect explanations, and cover letters
+	Summary int
+	// Report is for structured reports: plans, audits, and digests
+	Report int
+}
+
+// DefaultOutputBudget leaves ample room for each kind of request
+var DefaultOutputBudget = OutputBudget{Short: 256, Full: 1024, Summary: 2048, Report: 8192}
+
+// withDefaults fills the zero fields of b from DefaultOutputBudget
+func (b OutputBudget) withDefaults() OutputBudget {
+	orDefault := func(value, def int) int {
+		if value > 0 {
+			return value
+		}
+		return def
+	}
+	return OutputBudget{
+		Short:   orDefault(b.Short, DefaultOutputBudget.Short),
+		Full:    orDefault(b.Full, DefaultOutputBudget.Full),
+		Summary: orDefault(b.Summary, DefaultOutputBudget.Summary),
+		Report:  orDefault(b.Report, DefaultOutputBudget.Report),
+	}
+}
+
+// commitTokens is the output budget of a commit message in the given format
+func (b OutputBudget) commitTokens(isShortCommit bool) int {
+	if isShortCommit {
+		return b.Short
+	}
+	return b.Full
 }
 
 // GeneratorConfig contains configuration for the commit message generator
@@ -162,192 +909,773 @@ type GeneratorConfig struct {
 	Model   string
 	Timeout time.Duration
 	APIKey  string
+	// AnthropicAPIKey is the key of ProviderAnthropic
+	AnthropicAPIKey string
+	// DraftModel is the local Ollama model used to draft messages (empty disables two-tier mode)
+	DraftModel string
+	// DraftTimeout bounds the local drafting call, which is usually slower than the cloud
+	DraftTimeout time.Duration
+	// OllamaURL is the local Ollama server address
+	OllamaURL string
+	// RelayURL and RelayToken configure ProviderRelay; RelayTokenSource,
+	// when set, is asked for the token on the first relay request instead
+	RelayURL         string
+	RelayToken       string
+	RelayTokenSource func() (string, error)
+	// Provider is the primary AI backend (empty means gemini)
+	Provider string
+	// FallbackProvider is used when the primary is unhealthy (empty disables failover)
+	FallbackProvider string
+	// FallbackModel is the model used on the fallback provider
+	FallbackModel string
+	// EmbeddingModel is the primary provider's model for embeddings
+	EmbeddingModel string
+	// MaxOutputTokens caps the response length of each kind of request
+	// (zero fields leave it to the provider)
+	MaxOutputTokens OutputBudget
+	// CostLimit and ConfirmCost guard against expensive requests, see Options
+	CostLimit   float64
+	ConfirmCost func(*CostEstimate) bool
+	// Prices add to or override the built-in model prices
+	Prices map[string]ModelPrice
+	// SemanticRelease enforces semantic-release's commit grammar
+	SemanticRelease bool
+	// Deterministic fixes the temperature and seed of every request
+	Deterministic bool
+	// ThinkingBudget is the thinking budget of every request, see TextRequest
+	ThinkingBudget int32
+	// SelfCritique has the model review each message against the diff and
+	// revise it once when the review finds problems
+	SelfCritique bool
+	// VerifyClaims checks messages for claims the diff does not support
+	VerifyClaims bool
+	// Grounded keeps only body statements that cite the diff
+	Grounded bool
+	// Anonymize maps sensitive terms to placeholders, see Options
+	Anonymize map[string]string
+	// Disabled makes every provider call fail with ErrDisabled
+	Disabled bool
+	// Failover tunes when to switch between primary and fallback
+	Failover *FailoverPolicy
+	// Transport configures the HTTP client shared by all providers
+	Transport *TransportOptions
+	// CacheSize is the number of generated messages kept in memory (0 disables caching)
+	CacheSize int
+	// Observer receives instrumentation events
+	Observer Observer
+	// audit records every provider call, if set
+	audit *auditLog
+	// SystemPrompt replaces the built-in system prompt when not empty
+	SystemPrompt string
+	// PromptFragments are appended to the system prompt in order
+	PromptFragments []string
+	// Validation is the rule set for generated messages (nil uses the defaults)
+	Validation *ValidationRules
+	// MaxAttempts bounds validation retries (0 uses DefaultMaxAttempts)
+	MaxAttempts int
+	// StyleSource is what the model imitates (see the Style constants)
+	StyleSource string
+	// Examples are the curated messages imitated with StyleExamples
+	Examples string
+	// Scopes is the scope glossary given to the model
+	Scopes []ScopeDefinition
+	// SubjectPrefix is the pinned start of every header (empty lets the model choose)
+	SubjectPrefix string
+	// Scope is the pinned scope when only the scope is fixed
+	Scope string
+	// ASCIIOnly restricts messages to ASCII characters
+	ASCIIOnly bool
+	// NoReflow disables hard-wrapping the body
+	NoReflow bool
+	// Issues adds auto-close keywords to fix commits (nil disables it)
+	Issues *IssueOptions
+	// Convention is the commit message convention (see the Convention constants)
+	Convention string
+	// ProvenanceTrailer is the AI-disclosure trailer key (empty disables it)
+	ProvenanceTrailer string
+	// SeriesLength reserves subject room for a "[PATCH n/m] " prefix (0 for none)
+	SeriesLength int
+	// Platform is the platform whose limits apply (empty for the built-in ones)
+	Platform string
+	// DedupeScope scopes subjects that still repeat a recent one
+	DedupeScope bool
+}
+
+// resolveWorkingDir makes a relative working directory absolute, so that it
+// keeps pointing at the same repository if the process changes directory,
+// and checks that it is a directory. The empty current directory is kept.
+func resolveWorkingDir(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory %s: %w", dir, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid working directory %s: not a directory", dir)
+	}
+	return abs, nil
 }
 
 // DefaultConfig returns a default configuration
 func DefaultConfig() *GeneratorConfig {
 	return &GeneratorConfig{
-		Model:   "gemini-2.5-flash-lite", // Fast and Dirty just like we like it
-		Timeout: 10 * time.Second,
+		Model:        "gemini-2.5-flash-lite", // Fast and Dirty just like we like it
+		Timeout:      10 * time.Second,
+		DraftTimeout: 60 * time.Second,
 	}
 }
 
 // NewCommitMessageGenerator creates a new commit message generator
 func NewCommitMessageGenerator(config *GeneratorConfig, isShortCommit bool) (*CommitMessageGenerator, error) {
-	if config.APIKey == "" {
-		return nil, fmt.Errorf("API key is required")
-	}
-
 	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
 	defer cancel()
 
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  config.APIKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	httpClient, err := newHTTPClient(config.Transport)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create AI client: %w", err)
+		return nil, err
 	}
 
-	var systemPrompt string
-	if isShortCommit {
-		systemPrompt = getShortCommitPrompt()
-	} else {
-		systemPrompt = getDefaultSystemPrompt()
+	provider, err := newProvider(ctx, config.Provider, config, httpClient)
+	if err != nil {
+		return nil, err
 	}
+	provider = observe(provider, config.Observer)
 
-	return &CommitMessageGenerator{
-		client:        client,
+	if config.FallbackProvider != "" {
+		fallback, err := newProvider(ctx, config.FallbackProvider, config, httpClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback provider: %w", err)
+		}
+		provider = newFailoverProvider(provider, observe(fallback, config.Observer), config.FallbackModel, config.Failover)
+	}
+	// The local draft model may see the terms; the polish request that
+	// carries its draft out goes through the anonymized provider
+	provider = audit(anonymize(provider, config.Anonymize), config.audit, config.Anonymize)
+
+	var draftProvider Provider
+	if config.DraftModel != "" {
+		draftProvider = audit(observe(newOllamaProvider(config.OllamaURL, httpClient), config.Observer), config.audit, nil)
+	}
+
+	var cache *responseCache
+	if config.CacheSize > 0 {
+		cache = newResponseCache(config.CacheSize)
+	}
+
+	generator := &CommitMessageGenerator{
+		provider:      provider,
+		draftProvider: draftProvider,
+		cache:         cache,
 		config:        config,
-		systemPrompt:  systemPrompt,
 		isShortCommit: isShortCommit,
-	}, nil
+	}
+	generator.systemPrompt = generator.systemPromptFor(isShortCommit)
+
+	return generator, nil
+}
+
+// promptKey is everything a rendered system prompt depends on
+type promptKey struct {
+	base             string
+	convention       string
+	styleSource      string
+	fragments        string
+	isShortCommit    bool
+	asciiOnly        bool
+	semanticRelease  bool
+	grounded         bool
+	maxSubjectLength int
+}
+
+// maxCachedPrompts bounds the prompt cache of a long-lived process whose
+// config keeps changing
+const maxCachedPrompts = 64
+
+// promptCache holds rendered system prompts shared by all generators of the
+// process, so that daemons serving many projects and per-request format
+// overrides render each prompt once. Keys cover every input, so a changed
+// config simply misses the cache.
+var promptCache = struct {
+	sync.Mutex
+	prompts map[promptKey]string
+}{prompts: make(map[promptKey]string)}
+
+// systemPromptFor returns the configured system prompt for the given format
+func (g *CommitMessageGenerator) systemPromptFor(isShortCommit bool) string {
+	key := promptKey{
+		base:            g.config.SystemPrompt,
+		convention:      g.config.Convention,
+		styleSource:     g.config.StyleSource,
+		fragments:       strings.Join(g.config.PromptFragments, "\x00"),
+		isShortCommit:   isShortCommit,
+		asciiOnly:       g.config.ASCIIOnly,
+		semanticRelease: g.config.SemanticRelease,
+		grounded:        g.config.Grounded,
+	}
+	if key.base == "" && key.convention == ConventionKernel {
+		key.maxSubjectLength = g.rulesFor(isShortCommit).MaxSubjectLength
+	}
+
+	promptCache.Lock()
+	defer promptCache.Unlock()
+	if prompt, ok := promptCache.prompts[key]; ok {
+		return prompt
+	}
+	if len(promptCache.prompts) >= maxCachedPrompts {
+		clear(promptCache.prompts)
+	}
+	prompt := renderSystemPrompt(key)
+	promptCache.prompts[key] = prompt
+	return prompt
+}
+
+// renderSystemPrompt composes the system prompt described by key
+func renderSystemPrompt(key promptKey) string {
+	var fragments []string
+	if key.fragments != "" {
+		fragments = strings.Split(key.fragments, "\x00")
+	}
+	return prompts.System{
+		Base:             key.base,
+		Convention:       key.convention,
+		StyleSource:      key.styleSource,
+		Fragments:        fragments,
+		Short:            key.isShortCommit,
+		ASCIIOnly:        key.asciiOnly,
+		SemanticRelease:  key.semanticRelease,
+		Grounded:         key.grounded,
+		MaxSubjectLength: key.maxSubjectLength,
+	}.Render()
+}
+
+// GenConfig overrides generator settings for a single call, so one
+// long-lived generator can serve differently configured requests.
+// Zero values keep the generator's own settings.
+type GenConfig struct {
+	// Model overrides the model for this call
+	Model string
+	// Temperature overrides the provider's sampling temperature
+	Temperature *float32
+	// SystemPrompt fully replaces the system prompt for this call
+	SystemPrompt string
+	// IsShortCommit overrides the short/full commit format
+	IsShortCommit *bool
+	// SubjectPrefix pins the start of the header for this call
+	SubjectPrefix string
+	// Scope pins the scope for this call when no SubjectPrefix is set
+	Scope string
+}
+
+// resolve fills the unset fields of cfg with the generator defaults
+func (g *CommitMessageGenerator) resolve(cfg *GenConfig) *GenConfig {
+	resolved := &GenConfig{
+		Model:         g.config.Model,
+		SystemPrompt:  g.systemPrompt,
+		IsShortCommit: &g.isShortCommit,
+		SubjectPrefix: g.config.SubjectPrefix,
+		Scope:         g.config.Scope,
+	}
+	if cfg == nil {
+		return resolved
+	}
+
+	if cfg.Model != "" {
+		resolved.Model = cfg.Model
+	}
+	resolved.Temperature = cfg.Temperature
+	if cfg.IsShortCommit != nil && *cfg.IsShortCommit != g.isShortCommit {
+		resolved.IsShortCommit = cfg.IsShortCommit
+		resolved.SystemPrompt = g.systemPromptFor(*cfg.IsShortCommit)
+	}
+	if cfg.SystemPrompt != "" {
+		resolved.SystemPrompt = cfg.SystemPrompt
+	}
+	if cfg.SubjectPrefix != "" || cfg.Scope != "" {
+		resolved.SubjectPrefix = cfg.SubjectPrefix
+		resolved.Scope = cfg.Scope
+	}
+	return resolved
 }
 
 // GenerateCommitMessage generates a commit message from git information
-func (g *CommitMessageGenerator) GenerateCommitMessage(gitInfo *GitInfo) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), g.config.Timeout)
-	defer cancel()
+func (g *CommitMessageGenerator) GenerateCommitMessage(gitInfo *GitInfo) (*Result, error) {
+	return g.GenerateWithConfig(context.Background(), gitInfo, nil)
+}
 
-	// Prepare the prompt
-	prompt := buildPrompt(gitInfo)
+// GenerateWithConfig generates a commit message with per-call overrides
+// A nil cfg uses the generator settings
+func (g *CommitMessageGenerator) GenerateWithConfig(ctx context.Context, gitInfo *GitInfo, cfg *GenConfig) (*Result, error) {
+	result, err := g.generateCached(ctx, gitInfo, cfg)
+	if err != nil {
+		return nil, err
+	}
+	result.Message = linkIssue(result.Message, gitInfo.Issue, g.config.Issues)
+	if !*g.resolve(cfg).IsShortCommit {
+		result.Message = listConflicts(result.Message, gitInfo.ConflictedFiles)
+	}
+	if g.config.ProvenanceTrailer != "" {
+		result.Message.AddTrailer(g.config.ProvenanceTrailer, fmt.Sprintf("commitgen (%s)", result.Model))
+		result.Message.NormalizeTrailers()
+	}
+	return result, nil
+}
 
-	// Configure the AI request
-	genConfig := &genai.GenerateContentConfig{
-		SystemInstruction: genai.NewContentFromText(g.systemPrompt, genai.RoleUser),
-		ThinkingConfig: &genai.ThinkingConfig{
-			IncludeThoughts: false,
-			ThinkingBudget:  func() *int32 { v := int32(0); return &v }(), // Disable thinking
-		},
+// generateCached generates a commit message, reusing a cached result for
+// identical calls
+func (g *CommitMessageGenerator) generateCached(ctx context.Context, gitInfo *GitInfo, cfg *GenConfig) (*Result, error) {
+	start := time.Now()
+	if g.closed.Load() {
+		return nil, ErrClosed
 	}
+	call := g.resolve(cfg)
 
-	// Generate the commit message
-	result, err := g.client.Models.GenerateContent(
-		ctx,
-		g.config.Model,
-		genai.Text(prompt),
-		genConfig,
-	)
+	prompt := g.callPrompt(ctx, gitInfo, call)
+
+	if g.cache == nil {
+		if err := g.checkCost(ctx, call, prompt, 1); err != nil {
+			return nil, err
+		}
+		result, err := g.generateValid(ctx, call, prompt, gitInfo)
+		if err == nil && g.config.Grounded {
+			err = g.ground(ctx, call, gitInfo, result)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Latency = time.Since(start)
+		return result, nil
+	}
+
+	var temperature string
+	if call.Temperature != nil {
+		temperature = fmt.Sprint(*call.Temperature)
+	}
+	key := cacheKey(call.Model, g.config.DraftModel, call.SystemPrompt, temperature, prompt)
+	if cached, ok := g.cache.get(key); ok {
+		g.observeCache(true)
+		// No tokens are spent on a cache hit
+		result := *cached
+		result.Tokens = Usage{}
+		result.Cached = true
+		result.Latency = time.Since(start)
+		return &result, nil
+	}
+	g.observeCache(false)
+
+	// Cache hits are free, so only misses are checked
+	if err := g.checkCost(ctx, call, prompt, 1); err != nil {
+		return nil, err
+	}
+	result, err := g.generateValid(ctx, call, prompt, gitInfo)
+	if err == nil && g.config.Grounded {
+		err = g.ground(ctx, call, gitInfo, result)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to generate commit message: %w", err)
+		return nil, err
 	}
+	result.Latency = time.Since(start)
+	g.cache.put(key, result)
 
-	return result.Text(), nil
+	return result, nil
 }
 
-// Close cleans up resources
-func (g *CommitMessageGenerator) Close() error {
-	// Add cleanup if needed
-	return nil
+// callPrompt builds the prompt of a call
+func (g *CommitMessageGenerator) callPrompt(ctx context.Context, gitInfo *GitInfo, call *GenConfig) string {
+	_, span := tracer.Start(ctx, "prompt.build")
+	defer span.End()
+	prompt := g.buildPrompt(gitInfo) + pinPrompt(call.SubjectPrefix, call.Scope)
+	span.SetAttributes(attribute.Int("commitgen.prompt_bytes", len(prompt)))
+	return prompt
 }
 
-// buildPrompt constructs the prompt for the AI
-func buildPrompt(gitInfo *GitInfo) string {
-	if gitInfo.HasHistory && gitInfo.RecentCommits != "" {
-		return fmt.Sprintf(
-			"Recent git log:\n%s\n\nGit diff:\n%s\n",
-			gitInfo.RecentCommits,
-			gitInfo.StagedDiff,
-		)
+// observeCache reports a cache lookup to the observer, if any
+func (g *CommitMessageGenerator) observeCache(hit bool) {
+	if g.config.Observer != nil {
+		g.config.Observer.CacheLookup(hit)
 	}
+}
 
-	// If no history, include default examples
-	return fmt.Sprintf(
-		"Recent git log:\n%s\n\nGit diff:\n%s\n",
-		getDefaultCommitExamples(),
-		gitInfo.StagedDiff,
-	)
+// rulesFor returns the validation rules for the given format
+func (g *CommitMessageGenerator) rulesFor(isShortCommit bool) *ValidationRules {
+	if g.config.Validation != nil {
+		rules := *g.config.Validation
+		rules.SubjectOnly = rules.SubjectOnly || isShortCommit
+		rules.SemanticRelease = rules.SemanticRelease || g.config.SemanticRelease
+		return &rules
+	}
+
+	if g.config.Convention == ConventionKernel {
+		return kernelValidationRules(isShortCommit, g.config.SeriesLength)
+	}
+
+	rules := DefaultValidationRules(isShortCommit)
+	if preset, ok := platformPresets[g.config.Platform]; ok {
+		rules.MaxSubjectLength = preset.MaxSubjectLength
+		rules.MaxBodyLineLength = preset.MaxBodyLineLength
+	}
+	if g.config.SystemPrompt != "" {
+		// A custom prompt may define its own convention
+		rules.RequireConventional = false
+	}
+	if g.config.SemanticRelease {
+		rules.AllowedTypes = SemanticReleaseTypes
+		rules.SemanticRelease = true
+	}
+	return rules
 }
 
-// getDefaultSystemPrompt returns the default system prompt
-func getDefaultSystemPrompt() string {
-	return `You are a git commit message generator. Analyze the provided git diff and recent git log to create a complete commit message with both subject and body.
+// generateValid generates a message and, while it breaks the validation
+// rules, re-prompts the model with the specific violations
+func (g *CommitMessageGenerator) generateValid(ctx context.Context, call *GenConfig, prompt string, gitInfo *GitInfo) (*Result, error) {
+	rules := allowPinnedType(g.rulesFor(*call.IsShortCommit), call.SubjectPrefix)
+	attempts := g.config.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxAttempts
+	}
 
-Format:
-- Subject line: type(scope): brief description (max 50 chars)
-- Blank line
-- Body: Detailed explanation of WHAT, HOW, and WHY (wrap at 72 chars)
+	var tokens Usage
+	attemptPrompt := prompt
+	// With self-critique, the first valid message is reviewed once; it is
+	// kept if the revision it leads to breaks the rules
+	reviewed := !g.config.SelfCritique
+	var accepted *Result
+	for attempt := 1; ; attempt++ {
+		result, err := g.generate(ctx, call, attemptPrompt)
+		if err != nil {
+			return nil, err
+		}
+		tokens.add(result.Tokens)
+		result.Tokens = tokens
+		result.Attempts = attempt
+		result.Message = applyPins(result.Message, call.SubjectPrefix, call.Scope)
+		if g.config.Convention == ConventionKernel {
+			result.Message = stripPatchPrefix(result.Message)
+		}
+		if g.config.ASCIIOnly {
+			// Models do not reliably follow the instruction, so enforce it
+			result.Message = ParseCommitMessage(ToASCII(result.Message.String()))
+		}
+		if !g.config.NoReflow && result.Message.Body != "" {
+			// The model's own wrapping is unreliable
+			result.Message.Body = Reflow(result.Message.Body, rules.MaxBodyLineLength)
+		}
+		if g.config.SemanticRelease {
+			result.Message = toSemanticRelease(result.Message)
+		}
+		result.Message.NormalizeTrailers()
 
-Rules for Subject:
-1. Use Conventional Commits format: type(scope): description
-2. Common types: feat, fix, refactor, chore, docs, style, test, perf, ci, build
-3. Keep under 50 characters
-4. Use imperative mood (e.g., "add feature" not "added feature")
+		violations := Validate(result.Message, rules)
+		duplicate := ""
+		if len(violations) == 0 && gitInfo != nil {
+			if duplicate = NearDuplicate(result.Message.Header, gitInfo.RecentSubjects); duplicate != "" {
+				violations = []Violation{duplicateViolation(duplicate)}
+			}
+		}
+		onlyClaims := false
+		if len(violations) == 0 && g.config.VerifyClaims {
+			claims, usage, err := g.verifyClaims(ctx, call, prompt, gitInfo, result.Message)
+			if err != nil {
+				return nil, err
+			}
+			tokens.add(usage)
+			result.Tokens = tokens
+			violations, onlyClaims = claims, true
+		}
+		if len(violations) == 0 && reviewed {
+			return result, nil
+		}
+		if len(violations) == 0 {
+			reviewed = true
+			problems, usage, err := g.critique(ctx, call, prompt, result.Message)
+			if err != nil {
+				return nil, err
+			}
+			tokens.add(usage)
+			result.Tokens = tokens
+			if len(problems) == 0 {
+				return result, nil
+			}
+			accepted = result
+			attemptPrompt = revisionPrompt(prompt, result.Message, problems)
+			continue
+		}
+		if attempt >= attempts {
+			if accepted != nil {
+				accepted.Tokens = tokens
+				accepted.Attempts = attempt
+				return accepted, nil
+			}
+			if duplicate != "" {
+				// A repeated subject is a smell of incremental work, not an
+				// invalid message
+				if g.config.DedupeScope && gitInfo.SuggestedScope != "" {
+					result.Message = differentiate(result.Message, gitInfo.SuggestedScope)
+				}
+				if NearDuplicate(result.Message.Header, []string{duplicate}) != "" {
+					result.DuplicateOf = duplicate
+				}
+				return result, nil
+			}
+			if onlyClaims {
+				// Claims are judged, not measured, so flag them instead of failing
+				for _, v := range violations {
+					result.UnsupportedClaims = append(result.UnsupportedClaims, v.Message)
+				}
+				return result, nil
+			}
+			return nil, &ValidationError{Violations: violations, Result: result}
+		}
+		attemptPrompt = feedbackPrompt(prompt, result.Message, violations)
+	}
+}
 
-Rules for Body:
-1. Explain WHAT changed (summary of changes)
-2. Explain HOW it was implemented (approach/method)
-3. Explain WHY it was necessary (motivation/context)
-4. Wrap lines at 72 characters
-5. Use bullet points for multiple changes
-6. Reference issues/tickets if relevant
+// generate runs the provider pipeline for a prepared prompt
+func (g *CommitMessageGenerator) generate(parent context.Context, call *GenConfig, prompt string) (*Result, error) {
+	var result *Result
+	var err error
+	if g.draftProvider != nil {
+		result, err = g.generateTwoTier(parent, call, prompt)
+	} else {
+		result, err = g.generateDirect(parent, call, prompt)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-Example:
-feat(auth): add JWT-based user authentication
+	_, span := tracer.Start(parent, "postprocess")
+	result.Message = ParseCommitMessage(postProcess(result.Message.String()))
+	span.End()
 
-- Implement JWT token generation and validation
-- Add middleware for protecting authenticated routes
-- Create user login/logout endpoints with secure session handling
+	return result, nil
+}
 
-This change enables secure user sessions and replaces the previous
-cookie-based authentication which had security vulnerabilities.
-The new system provides better scalability and follows industry
-best practices for API authentication.
+// newResult builds a Result from a provider response
+func newResult(resp *TextResponse, model string) *Result {
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	return &Result{
+		Message:      ParseCommitMessage(resp.Text),
+		Model:        model,
+		Tokens:       resp.Usage,
+		FinishReason: resp.FinishReason,
+	}
+}
 
-Match the style and tone of recent commits in the git log.
-Output only the commit message, nothing else.`
+// generateDirect sends the full prompt to the provider
+func (g *CommitMessageGenerator) generateDirect(parent context.Context, call *GenConfig, prompt string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(parent, g.config.Timeout)
+	defer cancel()
+
+	// Generate the commit message
+	result, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           call.Model,
+		SystemPrompt:    call.SystemPrompt,
+		Prompt:          prompt,
+		Temperature:     call.Temperature,
+		MaxOutputTokens: g.config.MaxOutputTokens.commitTokens(*call.IsShortCommit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	return newResult(result, call.Model), nil
 }
 
-// getShortCommitPrompt returns the system prompt for short commit messages
-func getShortCommitPrompt() string {
-	return `You are a git commit message generator. Analyze the provided git diff and create a single-line commit message.
+// generateTwoTier drafts the message locally from the full prompt and then
+// asks the cloud model to polish the draft without ever seeing the diff
+func (g *CommitMessageGenerator) generateTwoTier(parent context.Context, call *GenConfig, prompt string) (*Result, error) {
+	draftCtx, cancelDraft := context.WithTimeout(parent, g.config.DraftTimeout)
+	defer cancelDraft()
+
+	draft, err := g.callProvider(draftCtx, g.draftProvider, &TextRequest{
+		Model:           g.config.DraftModel,
+		SystemPrompt:    call.SystemPrompt,
+		Prompt:          prompt,
+		MaxOutputTokens: g.config.MaxOutputTokens.commitTokens(*call.IsShortCommit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to draft commit message locally: %w", err)
+	}
 
-Rules:
-1. Use Conventional Commits format: type(scope): description
-2. Common types: feat, fix, refactor, chore, docs, style, test, perf, ci, build
-3. Keep under 50 characters total
-4. Use imperative mood (e.g., "add feature" not "added feature")
-5. Be concise but descriptive
-6. NO body text, NO explanations, just the subject line
+	ctx, cancel := context.WithTimeout(parent, g.config.Timeout)
+	defer cancel()
 
-Examples:
-feat(auth): add JWT authentication
-fix(db): resolve connection timeout
-refactor(api): simplify error handling
-docs(readme): update installation steps
-test(user): add login validation tests
+	polished, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           call.Model,
+		SystemPrompt:    prompts.Polish(*call.IsShortCommit, g.config.Convention),
+		Prompt:          fmt.Sprintf("Draft commit message:\n%s\n", strings.TrimSpace(draft.Text)),
+		Temperature:     call.Temperature,
+		MaxOutputTokens: g.config.MaxOutputTokens.commitTokens(*call.IsShortCommit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to polish commit message: %w", err)
+	}
 
-Output ONLY the commit subject line, nothing else.`
+	result := newResult(polished, call.Model)
+	result.Tokens.add(draft.Usage)
+	return result, nil
 }
 
-// getDefaultCommitExamples provides example commit messages when no git history exists
-func getDefaultCommitExamples() string {
-	return `Example commit messages for reference:
+// deterministicSeed is the sampling seed of deterministic requests
+const deterministicSeed = 42
 
-feat(auth): add JWT-based user authentication
+// defaultRetryMaxOutputTokens is the output budget used when retrying a
+// truncated response that did not set an explicit limit
+const defaultRetryMaxOutputTokens = 2048
 
-- Implement JWT token generation and validation
-- Add middleware for protecting authenticated routes
-- Create secure login/logout endpoints
+// callProvider sends req and checks the response for blocked, empty, and
+// truncated output. Empty responses are retried once with a higher
+// temperature and truncated ones once with a larger output budget; blocked
+// responses return a *BlockedError since retrying will not help.
+func (g *CommitMessageGenerator) callProvider(ctx context.Context, provider Provider, req *TextRequest) (*TextResponse, error) {
+	if g.config.Disabled {
+		return nil, ErrDisabled
+	}
+	if g.config.ThinkingBudget != 0 && req.ThinkingBudget == nil {
+		thinking := *req
+		thinking.ThinkingBudget = &g.config.ThinkingBudget
+		req = &thinking
+	}
+	if g.config.Deterministic {
+		fixed := *req
+		if fixed.Temperature == nil {
+			fixed.Temperature = new(float32)
+		}
+		seed := int32(deterministicSeed)
+		fixed.Seed = &seed
+		req = &fixed
+	}
+	resp, err := provider.GenerateText(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.BlockReason != "" {
+		return nil, &BlockedError{Reason: resp.BlockReason}
+	}
 
-This enables secure user sessions and improves API security
-by replacing cookie-based auth with industry-standard JWT tokens.
+	empty := strings.TrimSpace(resp.Text) == ""
+	truncated := resp.FinishReason == FinishReasonLength
+	if !empty && !truncated {
+		return resp, nil
+	}
 
-fix(db): resolve connection timeout issues
+	retry := *req
+	if truncated {
+		retry.MaxOutputTokens = max(req.MaxOutputTokens*2, defaultRetryMaxOutputTokens)
+	} else {
+		// Nudge sampling away from whatever produced nothing
+		temperature := float32(1.0)
+		retry.Temperature = &temperature
+	}
 
-- Increase connection pool size from 10 to 50
-- Add retry logic for failed connections
-- Implement connection health checks
+	retried, err := provider.GenerateText(ctx, &retry)
+	if err != nil {
+		return nil, err
+	}
+	if retried.BlockReason != "" {
+		return nil, &BlockedError{Reason: retried.BlockReason}
+	}
+	retried.Usage.add(resp.Usage)
 
-Fixes frequent timeout errors during peak usage periods
-that were causing 500 errors for users.
+	if strings.TrimSpace(retried.Text) == "" {
+		if !empty {
+			// Keep the truncated text rather than nothing at all
+			return resp, nil
+		}
+		return nil, ErrEmptyResponse
+	}
+	return retried, nil
+}
 
-refactor(api): simplify error handling across endpoints
+// Close cleans up resources
+// It is safe to call Close more than once and concurrently with generation
+func (g *CommitMessageGenerator) Close() error {
+	g.closeOnce.Do(func() {
+		g.closed.Store(true)
+		var draftErr error
+		if g.draftProvider != nil {
+			draftErr = g.draftProvider.Close()
+		}
+		g.closeErr = errors.Join(draftErr, g.provider.Close())
+	})
+	return g.closeErr
+}
 
-- Create centralized error handler middleware
-- Standardize error response format
-- Remove duplicate error handling code
+// postProcess cleans up the raw model output
+func postProcess(message string) string {
+	return strings.TrimSpace(message)
+}
+
+// usesGemini reports whether the named provider is the Gemini API
+func usesGemini(provider string) bool {
+	return provider == "" || provider == ProviderGemini
+}
 
-Improves code maintainability and provides consistent
-error messages to frontend clients.`
+// needsAPIKey reports whether the named provider authenticates with an API key
+func needsAPIKey(provider string) bool {
+	return usesGemini(provider) || provider == ProviderAnthropic
+}
+
+// apiKeys resolves the Gemini and Anthropic API keys. Options.APIKey and
+// APIKeyEnv are the key of the primary provider; otherwise each provider
+// reads its own environment variable.
+func apiKeys(opts *Options) (gemini, anthropic string) {
+	key := cmp.Or(opts.APIKey, os.Getenv(APIKeyEnv))
+	if opts.Provider == ProviderAnthropic {
+		return os.Getenv(LegacyAPIKeyEnv), cmp.Or(key, os.Getenv(AnthropicAPIKeyEnv))
+	}
+	return cmp.Or(key, os.Getenv(LegacyAPIKeyEnv)), os.Getenv(AnthropicAPIKeyEnv)
+}
+
+// missingAPIKey returns an error when the primary or fallback provider
+// needs an API key that is not set
+func missingAPIKey(opts *Options) error {
+	gemini, anthropic := apiKeys(opts)
+	providers := []string{opts.Provider}
+	if opts.FallbackProvider != "" {
+		providers = append(providers, opts.FallbackProvider)
+	}
+	for _, provider := range providers {
+		switch {
+		case usesGemini(provider) && gemini == "":
+			return fmt.Errorf("API key not provided in options or the %s environment variable", APIKeyEnv)
+		case provider == ProviderAnthropic && anthropic == "":
+			return fmt.Errorf("API key for anthropic not provided in options or the %s environment variable", AnthropicAPIKeyEnv)
+		}
+	}
+	return nil
+}
+
+// buildPrompt constructs the prompt for the AI
+func (g *CommitMessageGenerator) buildPrompt(gitInfo *GitInfo) string {
+	history := gitInfo.RecentCommits
+	if !gitInfo.HasHistory {
+		history = ""
+	}
+	return prompts.Change{
+		StyleSource:         g.config.StyleSource,
+		Convention:          g.config.Convention,
+		Examples:            g.config.Examples,
+		History:             history,
+		SuggestedScope:      gitInfo.SuggestedScope,
+		Scopes:              g.config.Scopes,
+		Hint:                gitInfo.Hint,
+		Draft:               gitInfo.Draft,
+		Revision:            gitInfo.Revision,
+		Prefix:              gitInfo.Prefix,
+		Notes:               gitInfo.Notes,
+		RelatedFiles:        gitInfo.RelatedFiles,
+		BlameContext:        gitInfo.BlameContext,
+		ConflictedFiles:     gitInfo.ConflictedFiles,
+		ConflictResolutions: gitInfo.ConflictResolutions,
+		Diff:                gitInfo.StagedDiff,
+		ContentOmitted:      gitInfo.ContentOmitted,
+	}.Render()
 }
@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeProvider is a Provider that only records being closed
+type closeProvider struct {
+	err    error
+	closed bool
+}
+
+func (p *closeProvider) Name() string { return "close" }
+
+func (p *closeProvider) GenerateText(context.Context, *TextRequest) (*TextResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *closeProvider) Close() error {
+	p.closed = true
+	return p.err
+}
+
+func TestCloseClosesBothProviders(t *testing.T) {
+	draftErr, providerErr := errors.New("draft"), errors.New("provider")
+	draft := &closeProvider{err: draftErr}
+	provider := &closeProvider{err: providerErr}
+	g := &CommitMessageGenerator{provider: provider, draftProvider: draft}
+
+	err := g.Close()
+	if !draft.closed || !provider.closed {
+		t.Errorf("Close closed the draft provider: %t, the provider: %t; want both", draft.closed, provider.closed)
+	}
+	if !errors.Is(err, draftErr) || !errors.Is(err, providerErr) {
+		t.Errorf("Close() = %v, want both errors", err)
+	}
+	if again := g.Close(); again != err {
+		t.Errorf("second Close() = %v, want %v", again, err)
+	}
+}
+
+// BenchmarkGenerateParallel generates messages concurrently through one
+// generator against a local Ollama stand-in, as the daemon does
+func BenchmarkGenerateParallel(b *testing.B) {
+	var connections atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"model":"bench","response":"fix(core): handle empty input","done_reason":"stop","prompt_eval_count":100,"eval_count":10}`)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	g, err := NewCommitMessageGenerator(&GeneratorConfig{
+		Provider:    ProviderOllama,
+		Model:       "bench",
+		OllamaURL:   server.URL,
+		Timeout:     10 * time.Second,
+		MaxAttempts: 1,
+	}, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer g.Close()
+
+	gitInfo := &GitInfo{StagedDiff: "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package main\n+package main // x\n"}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := g.GenerateWithConfig(context.Background(), gitInfo, nil); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.StopTimer()
+	b.ReportMetric(float64(connections.Load()), "conns")
+}
@@ -1,14 +1,17 @@
 package generator
 
 import (
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // GitRepository represents a git repository and provides methods to extract information
 type GitRepository struct {
 	workingDir string
+	writeGuard
 }
 
 // NewGitRepository creates a new GitRepository instance
@@ -19,6 +22,20 @@ func NewGitRepository(workingDir string) *GitRepository {
 	}
 }
 
+// SetAuditLog records every command that changes the repository in the
+// audit log at path, see Options.AuditLog
+func (g *GitRepository) SetAuditLog(path string) error {
+	log, err := newAuditLog(path, g.workingDir)
+	g.audit = log
+	return err
+}
+
+// CheckWritable returns ErrReadOnly in read-only mode, for callers that
+// change the repository by other means, e.g. by installing a hook
+func (g *GitRepository) CheckWritable(action string) error {
+	return checkWritable(g.readOnly, action)
+}
+
 // GetStagedDiff returns the staged changes in the repository
 func (g *GitRepository) GetStagedDiff() (string, error) {
 	cmd := exec.Command("git", "--no-pager", "diff", "--staged")
@@ -74,25 +91,85 @@ func (g *GitRepository) GetDetailedCommitHistory(count int) (string, error) {
 }
 
 // HasStagedChanges checks if there are any staged changes
+// Only object ids are compared, so no file contents are read or fetched
 func (g *GitRepository) HasStagedChanges() (bool, error) {
-	diff, err := g.GetStagedDiff()
+	_, err := g.run("diff", "--staged", "--quiet")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
 	if err != nil {
-		return false, err
+		return false, fmt.Errorf("failed to check for staged changes: %w", err)
 	}
-
-	return strings.TrimSpace(diff) != "", nil
+	return false, nil
 }
 
 // GitInfo contains all the git information needed for commit message generation
 type GitInfo struct {
-	StagedDiff    string
-	RecentCommits string
-	HasHistory    bool
+	StagedDiff    string `json:"staged_diff"`
+	RecentCommits string `json:"recent_commits,omitempty"`
+	HasHistory    bool   `json:"has_history,omitempty"`
+	// SuggestedScope is the module containing every staged file, if any
+	SuggestedScope string `json:"suggested_scope,omitempty"`
+	// BlameContext lists the commits that last touched the modified lines
+	BlameContext string `json:"blame_context,omitempty"`
+	// RelatedFiles are unstaged files that historically change together
+	// with the staged files
+	RelatedFiles []string `json:"related_files,omitempty"`
+	// Notes is free-text intent the developer left in .git/COMMIT_CONTEXT
+	Notes string `json:"notes,omitempty"`
+	// Hint is the user's own summary of the change, steering the type and
+	// subject while the model writes the details
+	Hint string `json:"hint,omitempty"`
+	// Issue is the number of the issue the change belongs to, e.g. detected
+	// from the branch name
+	Issue string `json:"issue,omitempty"`
+	// Draft is an existing message to revise instead of starting over, and
+	// Revision says how to revise it, e.g. "mention the migration"
+	Draft    string `json:"draft,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// Prefix is the start of the message the author already typed, which
+	// the message must begin with, for completing it as they type
+	Prefix string `json:"prefix,omitempty"`
+	// ContentOmitted means StagedDiff only lists the changed files, because
+	// the contents were unavailable or NoContent was requested
+	ContentOmitted bool `json:"content_omitted,omitempty"`
+	// ConflictedFiles are the files that had conflicts in the merge being
+	// concluded, and ConflictResolutions shows how each was resolved
+	ConflictedFiles     []string `json:"conflicted_files,omitempty"`
+	ConflictResolutions string   `json:"conflict_resolutions,omitempty"`
+	// RecentSubjects are the subjects of the last commits, which the new
+	// subject should not repeat (see Options.DedupeSubjects)
+	RecentSubjects []string `json:"recent_subjects,omitempty"`
+}
+
+// ContextOptions controls what GetCommitContextWithOptions collects
+type ContextOptions struct {
+	// NoHistory skips git log collection entirely
+	NoHistory bool
+	// Blame collects the commits that last touched the modified lines
+	Blame bool
+	// RelatedFiles collects files that historically change with the staged files
+	RelatedFiles bool
+	// NoContent describes the change from the staged file list alone,
+	// without reading file contents (e.g. in partial clones)
+	NoContent bool
+	// NoMerges leaves merge commits out of the history
+	NoMerges bool
 }
 
 // GetCommitContext gathers all necessary git information in one call
 // This is the primary method that consuming applications should use
 func (g *GitRepository) GetCommitContext() (*GitInfo, error) {
+	return g.GetCommitContextWithOptions(nil)
+}
+
+// GetCommitContextWithOptions is GetCommitContext with control over what is collected
+func (g *GitRepository) GetCommitContextWithOptions(opts *ContextOptions) (*GitInfo, error) {
+	if opts == nil {
+		opts = &ContextOptions{}
+	}
+
 	// Check for staged changes first
 	hasStagedChanges, err := g.HasStagedChanges()
 	if err != nil {
@@ -100,30 +177,159 @@ func (g *GitRepository) GetCommitContext() (*GitInfo, error) {
 	}
 
 	if !hasStagedChanges {
-		return nil, fmt.Errorf("no staged changes found")
+		return nil, fmt.Errorf("%w: nothing is staged", ErrNoChanges)
 	}
 
 	// Get staged diff
-	diff, err := g.GetStagedDiff()
-	if err != nil {
-		return nil, err
+	var diff string
+	contentOmitted := opts.NoContent
+	if !opts.NoContent {
+		diff, err = g.GetStagedDiff()
+		// In a partial clone the old side of the diff may be missing and
+		// impossible to fetch (offline, expired credentials); describe the
+		// change from the file list rather than failing
+		if err != nil && g.IsPartialClone() {
+			contentOmitted = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if contentOmitted {
+		if diff, err = g.GetStagedFileSummary(); err != nil {
+			return nil, err
+		}
 	}
 
 	// Get recent commits (try detailed first, fall back to simple)
-	recentCommits, err := g.GetDetailedCommitHistory(10)
-	hasHistory := true
-	if err != nil {
-		// Try simple format as fallback
-		recentCommits, err = g.GetRecentCommits(10)
+	var recentCommits string
+	hasHistory := false
+	if !opts.NoHistory {
+		recentCommits, err = g.GetCappedCommitHistory(10, opts.NoMerges)
+		hasHistory = true
 		if err != nil {
-			hasHistory = false
-			recentCommits = ""
+			// Try simple format as fallback
+			recentCommits, err = g.GetRecentCommits(10)
+			if err != nil {
+				hasHistory = false
+				recentCommits = ""
+			}
 		}
 	}
 
+	// Blame context is a hint only, e.g. there is nothing to blame before
+	// the first commit, so failures leave it empty
+	var blameContext string
+	if opts.Blame && !contentOmitted {
+		blameContext, _ = g.GetBlameContext(diff)
+	}
+
+	// The issue link is optional too, a detached HEAD simply has none
+	branch, _ := g.CurrentBranch()
+
+	// Notes are optional context, a broken notes file must not block generation
+	notes, _ := g.ReadNotes()
+
+	var relatedFiles []string
+	if opts.RelatedFiles {
+		relatedFiles = g.relatedFiles()
+	}
+
+	// Conflicts are context too, so failures leave them out
+	conflicted, _ := g.ConflictedFiles()
+	var resolutions string
+	if len(conflicted) > 0 && !contentOmitted {
+		resolutions = g.GetConflictResolutions(conflicted)
+	}
+
 	return &GitInfo{
-		StagedDiff:    diff,
-		RecentCommits: recentCommits,
-		HasHistory:    hasHistory,
+		StagedDiff:     diff,
+		RecentCommits:  recentCommits,
+		HasHistory:     hasHistory,
+		SuggestedScope: g.inferScope(),
+		BlameContext:   blameContext,
+		RelatedFiles:   relatedFiles,
+		Notes:          notes,
+		Issue:          IssueFromBranch(branch),
+		ContentOmitted: contentOmitted,
+
+		ConflictedFiles:     conflicted,
+		ConflictResolutions: resolutions,
 	}, nil
 }
+
+// inferScope suggests a scope from the module boundaries of the staged files
+// Scope inference is a hint only, so failures yield no suggestion
+func (g *GitRepository) inferScope() string {
+	files, err := g.GetStagedFiles()
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+
+	modules, err := g.GetModuleMap()
+	if err != nil {
+		return ""
+	}
+	return modules.InferScope(files)
+}
+
+// relatedFiles lists the paths that historically change with the staged files
+// Like the scope, related files are a hint only, so failures yield none
+func (g *GitRepository) relatedFiles() []string {
+	files, err := g.GetStagedFiles()
+	if err != nil || len(files) == 0 {
+		return nil
+	}
+
+	related, err := g.GetRelatedFiles(files)
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, len(related))
+	for i, file := range related {
+		paths[i] = file.Path
+	}
+	return paths
+}
+
+// Commit is a single commit read from the repository history
+type Commit struct {
+	Hash        string    `json:"hash"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	Date        time.Time `json:"date"`
+	Message     string    `json:"message"`
+}
+
+// commitFormat separates fields with US and records with RS so that any
+// commit message can be parsed unambiguously
+const commitFormat = "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%B%x1e"
+
+// GetCommits returns the commits selected by the given git log arguments,
+// e.g. GetCommits("-50") or GetCommits("main..HEAD")
+func (g *GitRepository) GetCommits(args ...string) ([]Commit, error) {
+	output, err := g.run(append([]string{"log", commitFormat}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", err)
+	}
+	return parseCommits(output), nil
+}
+
+// parseCommits parses the output of git log with commitFormat
+func parseCommits(output string) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimLeft(record, "\n"), "\x1f", 5)
+		if len(fields) < 5 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[3])
+		commits = append(commits, Commit{
+			Hash:        fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Date:        date,
+			Message:     strings.TrimSpace(fields[4]),
+		})
+	}
+	return commits
+}
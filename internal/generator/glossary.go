@@ -0,0 +1,89 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// DefaultScopesFile is the scope glossary looked for in the repository
+// root when none is configured
+const DefaultScopesFile = "scopes.yaml"
+
+// ScopeDefinition is a scope and the part of the codebase it stands for,
+// e.g. "parser" for "the SQL parsing layer"
+type ScopeDefinition = prompts.ScopeDefinition
+
+// LoadScopeGlossary reads a YAML file mapping each scope to its
+// description, keeping the order of the file:
+//
+//	parser: the SQL parsing layer
+//	planner: query planning and cost estimation
+func LoadScopeGlossary(path string) ([]ScopeDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scope glossary: %w", err)
+	}
+	return parseScopeGlossary(data, path)
+}
+
+// parseScopeGlossary decodes a scope glossary; name is for errors only
+func parseScopeGlossary(data []byte, name string) ([]ScopeDefinition, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	// A mapping node keeps the order of its keys, unlike a Go map
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping of scope to description", name)
+	}
+
+	var scopes []ScopeDefinition
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Kind != yaml.ScalarNode || value.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("%s:%d: the description of a scope must be a string", name, key.Line)
+		}
+		scope := strings.TrimSpace(key.Value)
+		if scope == "" || strings.ContainsAny(scope, "()\n") {
+			return nil, fmt.Errorf("%s:%d: %q cannot be a scope", name, key.Line, key.Value)
+		}
+		if seen[scope] {
+			return nil, fmt.Errorf("%s:%d: scope %q is defined twice", name, key.Line, scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, ScopeDefinition{Name: scope, Description: strings.Join(strings.Fields(value.Value), " ")})
+	}
+	return scopes, nil
+}
+
+// scopeGlossary loads the configured scope glossary, or else the
+// repository's DefaultScopesFile when there is one
+func (g *GitRepository) scopeGlossary(path string) ([]ScopeDefinition, error) {
+	if path != "" {
+		return LoadScopeGlossary(path)
+	}
+
+	root, err := g.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		// Outside a repository there is no default glossary to find
+		return nil, nil
+	}
+	scopes, err := LoadScopeGlossary(filepath.Join(strings.TrimSpace(root), DefaultScopesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return scopes, err
+}
@@ -0,0 +1,188 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// Citation points a statement of a message at the part of the diff it
+// describes: a hunk of File, or the whole file when it has no hunks, e.g.
+// binary files or a change described from file names only
+type Citation struct {
+	File string `json:"file"`
+	Hunk *Hunk  `json:"hunk,omitempty"`
+}
+
+// GroundedStatement is a body statement with the diff it describes
+type GroundedStatement struct {
+	Text      string     `json:"text"`
+	Citations []Citation `json:"citations"`
+}
+
+// Grounding maps the body of a grounded message to the diff
+type Grounding struct {
+	// Statements are the statements kept in the body, in order
+	Statements []GroundedStatement `json:"statements"`
+	// Dropped are the statements removed because nothing in the diff
+	// backs them
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+// bodyStatement is a bullet, with its continuation lines, or a paragraph
+type bodyStatement struct {
+	text   string
+	bullet bool
+}
+
+// splitStatements splits a body into bullets and paragraphs
+func splitStatements(body string) []bodyStatement {
+	var statements []bodyStatement
+	for _, paragraph := range strings.Split(body, "\n\n") {
+		lines := strings.Split(strings.Trim(paragraph, "\n"), "\n")
+		if !isBullet(lines[0]) {
+			if text := strings.TrimSpace(paragraph); text != "" {
+				statements = append(statements, bodyStatement{text: text})
+			}
+			continue
+		}
+		for _, line := range lines {
+			if isBullet(line) || len(statements) == 0 || !statements[len(statements)-1].bullet {
+				statements = append(statements, bodyStatement{text: line, bullet: isBullet(line)})
+				continue
+			}
+			statements[len(statements)-1].text += "\n" + line
+		}
+	}
+	return statements
+}
+
+// isBullet reports whether line starts a list item
+func isBullet(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+// joinStatements renders statements as a body: bullets one per line,
+// paragraphs separated by blank lines
+func joinStatements(statements []bodyStatement) string {
+	var b strings.Builder
+	for i, statement := range statements {
+		if i > 0 {
+			if statement.bullet && statements[i-1].bullet {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(statement.text)
+	}
+	return b.String()
+}
+
+// diffCitations lists every hunk of the change as a Citation, in diff order
+func diffCitations(gitInfo *GitInfo) []Citation {
+	var citations []Citation
+	for _, file := range changedFiles(gitInfo) {
+		if len(file.Hunks) == 0 {
+			citations = append(citations, Citation{File: file.Path()})
+			continue
+		}
+		for _, hunk := range file.Hunks {
+			citations = append(citations, Citation{File: file.Path(), Hunk: &hunk})
+		}
+	}
+	return citations
+}
+
+// ground asks the model which hunks each body statement of result
+// describes, then drops the statements nothing backs. Trailers are kept
+// as they are. In two-tier mode the local draft model grounds, so the
+// diff stays local.
+func (g *CommitMessageGenerator) ground(parent context.Context, call *GenConfig, gitInfo *GitInfo, result *Result) error {
+	body, trailers := splitTrailers(result.Message.Body)
+	statements := splitStatements(body)
+	grounding := &Grounding{Statements: []GroundedStatement{}}
+	result.Grounding = grounding
+	if len(statements) == 0 {
+		return nil
+	}
+	citations := diffCitations(gitInfo)
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Git diff:\n%s\n\nHunks:\n", gitInfo.StagedDiff)
+	for i, citation := range citations {
+		if citation.Hunk == nil {
+			fmt.Fprintf(&prompt, "[%d] %s (whole file)\n", i+1, citation.File)
+			continue
+		}
+		h := citation.Hunk
+		fmt.Fprintf(&prompt, "[%d] %s @@ -%d,%d +%d,%d @@\n", i+1, citation.File, h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+	}
+	prompt.WriteString("\nStatements:\n")
+	for i, statement := range statements {
+		fmt.Fprintf(&prompt, "[%d] %s\n", i+1, statement.text)
+	}
+
+	provider, model := g.provider, call.Model
+	if g.draftProvider != nil {
+		provider, model = g.draftProvider, g.config.DraftModel
+	}
+	ctx, cancel := context.WithTimeout(parent, g.config.Timeout)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "ground")
+	defer span.End()
+
+	resp, err := g.callProvider(ctx, provider, &TextRequest{
+		Model:           model,
+		SystemPrompt:    prompts.Template(prompts.Grounding),
+		Prompt:          prompt.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Full,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ground commit message: %w", err)
+	}
+	result.Tokens.add(resp.Usage)
+
+	var mapping struct {
+		Statements []struct {
+			Statement int   `json:"statement"`
+			Hunks     []int `json:"hunks"`
+		} `json:"statements"`
+	}
+	if err := decodeJSONResponse(resp.Text, &mapping); err != nil {
+		return fmt.Errorf("failed to ground commit message: %w", err)
+	}
+
+	// Only citations of hunks that exist count
+	cited := make([][]Citation, len(statements))
+	for _, m := range mapping.Statements {
+		if m.Statement < 1 || m.Statement > len(statements) {
+			continue
+		}
+		for _, n := range m.Hunks {
+			if n >= 1 && n <= len(citations) {
+				cited[m.Statement-1] = append(cited[m.Statement-1], citations[n-1])
+			}
+		}
+	}
+
+	var kept []bodyStatement
+	for i, statement := range statements {
+		if len(cited[i]) == 0 {
+			grounding.Dropped = append(grounding.Dropped, statement.text)
+			continue
+		}
+		kept = append(kept, statement)
+		grounding.Statements = append(grounding.Statements, GroundedStatement{Text: statement.text, Citations: cited[i]})
+	}
+
+	message := result.Message
+	message.Body = joinStatements(kept)
+	for _, trailer := range trailers {
+		message.AddTrailer(trailer.Key, trailer.Value)
+	}
+	result.Message = message
+	return nil
+}
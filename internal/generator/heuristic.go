@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// HeuristicModel is the Result.Model of messages written without a model
+const HeuristicModel = "heuristic"
+
+// maxHeuristicFiles caps the file list in the body of a heuristic message
+const maxHeuristicFiles = 20
+
+// buildFiles are the names of build and dependency manifests
+var buildFiles = []string{
+	"go.mod", "go.sum", "Makefile", "Dockerfile", "package.json",
+	"package-lock.json", "Cargo.toml", "Cargo.lock", "pyproject.toml",
+}
+
+// HeuristicMessage describes a change from its file list alone, for when
+// the code must not be sent to a model. The type is guessed from the kind
+// of files, the scope is the suggested scope, and the hint, if any, is used
+// as the subject.
+func HeuristicMessage(info *GitInfo, isShortCommit bool) CommitMessage {
+	files := changedFiles(info)
+	subject := info.Hint
+	if subject == "" {
+		subject = heuristicSubject(files)
+	}
+
+	message := SubjectPrefix(heuristicType(files), info.SuggestedScope) + subject
+	if !isShortCommit && len(files) > 1 {
+		var body strings.Builder
+		body.WriteString("Changed files:\n")
+		for i, file := range files {
+			if i == maxHeuristicFiles {
+				fmt.Fprintf(&body, "- and %d more\n", len(files)-i)
+				break
+			}
+			fmt.Fprintf(&body, "- %s\n", file.Path())
+		}
+		message += "\n\n" + body.String()
+	}
+	return ParseCommitMessage(message)
+}
+
+// heuristicType picks the commit type shared by every file, falling back
+// to feat for pure additions and chore otherwise
+func heuristicType(files []DiffFile) string {
+	kinds := []struct {
+		commitType string
+		match      func(string) bool
+	}{
+		{"docs", isDocFile},
+		{"test", isTestFile},
+		{"ci", func(p string) bool { return strings.HasPrefix(p, ".github/") || p == ".gitlab-ci.yml" }},
+		{"build", func(p string) bool { return slices.Contains(buildFiles, path.Base(p)) }},
+	}
+	for _, kind := range kinds {
+		if len(files) > 0 && allFiles(files, func(f DiffFile) bool { return kind.match(f.Path()) }) {
+			return kind.commitType
+		}
+	}
+	if len(files) > 0 && allFiles(files, func(f DiffFile) bool { return f.OldPath == "" }) {
+		return "feat"
+	}
+	return "chore"
+}
+
+// heuristicSubject summarizes what happened to the files, e.g.
+// "update key.go" or "remove 3 files in internal/crypto"
+func heuristicSubject(files []DiffFile) string {
+	verb := "update"
+	switch {
+	case len(files) == 0:
+		return "update files"
+	case allFiles(files, func(f DiffFile) bool { return f.OldPath == "" }):
+		verb = "add"
+	case allFiles(files, func(f DiffFile) bool { return f.NewPath == "" }):
+		verb = "remove"
+	}
+	if len(files) == 1 {
+		return verb + " " + path.Base(files[0].Path())
+	}
+
+	dir := path.Dir(files[0].Path())
+	for _, file := range files[1:] {
+		for dir != "." && !strings.HasPrefix(file.Path(), dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	if dir == "." {
+		return fmt.Sprintf("%s %d files", verb, len(files))
+	}
+	return fmt.Sprintf("%s %d files in %s", verb, len(files), dir)
+}
+
+func isDocFile(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".md" || ext == ".rst" || ext == ".adoc" || strings.HasPrefix(p, "docs/") ||
+		strings.HasPrefix(strings.ToUpper(path.Base(p)), "LICENSE")
+}
+
+func isTestFile(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") || strings.Contains(base, ".test.") ||
+		strings.Contains(base, ".spec.") || strings.HasPrefix(base, "test_") ||
+		strings.HasPrefix(p, "test/") || strings.HasPrefix(p, "tests/") || strings.Contains(p, "/testdata/")
+}
+
+func allFiles(files []DiffFile, match func(DiffFile) bool) bool {
+	for _, file := range files {
+		if !match(file) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MercurialRepository is an hg repository; the change being described is
+// the working directory, as hg has no staging area
+type MercurialRepository struct {
+	workingDir string
+}
+
+// NewMercurialRepository creates a MercurialRepository for workingDir
+// If workingDir is empty, it uses the current directory
+func NewMercurialRepository(workingDir string) *MercurialRepository {
+	return &MercurialRepository{workingDir: workingDir}
+}
+
+// run executes an hg command in the working directory and returns its stdout
+func (h *MercurialRepository) run(args ...string) (string, error) {
+	return runIn(h.workingDir, "hg", append([]string{"--pager=never", "--color=never"}, args...)...)
+}
+
+// Name implements VCS
+func (h *MercurialRepository) Name() string {
+	return VCSMercurial
+}
+
+// GetDiff returns the uncommitted changes in git format
+func (h *MercurialRepository) GetDiff() (string, error) {
+	output, err := h.run("diff", "--git")
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory diff: %w", err)
+	}
+	return output, nil
+}
+
+// HasChanges implements VCS
+func (h *MercurialRepository) HasChanges() (bool, error) {
+	diff, err := h.GetDiff()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(diff) != "", nil
+}
+
+// GetRecentDescriptions returns the descriptions of the last count changesets
+func (h *MercurialRepository) GetRecentDescriptions(count int) (string, error) {
+	output, err := h.run("log", "--limit", fmt.Sprint(count), "--template", "{desc}\n\n")
+	if err != nil {
+		return "", fmt.Errorf("failed to get recent descriptions: %w", err)
+	}
+	return output, nil
+}
+
+// ChangeContext implements VCS
+func (h *MercurialRepository) ChangeContext(opts *ContextOptions) (*GitInfo, error) {
+	if opts == nil {
+		opts = &ContextOptions{}
+	}
+
+	diff, err := h.GetDiff()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, fmt.Errorf("%w in the working directory", ErrNoChanges)
+	}
+
+	info := &GitInfo{StagedDiff: diff}
+	if !opts.NoHistory {
+		if history, err := h.GetRecentDescriptions(10); err == nil && strings.TrimSpace(history) != "" {
+			info.RecentCommits = history
+			info.HasHistory = true
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxHistoryMessageBytes caps each commit message added to the prompt;
+	// a single huge message (changelogs, generated text) would otherwise
+	// dominate the context
+	maxHistoryMessageBytes = 2048
+	// maxHistoryBytes caps the whole history added to the prompt
+	maxHistoryBytes = 12 * 1024
+)
+
+// GetCappedCommitHistory returns up to count recent commits formatted like
+// git log, with long messages truncated and the total size capped. With
+// noMerges, merge commits are skipped.
+func (g *GitRepository) GetCappedCommitHistory(count int, noMerges bool) (string, error) {
+	args := []string{fmt.Sprintf("-%d", count)}
+	if noMerges {
+		args = append(args, "--no-merges")
+	}
+	commits, err := g.GetCommits(args...)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return "", fmt.Errorf("no git history found")
+	}
+
+	var b strings.Builder
+	for _, commit := range commits {
+		entry := formatHistoryEntry(commit)
+		// Always keep at least one commit to imitate
+		if b.Len() > 0 && b.Len()+len(entry) > maxHistoryBytes {
+			break
+		}
+		b.WriteString(entry)
+	}
+	return b.String(), nil
+}
+
+// formatHistoryEntry renders a commit the way git log does, truncating
+// messages longer than maxHistoryMessageBytes
+func formatHistoryEntry(commit Commit) string {
+	message := truncateBytes(commit.Message, maxHistoryMessageBytes)
+	if len(message) < len(commit.Message) {
+		message += "\n[...]"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "commit %s\nAuthor: %s <%s>\nDate:   %s\n\n", commit.Hash, commit.AuthorName, commit.AuthorEmail, commit.Date.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	for _, line := range strings.Split(message, "\n") {
+		b.WriteString("    ")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// truncateBytes shortens s to at most n bytes without splitting a UTF-8 character
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
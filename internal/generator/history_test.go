@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkCommits is the length of the history of benchmarkRepository
+const benchmarkCommits = 2000
+
+// benchmarkRepository creates a repository with a long history, in which
+// every tenth message is a huge changelog and every twentieth commit is a
+// merge, and with a staged change
+func benchmarkRepository(b *testing.B) *GitRepository {
+	b.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		b.Skip("git is not installed")
+	}
+	dir := b.TempDir()
+	git := func(stdin string, args ...string) {
+		b.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Stdin = strings.NewReader(stdin)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			b.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+		}
+	}
+
+	var stream strings.Builder
+	for i := 1; i <= benchmarkCommits; i++ {
+		message := fmt.Sprintf("feat(core): change %d\n\nDescribe change %d.\n", i, i)
+		if i%10 == 0 {
+			message += strings.Repeat("- a changelog line of a generated release\n", 1500)
+		}
+		content := fmt.Sprintf("package main\n\nconst version = %d\n", i)
+		fmt.Fprintf(&stream, "commit refs/heads/main\nmark :%d\ncommitter A <a@example.com> %d +0000\n", i, 1700000000+i)
+		fmt.Fprintf(&stream, "data %d\n%s\n", len(message), message)
+		if i > 1 {
+			fmt.Fprintf(&stream, "from :%d\n", i-1)
+		}
+		if i%20 == 0 {
+			fmt.Fprintf(&stream, "merge :%d\n", i-10)
+		}
+		fmt.Fprintf(&stream, "M 644 inline main.go\ndata %d\n%s\n", len(content), content)
+	}
+
+	git("", "init", "-q")
+	git(stream.String(), "fast-import", "--quiet")
+	git("", "symbolic-ref", "HEAD", "refs/heads/main")
+	git("", "reset", "-q", "--hard")
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nconst version = 0\n"), 0o644); err != nil {
+		b.Fatal(err)
+	}
+	git("", "add", "main.go")
+	return NewGitRepository(dir)
+}
+
+func BenchmarkGetCappedCommitHistory(b *testing.B) {
+	repo := benchmarkRepository(b)
+	for _, noMerges := range []bool{false, true} {
+		b.Run(fmt.Sprintf("noMerges=%t", noMerges), func(b *testing.B) {
+			for b.Loop() {
+				history, err := repo.GetCappedCommitHistory(50, noMerges)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(history) > maxHistoryBytes+maxHistoryMessageBytes+1024 {
+					b.Fatalf("history is %d bytes", len(history))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetCommitContext(b *testing.B) {
+	repo := benchmarkRepository(b)
+	benchmarks := []struct {
+		name string
+		opts ContextOptions
+	}{
+		{"default", ContextOptions{}},
+		{"noMerges", ContextOptions{NoMerges: true}},
+		{"noHistory", ContextOptions{NoHistory: true}},
+	}
+	for _, bb := range benchmarks {
+		b.Run(bb.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := repo.GetCommitContextWithOptions(&bb.opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFormatHistoryEntry(b *testing.B) {
+	commit := Commit{
+		Hash:        strings.Repeat("a", 40),
+		AuthorName:  "A",
+		AuthorEmail: "a@example.com",
+		Date:        time.Unix(1700000000, 0).UTC(),
+		Message:     "feat(core): release\n\n" + strings.Repeat("- a changelog line of a generated release\n", 1500),
+	}
+	for b.Loop() {
+		formatHistoryEntry(commit)
+	}
+}
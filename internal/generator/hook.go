@@ -0,0 +1,21 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultHookTimeout is how long the prepare-commit-msg hook waits for a
+// suggestion before letting the commit go ahead without one
+const DefaultHookTimeout = 3 * time.Second
+
+// HookPath returns the location of the named git hook, honoring
+// core.hooksPath
+func (g *GitRepository) HookPath(name string) (string, error) {
+	path, err := g.run("rev-parse", "--path-format=absolute", "--git-path", "hooks/"+name)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	return strings.TrimSpace(path), nil
+}
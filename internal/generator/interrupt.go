@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// InterruptedError is returned when generation is cancelled, e.g. by
+// Ctrl-C. It records what had reached the provider by then, so the user
+// knows whether tokens were billed, and the last answer received.
+type InterruptedError struct {
+	// Sent and Answered count the provider requests made and completed
+	Sent, Answered int
+	// Billable is set when a request went to a hosted provider rather than
+	// the local Ollama server
+	Billable bool
+	// Tokens adds up the usage of the answered requests
+	Tokens Usage
+	// Partial is the text of the last answer, e.g. a first attempt being
+	// retried or a draft waiting to be polished; empty if none arrived
+	Partial string
+
+	err error
+}
+
+func (e *InterruptedError) Error() string {
+	var b strings.Builder
+	b.WriteString("interrupted")
+	switch {
+	case e.Sent == 0:
+		b.WriteString(" before any request was sent, nothing was billed")
+	case !e.Billable:
+		b.WriteString(", only the local Ollama server was called, nothing was billed")
+	default:
+		fmt.Fprintf(&b, ", %d of %d provider requests answered", e.Answered, e.Sent)
+		if e.Answered > 0 {
+			fmt.Fprintf(&b, " (%d tokens billed)", e.Tokens.TotalTokens)
+		}
+		if e.Sent > e.Answered {
+			b.WriteString("; the cancelled request may still be billed for its prompt")
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the cancellation cause, usually context.Canceled
+func (e *InterruptedError) Unwrap() error {
+	return e.err
+}
+
+// meter counts the provider requests of one generation
+type meter struct {
+	mu         sync.Mutex
+	sent       int
+	answered   int
+	billable   bool
+	tokens     Usage
+	lastAnswer string
+}
+
+type meterKey struct{}
+
+// withMeter returns ctx carrying a fresh meter, unless it already has one
+// from an enclosing generation
+func withMeter(ctx context.Context) (context.Context, *meter) {
+	if m, ok := ctx.Value(meterKey{}).(*meter); ok {
+		return ctx, m
+	}
+	m := &meter{}
+	return context.WithValue(ctx, meterKey{}, m), m
+}
+
+// interrupted turns err into an InterruptedError when ctx was cancelled
+func (m *meter) interrupted(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return &InterruptedError{
+		Sent:     m.sent,
+		Answered: m.answered,
+		Billable: m.billable,
+		Tokens:   m.tokens,
+		Partial:  m.lastAnswer,
+		err:      context.Cause(ctx),
+	}
+}
+
+// meteredProvider reports every call of the wrapped provider to the meter
+// of the request's context
+type meteredProvider struct {
+	Provider
+}
+
+// GenerateText forwards the request and counts it
+func (p *meteredProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	m, _ := ctx.Value(meterKey{}).(*meter)
+	if m == nil {
+		return p.Provider.GenerateText(ctx, req)
+	}
+
+	m.mu.Lock()
+	m.sent++
+	m.billable = m.billable || p.Provider.Name() != ProviderOllama
+	m.mu.Unlock()
+
+	resp, err := p.Provider.GenerateText(ctx, req)
+	if resp != nil {
+		m.mu.Lock()
+		m.answered++
+		m.tokens.add(resp.Usage)
+		if resp.Text != "" {
+			m.lastAnswer = resp.Text
+		}
+		m.mu.Unlock()
+	}
+	return resp, err
+}
+
+// Embed forwards the request
+func (p *meteredProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	return embed(ctx, p.Provider, req)
+}
+
+// Ping forwards health checks when the wrapped provider supports them
+func (p *meteredProvider) Ping(ctx context.Context, model string) error {
+	if checker, ok := p.Provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// newInterruptingCommitGen returns a CommitGen for a repository with a
+// staged change, whose Ollama server cancels the returned context as soon
+// as a request arrives, like Ctrl-C while waiting for the model
+func newInterruptingCommitGen(t *testing.T) (*CommitGen, context.Context) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for _, args := range [][]string{{"init", "-q"}, {"add", "main.go"}} {
+		if args[0] == "add" {
+			if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client hanging up once the body is read
+		io.Copy(io.Discard, r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(cancel)
+
+	commitGen, err := New(&Options{
+		Provider:   ProviderOllama,
+		Model:      "llama3",
+		OllamaURL:  server.URL,
+		WorkingDir: dir,
+		NoHistory:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { commitGen.Close() })
+	return commitGen, ctx
+}
+
+func TestCandidatesInterrupted(t *testing.T) {
+	commitGen, ctx := newInterruptingCommitGen(t)
+	_, err := commitGen.Candidates(ctx, 2)
+	var interrupted *InterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Sent == 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("Candidates() error = %v, want an InterruptedError after a request was sent", err)
+	}
+}
+
+func TestGenerateFromDiffContextInterrupted(t *testing.T) {
+	commitGen, ctx := newInterruptingCommitGen(t)
+	_, err := commitGen.GenerateFromDiffContext(ctx, "diff --git a/main.go b/main.go\n+package main\n", "")
+	var interrupted *InterruptedError
+	if !errors.As(err, &interrupted) || interrupted.Sent != 1 || !errors.Is(err, context.Canceled) {
+		t.Errorf("GenerateFromDiffContext() error = %v, want an InterruptedError after one request", err)
+	}
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Positions accepted by Options.IssuePosition
+const (
+	// IssueFooter adds the closing keyword as a trailer, e.g. "Fixes: #123"
+	IssueFooter = "footer"
+	// IssueBody adds the closing keyword as the last body paragraph, e.g. "Fixes #123"
+	IssueBody = "body"
+)
+
+// DefaultIssueKeyword closes issues on both GitHub and GitLab
+const DefaultIssueKeyword = "Fixes"
+
+// branchIssuePatterns find an issue number in a branch name, e.g.
+// "123-fix-login", "fix/123-login", "issue-123", "gh-123", or "bug/#123"
+var branchIssuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(?:^|[/_-])(?:issue|issues|gh|bug)[-_]?#?(\d+)(?:[/_-]|$)`),
+	regexp.MustCompile(`(?:^|/)#?(\d+)(?:[_-]|$)`),
+}
+
+// CurrentBranch returns the checked out branch name, or "" on a detached HEAD
+func (g *GitRepository) CurrentBranch() (string, error) {
+	output, err := g.run("branch", "--show-current")
+	if err != nil {
+		return "", fmt.Errorf("failed to read current branch: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// IssueFromBranch returns the issue number a branch name refers to, if any
+func IssueFromBranch(branch string) string {
+	for _, pattern := range branchIssuePatterns {
+		if m := pattern.FindStringSubmatch(branch); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// IssueOptions controls the auto-close keyword added to fix commits
+type IssueOptions struct {
+	// Keyword is the closing keyword (empty uses DefaultIssueKeyword)
+	Keyword string
+	// Position is IssueFooter (default) or IssueBody
+	Position string
+}
+
+// linkIssue adds the closing keyword for issue to fix commits that do not
+// reference it yet
+func linkIssue(msg CommitMessage, issue string, opts *IssueOptions) CommitMessage {
+	if opts == nil || issue == "" || msg.Type != "fix" {
+		return msg
+	}
+	ref := "#" + strings.TrimPrefix(issue, "#")
+	if strings.Contains(msg.Body, ref) {
+		return msg
+	}
+
+	keyword := opts.Keyword
+	if keyword == "" {
+		keyword = DefaultIssueKeyword
+	}
+
+	if opts.Position == IssueBody {
+		text, trailers := splitTrailers(msg.Body)
+		if text == "" {
+			text = keyword + " " + ref
+		} else {
+			text += "\n\n" + keyword + " " + ref
+		}
+		msg.Body = joinTrailers(text, trailers)
+		return msg
+	}
+
+	msg.AddTrailer(keyword, ref)
+	msg.NormalizeTrailers()
+	return msg
+}
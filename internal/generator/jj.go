@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JujutsuRepository is a jj repository; the change being described is the
+// working copy commit (@), as jj has no staging area
+type JujutsuRepository struct {
+	workingDir string
+	writeGuard
+}
+
+// NewJujutsuRepository creates a JujutsuRepository for workingDir
+// If workingDir is empty, it uses the current directory
+func NewJujutsuRepository(workingDir string) *JujutsuRepository {
+	return &JujutsuRepository{workingDir: workingDir}
+}
+
+// SetAuditLog records every command that changes the repository in the
+// audit log at path, see Options.AuditLog
+func (j *JujutsuRepository) SetAuditLog(path string) error {
+	log, err := newAuditLog(path, j.workingDir)
+	j.audit = log
+	return err
+}
+
+// run executes a jj command in the working directory and returns its stdout
+func (j *JujutsuRepository) run(args ...string) (string, error) {
+	return runIn(j.workingDir, "jj", append([]string{"--no-pager", "--color=never"}, args...)...)
+}
+
+// Name implements VCS
+func (j *JujutsuRepository) Name() string {
+	return VCSJujutsu
+}
+
+// GetDiff returns the changes of the working copy commit in git format
+func (j *JujutsuRepository) GetDiff() (string, error) {
+	output, err := j.run("diff", "--git", "-r", "@")
+	if err != nil {
+		return "", fmt.Errorf("failed to get working copy diff: %w", err)
+	}
+	return output, nil
+}
+
+// HasChanges implements VCS
+func (j *JujutsuRepository) HasChanges() (bool, error) {
+	diff, err := j.GetDiff()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(diff) != "", nil
+}
+
+// GetRecentDescriptions returns the descriptions of the count changes before @
+func (j *JujutsuRepository) GetRecentDescriptions(count int) (string, error) {
+	output, err := j.run("log", "--no-graph", "-r", "::@-", "--limit", fmt.Sprint(count),
+		"-T", `if(description, description ++ "\n")`)
+	if err != nil {
+		return "", fmt.Errorf("failed to get recent descriptions: %w", err)
+	}
+	return output, nil
+}
+
+// Describe sets the description of the working copy change
+func (j *JujutsuRepository) Describe(message string) error {
+	if err := j.DescribeCommand(message).run(j.workingDir, j.writeGuard, "describe the change"); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return err
+		}
+		return fmt.Errorf("failed to describe change: %w", err)
+	}
+	return nil
+}
+
+// DescribeCommand returns the command Describe runs
+func (j *JujutsuRepository) DescribeCommand(message string) Command {
+	return Command{Args: []string{"jj", "describe", "--stdin", "-r", "@"}, Stdin: message}
+}
+
+// ChangeContext implements VCS
+func (j *JujutsuRepository) ChangeContext(opts *ContextOptions) (*GitInfo, error) {
+	if opts == nil {
+		opts = &ContextOptions{}
+	}
+
+	diff, err := j.GetDiff()
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(diff) == "" {
+		return nil, fmt.Errorf("%w: the working copy change is empty", ErrNoChanges)
+	}
+
+	info := &GitInfo{StagedDiff: diff}
+	if !opts.NoHistory {
+		// History is optional, e.g. a fresh repository has none
+		if history, err := j.GetRecentDescriptions(10); err == nil && strings.TrimSpace(history) != "" {
+			info.RecentCommits = history
+			info.HasHistory = true
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// Conventions accepted by Options.Convention
+const (
+	// ConventionConventional is Conventional Commits, "type(scope): subject"
+	ConventionConventional = prompts.ConventionConventional
+	// ConventionKernel is the Linux kernel / mailing list style,
+	// "subsystem: summary", for git send-email patch series
+	ConventionKernel = prompts.ConventionKernel
+)
+
+// kernelSubjectLimit is the kernel's recommended subject length including
+// the "[PATCH n/m] " prefix git format-patch adds
+const kernelSubjectLimit = 75
+
+// patchPrefixPattern matches a "[PATCH]" or "[PATCH v2 3/7]" style prefix
+var patchPrefixPattern = regexp.MustCompile(`^\[(?:RFC )?PATCH[^\]]*\]\s*`)
+
+// patchPrefixLength is the length of the "[PATCH n/m] " prefix
+// git format-patch adds for a series of seriesLength patches
+func patchPrefixLength(seriesLength int) int {
+	if seriesLength <= 1 {
+		return len("[PATCH] ")
+	}
+	digits := len(fmt.Sprint(seriesLength))
+	return len("[PATCH /] ") + 2*digits
+}
+
+// kernelValidationRules returns the rules of the kernel convention
+func kernelValidationRules(isShortCommit bool, seriesLength int) *ValidationRules {
+	return &ValidationRules{
+		MaxSubjectLength:  kernelSubjectLimit - patchPrefixLength(seriesLength),
+		MaxBodyLineLength: 72,
+		SubjectOnly:       isShortCommit,
+	}
+}
+
+// stripPatchPrefix removes a "[PATCH n/m]" prefix the model copied from
+// the history; git format-patch adds its own
+func stripPatchPrefix(msg CommitMessage) CommitMessage {
+	if !patchPrefixPattern.MatchString(msg.Header) {
+		return msg
+	}
+	msg.Header = patchPrefixPattern.ReplaceAllString(msg.Header, "")
+	return ParseCommitMessage(msg.String())
+}
+
+// CoverLetter writes a cover letter for the patch series of the commits in
+// base..HEAD, e.g. CoverLetter(ctx, "origin/main")
+func (c *CommitGen) CoverLetter(ctx context.Context, base string) (*Result, error) {
+	commits, err := c.repo.GetCommits("--reverse", "--no-merges", base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits between %s and HEAD", base)
+	}
+	names, err := c.repo.run("diff", "--no-renames", "--name-only", "-z", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the series files: %w", err)
+	}
+	var changed []DiffFile
+	for _, path := range splitNull(names) {
+		changed = append(changed, DiffFile{NewPath: path})
+	}
+	// The diffstat names the files, and the messages describe them
+	if forbidden := ForbiddenPaths(c.policyPaths, changed); len(forbidden) > 0 {
+		return nil, &PolicyError{Paths: forbidden}
+	}
+	diffstat, err := c.repo.run("diff", "--stat", base+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read series diffstat: %w", err)
+	}
+
+	var b strings.Builder
+	for i, commit := range commits {
+		fmt.Fprintf(&b, "[PATCH %d/%d] %s\n\n", i+1, len(commits), commit.Message)
+	}
+	fmt.Fprintf(&b, "Diffstat:\n%s\n", diffstat)
+
+	g := c.generator
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    prompts.CoverLetter(kernelSubjectLimit - patchPrefixLength(len(commits))),
+		Prompt:          b.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Summary,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write cover letter: %w", err)
+	}
+
+	result := newResult(resp, g.config.Model)
+	result.Message = stripPatchPrefix(result.Message)
+	result.Message.Body = Reflow(result.Message.Body, DefaultWrapWidth)
+	return result, nil
+}
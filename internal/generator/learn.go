@@ -0,0 +1,310 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Thresholds deciding which habits of a history become rules
+const (
+	// learnMajority is the share of commits a habit needs to become a rule
+	learnMajority = 0.8
+	// learnCommon is the share of commits a type, scope, or trailer needs
+	// to be worth mentioning
+	learnCommon = 0.02
+	// learnMaxScopes caps the scope vocabulary named in the rules
+	learnMaxScopes = 20
+)
+
+// TermCount is how many learned commits use a type, scope, or trailer key
+type TermCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Convention is the de facto commit convention of one or more
+// repositories, derived from their history without calling a model
+type Convention struct {
+	// Repos are the repositories the commits came from
+	Repos []string `json:"repos"`
+	// Commits is the number of commits looked at, merges excluded
+	Commits int `json:"commits"`
+	// Conventional is the number of commits with a Conventional Commits header
+	Conventional int `json:"conventional"`
+	// Scoped is the number of Conventional Commits headers with a scope
+	Scoped int `json:"scoped"`
+	// Lowercase is the number of subjects starting with a lowercase letter
+	Lowercase int `json:"lowercase"`
+	// Period is the number of subjects ending with a period
+	Period int `json:"period"`
+	// Types and Scopes are the Conventional Commits vocabulary, most used first
+	Types  []TermCount `json:"types,omitempty"`
+	Scopes []TermCount `json:"scopes,omitempty"`
+	// Trailers counts the commits using each trailer key, most used first
+	Trailers []TermCount `json:"trailers,omitempty"`
+	// SubjectLength is the mean header length and SubjectLengthP90 the
+	// length nine in ten headers stay within
+	SubjectLength    int `json:"subject_length"`
+	SubjectLengthP90 int `json:"subject_length_p90"`
+	// WithBody is the number of commits with a body besides trailers
+	WithBody int `json:"with_body"`
+	// BodyLines is the mean number of body lines of those commits
+	BodyLines float64 `json:"body_lines"`
+}
+
+// Learn derives the commit convention of the repositories in dirs from
+// their last count commits each. It needs no provider.
+func Learn(dirs []string, count int) (*Convention, error) {
+	var commits []Commit
+	var names []string
+	for _, dir := range dirs {
+		repo := NewGitRepository(dir)
+		name := repo.name()
+		repoCommits, err := repo.GetCommits("--no-merges", fmt.Sprintf("-%d", count))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		commits = append(commits, repoCommits...)
+		names = append(names, name)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%w: no commits to learn from", ErrNoChanges)
+	}
+
+	convention := LearnConvention(commits)
+	convention.Repos = names
+	return convention, nil
+}
+
+// LearnConvention derives a commit convention from commits
+func LearnConvention(commits []Commit) *Convention {
+	cv := &Convention{Commits: len(commits)}
+	types := make(map[string]int)
+	scopes := make(map[string]int)
+	trailers := make(map[string]int)
+	var lengths []int
+	bodyLines := 0
+
+	for _, commit := range commits {
+		msg := ParseCommitMessage(commit.Message)
+		lengths = append(lengths, len([]rune(msg.Header)))
+
+		if msg.Type != "" {
+			cv.Conventional++
+			types[msg.Type]++
+			if msg.Scope != "" {
+				cv.Scoped++
+				scopes[msg.Scope]++
+			}
+		}
+		if first := []rune(msg.Subject); len(first) > 0 && unicode.IsLower(first[0]) {
+			cv.Lowercase++
+		}
+		if strings.HasSuffix(msg.Subject, ".") {
+			cv.Period++
+		}
+
+		body, found := splitTrailers(msg.Body)
+		seen := make(map[string]bool)
+		for _, t := range found {
+			key := canonicalTrailerKey(t.Key)
+			if !seen[key] {
+				trailers[key]++
+				seen[key] = true
+			}
+		}
+		if body = strings.TrimSpace(body); body != "" {
+			cv.WithBody++
+			bodyLines += strings.Count(body, "\n") + 1
+		}
+	}
+
+	cv.Types = rankTerms(types)
+	cv.Scopes = rankTerms(scopes)
+	cv.Trailers = rankTerms(trailers)
+	if len(lengths) > 0 {
+		total := 0
+		for _, n := range lengths {
+			total += n
+		}
+		cv.SubjectLength = total / len(lengths)
+		slices.Sort(lengths)
+		cv.SubjectLengthP90 = lengths[(len(lengths)*9-1)/10]
+	}
+	if cv.WithBody > 0 {
+		cv.BodyLines = float64(bodyLines) / float64(cv.WithBody)
+	}
+	return cv
+}
+
+// canonicalTrailerKey spells trailer keys the same way regardless of case,
+// e.g. "Signed-Off-By" as "Signed-off-by"
+func canonicalTrailerKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+// rankTerms sorts counts by use, most used first, then by name
+func rankTerms(counts map[string]int) []TermCount {
+	terms := make([]TermCount, 0, len(counts))
+	for name, n := range counts {
+		terms = append(terms, TermCount{Name: name, Count: n})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Name < terms[j].Name
+	})
+	return terms
+}
+
+// share is the fraction of the commits n stands for
+func (cv *Convention) share(n int) float64 {
+	if cv.Commits == 0 {
+		return 0
+	}
+	return float64(n) / float64(cv.Commits)
+}
+
+// common returns the names of the terms used by at least learnCommon of
+// the commits, and by two of them at least, keeping the first limit
+func (cv *Convention) common(terms []TermCount, limit int) []string {
+	var names []string
+	for _, t := range terms {
+		if t.Count < 2 || cv.share(t.Count) < learnCommon || (limit > 0 && len(names) == limit) {
+			break
+		}
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// Rules are the prompt rules that describe the convention, only for the
+// habits most of the history follows
+func (cv *Convention) Rules() []string {
+	var rules []string
+	conventional := cv.share(cv.Conventional) >= learnMajority
+
+	if conventional {
+		rules = append(rules, "Use a Conventional Commits header: type(scope): subject")
+		if types := cv.common(cv.Types, 0); len(types) > 0 {
+			rules = append(rules, "Use one of these types: "+strings.Join(types, ", "))
+		}
+		scoped := float64(cv.Scoped) / float64(cv.Conventional)
+		if scoped >= learnMajority {
+			rules = append(rules, "Always give a scope")
+		}
+		if scoped < 1-learnMajority {
+			rules = append(rules, "Leave the scope out")
+		} else if scopes := cv.common(cv.Scopes, learnMaxScopes); len(scopes) > 0 {
+			rules = append(rules, "Prefer an existing scope: "+strings.Join(scopes, ", "))
+		}
+	} else if cv.share(cv.Conventional) < 1-learnMajority {
+		rules = append(rules, "Do not use Conventional Commits prefixes, write a plain subject")
+	}
+
+	switch lower := cv.share(cv.Lowercase); {
+	case lower >= learnMajority:
+		rules = append(rules, "Start the subject with a lowercase letter")
+	case lower < 1-learnMajority:
+		rules = append(rules, "Start the subject with a capital letter")
+	}
+	if cv.share(cv.Period) < 1-learnMajority {
+		rules = append(rules, "Do not end the subject with a period")
+	}
+	if cv.SubjectLengthP90 > 0 {
+		rules = append(rules, fmt.Sprintf("Keep the subject line within %d characters", cv.SubjectLengthP90))
+	}
+
+	lines := plural(max(1, int(math.Round(cv.BodyLines))), "line")
+	switch body := cv.share(cv.WithBody); {
+	case body >= learnMajority:
+		rules = append(rules, fmt.Sprintf("Always add a body explaining why, about %s", lines))
+	case body < 1-learnMajority:
+		rules = append(rules, "Leave the body out unless the reason for the change is not obvious")
+	default:
+		rules = append(rules, fmt.Sprintf("Add a body, about %s, when the change needs explaining", lines))
+	}
+
+	for _, t := range cv.Trailers {
+		if cv.share(t.Count) >= learnMajority {
+			rules = append(rules, fmt.Sprintf("End the message with a %s: trailer", t.Name))
+		}
+	}
+	return rules
+}
+
+// Fragment renders the rules as a prompt fragment, a file named by
+// prompt_fragments
+func (cv *Convention) Fragment() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Follow the commit convention of this team, learned from %s:\n", plural(cv.Commits, "commit"))
+	for _, rule := range cv.Rules() {
+		fmt.Fprintf(&b, "- %s\n", rule)
+	}
+	return b.String()
+}
+
+// Config renders a starter .commitgen.toml: the rules as prompt_rules,
+// preceded by the statistics they were drawn from as comments
+func (cv *Convention) Config() (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Learned by commit-gen learn from %s", plural(cv.Commits, "commit"))
+	if len(cv.Repos) > 0 {
+		fmt.Fprintf(&b, " in %s", strings.Join(cv.Repos, ", "))
+	}
+	b.WriteString("\n#\n")
+	fmt.Fprintf(&b, "# Conventional Commits: %.0f%%, with a scope: %.0f%%\n", 100*cv.share(cv.Conventional), 100*cv.share(cv.Scoped))
+	if len(cv.Types) > 0 {
+		fmt.Fprintf(&b, "# Types: %s\n", cv.describe(cv.Types, 0))
+	}
+	if len(cv.Scopes) > 0 {
+		fmt.Fprintf(&b, "# Scopes: %s\n", cv.describe(cv.Scopes, learnMaxScopes))
+	}
+	fmt.Fprintf(&b, "# Subject length: %d on average, %d for nine in ten\n", cv.SubjectLength, cv.SubjectLengthP90)
+	fmt.Fprintf(&b, "# With a body: %.0f%%, %.1f lines on average\n", 100*cv.share(cv.WithBody), cv.BodyLines)
+	if len(cv.Trailers) > 0 {
+		fmt.Fprintf(&b, "# Trailers: %s\n", cv.describe(cv.Trailers, 0))
+	}
+	b.WriteString("\n")
+
+	// A JSON string is a valid TOML basic string
+	b.WriteString("prompt_rules = [\n")
+	for _, rule := range cv.Rules() {
+		quoted, err := json.Marshal(rule)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "  %s,\n", quoted)
+	}
+	b.WriteString("]\n")
+	return b.String(), nil
+}
+
+// describe lists terms with the share of commits using them, keeping the
+// first limit (0 keeps all)
+func (cv *Convention) describe(terms []TermCount, limit int) string {
+	var parts []string
+	for i, t := range terms {
+		if limit > 0 && i == limit {
+			parts = append(parts, fmt.Sprintf("and %d more", len(terms)-limit))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s %.0f%%", t.Name, 100*cv.share(t.Count)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// plural formats a count with its noun, e.g. "1 commit" or "3 commits"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,23 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// decodeJSONResponse decodes a JSON model response into v, tolerating the
+// markdown code fences models like to wrap JSON in
+func decodeJSONResponse(text string, v any) error {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), v); err != nil {
+		return fmt.Errorf("failed to decode model JSON: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CommitMessage is a commit message split into its Conventional Commits parts
+type CommitMessage struct {
+	// Header is the full first line, e.g. "feat(auth)!: add JWT login"
+	Header string `json:"header"`
+	// Type, Scope, and Subject are parsed from a Conventional Commits header
+	// and are empty (except Subject) when the header does not follow it
+	Type     string `json:"type,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	Breaking bool   `json:"breaking,omitempty"`
+	Subject  string `json:"subject"`
+	// Body is everything after the blank line following the header
+	Body string `json:"body,omitempty"`
+}
+
+// headerPattern matches "type(scope)!: subject"
+var headerPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()\r\n]*)\))?(!)?: (.+)$`)
+
+// ParseCommitMessage splits a raw commit message into its parts
+func ParseCommitMessage(raw string) CommitMessage {
+	raw = strings.TrimSpace(strings.ReplaceAll(raw, "\r\n", "\n"))
+
+	header, body, _ := strings.Cut(raw, "\n")
+	msg := CommitMessage{
+		Header:  strings.TrimSpace(header),
+		Subject: strings.TrimSpace(header),
+		Body:    strings.TrimSpace(body),
+	}
+
+	if m := headerPattern.FindStringSubmatch(msg.Header); m != nil {
+		msg.Type = strings.ToLower(m[1])
+		msg.Scope = m[2]
+		msg.Breaking = m[3] == "!"
+		msg.Subject = m[4]
+	}
+	if strings.Contains(msg.Body, "BREAKING CHANGE:") || strings.Contains(msg.Body, "BREAKING-CHANGE:") {
+		msg.Breaking = true
+	}
+
+	return msg
+}
+
+// String renders the message as git expects it
+func (m CommitMessage) String() string {
+	if m.Body == "" {
+		return m.Header
+	}
+	return m.Header + "\n\n" + m.Body
+}
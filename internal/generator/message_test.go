@@ -0,0 +1,49 @@
+package generator
+
+import "testing"
+
+func TestParseCommitMessage(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want CommitMessage
+	}{
+		{
+			raw:  "feat(auth)!: add JWT login\r\n\r\nUse tokens.\r\n",
+			want: CommitMessage{Header: "feat(auth)!: add JWT login", Type: "feat", Scope: "auth", Breaking: true, Subject: "add JWT login", Body: "Use tokens."},
+		},
+		{
+			raw:  "Fix: body\n\nBREAKING CHANGE: the API changed",
+			want: CommitMessage{Header: "Fix: body", Type: "fix", Breaking: true, Subject: "body", Body: "BREAKING CHANGE: the API changed"},
+		},
+		{
+			raw:  "Update the readme",
+			want: CommitMessage{Header: "Update the readme", Subject: "Update the readme"},
+		},
+		{
+			raw:  "fix(a(b)): nested",
+			want: CommitMessage{Header: "fix(a(b)): nested", Subject: "fix(a(b)): nested"},
+		},
+		{
+			raw:  "",
+			want: CommitMessage{},
+		},
+	}
+	for _, tt := range tests {
+		if got := ParseCommitMessage(tt.raw); got != tt.want {
+			t.Errorf("ParseCommitMessage(%q) = %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func FuzzParseCommitMessage(f *testing.F) {
+	f.Add("feat(auth)!: add JWT login\n\nUse tokens.")
+	f.Add("fix: crlf\r\n\r\nbody\r\n")
+	f.Add("feat(): \n\n\nBREAKING-CHANGE: x")
+	f.Add("\x00\xff(:)!:  ")
+	f.Fuzz(func(t *testing.T, raw string) {
+		msg := ParseCommitMessage(raw)
+		if again := ParseCommitMessage(msg.String()); again != msg {
+			t.Errorf("ParseCommitMessage does not round-trip %q: %+v, then %+v", raw, msg, again)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotesFileName is the file inside the git directory holding free-text
+// intent for the next commit
+const NotesFileName = "COMMIT_CONTEXT"
+
+// NotesPath returns the location of the commit notes file
+func (g *GitRepository) NotesPath() (string, error) {
+	path, err := g.run("rev-parse", "--path-format=absolute", "--git-path", NotesFileName)
+	if err != nil {
+		return "", fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	return strings.TrimSpace(path), nil
+}
+
+// ReadNotes returns the commit notes for the pending commit. Notes written
+// before the current HEAD commit belonged to that commit, so they are
+// cleared, or in read-only mode ignored, instead of being returned.
+func (g *GitRepository) ReadNotes() (string, error) {
+	path, err := g.NotesPath()
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit notes: %w", err)
+	}
+
+	if committed, ok := g.headCommitTime(); ok && committed.After(info.ModTime()) {
+		if !cacheWritable(g.readOnly) {
+			return "", nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to clear commit notes: %w", err)
+		}
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit notes: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// AddNote appends a line to the commit notes file
+func (g *GitRepository) AddNote(note string) error {
+	if err := checkWritable(g.readOnly, "add a note"); err != nil {
+		return err
+	}
+	// Drop notes left over from the previous commit before appending
+	if _, err := g.ReadNotes(); err != nil {
+		return err
+	}
+
+	path, err := g.NotesPath()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write commit notes: %w", err)
+	}
+	if _, err := fmt.Fprintln(f, strings.TrimSpace(note)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write commit notes: %w", err)
+	}
+	return f.Close()
+}
+
+// ClearNotes removes the commit notes file, e.g. from a post-commit hook
+func (g *GitRepository) ClearNotes() error {
+	if err := checkWritable(g.readOnly, "clear the notes"); err != nil {
+		return err
+	}
+	path, err := g.NotesPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to clear commit notes: %w", err)
+	}
+	return nil
+}
+
+// headCommitTime returns the committer time of HEAD, if there is one
+func (g *GitRepository) headCommitTime() (time.Time, bool) {
+	output, err := g.run("log", "-1", "--format=%ct")
+	if err != nil {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
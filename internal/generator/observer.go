@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"context"
+	"time"
+)
+
+// Observer receives instrumentation events from the generator, e.g. to
+// export metrics from daemon mode. Implementations must be safe for
+// concurrent use.
+type Observer interface {
+	// ProviderCall is called after every provider request
+	ProviderCall(provider, model string, latency time.Duration, usage Usage, err error)
+	// CacheLookup is called for every response cache lookup
+	CacheLookup(hit bool)
+}
+
+// observedProvider reports every call of the wrapped provider to an Observer
+type observedProvider struct {
+	Provider
+	observer Observer
+}
+
+// GenerateText forwards the request and reports its outcome
+func (p *observedProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	start := time.Now()
+	resp, err := p.Provider.GenerateText(ctx, req)
+
+	var usage Usage
+	if resp != nil {
+		usage = resp.Usage
+	}
+	p.observer.ProviderCall(p.Provider.Name(), req.Model, time.Since(start), usage, err)
+
+	return resp, err
+}
+
+// Embed forwards the request and reports its outcome
+func (p *observedProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	start := time.Now()
+	vectors, err := embed(ctx, p.Provider, req)
+	p.observer.ProviderCall(p.Provider.Name(), req.Model, time.Since(start), Usage{}, err)
+	return vectors, err
+}
+
+// Ping forwards health checks when the wrapped provider supports them
+func (p *observedProvider) Ping(ctx context.Context, model string) error {
+	if checker, ok := p.Provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
+
+// observe wraps provider with tracing, metering for InterruptedError and,
+// if configured, an Observer
+func observe(provider Provider, observer Observer) Provider {
+	provider = &tracedProvider{Provider: &meteredProvider{Provider: provider}}
+	if observer == nil {
+		return provider
+	}
+	return &observedProvider{Provider: provider, observer: observer}
+}
@@ -0,0 +1,256 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// DefaultOllamaURL is used when neither Options.OllamaURL nor OLLAMA_HOST is set
+const DefaultOllamaURL = "http://localhost:11434"
+
+// ollamaProvider talks to a local Ollama server
+type ollamaProvider struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// newOllamaProvider creates an Ollama backed Provider
+// If baseURL is empty, OLLAMA_HOST or DefaultOllamaURL is used
+func newOllamaProvider(baseURL string, httpClient *http.Client) *ollamaProvider {
+	if baseURL == "" {
+		baseURL = os.Getenv("OLLAMA_HOST")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+
+	return &ollamaProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// ollamaGenerateRequest is the body of POST /api/generate
+type ollamaGenerateRequest struct {
+	Model   string         `json:"model"`
+	System  string         `json:"system,omitempty"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// ollamaGenerateResponse is the non-streaming reply of POST /api/generate
+type ollamaGenerateResponse struct {
+	Model    string `json:"model"`
+	Response string `json:"response"`
+	Error    string `json:"error"`
+	// DoneReason is "stop" or "length"
+	DoneReason string `json:"done_reason"`
+	// Token counts of the prompt and of the generated response
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// Name returns the provider identifier
+func (p *ollamaProvider) Name() string {
+	return "ollama"
+}
+
+// GenerateText sends the request to the Ollama generate endpoint
+func (p *ollamaProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	options := map[string]any{}
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if req.MaxOutputTokens > 0 {
+		options["num_predict"] = req.MaxOutputTokens
+	}
+
+	body, err := json.Marshal(&ollamaGenerateRequest{
+		Model:   req.Model,
+		System:  req.SystemPrompt,
+		Prompt:  req.Prompt,
+		Stream:  false,
+		Options: options,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ollama request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ollama response: %w", err)
+	}
+
+	var out ollamaGenerateResponse
+	decodeErr := json.Unmarshal(data, &out)
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		message := out.Error
+		if decodeErr != nil {
+			// Proxies answer with plain text or HTML
+			message = strings.TrimSpace(truncateBytes(string(data), 200))
+		}
+		return nil, &ProviderError{Provider: ProviderOllama, StatusCode: resp.StatusCode, Message: message}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", decodeErr)
+	}
+
+	finishReason := FinishReasonStop
+	if out.DoneReason == "length" {
+		finishReason = FinishReasonLength
+	}
+
+	return &TextResponse{
+		Text:         out.Response,
+		Model:        out.Model,
+		FinishReason: finishReason,
+		Usage: Usage{
+			PromptTokens: out.PromptEvalCount,
+			OutputTokens: out.EvalCount,
+			TotalTokens:  out.PromptEvalCount + out.EvalCount,
+		},
+	}, nil
+}
+
+// ollamaEmbedRequest is the body of POST /api/embed
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaEmbedResponse is the reply of POST /api/embed
+type ollamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Error      string      `json:"error"`
+}
+
+// Embed sends the texts to the Ollama embed endpoint
+func (p *ollamaProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	body, err := json.Marshal(&ollamaEmbedRequest{Model: req.Model, Input: req.Texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ollama request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ollama response: %w", err)
+	}
+
+	var out ollamaEmbedResponse
+	decodeErr := json.Unmarshal(data, &out)
+	if resp.StatusCode != http.StatusOK || out.Error != "" {
+		message := out.Error
+		if decodeErr != nil {
+			message = strings.TrimSpace(truncateBytes(string(data), 200))
+		}
+		return nil, &ProviderError{Provider: ProviderOllama, StatusCode: resp.StatusCode, Message: message}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", decodeErr)
+	}
+	if len(out.Embeddings) != len(req.Texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d texts", len(out.Embeddings), len(req.Texts))
+	}
+	return out.Embeddings, nil
+}
+
+// Ping checks that the Ollama server is up and answering
+func (p *ollamaProvider) Ping(ctx context.Context, model string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create ollama request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return &ProviderError{Provider: ProviderOllama, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// hasModel reports whether model has been pulled to the Ollama server; a
+// model without a tag stands for its "latest" tag
+func (p *ollamaProvider) hasModel(ctx context.Context, model string) (bool, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create ollama request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("failed to reach ollama at %s: %w", p.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return false, &ProviderError{Provider: ProviderOllama, StatusCode: resp.StatusCode}
+	}
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return false, fmt.Errorf("failed to decode ollama models: %w", err)
+	}
+	if !strings.Contains(model, ":") {
+		model += ":latest"
+	}
+	for _, m := range tags.Models {
+		if m.Name == model {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Close cleans up resources
+func (p *ollamaProvider) Close() error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GetStagedFileSummary lists the staged files with their status (A, M, D,
+// ...) without reading any file contents, so it works in partial clones
+// whose blobs are not available locally
+func (g *GitRepository) GetStagedFileSummary() (string, error) {
+	// Rename detection compares contents, which could trigger a fetch
+	cmd := exec.Command("git", "-c", "core.quotepath=off", "diff", "--staged", "--name-status", "--no-renames")
+	if g.workingDir != "" {
+		cmd.Dir = g.workingDir
+	}
+	// Fail instead of fetching missing objects from the promisor remote
+	cmd.Env = append(os.Environ(), "GIT_NO_LAZY_FETCH=1")
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list staged files: %w", err)
+	}
+	return string(output), nil
+}
+
+// IsPartialClone reports whether objects may be missing locally because
+// the repository was cloned with --filter
+func (g *GitRepository) IsPartialClone() bool {
+	output, err := g.run("config", "--get-regexp", `^(extensions\.partialclone|remote\..*\.promisor)$`)
+	return err == nil && strings.TrimSpace(output) != ""
+}
@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SubjectPrefix returns the Conventional Commits header prefix for a type and
+// optional scope, e.g. SubjectPrefix("fix", "parser") == "fix(parser): "
+func SubjectPrefix(commitType, scope string) string {
+	if scope == "" {
+		return commitType + ": "
+	}
+	return commitType + "(" + scope + "): "
+}
+
+// pinPrompt tells the model which header parts are fixed
+func pinPrompt(subjectPrefix, scope string) string {
+	switch {
+	case subjectPrefix != "":
+		return fmt.Sprintf("The subject line must start with exactly %q. Write only the description that follows it, and the body.\n", subjectPrefix)
+	case scope != "":
+		return fmt.Sprintf("Use %q as the commit scope.\n", scope)
+	}
+	return ""
+}
+
+// applyPins forces the pinned prefix or scope onto msg, replacing whatever
+// type and scope the model chose
+func applyPins(msg CommitMessage, subjectPrefix, scope string) CommitMessage {
+	switch {
+	case subjectPrefix != "":
+		if strings.HasPrefix(msg.Header, subjectPrefix) {
+			return msg
+		}
+		prefix := subjectPrefix
+		// Keep a breaking change marker the model put on its own prefix
+		if msg.Type != "" && msg.Breaking && strings.HasSuffix(prefix, ": ") && !strings.HasSuffix(prefix, "!: ") {
+			if _, marked := strings.CutSuffix(strings.TrimSuffix(msg.Header, msg.Subject), "!: "); marked {
+				prefix = strings.TrimSuffix(prefix, ": ") + "!: "
+			}
+		}
+		msg.Header = prefix + msg.Subject
+	case scope != "" && msg.Type != "":
+		breaking := ""
+		if strings.HasSuffix(strings.TrimSuffix(msg.Header, msg.Subject), "!: ") {
+			breaking = "!"
+		}
+		msg.Header = fmt.Sprintf("%s(%s)%s: %s", msg.Type, scope, breaking, msg.Subject)
+	default:
+		return msg
+	}
+	return ParseCommitMessage(msg.String())
+}
+
+// allowPinnedType makes sure a type pinned by the user passes validation
+func allowPinnedType(rules *ValidationRules, subjectPrefix string) *ValidationRules {
+	pinned := ParseCommitMessage(subjectPrefix + "x").Type
+	if pinned == "" || len(rules.AllowedTypes) == 0 || slices.Contains(rules.AllowedTypes, pinned) {
+		return rules
+	}
+	allowed := *rules
+	allowed.AllowedTypes = append(slices.Clone(rules.AllowedTypes), pinned)
+	return &allowed
+}
@@ -0,0 +1,209 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// emptyTreeHash is git's empty tree, the base to diff against before the first commit
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// Limits on how much of an untracked file the planner shows the model
+const (
+	planUntrackedMaxBytes = 8 << 10
+	planUntrackedMaxLines = 200
+)
+
+// PlannedCommit is one commit of a Plan
+type PlannedCommit struct {
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+}
+
+// Plan is an ordered series of commits that together take up every
+// uncommitted change
+type Plan struct {
+	Commits []PlannedCommit `json:"commits"`
+}
+
+// GetUncommittedChanges returns the diff of everything not yet committed,
+// staged or not, with untracked files shown as new files, and the paths
+// of all changed files. Renames are shown as a deletion and an addition,
+// so that each path can be committed on its own.
+func (g *GitRepository) GetUncommittedChanges() (string, []string, error) {
+	base := "HEAD"
+	if _, err := g.run("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		base = emptyTreeHash
+	}
+
+	diff, err := g.run("diff", "--no-renames", base)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get uncommitted changes: %w", err)
+	}
+	names, err := g.run("diff", "--no-renames", "--name-only", "-z", base)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list uncommitted changes: %w", err)
+	}
+	untracked, err := g.run("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list untracked files: %w", err)
+	}
+
+	files := splitNull(names)
+	var b strings.Builder
+	b.WriteString(diff)
+	for _, path := range splitNull(untracked) {
+		files = append(files, path)
+		b.WriteString(g.untrackedDiff(path))
+	}
+	return b.String(), files, nil
+}
+
+// untrackedDiff shows an untracked file as a new file, cut short when it
+// is large; binary files are only named
+func (g *GitRepository) untrackedDiff(path string) string {
+	header := fmt.Sprintf("diff --git a/%s b/%s\nnew file\n--- /dev/null\n+++ b/%s\n", path, path, path)
+	data, err := os.ReadFile(filepath.Join(g.workingDir, path))
+	if err != nil || bytes.IndexByte(data, 0) >= 0 {
+		return header + "Binary or unreadable file\n"
+	}
+
+	lines := strings.Split(strings.TrimSuffix(truncateBytes(string(data), planUntrackedMaxBytes), "\n"), "\n")
+	truncated := len(data) > planUntrackedMaxBytes || len(lines) > planUntrackedMaxLines
+	lines = lines[:min(len(lines), planUntrackedMaxLines)]
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, line := range lines {
+		b.WriteString("+" + line + "\n")
+	}
+	if truncated {
+		b.WriteString("... (file truncated)\n")
+	}
+	return b.String()
+}
+
+// CommitFiles commits the current content of files, including deletions
+// and untracked files, with message. Other staged changes are left staged
+// and out of the commit.
+func (g *GitRepository) CommitFiles(message string, files []string) error {
+	commands := g.CommitFilesCommands(message, files)
+	// Command.run, unlike run, keeps git's explanation of a failure
+	if err := commands[0].run(g.workingDir, g.writeGuard, "commit"); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return err
+		}
+		return fmt.Errorf("failed to stage %s: %w", strings.Join(files, ", "), err)
+	}
+	if err := commands[1].run(g.workingDir, g.writeGuard, "commit"); err != nil {
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
+// CommitFilesCommands returns the commands CommitFiles runs
+func (g *GitRepository) CommitFilesCommands(message string, files []string) []Command {
+	return []Command{
+		{Args: append([]string{"git", "add", "--all", "--"}, files...)},
+		{Args: append([]string{"git", "commit", "--quiet", "--file=-", "--"}, files...), Stdin: message},
+	}
+}
+
+// splitNull splits the output of a git command run with -z
+func splitNull(output string) []string {
+	return strings.FieldsFunc(output, func(r rune) bool { return r == 0 })
+}
+
+// Plan proposes how to split every uncommitted change, staged or not and
+// including untracked files, into an ordered series of focused commits,
+// each with its message. Every changed file ends up in exactly one commit.
+func (c *CommitGen) Plan(ctx context.Context) (plan *Plan, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Plan")
+	defer func() { endSpan(span, err) }()
+
+	diff, files, err := c.repo.GetUncommittedChanges()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: the working tree is clean", ErrNoChanges)
+	}
+	changed := make([]DiffFile, len(files))
+	for i, path := range files {
+		changed[i] = DiffFile{NewPath: path}
+	}
+	if forbidden := ForbiddenPaths(c.policyPaths, changed); len(forbidden) > 0 {
+		return nil, &PolicyError{Paths: forbidden}
+	}
+
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Changed files:\n%s\n\n", strings.Join(files, "\n"))
+	if history, err := c.repo.GetRecentCommits(10); err == nil && history != "" {
+		fmt.Fprintf(&prompt, "Recent git log:\n%s\n\n", history)
+	}
+	fmt.Fprintf(&prompt, "Git diff:\n%s", diff)
+
+	g := c.generator
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    prompts.Template(prompts.Plan),
+		Prompt:          prompt.String(),
+		MaxOutputTokens: g.config.MaxOutputTokens.Report,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan commits: %w", err)
+	}
+
+	plan = &Plan{}
+	if err := decodeJSONResponse(resp.Text, plan); err != nil {
+		return nil, err
+	}
+	return completePlan(plan, files)
+}
+
+// completePlan makes the model's plan cover every changed file exactly
+// once: unknown and repeated files are dropped, forgotten ones go into the
+// last commit, and commits left without files are removed
+func completePlan(plan *Plan, files []string) (*Plan, error) {
+	remaining := make(map[string]bool, len(files))
+	for _, path := range files {
+		remaining[path] = true
+	}
+
+	result := &Plan{}
+	for _, commit := range plan.Commits {
+		var kept []string
+		for _, path := range commit.Files {
+			if remaining[path] {
+				kept = append(kept, path)
+				delete(remaining, path)
+			}
+		}
+		message := ParseCommitMessage(commit.Message).String()
+		if len(kept) > 0 && message != "" {
+			result.Commits = append(result.Commits, PlannedCommit{Message: message, Files: kept})
+		}
+	}
+	if len(result.Commits) == 0 {
+		return nil, errors.New("the model proposed no usable commits")
+	}
+
+	last := &result.Commits[len(result.Commits)-1]
+	for _, path := range files {
+		if remaining[path] && !slices.Contains(last.Files, path) {
+			last.Files = append(last.Files, path)
+		}
+	}
+	return result, nil
+}
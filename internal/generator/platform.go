@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Platforms accepted by Options.Platform
+const (
+	// PlatformAuto picks the preset from the host of the repository's
+	// remote (the default)
+	PlatformAuto = "auto"
+	// PlatformNone keeps the built-in limits whatever the remote
+	PlatformNone      = "none"
+	PlatformGitHub    = "github"
+	PlatformGitLab    = "gitlab"
+	PlatformGerrit    = "gerrit"
+	PlatformBitbucket = "bitbucket"
+)
+
+// PlatformPreset holds the message limits that render well on a code
+// hosting platform
+type PlatformPreset struct {
+	MaxSubjectLength  int
+	MaxBodyLineLength int
+}
+
+// platformPresets are the presets by platform. GitHub and GitLab cut
+// subjects at 72 characters in commit lists, Gerrit warns about subjects
+// over 65 and body lines over 70, and Bitbucket shows 72 characters of
+// the first line.
+var platformPresets = map[string]PlatformPreset{
+	PlatformGitHub:    {MaxSubjectLength: 72, MaxBodyLineLength: 72},
+	PlatformGitLab:    {MaxSubjectLength: 72, MaxBodyLineLength: 72},
+	PlatformGerrit:    {MaxSubjectLength: 65, MaxBodyLineLength: 70},
+	PlatformBitbucket: {MaxSubjectLength: 72, MaxBodyLineLength: 72},
+}
+
+// DetectPlatform maps a remote URL, e.g. "git@github.com:o/r.git" or
+// "ssh://review.example.com:29418/r", to the platform hosting it, or ""
+// when the host is not recognized
+func DetectPlatform(remoteURL string) string {
+	host, port := remoteHost(remoteURL)
+	switch {
+	case host == "":
+		return ""
+	case host == "github.com" || strings.HasPrefix(host, "github."):
+		return PlatformGitHub
+	case strings.Contains(host, "gitlab"):
+		return PlatformGitLab
+	case strings.Contains(host, "bitbucket"):
+		return PlatformBitbucket
+	case strings.HasSuffix(host, ".googlesource.com"), port == "29418",
+		strings.HasPrefix(host, "gerrit."), strings.HasPrefix(host, "review."):
+		// 29418 is Gerrit's SSH port
+		return PlatformGerrit
+	}
+	return ""
+}
+
+// remoteHost returns the lower-cased host and the port of a remote URL,
+// including the scp-like "user@host:path" form git accepts
+func remoteHost(remoteURL string) (host, port string) {
+	if !strings.Contains(remoteURL, "://") {
+		before, _, found := strings.Cut(remoteURL, ":")
+		if !found {
+			// A local path
+			return "", ""
+		}
+		_, host, _ = strings.Cut(before, "@")
+		if host == "" {
+			host = before
+		}
+		return strings.ToLower(host), ""
+	}
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return "", ""
+	}
+	return strings.ToLower(u.Hostname()), u.Port()
+}
+
+// RemoteURL returns the URL of the "origin" remote, or of the first remote
+// when there is no origin; empty when the repository has no remote
+func (g *GitRepository) RemoteURL() (string, error) {
+	if output, err := g.run("remote", "get-url", "origin"); err == nil {
+		return strings.TrimSpace(output), nil
+	}
+	output, err := g.run("remote")
+	if err != nil {
+		return "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	remotes := strings.Fields(output)
+	if len(remotes) == 0 {
+		return "", nil
+	}
+	output, err = g.run("remote", "get-url", remotes[0])
+	if err != nil {
+		return "", fmt.Errorf("failed to read the URL of remote %s: %w", remotes[0], err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// resolvePlatform turns Options.Platform into the platform whose preset
+// applies, "" for none, detecting it from the remote of repo for
+// PlatformAuto
+func resolvePlatform(platform string, repo *GitRepository) (string, error) {
+	switch platform {
+	case "", PlatformAuto:
+		// No remote, or no git repository at all, just means no preset
+		remoteURL, _ := repo.RemoteURL()
+		return DetectPlatform(remoteURL), nil
+	case PlatformNone:
+		return "", nil
+	}
+	if _, ok := platformPresets[platform]; !ok {
+		return "", fmt.Errorf("unknown platform %q", platform)
+	}
+	return platform, nil
+}
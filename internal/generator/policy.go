@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Policy actions for changes that touch forbidden paths
+const (
+	// PolicyRefuse fails generation with a *PolicyError
+	PolicyRefuse = "refuse"
+	// PolicyOffline describes the change with HeuristicMessage, so nothing
+	// is sent to a model
+	PolicyOffline = "offline"
+)
+
+// PolicyError is returned when the change touches paths where the repository
+// policy forbids AI generation
+type PolicyError struct {
+	// Paths are the changed files matching a forbidden glob
+	Paths []string
+}
+
+// Error implements the error interface
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("AI generation is forbidden by repository policy for: %s", strings.Join(e.Paths, ", "))
+}
+
+// ForbiddenPaths returns the changed files matching any of the globs.
+// Like .gitignore, a glob without a slash matches a file or directory name
+// at any depth ("*.pem", "secrets"), while one with a slash is relative to
+// the repository root ("internal/crypto/**"). A trailing "/" or "/**"
+// matches everything below the directory.
+func ForbiddenPaths(globs []string, files []DiffFile) []string {
+	var forbidden []string
+	for _, file := range files {
+		// A rename out of a forbidden directory still exposes its contents
+		for _, p := range []string{file.OldPath, file.NewPath} {
+			if p == "" || slices.Contains(forbidden, p) {
+				continue
+			}
+			if slices.ContainsFunc(globs, func(glob string) bool { return matchPolicyGlob(glob, p) }) {
+				forbidden = append(forbidden, p)
+			}
+		}
+	}
+	return forbidden
+}
+
+// matchPolicyGlob reports whether file, or a directory containing it, matches glob
+func matchPolicyGlob(glob, file string) bool {
+	glob = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(glob), "**"), "/")
+	anchored := strings.Contains(glob, "/")
+	glob = strings.TrimPrefix(glob, "/")
+	if glob == "" {
+		return false
+	}
+
+	parts := strings.Split(file, "/")
+	for i := range parts {
+		candidate := parts[i]
+		if anchored {
+			candidate = strings.Join(parts[:i+1], "/")
+		}
+		if ok, _ := path.Match(glob, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// changedFiles lists the files of the change, from the diff or, when the
+// contents were omitted, from the "status<TAB>path" summary
+func changedFiles(info *GitInfo) []DiffFile {
+	if !info.ContentOmitted {
+		return ParseDiff(info.StagedDiff)
+	}
+
+	var files []DiffFile
+	for _, line := range strings.Split(info.StagedDiff, "\n") {
+		status, file, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || status == "" {
+			continue
+		}
+		switch status[0] {
+		case 'A':
+			files = append(files, DiffFile{NewPath: file})
+		case 'D':
+			files = append(files, DiffFile{OldPath: file})
+		default:
+			files = append(files, DiffFile{OldPath: file, NewPath: file})
+		}
+	}
+	return files
+}
@@ -0,0 +1,152 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Provider is an AI backend capable of turning a prompt into text
+type Provider interface {
+	// Name returns the provider identifier (e.g. "gemini", "ollama")
+	Name() string
+	// GenerateText runs a single completion request
+	GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error)
+	// Close releases any resources held by the provider
+	Close() error
+}
+
+// TextRequest describes a single completion request sent to a Provider
+type TextRequest struct {
+	Model        string
+	SystemPrompt string
+	Prompt       string
+	// Temperature overrides the provider default when not nil
+	Temperature *float32
+	// Seed makes sampling repeatable on providers that support it, when not nil
+	Seed *int32
+	// ThinkingBudget lets models that support it think for up to this many
+	// tokens before answering; nil or 0 disables thinking
+	ThinkingBudget *int32
+	// MaxOutputTokens caps the response length (0 uses the provider default)
+	MaxOutputTokens int
+}
+
+// TextResponse is the raw output of a Provider
+type TextResponse struct {
+	Text  string
+	Model string
+	Usage Usage
+	// FinishReason is one of the FinishReason constants
+	FinishReason string
+	// BlockReason is set when the provider refused to answer
+	BlockReason string
+}
+
+// Usage reports the tokens consumed by a request
+type Usage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// HealthChecker is implemented by providers that can cheaply verify they are
+// reachable without running a full generation
+type HealthChecker interface {
+	Ping(ctx context.Context, model string) error
+}
+
+// Embedder is implemented by providers that can turn text into embedding
+// vectors, e.g. for searching the commit history by meaning
+type Embedder interface {
+	Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error)
+}
+
+// EmbedRequest asks for one embedding vector per text
+type EmbedRequest struct {
+	Model string
+	Texts []string
+	// Query marks the texts as search queries rather than documents, for
+	// models that embed the two differently
+	Query bool
+}
+
+// ErrNoEmbeddings is returned by providers that cannot compute embeddings
+var ErrNoEmbeddings = errors.New("the provider does not support embeddings")
+
+// embed embeds with provider, or returns ErrNoEmbeddings when it cannot
+func embed(ctx context.Context, provider Provider, req *EmbedRequest) ([][]float32, error) {
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", provider.Name(), ErrNoEmbeddings)
+	}
+	return embedder.Embed(ctx, req)
+}
+
+// Provider names accepted by Options.Provider
+const (
+	ProviderGemini = "gemini"
+	ProviderOllama = "ollama"
+)
+
+// defaultModels maps each provider to the model used when none is configured
+var defaultModels = map[string]string{
+	ProviderGemini:    "gemini-2.5-flash-lite",
+	ProviderAnthropic: "claude-haiku-4-5",
+	ProviderOllama:    "llama3.2",
+}
+
+// defaultEmbeddingModels maps each provider to the embedding model used when
+// none is configured
+var defaultEmbeddingModels = map[string]string{
+	ProviderGemini: "gemini-embedding-001",
+	ProviderOllama: "nomic-embed-text",
+}
+
+// DefaultModel returns the default model for the named provider
+func DefaultModel(provider string) string {
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	return defaultModels[provider]
+}
+
+// DefaultEmbeddingModel returns the default embedding model for the named
+// provider, or "" when it has none
+func DefaultEmbeddingModel(provider string) string {
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	return defaultEmbeddingModels[provider]
+}
+
+// newProvider creates the named provider from the generator config
+func newProvider(ctx context.Context, name string, config *GeneratorConfig, httpClient *http.Client) (Provider, error) {
+	switch name {
+	case "", ProviderGemini:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("API key is required")
+		}
+		var baseURL string
+		if config.Transport != nil {
+			baseURL = config.Transport.BaseURL
+		}
+		return newGeminiProvider(ctx, config.APIKey, baseURL, httpClient)
+	case ProviderAnthropic:
+		if config.AnthropicAPIKey == "" {
+			return nil, fmt.Errorf("API key is required")
+		}
+		var baseURL string
+		if config.Transport != nil {
+			baseURL = config.Transport.BaseURL
+		}
+		return newAnthropicProvider(config.AnthropicAPIKey, baseURL, httpClient), nil
+	case ProviderOllama:
+		return newOllamaProvider(config.OllamaURL, httpClient), nil
+	case ProviderRelay:
+		return newRelayProvider(config.RelayURL, config.relayToken, httpClient)
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
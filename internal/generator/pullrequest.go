@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// PullRequest is a pull request as a forge reports it, for
+// SummarizePullRequest
+type PullRequest struct {
+	Title string
+	// Body is the description the author wrote
+	Body string
+	// Commits are the commits of the pull request, oldest first
+	Commits []Commit
+	// Diff is the unified diff of the whole pull request
+	Diff string
+}
+
+// PullRequestSummary is what SummarizePullRequest writes for a pull request
+type PullRequestSummary struct {
+	// Summary describes the pull request for its reviewers
+	Summary *Summary `json:"summary"`
+	// SquashMessage is the suggested message for a squash merge
+	SquashMessage *Result `json:"squash_message"`
+}
+
+// SummarizePullRequest writes a summary of pr for its reviewers and the
+// commit message to squash-merge it with. The squash message is generated
+// from the diff like any commit message, with the title as the hint and
+// the description as the author's notes.
+func (c *CommitGen) SummarizePullRequest(ctx context.Context, pr *PullRequest) (summary *PullRequestSummary, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.SummarizePullRequest")
+	defer func() { endSpan(span, err) }()
+
+	if strings.TrimSpace(pr.Diff) == "" {
+		return nil, fmt.Errorf("%w: the pull request has no changes", ErrNoChanges)
+	}
+
+	squash, err := c.generateAllowed(ctx, &GitInfo{
+		StagedDiff: pr.Diff,
+		Hint:       pr.Title,
+		Notes:      pr.Body,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Title: %s\n\n", pr.Title)
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		fmt.Fprintf(&b, "Description:\n%s\n\n", truncateBytes(body, maxHistoryMessageBytes))
+	}
+	b.WriteString("Commits:\n")
+	b.WriteString(formatCommitLog(pr.Commits, ""))
+	fmt.Fprintf(&b, "Suggested squash commit message:\n%s\n", squash.Message)
+
+	prSummary, err := c.generator.summarize(ctx, prompts.Template(prompts.PullRequestSummary), b.String(), len(pr.Commits))
+	if err != nil {
+		return nil, err
+	}
+	return &PullRequestSummary{Summary: prSummary, SquashMessage: squash}, nil
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Quality levels accepted by Options.Quality
+const (
+	QualityFast     = "fast"
+	QualityBalanced = "balanced"
+	QualityMax      = "max"
+)
+
+// QualityPreset bundles the provider-specific knobs behind a quality level,
+// so users pick speed against care without learning them
+type QualityPreset struct {
+	// Models maps a provider to the model of this level; providers not
+	// listed, like Ollama whose models the user installs, keep their model
+	Models map[string]string
+	// ThinkingBudget is the number of tokens a model that supports
+	// thinking may think for before answering; 0 disables thinking
+	ThinkingBudget int32
+	// Candidates is the number of messages to generate for the user to
+	// pick from when a person is there to pick
+	Candidates int
+	// SelfCritique reviews each message against the diff and revises it
+	// when the review finds problems, at the cost of one or two more calls
+	SelfCritique bool
+}
+
+// QualityPresets maps each quality level to its preset. QualityFast is
+// what commit-gen does without a quality level.
+var QualityPresets = map[string]QualityPreset{
+	QualityFast: {
+		Models:     map[string]string{ProviderGemini: "gemini-2.5-flash-lite", ProviderAnthropic: "claude-haiku-4-5"},
+		Candidates: 1,
+	},
+	QualityBalanced: {
+		Models:         map[string]string{ProviderGemini: "gemini-2.5-flash", ProviderAnthropic: "claude-sonnet-4-5"},
+		ThinkingBudget: 1024,
+		Candidates:     1,
+	},
+	QualityMax: {
+		Models:         map[string]string{ProviderGemini: "gemini-2.5-pro", ProviderAnthropic: "claude-opus-4-1"},
+		ThinkingBudget: 4096,
+		Candidates:     3,
+		SelfCritique:   true,
+	},
+}
+
+// Quality returns the preset of a quality level; an empty level is
+// QualityFast
+func Quality(level string) (QualityPreset, error) {
+	if level == "" {
+		level = QualityFast
+	}
+	preset, ok := QualityPresets[level]
+	if !ok {
+		levels := make([]string, 0, len(QualityPresets))
+		for name := range QualityPresets {
+			levels = append(levels, name)
+		}
+		slices.Sort(levels)
+		return QualityPreset{}, fmt.Errorf("unknown quality %q, use %s", level, strings.Join(levels, ", "))
+	}
+	return preset, nil
+}
+
+// model returns the model of the preset for provider, or "" to keep the
+// provider default
+func (p QualityPreset) model(provider string) string {
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	return p.Models[provider]
+}
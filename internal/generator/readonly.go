@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrReadOnly is returned instead of changing the repository while
+// read-only mode is on with Options.ReadOnly or ReadOnlyEnv
+var ErrReadOnly = errors.New("read-only mode is on")
+
+// ReadOnlyEnv switches read-only mode on when set to 1 (or true, yes, on),
+// e.g. for review bots and editor integrations that must never touch the
+// repository they look at
+const ReadOnlyEnv = "COMMITGEN_READ_ONLY"
+
+// ReadOnly reports whether ReadOnlyEnv switches read-only mode on
+func ReadOnly() bool {
+	return envEnabled(ReadOnlyEnv)
+}
+
+// checkWritable is the one check every change commit-gen makes to a
+// repository passes first: staging, committing, describing a change, and
+// the notes it keeps in the git directory. action says what was refused,
+// e.g. "stage changes".
+func checkWritable(readOnly bool, action string) error {
+	if readOnly || ReadOnly() {
+		return fmt.Errorf("%w, cannot %s", ErrReadOnly, action)
+	}
+	return nil
+}
+
+// Command is a command that changes the repository. Staging, committing,
+// and describing go through one, so callers can show exactly what is about
+// to run before it does.
+type Command struct {
+	Args []string
+	// Stdin is what the command reads, e.g. the commit message
+	Stdin string
+}
+
+// shellSafe matches arguments that need no quoting in a shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// String returns the command as it would be typed in a shell, followed by
+// the first line of its input as a comment
+func (c Command) String() string {
+	text := c.commandLine()
+	if line, _, _ := strings.Cut(strings.TrimSpace(c.Stdin), "\n"); line != "" {
+		text += "  # " + line
+	}
+	return text
+}
+
+// commandLine returns the arguments as they would be typed in a shell
+func (c Command) commandLine() string {
+	words := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		if shellSafe.MatchString(arg) {
+			words[i] = arg
+		} else {
+			words[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// writeGuard is what every change to a repository is checked against and
+// recorded in
+type writeGuard struct {
+	// readOnly refuses every change, see checkWritable
+	readOnly bool
+	// audit records every command that ran, if set
+	audit *auditLog
+}
+
+// SetReadOnly switches read-only mode on or off for the repository; with
+// ReadOnlyEnv set it stays on
+func (w *writeGuard) SetReadOnly(readOnly bool) {
+	w.readOnly = readOnly
+}
+
+// run runs the command in dir once checkWritable allows it, and records
+// it in the audit log. Its input, e.g. a commit message, is not recorded.
+func (c Command) run(dir string, guard writeGuard, action string) error {
+	if err := checkWritable(guard.readOnly, action); err != nil {
+		return err
+	}
+	_, err := runWithStdin(dir, c.Stdin, c.Args[0], c.Args[1:]...)
+	entry := AuditEntry{Event: AuditGit, Command: c.commandLine()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := guard.audit.record(entry); logErr != nil && err == nil {
+		return logErr
+	}
+	return err
+}
+
+// cacheWritable reports whether the best-effort caches in the git
+// directory may be written; read-only mode only rebuilds them in memory
+func cacheWritable(readOnly bool) bool {
+	return !readOnly && !ReadOnly()
+}
@@ -0,0 +1,164 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultWrapWidth is the conventional git body width
+const DefaultWrapWidth = 72
+
+// listItemPattern matches a bullet or numbered list marker
+var listItemPattern = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])(\s+|$)`)
+
+// blockStartPattern matches a word that starts a list item or a code
+// fence when it starts a line
+var blockStartPattern = regexp.MustCompile("^([-*+]|\\d+[.)]|```.*)$")
+
+// lineEndings turns CRLF and lone CR line endings into LF
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// Reflow hard-wraps body text to width columns. Paragraphs are refilled,
+// list items keep a hanging indent, and fenced or indented code, trailer
+// blocks, and `code spans` are never broken. Reflow is deterministic, so
+// applying it twice gives the same result.
+func Reflow(body string, width int) string {
+	if width <= 0 {
+		width = DefaultWrapWidth
+	}
+
+	lines := strings.Split(lineEndings.Replace(body), "\n")
+	var out []string
+	var paragraph []string
+
+	flush := func() {
+		out = append(out, reflowParagraph(paragraph, width)...)
+		paragraph = nil
+	}
+
+	inFence := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			inFence = !inFence
+			out = append(out, line)
+		case inFence:
+			out = append(out, line)
+		case trimmed == "":
+			flush()
+			out = append(out, "")
+		case len(paragraph) == 0 && isIndentedCode(line):
+			out = append(out, line)
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	return strings.Join(out, "\n")
+}
+
+// isIndentedCode reports whether line starts an indented code block
+func isIndentedCode(line string) bool {
+	return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") && !listItemPattern.MatchString(line)
+}
+
+// reflowParagraph refills a block of consecutive non-blank lines
+func reflowParagraph(lines []string, width int) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	// Trailers must stay one per line for git interpret-trailers
+	allTrailers := true
+	for _, line := range lines {
+		if !isTrailerLine(line) {
+			allTrailers = false
+			break
+		}
+	}
+	if allTrailers {
+		return lines
+	}
+
+	var out []string
+	var words []string
+	var first, hanging string
+
+	emit := func() {
+		if len(words) > 0 {
+			out = append(out, fill(words, first, hanging, width)...)
+		}
+		words = nil
+	}
+
+	for _, line := range lines {
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			emit()
+			first = m[1] + m[2] + " "
+			hanging = strings.Repeat(" ", len(first))
+			words = splitWords(line[len(m[0]):])
+			if len(words) == 0 {
+				// An empty list item has nothing to fill. It is kept as it
+				// is, since without the space after the marker it would no
+				// longer be a list item.
+				out = append(out, line)
+			}
+			continue
+		}
+		if len(words) == 0 {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			first, hanging = indent, indent
+		}
+		words = append(words, splitWords(line)...)
+	}
+	emit()
+
+	return out
+}
+
+// splitWords splits text on whitespace, keeping `code spans` whole
+func splitWords(text string) []string {
+	var words []string
+	var current strings.Builder
+	inSpan := false
+
+	for _, r := range text {
+		switch {
+		case r == '`':
+			inSpan = !inSpan
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inSpan:
+			if current.Len() > 0 {
+				words = append(words, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		words = append(words, current.String())
+	}
+	return words
+}
+
+// fill greedily packs words into lines of at most width columns. A word
+// longer than the width gets a line of its own rather than being broken,
+// and a word that would start a list item or a code fence at the start of
+// a line stays on the line before, so that reflowing again changes nothing.
+func fill(words []string, first, hanging string, width int) []string {
+	var lines []string
+	line := first + words[0]
+	for _, word := range words[1:] {
+		if len([]rune(line))+1+len([]rune(word)) > width && !blockStartPattern.MatchString(word) {
+			lines = append(lines, line)
+			line = hanging + word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
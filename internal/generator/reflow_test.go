@@ -0,0 +1,58 @@
+package generator
+
+import "testing"
+
+func TestReflow(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "paragraph",
+			body: "Fix the\nlogin of users.",
+			want: "Fix the\nlogin of\nusers.",
+		},
+		{
+			name: "list",
+			body: "- one two three\n- four",
+			want: "- one two\n  three\n- four",
+		},
+		{
+			name: "empty list item",
+			body: "- a\n- \n- b",
+			want: "- a\n- \n- b",
+		},
+		{
+			name: "trailers",
+			body: "Refs: #1\nSigned-off-by: A <a@example.com>",
+			want: "Refs: #1\nSigned-off-by: A <a@example.com>",
+		},
+		{
+			name: "code",
+			body: "```\nlong line of code\n```",
+			want: "```\nlong line of code\n```",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reflow(tt.body, 10); got != tt.want {
+				t.Errorf("Reflow(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzReflow(f *testing.F) {
+	f.Add("Fix the login of users whose session expired.", 72)
+	f.Add("- a\n- \n- b", 72)
+	f.Add("1. one\n2) two\n   more\n\n    code\n\tcode", 20)
+	f.Add("```\nfenced\n```\nSee `a very long code span` here.", 10)
+	f.Add("Body.\r\n\r\nRefs: #1\r\nSigned-off-by: A <a@example.com>", 0)
+	f.Fuzz(func(t *testing.T, body string, width int) {
+		once := Reflow(body, width)
+		if twice := Reflow(once, width); twice != once {
+			t.Errorf("Reflow is not idempotent:\n%q\n%q\n%q", body, once, twice)
+		}
+	})
+}
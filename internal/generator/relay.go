@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ProviderRelay sends the prepared prompt to a commit-gen relay, a company-run
+// `commit-gen serve` that holds the provider keys
+const ProviderRelay = "relay"
+
+// RelayPath is the relay endpoint, relative to the relay URL
+const RelayPath = "/v1/relay"
+
+// RelayRequest is the body of POST /v1/relay. Only the prompt leaves the
+// developer's machine; the relay picks the provider and holds its key.
+type RelayRequest struct {
+	// Model is empty to use the relay's default
+	Model           string   `json:"model,omitempty"`
+	SystemPrompt    string   `json:"system_prompt"`
+	Prompt          string   `json:"prompt"`
+	Temperature     *float32 `json:"temperature,omitempty"`
+	Seed            *int32   `json:"seed,omitempty"`
+	ThinkingBudget  *int32   `json:"thinking_budget,omitempty"`
+	MaxOutputTokens int      `json:"max_output_tokens,omitempty"`
+}
+
+// RelayResponse is the reply of POST /v1/relay
+type RelayResponse struct {
+	Text         string `json:"text,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Usage        Usage  `json:"usage"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	BlockReason  string `json:"block_reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// relayProvider talks to a commit-gen relay
+type relayProvider struct {
+	baseURL    string
+	token      func() (string, error)
+	httpClient *http.Client
+}
+
+// newRelayProvider creates a relay backed Provider authenticating with the
+// bearer token, e.g. an SSO token, that token returns
+func newRelayProvider(baseURL string, token func() (string, error), httpClient *http.Client) (*relayProvider, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("relay URL is required for the relay provider")
+	}
+	return &relayProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Name returns the provider identifier
+func (p *relayProvider) Name() string {
+	return ProviderRelay
+}
+
+// GenerateText forwards the request to the relay
+func (p *relayProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	body, err := json.Marshal(&RelayRequest{
+		Model:           req.Model,
+		SystemPrompt:    req.SystemPrompt,
+		Prompt:          req.Prompt,
+		Temperature:     req.Temperature,
+		Seed:            req.Seed,
+		ThinkingBudget:  req.ThinkingBudget,
+		MaxOutputTokens: req.MaxOutputTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode relay request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+RelayPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create relay request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if err := p.authorize(httpReq); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach relay: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relay response: %w", err)
+	}
+
+	var relayResp RelayResponse
+	if err := json.Unmarshal(respBody, &relayResp); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to decode relay response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		message := relayResp.Error
+		if message == "" {
+			message = strings.TrimSpace(string(respBody))
+		}
+		return nil, &ProviderError{Provider: ProviderRelay, StatusCode: resp.StatusCode, Message: message}
+	}
+
+	return &TextResponse{
+		Text:         relayResp.Text,
+		Model:        relayResp.Model,
+		Usage:        relayResp.Usage,
+		FinishReason: relayResp.FinishReason,
+		BlockReason:  relayResp.BlockReason,
+	}, nil
+}
+
+// Ping checks that the relay is up, through its health endpoint
+func (p *relayProvider) Ping(ctx context.Context, model string) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/healthz", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create relay request: %w", err)
+	}
+	if err := p.authorize(httpReq); err != nil {
+		return err
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach relay: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return &ProviderError{Provider: ProviderRelay, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// authorize adds the relay token, getting it first if need be
+func (p *relayProvider) authorize(req *http.Request) error {
+	token, err := p.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// relayToken returns RelayToken, or else the token RelayTokenSource gets
+func (c *GeneratorConfig) relayToken() (string, error) {
+	if c.RelayToken != "" || c.RelayTokenSource == nil {
+		return c.RelayToken, nil
+	}
+	token, err := c.RelayTokenSource()
+	if err != nil && !errors.Is(err, ErrRelayToken) {
+		err = fmt.Errorf("%w: %v", ErrRelayToken, err)
+	}
+	return token, err
+}
+
+// Close releases resources held by the provider
+func (p *relayProvider) Close() error {
+	return nil
+}
+
+// Complete runs a single prepared prompt on the configured provider, with
+// failover; this is what a relay serves. An empty model uses the default.
+func (c *CommitGen) Complete(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	if c.generator.config.Disabled {
+		return nil, ErrDisabled
+	}
+	if req.Model == "" {
+		req.Model = c.generator.config.Model
+	}
+	return c.generator.provider.GenerateText(ctx, req)
+}
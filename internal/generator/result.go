@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"time"
+)
+
+// Finish reasons normalized across providers
+const (
+	FinishReasonStop   = "stop"
+	FinishReasonLength = "length"
+	FinishReasonSafety = "safety"
+	FinishReasonOther  = "other"
+)
+
+// Result is a generated commit message together with its provenance
+type Result struct {
+	Message CommitMessage `json:"message"`
+	// Model is the model that produced the final message
+	Model string `json:"model"`
+	// Tokens is the total usage of every provider call made for this result
+	Tokens Usage `json:"tokens"`
+	// Latency is the wall time spent generating, including git collection
+	Latency time.Duration `json:"latency"`
+	// Cached is true when the message came from the response cache
+	Cached bool `json:"cached"`
+	// Attempts is the number of generations needed to pass validation
+	Attempts int `json:"attempts"`
+	// FinishReason tells why the model stopped; FinishReasonLength means the
+	// message was truncated
+	FinishReason string `json:"finish_reason"`
+	// UnsupportedClaims are the claims of the message the diff does not
+	// support and retries did not fix, with VerifyClaims
+	UnsupportedClaims []string `json:"unsupported_claims,omitempty"`
+	// DuplicateOf is the recent commit subject the message still nearly
+	// repeats after every attempt, with DedupeSubjects
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// Grounding maps the body to the diff, with Grounded
+	Grounding *Grounding `json:"grounding,omitempty"`
+}
+
+// Truncated reports whether the model stopped because it ran out of tokens
+func (r *Result) Truncated() bool {
+	return r.FinishReason == FinishReasonLength
+}
+
+// String returns the commit message text
+func (r *Result) String() string {
+	return r.Message.String()
+}
+
+// add accumulates the usage of another call
+func (u *Usage) add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.OutputTokens += other.OutputTokens
+	u.TotalTokens += other.TotalTokens
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Reword is a new message for an existing commit
+type Reword struct {
+	Commit Commit `json:"commit"`
+	// Result is the generated message
+	Result *Result `json:"result"`
+}
+
+// RewordRange generates a new message for every commit of revRange but
+// merges, e.g. "main..HEAD", oldest first, to clean up a branch before
+// opening a pull request. RebaseTodo and FilterRepoScript turn the result
+// into a script that puts the messages in place.
+func (c *CommitGen) RewordRange(ctx context.Context, revRange string) ([]Reword, error) {
+	return c.reword(ctx, revRange, func(Commit) bool { return true })
+}
+
+// reword generates a new message for the commits of revRange but merges
+// that keep selects
+func (c *CommitGen) reword(ctx context.Context, revRange string, keep func(Commit) bool) ([]Reword, error) {
+	commits, err := c.repo.GetCommits("--reverse", "--no-merges", revRange)
+	if err != nil {
+		return nil, err
+	}
+	var rewords []Reword
+	for _, commit := range commits {
+		if !keep(commit) {
+			continue
+		}
+		result, err := c.RewordCommit(ctx, commit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reword %s: %w", shortHash(commit.Hash), err)
+		}
+		rewords = append(rewords, Reword{Commit: commit, Result: result})
+	}
+	return rewords, nil
+}
+
+// RewordCommit generates a message for an existing commit from its diff,
+// imitating the history before it
+func (c *CommitGen) RewordCommit(ctx context.Context, commit Commit) (result *Result, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.RewordCommit")
+	defer func() { endSpan(span, err) }()
+
+	diff, err := c.repo.run("show", "--format=", "--patch", commit.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", shortHash(commit.Hash), err)
+	}
+	// The history before the commit, so the rewritten branch does not
+	// imitate the messages being replaced
+	history, _ := c.repo.run("log", "-10", "--no-merges", "--format=%s%n%n%b", commit.Hash+"^")
+	gitInfo := &GitInfo{
+		StagedDiff:    diff,
+		RecentCommits: history,
+		HasHistory:    history != "",
+		Hint:          c.hint,
+		Issue:         c.issue,
+	}
+	return c.generateAllowed(ctx, gitInfo, nil)
+}
+
+// RewriteMessages recreates the commits of base..HEAD, which must not
+// contain merges, with the new messages by hash, keeping their trees and
+// authors, and returns the command that moves the current branch to the
+// new commits. Nothing but new objects is written until that command runs,
+// and the old commits stay in the reflog.
+func (g *GitRepository) RewriteMessages(base string, messages map[string]string) (Command, error) {
+	if err := checkWritable(g.readOnly, "rewrite commits"); err != nil {
+		return Command{}, err
+	}
+	merges, err := g.run("rev-list", "--min-parents=2", base+"..HEAD")
+	if err != nil {
+		return Command{}, fmt.Errorf("failed to list the commits after %s: %w", base, err)
+	}
+	if strings.TrimSpace(merges) != "" {
+		return Command{}, fmt.Errorf("cannot rewrite %s..HEAD, it contains merges", base)
+	}
+	head, err := g.run("rev-parse", "HEAD")
+	if err != nil {
+		return Command{}, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	head = strings.TrimSpace(head)
+	commits, err := g.GetCommits("--reverse", base+"..HEAD")
+	if err != nil {
+		return Command{}, err
+	}
+
+	// Commits before the first reworded one keep their hashes
+	parent := ""
+	for _, commit := range commits {
+		message, reworded := messages[commit.Hash]
+		if parent == "" && !reworded {
+			continue
+		}
+		if parent == "" {
+			if parent, err = g.run("rev-parse", commit.Hash+"^"); err != nil {
+				return Command{}, fmt.Errorf("failed to resolve the parent of %s: %w", shortHash(commit.Hash), err)
+			}
+			parent = strings.TrimSpace(parent)
+		}
+		if !reworded {
+			message = commit.Message
+		}
+		if parent, err = g.commitTree(commit, parent, message); err != nil {
+			return Command{}, err
+		}
+	}
+	if parent == "" {
+		return Command{}, errors.New("no commit to reword")
+	}
+	return Command{Args: []string{"git", "update-ref", "-m", "commit-gen: reword", "HEAD", parent, head}}, nil
+}
+
+// commitTree creates a copy of commit on parent with message and returns
+// its hash; the author and the author date are kept
+func (g *GitRepository) commitTree(commit Commit, parent, message string) (string, error) {
+	cmd := exec.Command("git", "commit-tree", "-p", parent, "-F", "-", commit.Hash+"^{tree}")
+	if g.workingDir != "" {
+		cmd.Dir = g.workingDir
+	}
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+commit.AuthorName,
+		"GIT_AUTHOR_EMAIL="+commit.AuthorEmail,
+		"GIT_AUTHOR_DATE="+commit.Date.Format(time.RFC3339),
+	)
+	cmd.Stdin = strings.NewReader(message)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite commit %s: %w", shortHash(commit.Hash), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// RewriteCommand prepares the commits of base..HEAD with the messages of
+// rewords and returns the command Rewrite runs to move the branch to them
+func (c *CommitGen) RewriteCommand(base string, rewords []Reword) (Command, error) {
+	messages := make(map[string]string, len(rewords))
+	for _, reword := range rewords {
+		messages[reword.Commit.Hash] = reword.Result.String()
+	}
+	return c.repo.RewriteMessages(base, messages)
+}
+
+// Rewrite moves the current branch to the commits RewriteCommand prepared
+func (c *CommitGen) Rewrite(command Command) error {
+	return c.repo.UpdateRef(command)
+}
+
+// UpdateRef runs the command RewriteMessages returned
+func (g *GitRepository) UpdateRef(command Command) error {
+	if err := command.run(g.workingDir, g.writeGuard, "rewrite commits"); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return err
+		}
+		return fmt.Errorf("failed to move the branch: %w", err)
+	}
+	return nil
+}
+
+// shortHash abbreviates a commit hash for messages
+func shortHash(hash string) string {
+	return hash[:min(len(hash), 12)]
+}
+
+// rewriteWarning heads every script that rewrites history
+const rewriteWarning = `WARNING: this rewrites history. Every reworded commit, and every commit
+after it, gets a new hash. Do not run it on commits others have already
+pulled, and read every message below first: they were written by a model.`
+
+// RebaseTodo returns a git rebase todo list that picks the commits of
+// rewords and amends each with its new message, for
+//
+//	git -c sequence.editor="cp todo" rebase -i <base>
+//
+// rewords must hold every commit after base, as RewordRange returns them
+// for a range ending at HEAD without merges.
+func RebaseTodo(base string, rewords []Reword) string {
+	var b strings.Builder
+	for _, line := range strings.Split(rewriteWarning, "\n") {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	b.WriteString("# The old commits stay in the reflog until it expires.\n")
+	fmt.Fprintf(&b, "#\n# Use it as the todo of: git rebase -i %s\n\n", base)
+	for _, reword := range rewords {
+		subject, _, _ := strings.Cut(reword.Commit.Message, "\n")
+		fmt.Fprintf(&b, "pick %s %s\n", shortHash(reword.Commit.Hash), subject)
+		printf := Command{Args: append([]string{"printf", `%s\n`}, strings.Split(reword.Result.String(), "\n")...)}
+		fmt.Fprintf(&b, "exec %s | git commit --amend --quiet --cleanup=whitespace --file=-\n", printf.commandLine())
+	}
+	return b.String()
+}
+
+// FilterRepoScript returns a shell script that runs git filter-repo on
+// refs, e.g. "main..feature", replacing the message of every commit of
+// rewords
+func FilterRepoScript(refs string, rewords []Reword) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	for _, line := range strings.Split(rewriteWarning, "\n") {
+		fmt.Fprintf(&b, "# %s\n", line)
+	}
+	b.WriteString("# git filter-repo also expires the reflog, so back up the repository first.\n")
+	b.WriteString("# It refuses to run outside a fresh clone unless --force is added.\n\n")
+	b.WriteString("git filter-repo --refs " + (Command{Args: []string{refs}}).commandLine() + " --commit-callback '\n")
+	b.WriteString("messages = {\n")
+	for _, reword := range rewords {
+		fmt.Fprintf(&b, "    b\"%s\": %s,\n", reword.Commit.Hash, pythonBytes(reword.Result.String()+"\n"))
+	}
+	b.WriteString("}\n")
+	b.WriteString("commit.message = messages.get(commit.original_id, commit.message)\n")
+	b.WriteString("'\n")
+	return b.String()
+}
+
+// pythonBytes returns s as a Python bytes literal that is also safe inside
+// a single-quoted shell argument: quotes, backslashes, and everything but
+// printable ASCII are escaped
+func pythonBytes(s string) string {
+	var b strings.Builder
+	b.WriteString(`b"`)
+	for _, c := range []byte(s) {
+		switch {
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '"' || c == '\'' || c == '\\' || c < ' ' || c > '~':
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString(`"`)
+	return b.String()
+}
+
+// ResolveCommit returns the hash of the commit rev names
+func (g *GitRepository) ResolveCommit(rev string) (string, error) {
+	output, err := g.run("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %s", rev)
+	}
+	return strings.TrimSpace(output), nil
+}
@@ -0,0 +1,185 @@
+package generator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// manifestPatterns are the files that mark a module/package boundary
+var manifestPatterns = []string{
+	":(glob)**/go.mod",
+	":(glob)**/package.json",
+	":(glob)**/Cargo.toml",
+}
+
+// moduleCacheFile is the module map cache inside the cache directory
+const moduleCacheFile = "modules.json"
+
+// Module is a package rooted at a directory of the repository
+type Module struct {
+	// Dir is relative to the repository root ("." for the root)
+	Dir  string `json:"dir"`
+	Name string `json:"name"`
+}
+
+// ModuleMap maps repository directories to package names
+type ModuleMap struct {
+	// Fingerprint identifies the manifest contents the map was built from
+	Fingerprint string   `json:"fingerprint"`
+	Modules     []Module `json:"modules"`
+}
+
+// CacheDir returns the commit-gen cache directory inside the git directory
+func (g *GitRepository) CacheDir() (string, error) {
+	gitDir, err := g.run("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	return filepath.Join(strings.TrimSpace(gitDir), "commitgen-cache"), nil
+}
+
+// run executes a git command in the working directory and returns its stdout
+func (g *GitRepository) run(args ...string) (string, error) {
+	return runIn(g.workingDir, "git", args...)
+}
+
+// GetStagedFiles returns the paths of the staged files relative to the repository root
+func (g *GitRepository) GetStagedFiles() ([]string, error) {
+	output, err := g.run("diff", "--staged", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+	return strings.Fields(output), nil
+}
+
+// GetModuleMap returns the repository's module map, rebuilding the cached
+// copy only when a manifest changed
+func (g *GitRepository) GetModuleMap() (*ModuleMap, error) {
+	// The index entries carry the blob hash of every manifest, so hashing
+	// them detects changes without reading a single file
+	entries, err := g.run(append([]string{"ls-files", "-s", "--"}, manifestPatterns...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list manifests: %w", err)
+	}
+	sum := sha256.Sum256([]byte(entries))
+	fingerprint := hex.EncodeToString(sum[:])
+
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(cacheDir, moduleCacheFile)
+
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var cached ModuleMap
+		if json.Unmarshal(data, &cached) == nil && cached.Fingerprint == fingerprint {
+			return &cached, nil
+		}
+	}
+
+	root, err := g.run("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate repository root: %w", err)
+	}
+
+	modules := &ModuleMap{Fingerprint: fingerprint}
+	scanner := bufio.NewScanner(strings.NewReader(entries))
+	for scanner.Scan() {
+		// "<mode> <object> <stage>\t<path>"
+		_, file, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		name := readManifestName(filepath.Join(strings.TrimSpace(root), file))
+		if name == "" {
+			continue
+		}
+		modules.Modules = append(modules.Modules, Module{Dir: path.Dir(file), Name: name})
+	}
+
+	// Deepest directories first so lookups find the innermost module
+	sort.Slice(modules.Modules, func(i, j int) bool {
+		return len(modules.Modules[i].Dir) > len(modules.Modules[j].Dir)
+	})
+
+	// Caching is best-effort, a read-only .git must not break generation
+	if data, err := json.Marshal(modules); err == nil && cacheWritable(g.readOnly) {
+		if os.MkdirAll(cacheDir, 0o755) == nil {
+			os.WriteFile(cachePath, data, 0o644)
+		}
+	}
+
+	return modules, nil
+}
+
+// readManifestName extracts the package name from a manifest file
+func readManifestName(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+
+	switch filepath.Base(file) {
+	case "go.mod":
+		for _, line := range strings.Split(string(data), "\n") {
+			if modulePath, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+				return path.Base(strings.Trim(strings.TrimSpace(modulePath), `"`))
+			}
+		}
+	case "package.json":
+		var pkg struct {
+			Name string `json:"name"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			// "@org/name" -> "name"
+			return path.Base(pkg.Name)
+		}
+	case "Cargo.toml":
+		var cargo struct {
+			Package struct {
+				Name string `toml:"name"`
+			} `toml:"package"`
+		}
+		if _, err := toml.Decode(string(data), &cargo); err == nil {
+			return cargo.Package.Name
+		}
+	}
+	return ""
+}
+
+// ModuleFor returns the innermost module containing file, if any
+func (m *ModuleMap) ModuleFor(file string) (Module, bool) {
+	for _, module := range m.Modules {
+		if module.Dir == "." || strings.HasPrefix(file, module.Dir+"/") {
+			return module, true
+		}
+	}
+	return Module{}, false
+}
+
+// InferScope returns the name of the module containing every file, or ""
+// when the files span several modules or only touch the repository root
+func (m *ModuleMap) InferScope(files []string) string {
+	var scope string
+	for _, file := range files {
+		module, ok := m.ModuleFor(file)
+		if !ok || module.Dir == "." {
+			return ""
+		}
+		if scope != "" && scope != module.Name {
+			return ""
+		}
+		scope = module.Name
+	}
+	return scope
+}
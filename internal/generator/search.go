@@ -0,0 +1,267 @@
+package generator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// embeddingsCacheFile is the history index inside the cache directory
+	embeddingsCacheFile = "embeddings.json"
+	// maxIndexedCommits bounds the history that is embedded, newest first
+	maxIndexedCommits = 10000
+	// embedBatchSize is the number of commits embedded per request
+	embedBatchSize = 100
+	// maxIndexedFiles caps the file names embedded with each commit
+	maxIndexedFiles = 20
+)
+
+// SearchResult is a commit matching a search query
+type SearchResult struct {
+	Commit
+	// Score is the cosine similarity to the query, higher is closer
+	Score float64 `json:"score"`
+}
+
+// historyIndex holds an embedding of every indexed commit, by hash. It is
+// only valid for the model that built it.
+type historyIndex struct {
+	Model   string            `json:"model"`
+	Vectors map[string]vector `json:"vectors"`
+}
+
+// vector is a unit-length embedding, stored as base64 of little-endian
+// float32 values to keep the index small
+type vector []float32
+
+// MarshalJSON encodes the vector as a base64 string
+func (v vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+// UnmarshalJSON decodes a vector written by MarshalJSON
+func (v *vector) UnmarshalJSON(data []byte) error {
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(buf)%4 != 0 {
+		return errors.New("invalid embedding vector")
+	}
+	*v = make(vector, len(buf)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return nil
+}
+
+// normalize scales v to unit length, so that a dot product of two vectors
+// is their cosine similarity
+func normalize(v []float32) vector {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return v
+	}
+	out := make(vector, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+// dot returns the dot product of two vectors of the same model
+func dot(a, b vector) float64 {
+	var sum float64
+	for i := range min(len(a), len(b)) {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+// Search returns the limit commits of the current branch whose messages
+// and files best match query by meaning, e.g. "when did we change retry
+// backoff". Commits not yet in the embeddings index are embedded first,
+// so the first search of a repository embeds its history.
+func (c *CommitGen) Search(ctx context.Context, query string, limit int) (results []SearchResult, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Search")
+	defer func() { endSpan(span, err) }()
+
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("the search query is empty")
+	}
+	commits, index, err := c.updateIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	g := c.generator
+	embedCtx, cancel := context.WithTimeout(ctx, g.config.Timeout)
+	defer cancel()
+	vectors, err := embed(embedCtx, g.provider, &EmbedRequest{
+		Model: g.config.EmbeddingModel,
+		Texts: []string{query},
+		Query: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed the query: %w", err)
+	}
+	queryVector := normalize(vectors[0])
+
+	for _, commit := range commits {
+		if v, ok := index.Vectors[commit.Hash]; ok {
+			results = append(results, SearchResult{Commit: commit, Score: dot(queryVector, v)})
+		}
+	}
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return results[:min(len(results), limit)], nil
+}
+
+// updateIndex embeds the commits of the current branch missing from the
+// index and saves it, returning the commits along with the index
+func (c *CommitGen) updateIndex(ctx context.Context) ([]Commit, *historyIndex, error) {
+	g := c.generator
+	if g.config.Disabled {
+		return nil, nil, ErrDisabled
+	}
+	if g.config.EmbeddingModel == "" {
+		return nil, nil, fmt.Errorf("no embedding model is known for provider %s, set embedding_model", g.provider.Name())
+	}
+
+	commits, err := c.repo.GetCommits("--no-merges", fmt.Sprintf("-%d", maxIndexedCommits))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(commits) == 0 {
+		return nil, nil, fmt.Errorf("%w: the repository has no commits", ErrNoChanges)
+	}
+
+	index := c.repo.loadIndex(g.config.EmbeddingModel)
+	var missing []Commit
+	for _, commit := range commits {
+		if _, ok := index.Vectors[commit.Hash]; !ok {
+			missing = append(missing, commit)
+		}
+	}
+	if len(missing) == 0 {
+		return commits, index, nil
+	}
+
+	files := c.repo.commitFiles(len(commits))
+	for batch := range slices.Chunk(missing, embedBatchSize) {
+		texts := make([]string, len(batch))
+		for i, commit := range batch {
+			texts[i] = indexText(commit, files[commit.Hash])
+		}
+
+		embedCtx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+		vectors, err := embed(embedCtx, g.provider, &EmbedRequest{Model: g.config.EmbeddingModel, Texts: texts})
+		cancel()
+		if err != nil {
+			// Keep what was embedded so far for the next run
+			c.repo.saveIndex(index)
+			return nil, nil, fmt.Errorf("failed to embed commit history: %w", err)
+		}
+		for i, commit := range batch {
+			index.Vectors[commit.Hash] = normalize(vectors[i])
+		}
+	}
+	c.repo.saveIndex(index)
+	return commits, index, nil
+}
+
+// indexText is what gets embedded for a commit: its message and the files
+// it changed
+func indexText(commit Commit, files []string) string {
+	text := truncateBytes(commit.Message, maxHistoryMessageBytes)
+	if len(files) > maxIndexedFiles {
+		files = append(files[:maxIndexedFiles:maxIndexedFiles], "...")
+	}
+	if len(files) > 0 {
+		text += "\n\nFiles: " + strings.Join(files, ", ")
+	}
+	return text
+}
+
+// commitFiles returns the files changed by each of the last count commits,
+// by hash; it is best-effort context, so errors yield an empty map
+func (g *GitRepository) commitFiles(count int) map[string][]string {
+	files := make(map[string][]string)
+	output, err := g.run("log", "--no-merges", fmt.Sprintf("-%d", count), "--format=%x1e%H", "--name-only")
+	if err != nil {
+		return files
+	}
+	for _, record := range strings.Split(output, "\x1e") {
+		hash, names, _ := strings.Cut(strings.TrimSpace(record), "\n")
+		for _, name := range strings.Split(names, "\n") {
+			if name = strings.TrimSpace(name); name != "" {
+				files[hash] = append(files[hash], name)
+			}
+		}
+	}
+	return files
+}
+
+// loadIndex reads the history index for model, or returns an empty one
+// when there is none or it was built with another model
+func (g *GitRepository) loadIndex(model string) *historyIndex {
+	index := &historyIndex{Model: model, Vectors: make(map[string]vector)}
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return index
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, embeddingsCacheFile))
+	if err != nil {
+		return index
+	}
+	var cached historyIndex
+	if json.Unmarshal(data, &cached) == nil && cached.Model == model && cached.Vectors != nil {
+		return &cached
+	}
+	return index
+}
+
+// saveIndex writes the history index; like the module map, caching is
+// best-effort and a read-only .git only means embedding again next time
+func (g *GitRepository) saveIndex(index *historyIndex) {
+	cacheDir, err := g.CacheDir()
+	if err != nil || !cacheWritable(g.readOnly) {
+		return
+	}
+	data, err := json.Marshal(index)
+	if err != nil || os.MkdirAll(cacheDir, 0o755) != nil {
+		return
+	}
+	path := filepath.Join(cacheDir, embeddingsCacheFile)
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if os.WriteFile(tmp, data, 0o644) != nil {
+		os.Remove(tmp)
+		return
+	}
+	os.Rename(tmp, path)
+}
@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SemanticReleaseTypes are the commit types of semantic-release's default
+// (angular) preset
+var SemanticReleaseTypes = []string{"feat", "fix", "perf", "build", "chore", "ci", "docs", "refactor", "revert", "style", "test"}
+
+// Release types that a commit triggers in semantic-release
+const (
+	ReleaseMajor = "major"
+	ReleaseMinor = "minor"
+	ReleasePatch = "patch"
+)
+
+var (
+	// releaseHeaderPattern is the header grammar of the angular preset of
+	// conventional-changelog, which semantic-release uses by default. It
+	// has no "!" marker, so "feat!: x" is not a commit to it at all.
+	releaseHeaderPattern = regexp.MustCompile(`^(\w*)(?:\((.*)\))?: (.*)$`)
+	// releaseNotePattern matches the start of a breaking change note
+	releaseNotePattern = regexp.MustCompile(`^[\s|*]*(BREAKING CHANGE|BREAKING CHANGES)[:\s]+(.*)$`)
+)
+
+// ReleaseCommit is a commit message as semantic-release parses it
+type ReleaseCommit struct {
+	Type    string `json:"type"`
+	Scope   string `json:"scope,omitempty"`
+	Subject string `json:"subject"`
+	// Notes are the texts of the BREAKING CHANGE notes
+	Notes []string `json:"notes,omitempty"`
+}
+
+// ParseReleaseCommit parses message with the grammar of semantic-release's
+// default commit analyzer, failing when it would not see a commit there
+func ParseReleaseCommit(message string) (*ReleaseCommit, error) {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	header, body, _ := strings.Cut(message, "\n")
+	m := releaseHeaderPattern.FindStringSubmatch(header)
+	if m == nil || m[1] == "" {
+		return nil, fmt.Errorf("header %q does not match type(scope): subject", header)
+	}
+
+	commit := &ReleaseCommit{Type: m[1], Scope: m[2], Subject: m[3]}
+	for _, line := range strings.Split(body, "\n") {
+		if note := releaseNotePattern.FindStringSubmatch(line); note != nil {
+			commit.Notes = append(commit.Notes, strings.TrimSpace(note[2]))
+		} else if len(commit.Notes) > 0 {
+			// Later lines continue the note, as in the angular parser
+			last := &commit.Notes[len(commit.Notes)-1]
+			*last = strings.TrimSpace(*last + "\n" + line)
+		}
+	}
+	return commit, nil
+}
+
+// ReleaseType returns the release the commit triggers under the default
+// release rules, or "" for none
+func (c *ReleaseCommit) ReleaseType() string {
+	switch {
+	case len(c.Notes) > 0:
+		return ReleaseMajor
+	case c.Type == "feat":
+		return ReleaseMinor
+	case c.Type == "fix" || c.Type == "perf":
+		return ReleasePatch
+	}
+	return ""
+}
+
+// toSemanticRelease rewrites what the model gets wrong most often: a "!"
+// marker becomes a BREAKING CHANGE footer, and the type is lowercased
+func toSemanticRelease(msg CommitMessage) CommitMessage {
+	if msg.Type == "" {
+		return msg
+	}
+	prefix := strings.TrimSuffix(msg.Header, msg.Subject)
+	marked := strings.HasSuffix(prefix, "!: ")
+	if !marked && strings.HasPrefix(prefix, msg.Type) {
+		return msg
+	}
+
+	header := SubjectPrefix(msg.Type, msg.Scope) + msg.Subject
+	body := msg.Body
+	if marked && len(msg.GetTrailers("BREAKING CHANGE")) == 0 && len(msg.GetTrailers("BREAKING-CHANGE")) == 0 {
+		footer := "BREAKING CHANGE: " + msg.Subject
+		if body == "" {
+			body = footer
+		} else if _, trailers := splitTrailers(body); len(trailers) > 0 {
+			body += "\n" + footer
+		} else {
+			body += "\n\n" + footer
+		}
+	}
+	return ParseCommitMessage(header + "\n\n" + body)
+}
+
+// validateSemanticRelease reports where semantic-release would read msg
+// differently than intended
+func validateSemanticRelease(msg CommitMessage, add func(rule, format string, args ...any)) {
+	commit, err := ParseReleaseCommit(msg.String())
+	if err != nil {
+		add("semantic-release", "semantic-release cannot parse the message: %v; do not use \"!\" in the header", err)
+		return
+	}
+	if !slices.Contains(SemanticReleaseTypes, commit.Type) {
+		add("semantic-release", "semantic-release does not know type %q, use one of: %s", commit.Type, strings.Join(SemanticReleaseTypes, ", "))
+	}
+	if msg.Breaking && len(commit.Notes) == 0 {
+		add("semantic-release", "the breaking change must be described in a footer line starting with \"BREAKING CHANGE: \"")
+	}
+}
@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// HasUnstagedChanges reports whether tracked files have modifications that
+// are not staged
+func (g *GitRepository) HasUnstagedChanges() (bool, error) {
+	_, err := g.run("diff", "--quiet")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check for unstaged changes: %w", err)
+	}
+	return false, nil
+}
+
+// StageAll stages every modification and deletion of tracked files, like
+// git commit -a; untracked files are left alone
+func (g *GitRepository) StageAll() error {
+	if err := g.StageAllCommand().run(g.workingDir, g.writeGuard, "stage changes"); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return err
+		}
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	return nil
+}
+
+// StageAllCommand returns the command StageAll runs
+func (g *GitRepository) StageAllCommand() Command {
+	return Command{Args: []string{"git", "add", "--update"}}
+}
+
+// stageIfEmpty stages all tracked modifications when nothing is staged yet
+func (g *GitRepository) stageIfEmpty() error {
+	staged, err := g.HasStagedChanges()
+	if err != nil || staged {
+		return err
+	}
+	return g.StageAll()
+}
@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// generationsCacheFile records the messages generated for staged changes
+// inside the cache directory, one JSON object per line
+const generationsCacheFile = "generations.jsonl"
+
+// generationRecord is a message generated for the staged changes. Tree is
+// the tree the index would be committed as, so the commit it ended up in
+// can be found however its message was edited.
+type generationRecord struct {
+	Time   time.Time `json:"time"`
+	Tree   string    `json:"tree"`
+	Header string    `json:"header"`
+	Model  string    `json:"model,omitempty"`
+}
+
+// recordGeneration appends the generated message to the generations log.
+// Like the other caches it is best-effort: failures only leave the message
+// out of Stats.
+func (g *GitRepository) recordGeneration(result *Result) {
+	cacheDir, err := g.CacheDir()
+	if err != nil || !cacheWritable(g.readOnly) {
+		return
+	}
+	tree, err := g.run("write-tree")
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(generationRecord{
+		Time:   time.Now().UTC(),
+		Tree:   strings.TrimSpace(tree),
+		Header: result.Message.Header,
+		Model:  result.Model,
+	})
+	if err != nil || os.MkdirAll(cacheDir, 0o755) != nil {
+		return
+	}
+	file, err := os.OpenFile(filepath.Join(cacheDir, generationsCacheFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(append(data, '\n'))
+}
+
+// loadGenerations reads the generations log, oldest first
+func (g *GitRepository) loadGenerations() []generationRecord {
+	cacheDir, err := g.CacheDir()
+	if err != nil {
+		return nil
+	}
+	file, err := os.Open(filepath.Join(cacheDir, generationsCacheFile))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var records []generationRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record generationRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil && record.Tree != "" {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// latestGenerations maps each tree to the last message generated for it,
+// as a regenerated message replaces the one before
+func latestGenerations(records []generationRecord) map[string]generationRecord {
+	latest := make(map[string]generationRecord)
+	for _, record := range records {
+		latest[record.Tree] = record
+	}
+	return latest
+}
+
+// treeCommit is the tree a commit records and its header
+type treeCommit struct {
+	Tree   string
+	Header string
+}
+
+// recentTrees returns the tree and header of the last count commits,
+// merges excluded, newest first
+func (g *GitRepository) recentTrees(count int) ([]treeCommit, error) {
+	output, err := g.run("log", "--no-merges", fmt.Sprintf("-%d", count), "--format=%T%x1f%s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commits: %w", err)
+	}
+	var commits []treeCommit
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if tree, header, ok := strings.Cut(line, "\x1f"); ok {
+			commits = append(commits, treeCommit{Tree: tree, Header: header})
+		}
+	}
+	return commits, nil
+}
+
+// TypeStats is the distribution of Conventional Commits types and scopes
+// over a set of messages
+type TypeStats struct {
+	Messages int `json:"messages"`
+	// Conventional is the number of messages with a Conventional Commits header
+	Conventional int         `json:"conventional"`
+	Types        []TermCount `json:"types,omitempty"`
+	Scopes       []TermCount `json:"scopes,omitempty"`
+}
+
+// newTypeStats counts the types and scopes of headers
+func newTypeStats(headers []string) *TypeStats {
+	stats := &TypeStats{Messages: len(headers)}
+	types := make(map[string]int)
+	scopes := make(map[string]int)
+	for _, header := range headers {
+		msg := ParseCommitMessage(header)
+		if msg.Type == "" {
+			continue
+		}
+		stats.Conventional++
+		types[msg.Type]++
+		if msg.Scope != "" {
+			scopes[msg.Scope]++
+		}
+	}
+	stats.Types = rankTerms(types)
+	stats.Scopes = rankTerms(scopes)
+	return stats
+}
+
+// GenerationStats compares the generated messages that were committed
+// with the messages they were committed with
+type GenerationStats struct {
+	// Generated is the number of changes a message was generated for
+	Generated int `json:"generated"`
+	// Committed is the number of those changes found in the history
+	Committed int `json:"committed"`
+	// Unchanged were committed with the generated header as it was;
+	// TypeChanged and ScopeChanged had their type or scope edited
+	Unchanged    int `json:"unchanged"`
+	TypeChanged  int `json:"type_changed"`
+	ScopeChanged int `json:"scope_changed"`
+	// Suggested and Accepted are the types and scopes of the committed
+	// changes as generated and as committed
+	Suggested *TypeStats `json:"suggested"`
+	Accepted  *TypeStats `json:"accepted"`
+}
+
+// Stats summarizes the types and scopes of commit messages
+type Stats struct {
+	// Commits is the number of commits looked at, merges excluded
+	Commits int `json:"commits"`
+	// History is the distribution over those commits, when asked for
+	History *TypeStats `json:"history,omitempty"`
+	// Generations compares generated and committed messages
+	Generations *GenerationStats `json:"generations"`
+}
+
+// Stats summarizes the last count commits: how the messages commit-gen
+// generated for them compare to the ones committed, and with history, the
+// type and scope distribution of all of them. Messages are recorded when
+// generated for staged changes, so only commits made since then count.
+func (g *GitRepository) Stats(count int, history bool) (*Stats, error) {
+	commits, err := g.recentTrees(count)
+	if err != nil {
+		return nil, err
+	}
+
+	generations := latestGenerations(g.loadGenerations())
+	stats := &Stats{Commits: len(commits), Generations: &GenerationStats{Generated: len(generations)}}
+	var headers, suggested, accepted []string
+	for _, commit := range commits {
+		header := commit.Header
+		headers = append(headers, header)
+
+		record, ok := generations[commit.Tree]
+		if !ok {
+			continue
+		}
+		// A change committed twice, e.g. after a revert, counts once
+		delete(generations, commit.Tree)
+		suggested = append(suggested, record.Header)
+		accepted = append(accepted, header)
+
+		gen := stats.Generations
+		gen.Committed++
+		was, is := ParseCommitMessage(record.Header), ParseCommitMessage(header)
+		switch {
+		case record.Header == header:
+			gen.Unchanged++
+		case was.Type != is.Type:
+			gen.TypeChanged++
+		case was.Scope != is.Scope:
+			gen.ScopeChanged++
+		}
+	}
+
+	stats.Generations.Suggested = newTypeStats(suggested)
+	stats.Generations.Accepted = newTypeStats(accepted)
+	if history {
+		stats.History = newTypeStats(headers)
+	}
+	return stats, nil
+}
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/prompts"
+)
+
+// Summary is a prose summary of a range of commits, written for people
+// rather than for the commit log
+type Summary struct {
+	Text string `json:"text"`
+	// Commits is the number of commits summarized
+	Commits int    `json:"commits"`
+	Model   string `json:"model"`
+	Tokens  Usage  `json:"tokens"`
+}
+
+// PushSummary summarizes the commits a push would publish, those in
+// base..HEAD, as an update to paste into Slack or Teams, e.g.
+// PushSummary(ctx, "@{upstream}")
+func (c *CommitGen) PushSummary(ctx context.Context, base string) (summary *Summary, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.PushSummary")
+	defer func() { endSpan(span, err) }()
+
+	if _, err := c.repo.run("rev-parse", "--verify", "--quiet", base); err != nil {
+		return nil, fmt.Errorf("cannot resolve %s, push with -u once to set an upstream or name the base to compare to", base)
+	}
+	commits, err := c.repo.GetCommits("--reverse", "--no-merges", base+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("%w: HEAD has no commits that are not in %s", ErrNoChanges, base)
+	}
+	// The size of the push is context only
+	shortstat, _ := c.repo.run("diff", "--shortstat", base+"...HEAD")
+
+	return c.generator.summarize(ctx, prompts.Template(prompts.PushSummary), formatCommitLog(commits, shortstat), len(commits))
+}
+
+// StandupOptions selects the commits a standup update covers
+type StandupOptions struct {
+	// Repos are the repository directories to look at (default:
+	// Options.Repos, or else the working directory)
+	Repos []string
+	// Since is a git date, e.g. "yesterday" or "2026-10-16 00:00"
+	Since string
+	// Author filters commits like git log --author; StandupMe stands for
+	// the user.email of each repository
+	Author string
+}
+
+// StandupMe is the StandupOptions.Author of the user's own commits
+const StandupMe = "me"
+
+// Standup summarizes the author's recent commits on every local branch of
+// the repositories as a short update for a daily standup
+func (c *CommitGen) Standup(ctx context.Context, opts *StandupOptions) (summary *Summary, err error) {
+	ctx, span := tracer.Start(ctx, "commitgen.Standup")
+	defer func() { endSpan(span, err) }()
+
+	var log strings.Builder
+	total := 0
+	for _, dir := range c.reposOr(opts.Repos) {
+		repo := NewGitRepository(dir)
+		name := repo.name()
+
+		author := opts.Author
+		if author == StandupMe {
+			email, err := repo.run("config", "user.email")
+			if err != nil || strings.TrimSpace(email) == "" {
+				return nil, fmt.Errorf("%s: user.email is not set, name the author instead", name)
+			}
+			author = strings.TrimSpace(email)
+		}
+		args := []string{"--reverse", "--no-merges", "--branches"}
+		if opts.Since != "" {
+			args = append(args, "--since="+opts.Since)
+		}
+		if author != "" {
+			args = append(args, "--author="+author)
+		}
+		commits, err := repo.GetCommits(args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(commits) > 0 {
+			total += len(commits)
+			fmt.Fprintf(&log, "=== Repository %s ===\n%s\n", name, formatCommitLog(commits, ""))
+		}
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: no commits since %s", ErrNoChanges, opts.Since)
+	}
+
+	return c.generator.summarize(ctx, prompts.Template(prompts.Standup), log.String(), total)
+}
+
+// reposOr returns dirs, or else the configured repositories, or else the
+// working directory
+func (c *CommitGen) reposOr(dirs []string) []string {
+	if len(dirs) == 0 {
+		dirs = c.repos
+	}
+	if len(dirs) == 0 {
+		dirs = []string{c.repo.workingDir}
+	}
+	return dirs
+}
+
+// name returns the directory name of the repository root
+func (g *GitRepository) name() string {
+	if root, err := g.run("rev-parse", "--show-toplevel"); err == nil {
+		return filepath.Base(strings.TrimSpace(root))
+	}
+	return g.workingDir
+}
+
+// formatCommitLog lists commits, oldest first, for a summary prompt
+func formatCommitLog(commits []Commit, shortstat string) string {
+	var b strings.Builder
+	for _, commit := range commits {
+		fmt.Fprintf(&b, "--- %s by %s, %s ---\n%s\n\n",
+			commit.Hash[:min(len(commit.Hash), 12)], commit.AuthorName, commit.Date.Format("Mon Jan 2 15:04"), commit.Message)
+	}
+	if shortstat = strings.TrimSpace(shortstat); shortstat != "" {
+		fmt.Fprintf(&b, "In total: %s\n", shortstat)
+	}
+	return b.String()
+}
+
+// summarize asks the model to summarize the commit log in prompt
+func (g *CommitMessageGenerator) summarize(ctx context.Context, systemPrompt, prompt string, count int) (*Summary, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout*3)
+	defer cancel()
+
+	resp, err := g.callProvider(ctx, g.provider, &TextRequest{
+		Model:           g.config.Model,
+		SystemPrompt:    systemPrompt,
+		Prompt:          prompt,
+		MaxOutputTokens: g.config.MaxOutputTokens.Summary,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize commits: %w", err)
+	}
+
+	model := g.config.Model
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	return &Summary{
+		Text:    strings.TrimSpace(resp.Text),
+		Commits: count,
+		Model:   model,
+		Tokens:  resp.Usage,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CommitTemplate is the commit.template configured for a repository
+type CommitTemplate struct {
+	Text string
+	// CommentChar starts the lines git strips from messages (core.commentChar)
+	CommentChar string
+}
+
+// GetCommitTemplate returns the configured commit.template, or nil when none is set
+func (g *GitRepository) GetCommitTemplate() (*CommitTemplate, error) {
+	path, err := g.run("config", "--path", "commit.template")
+	if err != nil {
+		// git config exits non-zero when the key is not set
+		return nil, nil
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		// git resolves a relative template path from the repository root
+		if root, err := g.run("rev-parse", "--show-toplevel"); err == nil {
+			path = filepath.Join(strings.TrimSpace(root), path)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("commit.template %s does not exist", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit.template: %w", err)
+	}
+
+	commentChar := "#"
+	if value, err := g.run("config", "core.commentChar"); err == nil {
+		if value = strings.TrimSpace(value); value != "" && value != "auto" {
+			commentChar = value
+		}
+	}
+
+	return &CommitTemplate{Text: string(data), CommentChar: commentChar}, nil
+}
+
+// Merge returns message followed by the template's own sections (e.g.
+// "Ticket:" or "Reviewed-by:" lines). Comment lines are kept only with
+// keepComments, for files git cleans up itself before committing.
+func (t *CommitTemplate) Merge(message string, keepComments bool) string {
+	lines := strings.Split(strings.ReplaceAll(t.Text, "\r\n", "\n"), "\n")
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(message, "\n") {
+		if key, _, ok := strings.Cut(line, ":"); ok {
+			present[strings.ToLower(strings.TrimSpace(key))] = true
+		}
+	}
+
+	var kept []string
+	for _, line := range lines {
+		if t.isComment(line) {
+			if keepComments {
+				kept = append(kept, line)
+			}
+			continue
+		}
+		// Skip template fields the generated message already filled in
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(value) == "" && present[strings.ToLower(strings.TrimSpace(key))] {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	sections := strings.TrimSpace(collapseBlankLines(kept))
+	if sections == "" {
+		return message
+	}
+	return strings.TrimSpace(message) + "\n\n" + sections
+}
+
+// isComment reports whether git treats line as a comment
+func (t *CommitTemplate) isComment(line string) bool {
+	return t.CommentChar != "" && strings.HasPrefix(line, t.CommentChar)
+}
+
+// collapseBlankLines joins lines, squeezing runs of blank lines into one
+func collapseBlankLines(lines []string) string {
+	var b strings.Builder
+	blank := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blank = true
+			continue
+		}
+		if blank && b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		blank = false
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package generator
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer emits spans through the global OpenTelemetry tracer provider,
+// which is a no-op unless the application installs one
+var tracer = otel.Tracer("github.com/nguyenanhhao221/commit-gen/internal/generator")
+
+// endSpan records err on the span, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedProvider wraps every provider call in a span
+type tracedProvider struct {
+	Provider
+}
+
+// GenerateText forwards the request inside a "provider.generate" span
+func (p *tracedProvider) GenerateText(ctx context.Context, req *TextRequest) (*TextResponse, error) {
+	ctx, span := tracer.Start(ctx, "provider.generate", trace.WithAttributes(
+		attribute.String("commitgen.provider", p.Provider.Name()),
+		attribute.String("commitgen.model", req.Model),
+		attribute.Int("commitgen.prompt_bytes", len(req.Prompt)),
+	))
+
+	resp, err := p.Provider.GenerateText(ctx, req)
+	if resp != nil {
+		span.SetAttributes(
+			attribute.Int("commitgen.tokens.prompt", resp.Usage.PromptTokens),
+			attribute.Int("commitgen.tokens.output", resp.Usage.OutputTokens),
+		)
+	}
+	endSpan(span, err)
+
+	return resp, err
+}
+
+// Embed forwards the request inside a "provider.embed" span
+func (p *tracedProvider) Embed(ctx context.Context, req *EmbedRequest) ([][]float32, error) {
+	ctx, span := tracer.Start(ctx, "provider.embed", trace.WithAttributes(
+		attribute.String("commitgen.provider", p.Provider.Name()),
+		attribute.String("commitgen.model", req.Model),
+		attribute.Int("commitgen.texts", len(req.Texts)),
+	))
+
+	vectors, err := embed(ctx, p.Provider, req)
+	endSpan(span, err)
+
+	return vectors, err
+}
+
+// Ping forwards health checks when the wrapped provider supports them
+func (p *tracedProvider) Ping(ctx context.Context, model string) error {
+	if checker, ok := p.Provider.(HealthChecker); ok {
+		return checker.Ping(ctx, model)
+	}
+	return nil
+}
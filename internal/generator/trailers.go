@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProvenanceTrailer is the trailer key disclosing AI assistance
+const DefaultProvenanceTrailer = "Assisted-by"
+
+// Trailer is a "Key: value" footer line such as "Signed-off-by: Name <email>"
+type Trailer struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// String renders the trailer as git writes it
+func (t Trailer) String() string {
+	return t.Key + ": " + t.Value
+}
+
+// trailerLinePattern matches a trailer line, including the Conventional
+// Commits "BREAKING CHANGE" footer whose key contains a space
+var trailerLinePattern = regexp.MustCompile(`^(BREAKING CHANGE|[A-Za-z0-9][A-Za-z0-9-]*)[ \t]*:[ \t]*(\S.*)$`)
+
+// parseTrailerLine parses a "Key: value" trailer line. A URL such as
+// "https://example.com/issues/1" is not one, although its scheme looks like
+// a key.
+func parseTrailerLine(line string) (Trailer, bool) {
+	m := trailerLinePattern.FindStringSubmatch(line)
+	if m == nil || strings.HasPrefix(line[len(m[1]):], "://") {
+		return Trailer{}, false
+	}
+	return Trailer{Key: m[1], Value: strings.TrimSpace(m[2])}, true
+}
+
+// isTrailerLine reports whether line is a trailer line
+func isTrailerLine(line string) bool {
+	_, ok := parseTrailerLine(line)
+	return ok
+}
+
+// canonicalTrailerKeys spells well-known trailer keys the way git and forges do
+var canonicalTrailerKeys = map[string]string{
+	"signed-off-by":   "Signed-off-by",
+	"co-authored-by":  "Co-authored-by",
+	"reviewed-by":     "Reviewed-by",
+	"acked-by":        "Acked-by",
+	"tested-by":       "Tested-by",
+	"reported-by":     "Reported-by",
+	"suggested-by":    "Suggested-by",
+	"helped-by":       "Helped-by",
+	"assisted-by":     "Assisted-by",
+	"cc":              "Cc",
+	"fixes":           "Fixes",
+	"closes":          "Closes",
+	"refs":            "Refs",
+	"link":            "Link",
+	"change-id":       "Change-Id",
+	"breaking change": "BREAKING CHANGE",
+	"breaking-change": "BREAKING CHANGE",
+}
+
+// trailerRank orders trailer groups: what the change does to the API first,
+// references next, credits after, and the sign-off chain last
+func trailerRank(key string) int {
+	switch strings.ToLower(key) {
+	case "breaking change":
+		return 0
+	case "fixes", "closes", "resolves", "refs", "link":
+		return 1
+	case "co-authored-by", "reported-by", "suggested-by", "helped-by", "assisted-by":
+		return 3
+	case "reviewed-by", "acked-by", "tested-by", "cc":
+		return 4
+	case "signed-off-by":
+		return 5
+	}
+	return 2
+}
+
+// splitTrailers separates the trailer block (the last paragraph, when it
+// consists only of trailers and their continuation lines) from the body
+func splitTrailers(body string) (string, []Trailer) {
+	text := strings.TrimRight(body, "\n")
+	start := strings.LastIndex(text, "\n\n")
+	paragraph := text[start+1:]
+	if start >= 0 {
+		paragraph = text[start+2:]
+	}
+
+	var trailers []Trailer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if trailer, ok := parseTrailerLine(line); ok {
+			trailers = append(trailers, trailer)
+			continue
+		}
+		// Indented lines continue the previous trailer's value
+		if len(trailers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		return body, nil
+	}
+	if len(trailers) == 0 {
+		return body, nil
+	}
+
+	if start < 0 {
+		return "", trailers
+	}
+	return strings.TrimRight(text[:start], "\n"), trailers
+}
+
+// joinTrailers rebuilds a body from its text and trailer block
+func joinTrailers(text string, trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return text
+	}
+	lines := make([]string, len(trailers))
+	for i, t := range trailers {
+		lines[i] = t.String()
+	}
+	if text == "" {
+		return strings.Join(lines, "\n")
+	}
+	return text + "\n\n" + strings.Join(lines, "\n")
+}
+
+// Trailers returns the trailers of the message in order
+func (m CommitMessage) Trailers() []Trailer {
+	_, trailers := splitTrailers(m.Body)
+	return trailers
+}
+
+// GetTrailers returns the values of every trailer with key, compared
+// case-insensitively as git does
+func (m CommitMessage) GetTrailers(key string) []string {
+	var values []string
+	for _, t := range m.Trailers() {
+		if strings.EqualFold(t.Key, key) {
+			values = append(values, t.Value)
+		}
+	}
+	return values
+}
+
+// AddTrailer appends a trailer unless the exact key and value are already present
+func (m *CommitMessage) AddTrailer(key, value string) {
+	text, trailers := splitTrailers(m.Body)
+	for _, t := range trailers {
+		if strings.EqualFold(t.Key, key) && t.Value == value {
+			return
+		}
+	}
+	m.Body = joinTrailers(text, append(trailers, Trailer{Key: key, Value: value}))
+}
+
+// DedupTrailers removes repeated trailers with the same key and value,
+// keeping the first occurrence
+func (m *CommitMessage) DedupTrailers() {
+	text, trailers := splitTrailers(m.Body)
+	seen := make(map[Trailer]bool)
+	unique := trailers[:0]
+	for _, t := range trailers {
+		id := Trailer{Key: strings.ToLower(t.Key), Value: t.Value}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, t)
+		}
+	}
+	m.Body = joinTrailers(text, unique)
+}
+
+// NormalizeTrailers spells well-known keys canonically, writes every trailer
+// as "Key: value", removes duplicates, and groups them in a stable order
+// that keeps the sign-off chain last and in its original sequence
+func (m *CommitMessage) NormalizeTrailers() {
+	text, trailers := splitTrailers(m.Body)
+	if len(trailers) == 0 {
+		return
+	}
+	for i, t := range trailers {
+		if key, ok := canonicalTrailerKeys[strings.ToLower(t.Key)]; ok {
+			trailers[i].Key = key
+		}
+	}
+	sort.SliceStable(trailers, func(i, j int) bool {
+		return trailerRank(trailers[i].Key) < trailerRank(trailers[j].Key)
+	})
+	m.Body = joinTrailers(text, trailers)
+	m.DedupTrailers()
+}
@@ -0,0 +1,82 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitTrailers(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		text     string
+		trailers []Trailer
+	}{
+		{
+			name: "trailers",
+			body: "Fix the login.\n\nRefs: #12\nSigned-off-by: A <a@example.com>",
+			text: "Fix the login.",
+			trailers: []Trailer{
+				{Key: "Refs", Value: "#12"},
+				{Key: "Signed-off-by", Value: "A <a@example.com>"},
+			},
+		},
+		{
+			name: "URL last line",
+			body: "Fix the login.\n\nhttps://github.com/o/r/issues/1",
+			text: "Fix the login.\n\nhttps://github.com/o/r/issues/1",
+		},
+		{
+			name: "URL only",
+			body: "https://github.com/o/r/issues/1",
+			text: "https://github.com/o/r/issues/1",
+		},
+		{
+			name: "URL among trailers",
+			body: "Fix the login.\n\nRefs: #12\nhttps://github.com/o/r/issues/1",
+			text: "Fix the login.\n\nRefs: #12\nhttps://github.com/o/r/issues/1",
+		},
+		{
+			name:     "URL value",
+			body:     "Fix the login.\n\nLink: https://github.com/o/r/issues/1",
+			text:     "Fix the login.",
+			trailers: []Trailer{{Key: "Link", Value: "https://github.com/o/r/issues/1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, trailers := splitTrailers(tt.body)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if !reflect.DeepEqual(trailers, tt.trailers) {
+				t.Errorf("trailers = %q, want %q", trailers, tt.trailers)
+			}
+		})
+	}
+}
+
+func TestNormalizeTrailersKeepsURL(t *testing.T) {
+	body := "Fix the login.\n\nhttps://github.com/o/r/issues/1"
+	msg := CommitMessage{Header: "fix: login", Body: body}
+	msg.NormalizeTrailers()
+	if msg.Body != body {
+		t.Errorf("Body = %q, want %q", msg.Body, body)
+	}
+}
+
+func FuzzNormalizeTrailers(f *testing.F) {
+	f.Add("Fix the login.\n\nSigned-off-by: A <a@example.com>\nrefs: #1\nCo-authored-by: B <b@example.com>")
+	f.Add("https://github.com/o/r/issues/1")
+	f.Add("Body.\n\nBREAKING CHANGE: the API\n  changed\nFixes: #2\nFixes: #2")
+	f.Add("\n\nKey:\tvalue\n\n")
+	f.Fuzz(func(t *testing.T, body string) {
+		msg := CommitMessage{Header: "fix: login", Body: body}
+		msg.NormalizeTrailers()
+		once := msg.Body
+		msg.NormalizeTrailers()
+		if msg.Body != once {
+			t.Errorf("NormalizeTrailers is not idempotent:\n%q\n%q\n%q", body, once, msg.Body)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package generator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportOptions configures the HTTP client used to reach AI providers,
+// for example behind a corporate egress proxy or an LLM gateway
+type TransportOptions struct {
+	// ProxyURL overrides the HTTPS_PROXY/HTTP_PROXY environment variables
+	ProxyURL string
+	// Headers are added to every provider request (e.g. gateway auth headers)
+	Headers map[string]string
+	// ClientCertFile and ClientKeyFile enable mTLS with a PEM client certificate
+	ClientCertFile string
+	ClientKeyFile  string
+	// CACertFile adds a PEM CA bundle to the trusted roots (e.g. a TLS-inspecting proxy)
+	CACertFile string
+	// BaseURL overrides the cloud provider API endpoint (e.g. an LLM gateway)
+	BaseURL string
+}
+
+// maxIdleConnsPerHost keeps enough warm connections for concurrent generations
+const maxIdleConnsPerHost = 16
+
+// sharedHTTPClient is reused by every generator without custom transport
+// options so that keep-alive connections survive across CommitGen instances
+var sharedHTTPClient = &http.Client{Transport: newBaseTransport()}
+
+// newBaseTransport returns the default transport tuned for connection reuse
+func newBaseTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
+
+// newHTTPClient builds an HTTP client from the transport options
+// A nil opts returns the shared client
+func newHTTPClient(opts *TransportOptions) (*http.Client, error) {
+	if opts == nil {
+		return sharedHTTPClient, nil
+	}
+
+	transport := newBaseTransport()
+
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if opts.ClientCertFile != "" || opts.CACertFile != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if opts.ClientCertFile != "" {
+			keyFile := opts.ClientKeyFile
+			if keyFile == "" {
+				keyFile = opts.ClientCertFile
+			}
+			cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+
+		if opts.CACertFile != "" {
+			pem, err := os.ReadFile(opts.CACertFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates found in %s", opts.CACertFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if len(opts.Headers) > 0 {
+		roundTripper = &headerTransport{base: transport, headers: opts.Headers}
+	}
+
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// headerTransport adds fixed headers to every outgoing request
+type headerTransport struct {
+	base    http.RoundTripper
+	headers map[string]string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.base.RoundTrip(req)
+}
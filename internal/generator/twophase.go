@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"context"
+	"time"
+)
+
+// SubjectDraft is a header generated on its own for the staged changes,
+// so it can be shown right away; Body writes the rest of the message for
+// the same change only when the user asks for it
+type SubjectDraft struct {
+	// Result holds the header alone
+	Result *Result
+
+	commitGen *CommitGen
+	gitInfo   *GitInfo
+}
+
+// GenerateSubject generates the header for the staged changes with the
+// short prompt and output budget, which is much faster than a full message
+func (c *CommitGen) GenerateSubject(ctx context.Context) (draft *SubjectDraft, err error) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "commitgen.GenerateSubject")
+	defer func() { endSpan(span, err) }()
+	ctx, m := withMeter(ctx)
+	defer func() { err = m.interrupted(ctx, err) }()
+
+	gitInfo, err := c.changeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	short := true
+	result, err := c.generateAllowed(ctx, gitInfo, &GenConfig{IsShortCommit: &short})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.applyTemplate(result); err != nil {
+		return nil, err
+	}
+	result.Latency = time.Since(start)
+
+	return &SubjectDraft{Result: result, commitGen: c, gitInfo: gitInfo}, nil
+}
+
+// Body writes the full message continuing the header of the draft. The
+// change is not collected again, and the header is kept even when the
+// model would have written another one.
+func (d *SubjectDraft) Body(ctx context.Context) (result *Result, err error) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "commitgen.SubjectDraft.Body")
+	defer func() { endSpan(span, err) }()
+	ctx, m := withMeter(ctx)
+	defer func() { err = m.interrupted(ctx, err) }()
+
+	header := d.Result.Message.Header
+	info := *d.gitInfo
+	info.Prefix = header + "\n\n"
+	full := false
+	result, err = d.commitGen.generateAllowed(ctx, &info, &GenConfig{IsShortCommit: &full})
+	if err != nil {
+		return nil, err
+	}
+	if result.Message.Header != header {
+		result.Message = ParseCommitMessage(header + "\n\n" + result.Message.Body)
+	}
+	if err := d.commitGen.applyTemplate(result); err != nil {
+		return nil, err
+	}
+	result.Tokens.add(d.Result.Tokens)
+	result.Latency = time.Since(start)
+
+	return result, nil
+}
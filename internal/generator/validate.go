@@ -0,0 +1,142 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxAttempts is the number of generations tried before a message that
+// keeps failing validation is reported as an error
+const DefaultMaxAttempts = 3
+
+// DefaultTypes are the Conventional Commits types accepted by default
+var DefaultTypes = []string{"feat", "fix", "refactor", "chore", "docs", "style", "test", "perf", "ci", "build", "revert"}
+
+// ValidationRules configures which messages the validator accepts
+type ValidationRules struct {
+	// MaxSubjectLength limits the header line (0 disables the check)
+	MaxSubjectLength int
+	// MaxBodyLineLength limits body lines (0 disables the check)
+	MaxBodyLineLength int
+	// RequireConventional requires a "type(scope): subject" header
+	RequireConventional bool
+	// AllowedTypes restricts the commit type (empty allows any type)
+	AllowedTypes []string
+	// SubjectOnly rejects messages with a body
+	SubjectOnly bool
+	// SemanticRelease requires messages that semantic-release's default
+	// commit analyzer reads as intended, see ParseReleaseCommit
+	SemanticRelease bool
+}
+
+// DefaultValidationRules returns the rules matching the built-in prompts
+func DefaultValidationRules(isShortCommit bool) *ValidationRules {
+	return &ValidationRules{
+		MaxSubjectLength:    50,
+		MaxBodyLineLength:   72,
+		RequireConventional: true,
+		AllowedTypes:        DefaultTypes,
+		SubjectOnly:         isShortCommit,
+	}
+}
+
+// Violation is a single rule broken by a message
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// String returns the human readable description of the violation
+func (v Violation) String() string {
+	return v.Message
+}
+
+// ValidationError is returned when a message still breaks the rules after
+// every attempt; Result holds the last generated message
+type ValidationError struct {
+	Violations []Violation
+	Result     *Result
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "generated message failed validation: " + strings.Join(messages, "; ")
+}
+
+// Validate checks msg against rules and returns every violation found
+func Validate(msg CommitMessage, rules *ValidationRules) []Violation {
+	var violations []Violation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if msg.Header == "" {
+		add("empty", "the message is empty")
+		return violations
+	}
+
+	if n := len([]rune(msg.Header)); rules.MaxSubjectLength > 0 && n > rules.MaxSubjectLength {
+		add("subject-length", "subject is %d chars, limit is %d", n, rules.MaxSubjectLength)
+	}
+
+	if rules.RequireConventional {
+		if msg.Type == "" {
+			add("conventional", "subject %q does not follow the format type(scope): description", msg.Header)
+		} else if len(rules.AllowedTypes) > 0 && !slices.Contains(rules.AllowedTypes, msg.Type) {
+			add("type", "type %q is not allowed, use one of: %s", msg.Type, strings.Join(rules.AllowedTypes, ", "))
+		}
+	}
+
+	if rules.SemanticRelease {
+		validateSemanticRelease(msg, add)
+	}
+
+	if strings.HasSuffix(msg.Header, ".") {
+		add("subject-period", "subject must not end with a period")
+	}
+
+	// Trailers such as Signed-off-by or Assisted-by are metadata, not a body
+	if text, _ := splitTrailers(msg.Body); rules.SubjectOnly && text != "" {
+		add("subject-only", "only a subject line is allowed, remove the body")
+	}
+
+	if rules.MaxBodyLineLength > 0 {
+		inFence := false
+		for i, line := range strings.Split(msg.Body, "\n") {
+			// Code and trailers must not be wrapped
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+			}
+			if inFence || isIndentedCode(line) || isTrailerLine(line) {
+				continue
+			}
+			// A single unbreakable token (URL, path) cannot be wrapped
+			if n := len([]rune(line)); n > rules.MaxBodyLineLength && strings.Contains(strings.TrimSpace(line), " ") {
+				add("body-line-length", "body line %d is %d chars, limit is %d", i+1, n, rules.MaxBodyLineLength)
+			}
+		}
+	}
+
+	return violations
+}
+
+// feedbackPrompt extends prompt with the rejected message and its violations
+func feedbackPrompt(prompt string, rejected CommitMessage, violations []Violation) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\nYour previous commit message was:\n")
+	b.WriteString(rejected.String())
+	b.WriteString("\n\nIt was rejected because:\n")
+	for _, v := range violations {
+		b.WriteString("- ")
+		b.WriteString(v.Message)
+		b.WriteString("\n")
+	}
+	b.WriteString("\nWrite a corrected commit message that fixes every problem above.\n")
+	return b.String()
+}
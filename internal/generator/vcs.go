@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Version control systems accepted by Options.VCS
+const (
+	VCSGit       = "git"
+	VCSJujutsu   = "jj"
+	VCSMercurial = "hg"
+	// VCSAuto detects the VCS from the working directory
+	VCSAuto = "auto"
+)
+
+// VCS is a version control system commit messages are written for. The
+// generator only needs the pending change and some history, so everything
+// else (staging, committing) stays with the VCS-specific tooling.
+type VCS interface {
+	// Name returns the VCS identifier, e.g. "git" or "jj"
+	Name() string
+	// HasChanges reports whether there is a pending change to describe:
+	// the staged changes for git, the working copy for jj and hg
+	HasChanges() (bool, error)
+	// ChangeContext gathers the pending change and recent history
+	ChangeContext(opts *ContextOptions) (*GitInfo, error)
+}
+
+// NewVCS returns the VCS adapter for name in workingDir
+func NewVCS(name, workingDir string) (VCS, error) {
+	if name == VCSAuto {
+		name = DetectVCS(workingDir)
+	}
+
+	switch name {
+	case "", VCSGit:
+		return NewGitRepository(workingDir), nil
+	case VCSJujutsu:
+		return NewJujutsuRepository(workingDir), nil
+	case VCSMercurial:
+		return NewMercurialRepository(workingDir), nil
+	}
+	return nil, fmt.Errorf("unknown VCS %q", name)
+}
+
+// DetectVCS returns the VCS of the repository containing workingDir. A jj
+// repository colocated with git is reported as jj, since jj has no staging
+// area and its users describe changes rather than commit staged files.
+func DetectVCS(workingDir string) string {
+	dir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return VCSGit
+	}
+
+	for {
+		for _, candidate := range []struct{ marker, name string }{
+			{".jj", VCSJujutsu},
+			{".hg", VCSMercurial},
+			{".git", VCSGit},
+		} {
+			if _, err := os.Stat(filepath.Join(dir, candidate.marker)); err == nil {
+				return candidate.name
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return VCSGit
+		}
+		dir = parent
+	}
+}
+
+// runIn executes a command in dir (the current directory when empty) and
+// returns its stdout
+func runIn(dir, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// runWithStdin is runIn with input fed to the command's stdin
+func runWithStdin(dir, input, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	cmd.Stdin = strings.NewReader(input)
+
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// Name implements VCS
+func (g *GitRepository) Name() string {
+	return VCSGit
+}
+
+// HasChanges implements VCS; git describes the staged changes
+func (g *GitRepository) HasChanges() (bool, error) {
+	return g.HasStagedChanges()
+}
+
+// ChangeContext implements VCS
+func (g *GitRepository) ChangeContext(opts *ContextOptions) (*GitInfo, error) {
+	return g.GetCommitContextWithOptions(opts)
+}
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultWIPPrefix starts the subject of checkpoint commits
+const DefaultWIPPrefix = "WIP: "
+
+// WIPMessage writes a terse checkpoint message for the staged changes
+// without a model, e.g. "WIP: update 3 files in internal/auth": the prefix
+// followed by the hint, if any, or a summary of the staged files. It is
+// meant to be replaced by a proper message with RewordWIP before pushing.
+func (g *GitRepository) WIPMessage(prefix, hint string) (CommitMessage, error) {
+	// The file list is enough, and works in partial clones too
+	info, err := g.GetCommitContextWithOptions(&ContextOptions{NoHistory: true, NoContent: true})
+	if err != nil {
+		return CommitMessage{}, err
+	}
+	subject := cmp.Or(hint, heuristicSubject(changedFiles(info)))
+	return ParseCommitMessage(cmp.Or(prefix, DefaultWIPPrefix) + subject), nil
+}
+
+// IsWIP reports whether a commit subject marks a checkpoint commit: it
+// starts with prefix, or DefaultWIPPrefix, ignoring case and spacing
+func IsWIP(subject, prefix string) bool {
+	subject = strings.ToLower(strings.TrimSpace(subject))
+	for _, p := range []string{prefix, DefaultWIPPrefix} {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" && strings.HasPrefix(subject, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Commit commits the staged changes with message
+func (g *GitRepository) Commit(message string) error {
+	if err := g.CommitCommand(message).run(g.workingDir, g.writeGuard, "commit"); err != nil {
+		if errors.Is(err, ErrReadOnly) {
+			return err
+		}
+		return fmt.Errorf("git commit failed: %w", err)
+	}
+	return nil
+}
+
+// CommitCommand returns the command Commit runs
+func (g *GitRepository) CommitCommand(message string) Command {
+	return Command{Args: []string{"git", "commit", "--quiet", "--file=-"}, Stdin: message}
+}
+
+// RewordWIP generates proper messages for the checkpoint commits of
+// base..HEAD, those IsWIP recognizes with prefix, oldest first. Rewrite
+// puts them in place.
+func (c *CommitGen) RewordWIP(ctx context.Context, base, prefix string) ([]Reword, error) {
+	return c.reword(ctx, base+"..HEAD", func(commit Commit) bool {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		return IsWIP(subject, prefix)
+	})
+}
@@ -0,0 +1,33 @@
+package prompts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Presets are named System prompts for the conventions and formats
+// commit-gen supports, as a starting point to render or adjust
+var Presets = map[string]System{
+	"conventional":       {},
+	"conventional-short": {Short: true},
+	"kernel":             {Convention: ConventionKernel},
+	"kernel-short":       {Convention: ConventionKernel, Short: true},
+	"semantic-release":   {SemanticRelease: true},
+	"grounded":           {Grounded: true},
+	"ascii":              {ASCIIOnly: true},
+}
+
+// Preset returns the System of a preset
+func Preset(name string) (System, error) {
+	system, ok := Presets[name]
+	if !ok {
+		names := make([]string, 0, len(Presets))
+		for name := range Presets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		return System{}, fmt.Errorf("unknown prompt preset %q, use %s", name, strings.Join(names, ", "))
+	}
+	return system, nil
+}
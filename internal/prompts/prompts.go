@@ -0,0 +1,121 @@
+// Package prompts builds the prompts commit-gen sends to models: the system
+// prompt of a commit message from a System, the user prompt from a Change,
+// named System presets, and a registry of the templates of other tasks. It
+// has no side effects, so integrators and evals can build and inspect the
+// exact prompts without a provider.
+package prompts
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// Style sources, what the model imitates
+const (
+	// StyleHistory imitates the repository's recent commits
+	StyleHistory = "history"
+	// StyleConvention follows only the configured convention
+	StyleConvention = "convention"
+	// StyleExamples imitates a curated examples file
+	StyleExamples = "examples-file"
+)
+
+// Conventions the base prompts follow
+const (
+	// ConventionConventional is Conventional Commits, "type(scope): subject"
+	ConventionConventional = "conventional"
+	// ConventionKernel is the Linux kernel / mailing list style,
+	// "subsystem: summary", for git send-email patch series
+	ConventionKernel = "kernel"
+)
+
+// ASCII is appended to the system prompt when ASCII output is required
+const ASCII = "Use only ASCII characters: no emoji, no accented letters, and no typographic quotes or dashes."
+
+// SemanticRelease tells the model how semantic-release reads messages
+const SemanticRelease = `The message must be parseable by semantic-release: use only the types feat, fix, perf, build, chore, ci, docs, refactor, revert, style, or test. Never put "!" in the header; mark a breaking change with a final "BREAKING CHANGE: <what breaks and how to migrate>" footer instead.`
+
+// Grounded asks for a body that can be grounded statement by statement
+const Grounded = `Write the body as "- " bullets, one per distinct change, each describing only what the diff shows. Do not add bullets about motivation, testing, or follow-up work.`
+
+// Conflict asks the model to account for the conflict resolutions
+const Conflict = `This commit concludes a merge whose conflicts were resolved by hand. For each
+conflicted file below you get the resolution compared with our side (HEAD)
+and with their side. In the body, add a "Conflicts:" section with one bullet
+per conflicted file saying what conflicted and how it was resolved: which
+side was kept, or how the two were combined.
+
+`
+
+// DefaultKernelSubjectLength is the kernel's recommended subject length of
+// 75 less the "[PATCH] " prefix git format-patch adds
+const DefaultKernelSubjectLength = 67
+
+// System describes the system prompt of a commit message. The zero value
+// is the full Conventional Commits prompt that imitates the git history.
+type System struct {
+	// Base fully replaces the built-in base prompt; fragments still apply
+	Base string
+	// Convention is ConventionConventional (default) or ConventionKernel
+	Convention string
+	// StyleSource is StyleHistory (default), StyleConvention, or
+	// StyleExamples
+	StyleSource string
+	// Fragments are extra rules appended in order
+	Fragments []string
+	// Short asks for a subject line only
+	Short bool
+	// ASCIIOnly appends the ASCII fragment
+	ASCIIOnly bool
+	// SemanticRelease appends the SemanticRelease fragment
+	SemanticRelease bool
+	// Grounded appends the Grounded fragment to full messages
+	Grounded bool
+	// MaxSubjectLength is the subject limit of the kernel prompt; 0 is
+	// DefaultKernelSubjectLength
+	MaxSubjectLength int
+}
+
+// Render composes the system prompt s describes
+func (s System) Render() string {
+	var base string
+	switch {
+	case s.Base != "":
+		base = s.Base
+	case s.Convention == ConventionKernel:
+		base = Kernel(s.Short, s.StyleSource, cmp.Or(s.MaxSubjectLength, DefaultKernelSubjectLength))
+	case s.Short:
+		base = Short()
+	default:
+		base = Default(s.StyleSource)
+	}
+	fragments := slices.Clone(s.Fragments)
+	if s.ASCIIOnly {
+		fragments = append(fragments, ASCII)
+	}
+	if s.SemanticRelease {
+		fragments = append(fragments, SemanticRelease)
+	}
+	if s.Grounded && !s.Short {
+		fragments = append(fragments, Grounded)
+	}
+	return Compose(base, fragments)
+}
+
+// Compose appends the prompt fragments to the base prompt in order
+func Compose(base string, fragments []string) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(base))
+
+	for _, fragment := range fragments {
+		fragment = strings.TrimSpace(fragment)
+		if fragment == "" {
+			continue
+		}
+		b.WriteString("\n\nAdditional rules:\n")
+		b.WriteString(fragment)
+	}
+
+	return b.String()
+}
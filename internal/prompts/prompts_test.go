@@ -0,0 +1,133 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		fragments []string
+		want      string
+	}{
+		{
+			name: "no fragments",
+			base: "  Base.\n",
+			want: "Base.",
+		},
+		{
+			name:      "fragments in order",
+			base:      "Base.",
+			fragments: []string{"First.", "Second."},
+			want:      "Base.\n\nAdditional rules:\nFirst.\n\nAdditional rules:\nSecond.",
+		},
+		{
+			name:      "empty fragments are skipped",
+			base:      "Base.",
+			fragments: []string{"", " \n", "Only."},
+			want:      "Base.\n\nAdditional rules:\nOnly.",
+		},
+		{
+			name:      "empty base",
+			fragments: []string{"Only."},
+			want:      "\n\nAdditional rules:\nOnly.",
+		},
+		{
+			name: "empty",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compose(tt.base, tt.fragments); got != tt.want {
+				t.Errorf("Compose(%q, %q) = %q, want %q", tt.base, tt.fragments, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		system System
+		want   string
+	}{
+		{
+			name:   "zero value",
+			system: System{},
+			want:   Default(""),
+		},
+		{
+			name:   "short",
+			system: System{Short: true},
+			want:   Short(),
+		},
+		{
+			name:   "kernel",
+			system: System{Convention: ConventionKernel},
+			want:   Kernel(false, "", DefaultKernelSubjectLength),
+		},
+		{
+			name:   "base overrides the built-in prompt",
+			system: System{Base: "Custom.", Convention: ConventionKernel, Short: true},
+			want:   "Custom.",
+		},
+		{
+			name:   "base keeps the fragments",
+			system: System{Base: "Custom.", Fragments: []string{"Rule."}, ASCIIOnly: true},
+			want:   Compose("Custom.", []string{"Rule.", ASCII}),
+		},
+		{
+			name: "fragments before the built-in ones",
+			system: System{
+				Base:            "Custom.",
+				Fragments:       []string{"First.", "Second."},
+				ASCIIOnly:       true,
+				SemanticRelease: true,
+				Grounded:        true,
+			},
+			want: Compose("Custom.", []string{"First.", "Second.", ASCII, SemanticRelease, Grounded}),
+		},
+		{
+			name:   "grounded only applies to full messages",
+			system: System{Base: "Custom.", Short: true, Grounded: true},
+			want:   "Custom.",
+		},
+		{
+			name:   "empty fragments",
+			system: System{Base: "Custom.", Fragments: []string{"", "  "}},
+			want:   "Custom.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.system.Render(); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemRenderKeepsFragments(t *testing.T) {
+	fragments := []string{"Rule."}
+	System{Fragments: fragments, ASCIIOnly: true, SemanticRelease: true}.Render()
+	if len(fragments) != 1 || fragments[0] != "Rule." {
+		t.Errorf("Render changed the fragments to %q", fragments)
+	}
+}
+
+func TestPreset(t *testing.T) {
+	for name, want := range Presets {
+		got, err := Preset(name)
+		if err != nil || got.Render() != want.Render() {
+			t.Errorf("Preset(%q) = %+v, %v", name, got, err)
+		}
+	}
+
+	_, err := Preset("")
+	if err == nil || !strings.Contains(err.Error(), "conventional-short") {
+		t.Errorf("Preset(\"\") error = %v, want one listing the presets", err)
+	}
+}
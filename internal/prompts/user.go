@@ -0,0 +1,120 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Change is what the user prompt of a commit message tells the model about
+// the change. Empty fields are left out of the prompt.
+type Change struct {
+	// StyleSource and Convention pick what the model imitates, as in System
+	StyleSource string
+	Convention  string
+	// Examples are the example messages of StyleExamples
+	Examples string
+	// History is the recent git log of StyleHistory; without one, the
+	// built-in examples of the convention take its place
+	History string
+	// SuggestedScope is the package containing all changes
+	SuggestedScope string
+	// Scopes is the repository's scope glossary
+	Scopes []ScopeDefinition
+	// Hint is the author's own description of the change
+	Hint string
+	// Draft is a message to revise, as the Revision request asks
+	Draft    string
+	Revision string
+	// Prefix is what the author has typed of the message so far
+	Prefix string
+	// Notes are the developer's notes about the intent of the change
+	Notes string
+	// RelatedFiles usually change together with the changed files but are
+	// not part of the change
+	RelatedFiles []string
+	// BlameContext lists the commits that last changed the modified lines
+	BlameContext string
+	// ConflictedFiles are the files of a merge whose conflicts were
+	// resolved by hand, ConflictResolutions how they were resolved
+	ConflictedFiles     []string
+	ConflictResolutions string
+	// Diff is the diff of the change, or its file statuses and paths when
+	// ContentOmitted is set
+	Diff           string
+	ContentOmitted bool
+}
+
+// ScopeDefinition is a scope of the scope glossary and the part of the
+// codebase it stands for
+type ScopeDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Render writes the user prompt of a commit message for c
+func (c Change) Render() string {
+	var b strings.Builder
+
+	switch c.StyleSource {
+	case StyleExamples:
+		fmt.Fprintf(&b, "Example commit messages:\n%s\n\n", strings.TrimSpace(c.Examples))
+	case StyleConvention:
+		// Nothing to imitate, the system prompt carries the convention
+	default:
+		history := c.History
+		if history == "" {
+			// If no history, include default examples
+			history = DefaultExamples()
+			if c.Convention == ConventionKernel {
+				history = KernelExamples()
+			}
+		}
+		fmt.Fprintf(&b, "Recent git log:\n%s\n\n", history)
+	}
+	if len(c.Scopes) > 0 {
+		b.WriteString("Scopes of this repository (when the change belongs to one of these areas, use its scope exactly as written):\n")
+		for _, scope := range c.Scopes {
+			fmt.Fprintf(&b, "- %s: %s\n", scope.Name, scope.Description)
+		}
+		b.WriteString("\n")
+	}
+	if c.SuggestedScope != "" {
+		fmt.Fprintf(&b, "Suggested scope (package containing all changes): %s\n\n", c.SuggestedScope)
+	}
+	if c.Hint != "" {
+		fmt.Fprintf(&b, "The author describes this change as: %q\nBase the type and subject on this description and use the diff for the details in the body.\n\n", c.Hint)
+	}
+	if c.Draft != "" {
+		fmt.Fprintf(&b, "Current commit message draft:\n%s\n\n", c.Draft)
+		if c.Revision != "" {
+			fmt.Fprintf(&b, "Revise the draft as follows: %s\nKeep what the request does not ask to change.\n\n", c.Revision)
+		}
+	}
+	if c.Prefix != "" {
+		fmt.Fprintf(&b, "The author has started typing the commit message: %q\nContinue it: start the message with exactly these characters, unchanged, and complete the rest.\n\n", c.Prefix)
+	}
+	if c.Notes != "" {
+		fmt.Fprintf(&b, "Developer notes about the intent of this change:\n%s\n\n", c.Notes)
+	}
+	if len(c.RelatedFiles) > 0 {
+		fmt.Fprintf(&b, "Files that usually change together with these (not staged):\n%s\n\n", strings.Join(c.RelatedFiles, "\n"))
+	}
+	if c.BlameContext != "" {
+		fmt.Fprintf(&b, "Commits that last changed the modified lines:\n%s\n", c.BlameContext)
+	}
+	if len(c.ConflictedFiles) > 0 {
+		b.WriteString(Conflict)
+		if c.ConflictResolutions != "" {
+			fmt.Fprintf(&b, "Conflict resolutions:\n%s\n", c.ConflictResolutions)
+		} else {
+			fmt.Fprintf(&b, "Conflicted files:\n%s\n\n", strings.Join(c.ConflictedFiles, "\n"))
+		}
+	}
+	if c.ContentOmitted {
+		fmt.Fprintf(&b, "Changed files (status and path, contents unavailable):\n%s\n", c.Diff)
+	} else {
+		fmt.Fprintf(&b, "Git diff:\n%s\n", c.Diff)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChangeRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		change Change
+		want   string
+	}{
+		{
+			name:   "empty",
+			change: Change{},
+			want:   "Recent git log:\n" + DefaultExamples() + "\n\nGit diff:\n\n",
+		},
+		{
+			name:   "empty kernel",
+			change: Change{Convention: ConventionKernel},
+			want:   "Recent git log:\n" + KernelExamples() + "\n\nGit diff:\n\n",
+		},
+		{
+			name:   "convention only",
+			change: Change{StyleSource: StyleConvention, Diff: "+x"},
+			want:   "Git diff:\n+x\n",
+		},
+		{
+			name:   "examples",
+			change: Change{StyleSource: StyleExamples, Examples: "\nfeat: x\n\n", Diff: "+x"},
+			want:   "Example commit messages:\nfeat: x\n\nGit diff:\n+x\n",
+		},
+		{
+			name:   "history",
+			change: Change{History: "fix: y", Diff: "+x"},
+			want:   "Recent git log:\nfix: y\n\nGit diff:\n+x\n",
+		},
+		{
+			name:   "content omitted",
+			change: Change{StyleSource: StyleConvention, Diff: "M\tmain.go", ContentOmitted: true},
+			want:   "Changed files (status and path, contents unavailable):\nM\tmain.go\n",
+		},
+		{
+			name:   "revision without a draft",
+			change: Change{StyleSource: StyleConvention, Revision: "shorter"},
+			want:   "Git diff:\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.Render(); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangeRenderOrder(t *testing.T) {
+	change := Change{
+		StyleSource:     StyleConvention,
+		Scopes:          []ScopeDefinition{{Name: "auth", Description: "login"}},
+		SuggestedScope:  "auth",
+		Hint:            "the hint",
+		Draft:           "the draft",
+		Revision:        "the revision",
+		Prefix:          "the prefix",
+		Notes:           "the notes",
+		RelatedFiles:    []string{"related.go"},
+		BlameContext:    "the blame",
+		ConflictedFiles: []string{"conflicted.go"},
+		Diff:            "the diff",
+	}
+	got := change.Render()
+	sections := []string{
+		"Scopes of this repository",
+		"Suggested scope",
+		"the hint",
+		"the draft",
+		"the revision",
+		"the prefix",
+		"the notes",
+		"related.go",
+		"the blame",
+		"conflicted.go",
+		"Git diff:\nthe diff",
+	}
+	last := -1
+	for _, section := range sections {
+		i := strings.Index(got, section)
+		if i < 0 {
+			t.Fatalf("Render() has no %q:\n%s", section, got)
+		}
+		if i < last {
+			t.Errorf("Render() has %q out of order:\n%s", section, got)
+		}
+		last = i
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runJujutsu describes the jj working copy change with a generated message
+func runJujutsu(args []string) {
+	fs := flag.NewFlagSet("commit-gen jj", flag.ExitOnError)
+	shortCommit := fs.Bool("short", false, "Just generate short commit title")
+	dryRun := fs.Bool("dry-run", false, "Print the description instead of running jj describe")
+	yes := fs.Bool("yes", false, "Run jj describe without asking first")
+	var hint string
+	fs.StringVar(&hint, "hint", "", "Your summary of the change, used to steer the type and subject")
+	fs.StringVar(&hint, "m", "", "Shorthand for -hint")
+	fs.BoolVar(&quiet, "quiet", quiet, "Only print the description and fatal errors (default when stderr is not a terminal)")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	// Fail before spending a request when there is no one to confirm
+	if !*dryRun && !*yes && !canAsk() {
+		fail(exitcode.Usage, "Not describing the change without confirmation; pass -yes, or -dry-run to print it")
+	}
+
+	opts := loadOptions("")
+	opts.VCS = generator.VCSJujutsu
+	opts.IsShortCommit = *shortCommit
+	opts.Hint = hint
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	hasChanges, err := commitGen.HasStagedChanges()
+	if err != nil {
+		failErr(err, "Failed to read the working copy change")
+	}
+	if !hasChanges {
+		fail(exitcode.NoChanges, "The working copy change is empty, nothing to describe.")
+	}
+
+	ctx, stop := interruptible()
+	result, err := commitGen.GenerateContext(ctx)
+	stop()
+	var validationErr *generator.ValidationError
+	if errors.As(err, &validationErr) {
+		warnf("Last attempt:\n%s", validationErr.Result.Message)
+	}
+	if err != nil {
+		failErr(err, "Failed to generate change description")
+	}
+
+	if *dryRun {
+		fmt.Println(result.Message)
+		return
+	}
+
+	repo := commitGen.VCS().(*generator.JujutsuRepository)
+	requireConfirmation([]generator.Command{repo.DescribeCommand(result.Message.String())}, *yes)
+	if err := repo.Describe(result.Message.String()); err != nil {
+		failErr(err, "Failed to describe the change")
+	}
+	fmt.Println(result.Message)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runLearn derives the commit convention of existing repositories and
+// writes it as a starter config or prompt fragment
+func runLearn(args []string) {
+	fs := flag.NewFlagSet("commit-gen learn", flag.ExitOnError)
+	count := fs.Int("n", 500, "Number of recent commits to learn from in each repository")
+	var repos listFlag
+	fs.Var(&repos, "repo", "Repository to learn from (repeatable; default: repos in the config, or the current one)")
+	format := fs.String("format", "config", "Output: config (a starter .commitgen.toml), fragment (a prompt fragment), or json")
+	output := fs.String("o", "", "Write the result to this file instead of stdout")
+	fs.Parse(args)
+
+	if *format != "config" && *format != "fragment" && *format != "json" {
+		fail(exitcode.Usage, fmt.Sprintf("Unknown format %q, use config, fragment, or json", *format))
+	}
+
+	// Learning reads history only, so no provider has to be set up
+	if len(repos) == 0 {
+		repos = loadOptions("").Repos
+	}
+	if len(repos) == 0 {
+		repos = []string{"."}
+	}
+	convention, err := generator.Learn(repos, *count)
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges("No commits to learn from.")
+	}
+	if err != nil {
+		fatalf("Failed to learn the convention: %v", err)
+	}
+
+	var text string
+	switch *format {
+	case "config":
+		if text, err = convention.Config(); err != nil {
+			fatalf("Failed to encode the config: %v", err)
+		}
+	case "fragment":
+		text = convention.Fragment()
+	case "json":
+		data, err := json.MarshalIndent(convention, "", "  ")
+		if err != nil {
+			fatalf("Failed to encode the convention: %v", err)
+		}
+		text = string(data) + "\n"
+	}
+
+	if *output == "" {
+		fmt.Print(text)
+		return
+	}
+	if err := atomicfile.WriteFile(*output, []byte(text), 0o644); err != nil {
+		fatalf("Failed to write %s: %v", *output, err)
+	}
+	warnf("Learned the convention of %s, written to %s", plural(convention.Commits, "commit"), *output)
+	if *format == "fragment" {
+		// Only the user-wide config may name prompt fragments
+		path, err := filepath.Abs(*output)
+		if err != nil {
+			path = *output
+		}
+		fmt.Fprintf(os.Stderr, "Use it with: commit-gen config set --global prompt_fragments %s\n", path)
+	}
+}
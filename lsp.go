@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// lspGenerateCommand is the workspace command behind the code action
+const lspGenerateCommand = "commitgen.generate"
+
+// runLSP serves a minimal language server on stdin/stdout, so any
+// LSP-capable editor gets a "Generate commit message" code action and a
+// completion for the commit message file without a custom plugin
+func runLSP(args []string) {
+	fs := flag.NewFlagSet("commit-gen lsp", flag.ExitOnError)
+	shortCommit := fs.Bool("short", false, "Generate short commit titles")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	server := &lspServer{
+		out:       os.Stdout,
+		documents: make(map[string]*lspDocument),
+		repos:     make(map[string]*generator.CommitGen),
+		newOptions: func(dir string) *generator.Options {
+			opts := loadOptions(dir)
+			opts.IsShortCommit = *shortCommit
+			// Completion is requested again and again for the same change
+			opts.CacheSize = 16
+			providers.apply(opts)
+			return opts
+		},
+	}
+	defer server.close()
+
+	if err := server.serve(os.Stdin); err != nil {
+		fatalf("Language server failed: %v", err)
+	}
+}
+
+// lspDocument is an open document, kept in full
+type lspDocument struct {
+	languageID string
+	text       string
+}
+
+// lspServer implements the few LSP methods commit-gen needs
+type lspServer struct {
+	out        io.Writer
+	outMu      sync.Mutex
+	documents  map[string]*lspDocument
+	repos      map[string]*generator.CommitGen
+	newOptions func(dir string) *generator.Options
+	rootDir    string
+	nextID     int
+}
+
+// lspMessage is any JSON-RPC request, notification, or response
+type lspMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// lspPosition and lspRange are zero-based, as in LSP
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspTextDocumentParams struct {
+	TextDocument struct {
+		URI        string `json:"uri"`
+		LanguageID string `json:"languageId"`
+		Text       string `json:"text"`
+	} `json:"textDocument"`
+	Position       lspPosition `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// serve reads Content-Length framed messages until exit or EOF
+func (s *lspServer) serve(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	for {
+		body, err := readLSPMessage(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg lspMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.replyError(nil, -32700, "parse error: "+err.Error())
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		// Responses to our own requests (workspace/applyEdit) need no handling
+		if msg.Method == "" {
+			continue
+		}
+
+		result, err := s.handle(msg.Method, msg.Params)
+		if msg.ID == nil {
+			continue
+		}
+		if err != nil {
+			s.replyError(msg.ID, -32603, err.Error())
+		} else {
+			s.reply(msg.ID, result)
+		}
+	}
+}
+
+// handle runs a request or notification and returns its result
+func (s *lspServer) handle(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var init struct {
+			RootURI string `json:"rootUri"`
+		}
+		json.Unmarshal(params, &init)
+		s.rootDir = uriPath(init.RootURI)
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":         1, // full
+				"codeActionProvider":       true,
+				"completionProvider":       map[string]any{},
+				"inlineCompletionProvider": map[string]any{}, // ghost text, LSP 3.18
+				"executeCommandProvider":   map[string]any{"commands": []string{lspGenerateCommand}},
+			},
+			"serverInfo": map[string]string{"name": "commit-gen"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen", "textDocument/didChange":
+		var p lspTextDocumentParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		doc := s.documents[p.TextDocument.URI]
+		if doc == nil {
+			doc = &lspDocument{languageID: p.TextDocument.LanguageID}
+			s.documents[p.TextDocument.URI] = doc
+		}
+		doc.text = p.TextDocument.Text
+		if n := len(p.ContentChanges); n > 0 {
+			doc.text = p.ContentChanges[n-1].Text
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var p lspTextDocumentParams
+		json.Unmarshal(params, &p)
+		delete(s.documents, p.TextDocument.URI)
+		return nil, nil
+	case "textDocument/codeAction":
+		return s.codeActions(params)
+	case "workspace/executeCommand":
+		return s.executeCommand(params)
+	case "textDocument/completion":
+		return s.completion(params)
+	case "textDocument/inlineCompletion":
+		return s.inlineCompletion(params)
+	}
+	return nil, nil
+}
+
+// codeActions offers generation for commit message documents
+func (s *lspServer) codeActions(params json.RawMessage) (any, error) {
+	var p lspTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if !s.isCommitMessage(p.TextDocument.URI) {
+		return []any{}, nil
+	}
+	return []any{map[string]any{
+		"title": "Generate commit message",
+		"kind":  "source",
+		"command": map[string]any{
+			"title":     "Generate commit message",
+			"command":   lspGenerateCommand,
+			"arguments": []string{p.TextDocument.URI},
+		},
+	}}, nil
+}
+
+// executeCommand generates the message and asks the editor to apply it
+func (s *lspServer) executeCommand(params json.RawMessage) (any, error) {
+	var p struct {
+		Command   string   `json:"command"`
+		Arguments []string `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Command != lspGenerateCommand || len(p.Arguments) == 0 {
+		return nil, fmt.Errorf("unknown command %q", p.Command)
+	}
+
+	uri := p.Arguments[0]
+	edit, _, err := s.generate(uri)
+	if err != nil {
+		return nil, err
+	}
+	s.nextID++
+	s.send(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      "commitgen-" + strconv.Itoa(s.nextID),
+		"method":  "workspace/applyEdit",
+		"params": map[string]any{
+			"label": "Generate commit message",
+			"edit":  map[string]any{"changes": map[string][]lspTextEdit{uri: {*edit}}},
+		},
+	})
+	return nil, nil
+}
+
+// completion offers the generated message on the empty first line of a
+// commit message; once the user starts typing the draft, the code action
+// is the way to regenerate, so typing never triggers a provider call
+func (s *lspServer) completion(params json.RawMessage) (any, error) {
+	var p lspTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	doc := s.documents[p.TextDocument.URI]
+	if !s.isCommitMessage(p.TextDocument.URI) || doc == nil || p.Position.Line != 0 || draftText(doc.text) != "" {
+		return []any{}, nil
+	}
+
+	_, result, err := s.generate(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"isIncomplete": false,
+		"items": []any{map[string]any{
+			"label":    result.Message.Header,
+			"kind":     1, // text
+			"detail":   "commit-gen (" + result.Model + ")",
+			"textEdit": lspTextEdit{Range: lspRange{End: lspPosition{Character: p.Position.Character}}, NewText: result.String() + "\n"},
+		}},
+	}, nil
+}
+
+// inlineCompletion continues the draft from the cursor as ghost text, when
+// the cursor ends the draft. Editors ask for it as the user types, after a
+// pause, so only an idle cursor costs a provider call.
+func (s *lspServer) inlineCompletion(params json.RawMessage) (any, error) {
+	var p lspTextDocumentParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	none := map[string]any{"items": []any{}}
+	doc := s.documents[p.TextDocument.URI]
+	if !s.isCommitMessage(p.TextDocument.URI) || doc == nil || p.Position.Line >= draftLineCount(doc.text) {
+		return none, nil
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	before := utf16Prefix(lines[p.Position.Line], p.Position.Character)
+	prefix := strings.Join(append(lines[:p.Position.Line:p.Position.Line], before), "\n")
+	after := strings.Join(append([]string{lines[p.Position.Line][len(before):]}, lines[p.Position.Line+1:draftLineCount(doc.text)]...), "\n")
+	// An empty draft is completed in full by textDocument/completion
+	if strings.TrimSpace(prefix) == "" || strings.TrimSpace(after) != "" {
+		return none, nil
+	}
+
+	commitGen, err := s.commitGen(worktreeOf(uriPath(p.TextDocument.URI), s.rootDir))
+	if err != nil {
+		return nil, err
+	}
+	gitInfo, err := commitGen.GetGitInfo()
+	if err != nil {
+		return nil, err
+	}
+	continuation, err := commitGen.Continue(context.Background(), gitInfo, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if continuation.Text == "" {
+		return none, nil
+	}
+	return map[string]any{"items": []any{map[string]any{"insertText": continuation.Text}}}, nil
+}
+
+// utf16Prefix returns the start of line up to an LSP character offset,
+// which counts UTF-16 code units
+func utf16Prefix(line string, units int) string {
+	for i, r := range line {
+		if units <= 0 {
+			return line[:i]
+		}
+		units -= utf16.RuneLen(r)
+	}
+	return line
+}
+
+// generate writes a message for the repository of the commit message file
+// at uri, using the draft above the comment lines as the hint. The edit
+// replaces that draft and keeps git's comment lines.
+func (s *lspServer) generate(uri string) (*lspTextEdit, *generator.Result, error) {
+	doc := s.documents[uri]
+	if doc == nil {
+		return nil, nil, fmt.Errorf("document %s is not open", uri)
+	}
+	commitGen, err := s.commitGen(worktreeOf(uriPath(uri), s.rootDir))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gitInfo, err := commitGen.GetGitInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+	gitInfo.Hint = draftText(doc.text)
+	result, err := commitGen.GenerateWithConfig(context.Background(), gitInfo, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	draftLines := draftLineCount(doc.text)
+	if draftLines < len(lines) {
+		// Keep a blank line before git's comments
+		return &lspTextEdit{
+			Range:   lspRange{End: lspPosition{Line: draftLines}},
+			NewText: result.String() + "\n\n",
+		}, result, nil
+	}
+	last := lines[len(lines)-1]
+	return &lspTextEdit{
+		Range:   lspRange{End: lspPosition{Line: len(lines) - 1, Character: len(utf16.Encode([]rune(last)))}},
+		NewText: result.String() + "\n",
+	}, result, nil
+}
+
+// commitGen returns the generator for the repository at dir, creating it once
+func (s *lspServer) commitGen(dir string) (*generator.CommitGen, error) {
+	if commitGen := s.repos[dir]; commitGen != nil {
+		return commitGen, nil
+	}
+	commitGen, err := generator.New(s.newOptions(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize commit generator: %w", err)
+	}
+	s.repos[dir] = commitGen
+	return commitGen, nil
+}
+
+// isCommitMessage reports whether uri is a commit message being edited
+func (s *lspServer) isCommitMessage(uri string) bool {
+	if doc := s.documents[uri]; doc != nil && doc.languageID == "gitcommit" {
+		return true
+	}
+	return filepath.Base(uriPath(uri)) == "COMMIT_EDITMSG"
+}
+
+// close releases every generator
+func (s *lspServer) close() {
+	for _, commitGen := range s.repos {
+		commitGen.Close()
+	}
+}
+
+// draftLineCount is the number of lines before git's first comment line
+func draftLineCount(text string) int {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			return i
+		}
+	}
+	return len(lines)
+}
+
+// draftText is what the user wrote above git's comment lines
+func draftText(text string) string {
+	lines := strings.Split(text, "\n")
+	return strings.TrimSpace(strings.Join(lines[:draftLineCount(text)], "\n"))
+}
+
+// worktreeOf returns the working tree of the commit message file at path,
+// which lives in the git directory: .git/COMMIT_EDITMSG, or
+// .git/worktrees/<name>/COMMIT_EDITMSG for linked worktrees
+func worktreeOf(path, fallback string) string {
+	gitDir := filepath.Dir(path)
+	if link, err := os.ReadFile(filepath.Join(gitDir, "gitdir")); err == nil {
+		return filepath.Dir(strings.TrimSpace(string(link)))
+	}
+	if filepath.Base(gitDir) == ".git" {
+		return filepath.Dir(gitDir)
+	}
+	if fallback != "" {
+		return fallback
+	}
+	return gitDir
+}
+
+// uriPath converts a file:// URI to a path
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return u.Path
+}
+
+// readLSPMessage reads one Content-Length framed message body
+func readLSPMessage(reader *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// reply sends the result of request id
+func (s *lspServer) reply(id json.RawMessage, result any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+// replyError sends an error response for request id
+func (s *lspServer) replyError(id json.RawMessage, code int, message string) {
+	s.send(map[string]any{"jsonrpc": "2.0", "id": id, "error": map[string]any{"code": code, "message": message}})
+}
+
+// send writes one framed message
+func (s *lspServer) send(msg any) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		warnf("Warning: failed to encode LSP message: %v", err)
+		return
+	}
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
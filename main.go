@@ -1,53 +1,787 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
 
-	"github.com/joho/godotenv"
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
 	"github.com/nguyenanhhao221/commit-gen/internal/generator"
 )
 
 func main() {
-	// Load environment variables
-	err := godotenv.Load()
+	if !isTerminal(os.Stderr) {
+		// Tools capturing our stderr show errors verbatim, keep them clean
+		log.SetFlags(0)
+		log.SetPrefix("commit-gen: ")
+	}
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		warnf("Warning: tracing disabled: %v", err)
+	} else {
+		onExit(shutdownTracing)
+	}
+
+	os.Args = append(os.Args[:1], changeDir(os.Args[1:])...)
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			runExitHooks()
+			return
+		case "hook":
+			runHook(os.Args[2:])
+			runExitHooks()
+			return
+		case "note":
+			runNote(os.Args[2:])
+			runExitHooks()
+			return
+		case "jj":
+			runJujutsu(os.Args[2:])
+			runExitHooks()
+			return
+		case "lsp":
+			runLSP(os.Args[2:])
+			runExitHooks()
+			return
+		case "cover-letter":
+			runCoverLetter(os.Args[2:])
+			runExitHooks()
+			return
+		case "audit":
+			runAudit(os.Args[2:])
+			runExitHooks()
+			return
+		case "agent":
+			runAgent(os.Args[2:])
+			runExitHooks()
+			return
+		case "plan":
+			runPlan(os.Args[2:])
+			runExitHooks()
+			return
+		case "push-summary":
+			runPushSummary(os.Args[2:])
+			runExitHooks()
+			return
+		case "standup":
+			runStandup(os.Args[2:])
+			runExitHooks()
+			return
+		case "digest":
+			runDigest(os.Args[2:])
+			runExitHooks()
+			return
+		case "search":
+			runSearch(os.Args[2:])
+			runExitHooks()
+			return
+		case "index":
+			runIndex(os.Args[2:])
+			runExitHooks()
+			return
+		case "cache":
+			runCache(os.Args[2:])
+			runExitHooks()
+			return
+		case "export-profile":
+			runExportProfile(os.Args[2:])
+			runExitHooks()
+			return
+		case "import-profile":
+			runImportProfile(os.Args[2:])
+			runExitHooks()
+			return
+		case "bot":
+			runBot(os.Args[2:])
+			runExitHooks()
+			return
+		case "analyze":
+			runAnalyze(os.Args[2:])
+			runExitHooks()
+			return
+		case "bisect-explain":
+			runBisectExplain(os.Args[2:])
+			runExitHooks()
+			return
+		case "wip":
+			runWIP(os.Args[2:])
+			runExitHooks()
+			return
+		case "reword":
+			runReword(os.Args[2:])
+			runExitHooks()
+			return
+		case "stats":
+			runStats(os.Args[2:])
+			runExitHooks()
+			return
+		case "feedback":
+			runFeedback(os.Args[2:])
+			runExitHooks()
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			runExitHooks()
+			return
+		case "learn":
+			runLearn(os.Args[2:])
+			runExitHooks()
+			return
+		case "reword-wip":
+			runRewordWIP(os.Args[2:])
+			runExitHooks()
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			runExitHooks()
+			return
+		case "plugins":
+			runPlugins(os.Args[2:])
+			runExitHooks()
+			return
+		}
+		// Built-in subcommands win over plugins of the same name
+		if path, ok := findPlugin(os.Args[1]); ok {
+			runPlugin(path, os.Args[2:])
+			runExitHooks()
+			return
+		}
+	}
+
+	runGenerate(os.Args[1:])
+	runExitHooks()
+}
+
+// changeDir handles the -C <dir> options before the subcommand and returns
+// the rest of args. Like git, commit-gen then runs as if started in dir:
+// the repository, its config, and relative paths in other flags are found
+// from there. Each -C is relative to the one before.
+func changeDir(args []string) []string {
+	for len(args) > 0 && args[0] == "-C" {
+		if len(args) < 2 || args[1] == "" {
+			fail(exitcode.Usage, "-C needs a directory")
+		}
+		if err := os.Chdir(args[1]); err != nil {
+			fail(exitcode.Usage, fmt.Sprintf("Cannot change to %s: %v", args[1], errors.Unwrap(err)))
+		}
+		args = args[2:]
+	}
+	return args
+}
+
+// exitHooks run before the process exits, e.g. to flush traces
+var exitHooks []func()
+
+// onExit registers a function to run before the process exits
+func onExit(hook func()) {
+	exitHooks = append(exitHooks, hook)
+}
+
+// runExitHooks runs the registered exit hooks in reverse order
+func runExitHooks() {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	exitHooks = nil
+}
+
+// quiet suppresses warnings so that stdout carries only the message and
+// stderr only fatal errors; it defaults to on when stderr is not a terminal,
+// e.g. when run as a lazygit custom command
+var quiet = !isTerminal(os.Stderr)
+
+// warnf logs a non-fatal warning unless quiet is set
+func warnf(format string, args ...any) {
+	if !quiet {
+		log.Printf(format, args...)
+	}
+}
+
+// jsonErrors reports failures as an exitcode.Error JSON object on stderr
+var jsonErrors = os.Getenv("COMMITGEN_JSON_ERRORS") != ""
+
+// fatalf reports a failure and exits with exitcode.Failure
+func fatalf(format string, args ...any) {
+	fail(exitcode.Failure, fmt.Sprintf(format, args...))
+}
+
+// fail runs the exit hooks, reports message, and exits with code
+func fail(code int, message string) {
+	runExitHooks()
+	atomicfile.Cleanup()
+	if emacsOutput {
+		writeSexp(os.Stderr, exitcode.NewError(code, message))
+	} else if porcelain != "" {
+		writePorcelainError(code, message)
+	} else if jsonErrors {
+		json.NewEncoder(os.Stderr).Encode(exitcode.NewError(code, message))
+	} else {
+		log.Print(message)
+	}
+	os.Exit(code)
+}
+
+// failErr is fail with the exit code classified from err. With AI
+// generation switched off it exits 0, so that scripts and hooks carry on.
+func failErr(err error, context string) {
+	if errors.Is(err, generator.ErrDisabled) {
+		warnf("%s: %v", context, err)
+		exit(exitcode.OK)
+	}
+	var interrupted *generator.InterruptedError
+	if errors.As(err, &interrupted) && interrupted.Partial != "" && !jsonErrors && !emacsOutput && porcelain == "" {
+		fmt.Fprintf(os.Stderr, "--- partial output, interrupted before it was final ---\n%s\n--- end of partial output ---\n",
+			strings.TrimSpace(interrupted.Partial))
+	}
+	fail(exitcode.Classify(err), fmt.Sprintf("%s: %v", context, err))
+}
+
+// exit runs the exit hooks, removes the temporary files of unfinished
+// writes, and then exits with code
+func exit(code int) {
+	runExitHooks()
+	atomicfile.Cleanup()
+	os.Exit(code)
+}
+
+// providerFlags holds the flags shared by every command that talks to an AI provider
+type providerFlags struct {
+	provider         *string
+	model            *string
+	quality          *string
+	draftModel       *string
+	ollamaURL        *string
+	relayURL         *string
+	fallbackProvider *string
+	fallbackModel    *string
+	proxyURL         *string
+	headers          headerFlag
+	clientCert       *string
+	clientKey        *string
+	caCert           *string
+	baseURL          *string
+	envFile          *string
+	noAI             *bool
+	readOnly         *bool
+}
+
+// registerProviderFlags adds the provider selection flags to fs
+func registerProviderFlags(fs *flag.FlagSet) *providerFlags {
+	f := &providerFlags{
+		provider:         fs.String("provider", "", "AI provider: gemini (default), anthropic, ollama, or relay"),
+		model:            fs.String("model", "", "Model to use (defaults depend on the provider)"),
+		quality:          fs.String("quality", "", "fast (default), balanced, or max: picks the model, thinking budget, and candidate count"),
+		draftModel:       fs.String("draft-model", "", "Local Ollama model that drafts the message; the cloud model only polishes the draft"),
+		ollamaURL:        fs.String("ollama-url", "", "Ollama server URL (defaults to OLLAMA_HOST or http://localhost:11434)"),
+		relayURL:         fs.String("relay-url", "", "commit-gen relay URL for -provider relay"),
+		fallbackProvider: fs.String("fallback-provider", "", "Provider to fail over to when the primary is unhealthy"),
+		fallbackModel:    fs.String("fallback-model", "", "Model to use on the fallback provider"),
+		proxyURL:         fs.String("proxy", "", "Proxy URL for provider requests (overrides HTTPS_PROXY)"),
+		clientCert:       fs.String("client-cert", "", "PEM client certificate for mTLS"),
+		clientKey:        fs.String("client-key", "", "PEM client key for mTLS (defaults to -client-cert)"),
+		caCert:           fs.String("ca-cert", "", "Additional PEM CA bundle to trust"),
+		baseURL:          fs.String("base-url", "", "Override the provider API endpoint (e.g. an LLM gateway)"),
+		envFile:          fs.String("env-file", "", "Load environment variables (e.g. COMMITGEN_API_KEY) from this dotenv file"),
+		noAI:             fs.Bool("no-ai", false, "Never call a provider: skip generation and exit 0 (like COMMITGEN_DISABLE=1)"),
+		readOnly:         fs.Bool("read-only", false, "Never change the repository, e.g. by staging (like COMMITGEN_READ_ONLY=1)"),
+	}
+	fs.Var(&f.headers, "header", "Extra `Name: value` header for provider requests (repeatable)")
+	return f
+}
+
+// apply copies the provider flags that were set into opts, overriding config values
+func (f *providerFlags) apply(opts *generator.Options) {
+	loadEnvFile(*f.envFile)
+	// The env file may set COMMITGEN_* variables, which still lose to flags
+	if *f.envFile != "" {
+		if err := config.ApplyEnv(opts); err != nil {
+			fail(exitcode.Config, err.Error())
+		}
+	}
+
+	setIfNotEmpty(&opts.Provider, *f.provider)
+	setIfNotEmpty(&opts.Model, *f.model)
+	setIfNotEmpty(&opts.Quality, *f.quality)
+	setIfNotEmpty(&opts.DraftModel, *f.draftModel)
+	setIfNotEmpty(&opts.OllamaURL, *f.ollamaURL)
+	setIfNotEmpty(&opts.RelayURL, *f.relayURL)
+	setIfNotEmpty(&opts.FallbackProvider, *f.fallbackProvider)
+	setIfNotEmpty(&opts.FallbackModel, *f.fallbackModel)
+	opts.Disabled = opts.Disabled || *f.noAI
+	opts.ReadOnly = opts.ReadOnly || *f.readOnly
+
+	if *f.proxyURL != "" || len(f.headers) > 0 || *f.clientCert != "" || *f.caCert != "" || *f.baseURL != "" {
+		opts.Transport = &generator.TransportOptions{
+			ProxyURL:       *f.proxyURL,
+			Headers:        map[string]string(f.headers),
+			ClientCertFile: *f.clientCert,
+			ClientKeyFile:  *f.clientKey,
+			CACertFile:     *f.caCert,
+			BaseURL:        *f.baseURL,
+		}
+	}
+}
+
+// loadEnvFile loads the dotenv file named with -env-file
+func loadEnvFile(path string) {
+	if err := config.LoadEnvFile(path); err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+}
+
+// pickCandidate generates n messages, lets the user pick one, and prints it
+func pickCandidate(commitGen *generator.CommitGen, n int, asJSON bool, outputFormat *template.Template) {
+	ctx, stop := interruptible()
+	results, err := commitGen.Candidates(ctx, n)
+	stop()
+	if err != nil {
+		failErr(err, "Failed to generate commit messages")
+	}
+
+	if asJSON {
+		responses := make([]*generateResponse, len(results))
+		for i, result := range results {
+			responses[i] = newGenerateResponse(result)
+		}
+		printResponse(responses)
+		return
+	}
+
+	choice := 0
+	if len(results) > 1 {
+		messages := make([]string, len(results))
+		for i, result := range results {
+			messages[i] = result.String()
+		}
+		choice, err = pickItem("Pick a message:", messages)
+		if errors.Is(err, errPickerCancelled) {
+			fail(exitcode.Interrupted, "No message selected.")
+		}
+		if err != nil {
+			fail(exitcode.Usage, fmt.Sprintf("-candidates needs a terminal, or -json to print them all: %v", err))
+		}
+	}
+	if outputFormat != nil {
+		printFormatted(outputFormat, results[choice])
+		return
+	}
+	fmt.Println(results[choice].Message)
+}
+
+// readStdin returns the diff on stdin
+func readStdin() string {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fail(exitcode.Failure, fmt.Sprintf("Failed to read the diff from stdin: %v", err))
+	}
+	return string(data)
+}
+
+// readHistoryFile returns the commit messages named with -history-file
+func readHistoryFile(path string) string {
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Println("Warning: Error loading .env file, using system environment")
+		fail(exitcode.Config, fmt.Sprintf("Failed to read history file: %v", err))
 	}
+	return string(data)
+}
 
-	shortCommit := flag.Bool("short", false, "Just generate short commit title")
-	flag.Parse()
+// failNoChanges reports that there is nothing to describe
+func failNoChanges(message string) {
+	if jsonErrors || porcelain != "" {
+		fail(exitcode.NoChanges, message)
+	}
+	fmt.Println(message)
+	exit(exitcode.NoChanges)
+}
+
+// offerToStage stages all modified tracked files when -stage-all is set or
+// the user agrees at the prompt, and reports whether anything is staged now
+func offerToStage(commitGen *generator.CommitGen, stageAll bool) bool {
+	modified, err := commitGen.HasUnstagedChanges()
+	if err != nil {
+		failErr(err, "Failed to check for modified files")
+	}
+	if !modified {
+		return false
+	}
+
+	// -stage-all is the confirmation; otherwise ask, when the answer can matter
+	if !stageAll && (commitGen.ReadOnly() || !confirmCommands("Nothing is staged.", []generator.Command{commitGen.StageAllCommand()})) {
+		return false
+	}
+
+	if err := commitGen.StageAll(); err != nil {
+		failErr(err, "Failed to stage changes")
+	}
+	return true
+}
+
+// askCost shows the estimated cost of the requests and asks whether to send
+// them
+func askCost(estimate *generator.CostEstimate) bool {
+	return askYes(fmt.Sprintf("This is %s. Send it?", estimate))
+}
+
+// generateTwoPhase shows the subject as soon as it is ready and writes the
+// body only when the user asks for it
+func generateTwoPhase(commitGen *generator.CommitGen) (*generator.Result, error) {
+	// Ctrl-C at the question below should still just quit
+	ctx, stop := interruptible()
+	draft, err := commitGen.GenerateSubject(ctx)
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(os.Stderr, draft.Result.Message.Header)
+	if !askYes("Write the body too?") {
+		return draft.Result, nil
+	}
+	ctx, stop = interruptible()
+	defer stop()
+	return draft.Body(ctx)
+}
+
+// interruptible returns a context that Ctrl-C or SIGTERM cancels, so that
+// the provider request in flight is aborted and the command exits through
+// its usual error path; stop restores the default signal handling. Only the
+// first signal is caught, a second one kills the process right away.
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// setIfNotEmpty sets *dst to value when value is not empty
+func setIfNotEmpty(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// loadOptions builds generator options from the config files
+func loadOptions(workingDir string) *generator.Options {
+	cfg, err := config.Load(workingDir)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to load config: %v", err))
+	}
+
+	opts, err := cfg.Options(workingDir)
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	return opts
+}
+
+// gitRepository returns the repository in the current directory for
+// commands that need git but no provider, in read-only mode and with the
+// audit log the config asks for
+func gitRepository() *generator.GitRepository {
+	cfg, err := config.Load("")
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to load config: %v", err))
+	}
+	repo := generator.NewGitRepository("")
+	repo.SetReadOnly(config.Bool(cfg.ReadOnly))
+	if err := repo.SetAuditLog(cfg.AuditLog); err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	return repo
+}
+
+// headerFlag collects repeated -header "Name: value" flags
+type headerFlag map[string]string
+
+// String implements flag.Value
+func (h headerFlag) String() string {
+	parts := make([]string, 0, len(h))
+	for key, value := range h {
+		parts = append(parts, key+": "+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Set implements flag.Value
+func (h *headerFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("header must look like \"Name: value\"")
+	}
+	if *h == nil {
+		*h = headerFlag{}
+	}
+	(*h)[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	return nil
+}
+
+// listFlag collects a repeated flag
+type listFlag []string
+
+// String implements flag.Value
+func (l listFlag) String() string {
+	return strings.Join(l, ", ")
+}
+
+// Set implements flag.Value
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runGenerate generates a commit message for the staged changes and prints it
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("commit-gen", flag.ExitOnError)
+	shortCommit := fs.Bool("short", false, "Just generate short commit title")
+	systemPrompt := fs.String("system-prompt", "", "File that fully replaces the built-in system prompt")
+	noHistory := fs.Bool("no-history", false, "Ignore the git log and base the message only on the diff and convention")
+	styleSource := fs.String("style", "", "What to imitate: history (default), convention, or examples-file")
+	examplesFile := fs.String("examples", "", "File with example messages to imitate (implies -style examples-file)")
+	scopesFile := fs.String("scopes", "", "YAML scope glossary mapping each scope to what it covers (default: scopes.yaml in the repository root)")
+	blame := fs.Bool("blame", false, "Include the commits that last changed the modified lines")
+	related := fs.Bool("related", false, "Include files that historically change together with the staged files")
+	var hint string
+	fs.StringVar(&hint, "hint", "", "Your summary of the change, used to steer the type and subject")
+	fs.StringVar(&hint, "m", "", "Shorthand for -hint")
+	commitType := fs.String("type", "", "Pin the commit type (e.g. fix), the AI writes only the description and body")
+	scope := fs.String("scope", "", "Pin the commit scope (e.g. parser)")
+	noTemplate := fs.Bool("no-template", false, "Do not merge the message into the configured commit.template")
+	asciiOnly := fs.Bool("ascii", false, "Only use ASCII characters (no emoji or typographic punctuation)")
+	noReflow := fs.Bool("no-reflow", false, "Keep the body as the model wrapped it instead of reflowing it to 72 columns")
+	closeIssue := fs.Bool("close-issue", false, "Add \"Fixes: #N\" to fix commits when the branch names an issue")
+	issue := fs.String("issue", "", "Issue number to close (implies -close-issue)")
+	convention := fs.String("convention", "", "Message convention: conventional (default) or kernel for mailing-list patches")
+	platform := fs.String("platform", "", "Length limits of the hosting platform: auto (default, from the remote), none, github, gitlab, gerrit, or bitbucket")
+	series := fs.Int("series", 0, "Number of patches in the series, to leave room for the [PATCH n/m] prefix")
+	vcs := fs.String("vcs", "", "Version control system: git (default), jj, hg, or auto")
+	noContent := fs.Bool("no-content", false, "Describe the change from the staged file names only (for partial clones)")
+	semanticRelease := fs.Bool("semantic-release", false, "Only write messages semantic-release parses: its types, and BREAKING CHANGE footers instead of \"!\"")
+	dedupe := fs.Int("dedupe", 0, "Re-ask when the subject nearly repeats one of the last N commit subjects")
+	dedupeScope := fs.Bool("dedupe-scope", false, "Add the scope of the staged files to a subject that still repeats a recent one")
+	verifyClaims := fs.Bool("verify-claims", false, "Check the message for claims the diff does not support, like \"add tests\" when no test changed")
+	grounded := fs.Bool("grounded", false, "Write the body as bullets and drop any bullet no hunk of the diff backs (-json shows the mapping)")
+	deterministic := fs.Bool("deterministic", false, "Sample at temperature 0 with a fixed seed, for reproducible messages in CI")
+	noMerges := fs.Bool("no-merges", false, "Leave merge commits out of the history shown to the model")
+	fs.BoolVar(&quiet, "quiet", quiet, "Only print the message and fatal errors (default when stderr is not a terminal)")
+	twoPhase := fs.Bool("two-phase", false, "Show the subject first and write the body only if you ask (needs a terminal)")
+	candidates := fs.Int("candidates", 0, "Generate N alternative messages and pick one in a fuzzy picker (with -json, print them all)")
+	asJSON := fs.Bool("json", false, "Print the result as JSON on stdout and errors as JSON on stderr")
+	format := fs.String("format", "", "Print the result through a Go template, e.g. \"{{.Type}}|{{.Scope}}|{{.Subject}}\"")
+	fs.BoolVar(&emacsOutput, "emacs", false, "Like -json, but print Emacs Lisp plists (for the Magit integration)")
+	fs.Var(porcelainFlag{}, "porcelain", "Print the result in a stable, versioned format for scripts (v1, the default)")
+	stageAll := fs.Bool("stage-all", false, "Stage all modified tracked files when nothing is staged")
+	fromStdin := fs.Bool("stdin", false, "Describe the diff piped on stdin instead of the staged changes (for GUI clients); without one, fall back to git")
+	historyFile := fs.String("history-file", "", "File with the recent commit messages to show along with a diff from stdin")
+	maxAttempts := fs.Int("max-attempts", 0, "Regenerate a message that breaks the commit rules up to N times (default 3)")
+	confirmCost := fs.Bool("confirm-cost", false, "Show the estimated tokens and cost and ask before sending")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	// "commit-gen -" is a pure filter: the diff always comes from stdin and
+	// git is never asked. Flags may follow the "-".
+	filter := fs.Arg(0) == "-"
+	if filter {
+		fs.Parse(fs.Args()[1:])
+	}
+	// Porcelain output is for scripts: nothing on stderr but a fatal error,
+	// and no questions
+	if porcelain != "" {
+		quiet = true
+	}
+
+	// The kill switch skips everything, git included, to stay instant
+	if *providers.noAI || generator.Disabled() {
+		warnf("AI generation is disabled, no message generated")
+		exit(exitcode.OK)
+	}
+
+	// API key will be loaded from COMMITGEN_API_KEY, or else GOOGLE_API_KEY
+	// or ANTHROPIC_API_KEY depending on the provider
+	// WorkingDir defaults to current directory
+	opts := loadOptions("")
+	opts.IsShortCommit = *shortCommit
+	opts.MaxAttempts = *maxAttempts
+	opts.NoHistory = opts.NoHistory || *noHistory
+	opts.BlameContext = opts.BlameContext || *blame
+	opts.RelatedFiles = opts.RelatedFiles || *related
+	opts.Hint = hint
+	opts.Type = *commitType
+	opts.Scope = *scope
+	opts.NoTemplate = *noTemplate
+	opts.ASCIIOnly = opts.ASCIIOnly || *asciiOnly
+	opts.NoReflow = *noReflow
+	opts.Deterministic = opts.Deterministic || *deterministic
+	opts.VerifyClaims = opts.VerifyClaims || *verifyClaims
+	if *dedupe != 0 {
+		opts.DedupeSubjects = *dedupe
+	}
+	opts.DedupeScope = opts.DedupeScope || *dedupeScope
+	opts.Grounded = opts.Grounded || *grounded
+	opts.SemanticRelease = opts.SemanticRelease || *semanticRelease
+	opts.CloseIssues = opts.CloseIssues || *closeIssue || *issue != ""
+	opts.Issue = strings.TrimPrefix(*issue, "#")
+	setIfNotEmpty(&opts.Convention, *convention)
+	setIfNotEmpty(&opts.Platform, *platform)
+	opts.SeriesLength = *series
+	setIfNotEmpty(&opts.VCS, *vcs)
+	opts.NoContent = *noContent
+	opts.NoMerges = opts.NoMerges || *noMerges
+	setIfNotEmpty(&opts.StyleSource, *styleSource)
+	if *examplesFile != "" {
+		opts.ExamplesFile = *examplesFile
+		if *styleSource == "" {
+			opts.StyleSource = generator.StyleExamples
+		}
+	}
+	setIfNotEmpty(&opts.ScopesFile, *scopesFile)
+	setIfNotEmpty(&opts.SystemPromptFile, *systemPrompt)
+	providers.apply(opts)
+	*asJSON = *asJSON || emacsOutput
+	jsonErrors = jsonErrors || *asJSON
+	// Over cost_limit, ask when a person can answer and refuse otherwise
+	if *confirmCost && !canAsk() {
+		fail(exitcode.Usage, "-confirm-cost needs a terminal to ask on")
+	} else if canAsk() && (*confirmCost || opts.CostLimit > 0) {
+		if *confirmCost {
+			opts.CostLimit = 0
+		}
+		opts.ConfirmCost = askCost
+	}
+	var outputFormat *template.Template
+	if *format != "" {
+		if *asJSON {
+			fail(exitcode.Usage, "-format cannot be combined with -json or -emacs")
+		}
+		outputFormat = parseFormat(*format)
+	}
+	if porcelain != "" && (*asJSON || outputFormat != nil || *candidates > 1) {
+		fail(exitcode.Usage, "-porcelain cannot be combined with -json, -emacs, -format, or -candidates")
+	}
 
 	// Create commit generator
-	commitGen, err := generator.New(&generator.Options{
-		IsShortCommit: *shortCommit,
-		// API key will be loaded from GOOGLE_API_KEY environment variable
-		// WorkingDir defaults to current directory
-	})
+	commitGen, err := generator.New(opts)
 	if err != nil {
-		log.Fatalf("Failed to initialize commit generator: %v", err)
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
 	}
 	defer commitGen.Close()
+	defer sendFeedback(commitGen)
 
-	// Check for staged changes first
-	hasChanges, err := commitGen.HasStagedChanges()
-	if err != nil {
-		log.Fatalf("Failed to check for staged changes: %v", err)
+	// GUI clients like GitHub Desktop or Tower may pipe the selected
+	// changes, which need not be staged
+	var stdinDiff string
+	switch {
+	case filter:
+		if stdinDiff = readStdin(); strings.TrimSpace(stdinDiff) == "" {
+			failNoChanges("No diff on stdin.")
+		}
+	case *fromStdin && !isTerminal(os.Stdin):
+		if stdinDiff = readStdin(); strings.TrimSpace(stdinDiff) == "" {
+			stdinDiff = ""
+		}
 	}
 
-	if !hasChanges {
-		fmt.Println("No staged changes found. Please stage your changes with 'git add' first.")
-		os.Exit(1)
+	if stdinDiff == "" {
+		// Check for staged changes first
+		hasChanges, err := commitGen.HasStagedChanges()
+		if err != nil {
+			failErr(err, "Failed to check for staged changes")
+		}
+
+		if !hasChanges && commitGen.VCS().Name() == generator.VCSGit {
+			hasChanges = offerToStage(commitGen, *stageAll)
+		}
+
+		if !hasChanges {
+			message := "No changes found in the working copy."
+			if commitGen.VCS().Name() == generator.VCSGit {
+				message = "No staged changes found. Please stage your changes with 'git add' first."
+			}
+			failNoChanges(message)
+		}
 	}
 
-	// Generate commit message
-	message, err := commitGen.Generate()
+	// Higher quality levels offer a choice when someone is there to make it;
+	// New has already rejected unknown levels
+	if *candidates == 0 && canAsk() && stdinDiff == "" && !*twoPhase {
+		quality, _ := generator.Quality(opts.Quality)
+		*candidates = quality.Candidates
+	}
+	if *candidates > 1 {
+		if stdinDiff != "" {
+			fail(exitcode.Usage, "-candidates works on the staged changes only, not on a diff from stdin")
+		}
+		pickCandidate(commitGen, *candidates, *asJSON, outputFormat)
+		return
+	}
+
+	// Generate commit message; two-phase generation needs someone to ask,
+	// so hooks and scripts get the full message
+	var result *generator.Result
+	switch {
+	case stdinDiff != "":
+		ctx, stop := interruptible()
+		result, err = commitGen.GenerateFromDiffContext(ctx, stdinDiff, readHistoryFile(*historyFile))
+		stop()
+	case *twoPhase && !*shortCommit && canAsk():
+		result, err = generateTwoPhase(commitGen)
+	default:
+		ctx, stop := interruptible()
+		result, err = commitGen.GenerateContext(ctx)
+		stop()
+	}
+	var validationErr *generator.ValidationError
+	if errors.As(err, &validationErr) && !*asJSON {
+		warnf("Last attempt:\n%s", validationErr.Result.Message)
+	}
 	if err != nil {
-		log.Fatalf("Failed to generate commit message: %v", err)
+		failErr(err, "Failed to generate commit message")
+	}
+	if result.Truncated() && !*asJSON {
+		warnf("Warning: the model hit its output limit, the message may be truncated")
+	}
+	if len(result.UnsupportedClaims) > 0 && !*asJSON {
+		warnf("Warning: the message may claim what the diff does not show:\n- %s", strings.Join(result.UnsupportedClaims, "\n- "))
+	}
+	if result.DuplicateOf != "" && !*asJSON {
+		warnf("Warning: the subject nearly repeats the recent commit %q", result.DuplicateOf)
+	}
+
+	if *asJSON {
+		printResponse(newGenerateResponse(result))
+		return
+	}
+	if porcelain != "" {
+		writePorcelain(os.Stdout, result)
+		return
 	}
 
 	// Output the generated commit message
-	fmt.Println(message)
+	if outputFormat != nil {
+		printFormatted(outputFormat, result)
+		return
+	}
+	fmt.Println(result.Message)
 }
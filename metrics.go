@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// latencyBuckets are the histogram upper bounds in seconds
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// histogram is a cumulative Prometheus-style histogram
+type histogram struct {
+	counts []uint64 // one per bucket, non-cumulative
+	sum    float64
+	total  uint64
+}
+
+// observe records a value in seconds
+func (h *histogram) observe(seconds float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(latencyBuckets))
+	}
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.total++
+}
+
+// serveMetrics collects daemon metrics and renders them in the Prometheus
+// text exposition format. It implements generator.Observer.
+type serveMetrics struct {
+	mu              sync.Mutex
+	requests        map[string]uint64     // endpoint, code
+	requestLatency  map[string]*histogram // endpoint
+	providerCalls   map[string]uint64     // provider, model
+	providerErrors  map[string]uint64     // provider, model
+	providerLatency map[string]*histogram // provider, model
+	tokens          map[string]uint64     // provider, model, kind
+	feedback        map[string]uint64     // signal, model, type, edited
+	cacheHits       uint64
+	cacheMisses     uint64
+}
+
+// newServeMetrics creates an empty metrics registry
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		requests:        make(map[string]uint64),
+		requestLatency:  make(map[string]*histogram),
+		providerCalls:   make(map[string]uint64),
+		providerErrors:  make(map[string]uint64),
+		providerLatency: make(map[string]*histogram),
+		tokens:          make(map[string]uint64),
+		feedback:        make(map[string]uint64),
+	}
+}
+
+// labels renders label pairs as a Prometheus label set key
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%s", pairs[i], strconv.Quote(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// ProviderCall implements generator.Observer
+func (m *serveMetrics) ProviderCall(provider, model string, latency time.Duration, usage generator.Usage, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := labels("provider", provider, "model", model)
+	m.providerCalls[key]++
+	if err != nil {
+		m.providerErrors[key]++
+	}
+	if m.providerLatency[key] == nil {
+		m.providerLatency[key] = &histogram{}
+	}
+	m.providerLatency[key].observe(latency.Seconds())
+
+	m.tokens[labels("provider", provider, "model", model, "kind", "prompt")] += uint64(usage.PromptTokens)
+	m.tokens[labels("provider", provider, "model", model, "kind", "output")] += uint64(usage.OutputTokens)
+}
+
+// CacheLookup implements generator.Observer
+func (m *serveMetrics) CacheLookup(hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// Feedback counts the feedback signals sent by opted-in CLIs
+func (m *serveMetrics) Feedback(signals []generator.FeedbackSignal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range signals {
+		m.feedback[labels("signal", s.Signal, "model", s.Model, "type", s.Type, "edited", strings.Join(s.Edited, ","))]++
+	}
+}
+
+// instrument wraps an HTTP handler to count requests and measure latency
+func (m *serveMetrics) instrument(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests[labels("endpoint", endpoint, "code", strconv.Itoa(rec.status))]++
+		key := labels("endpoint", endpoint)
+		if m.requestLatency[key] == nil {
+			m.requestLatency[key] = &histogram{}
+		}
+		m.requestLatency[key].observe(time.Since(start).Seconds())
+	}
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// ServeHTTP renders all metrics in the Prometheus text format
+func (m *serveMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	writeCounter(w, "commitgen_http_requests_total", "HTTP requests served by endpoint and status code.", m.requests)
+	writeHistogram(w, "commitgen_http_request_duration_seconds", "HTTP request latency by endpoint.", m.requestLatency)
+	writeCounter(w, "commitgen_provider_requests_total", "Requests sent to AI providers.", m.providerCalls)
+	writeCounter(w, "commitgen_provider_errors_total", "Failed AI provider requests.", m.providerErrors)
+	writeHistogram(w, "commitgen_provider_request_duration_seconds", "AI provider request latency.", m.providerLatency)
+	writeCounter(w, "commitgen_tokens_total", "Tokens consumed by kind (prompt or output).", m.tokens)
+	writeCounter(w, "commitgen_feedback_signals_total", "Feedback on generated messages: accepted, edited (and what), or rejected.", m.feedback)
+	writeCounter(w, "commitgen_cache_hits_total", "Response cache hits.", map[string]uint64{"": m.cacheHits})
+	writeCounter(w, "commitgen_cache_misses_total", "Response cache misses.", map[string]uint64{"": m.cacheMisses})
+
+	var hitRate float64
+	if lookups := m.cacheHits + m.cacheMisses; lookups > 0 {
+		hitRate = float64(m.cacheHits) / float64(lookups)
+	}
+	fmt.Fprintf(w, "# HELP commitgen_cache_hit_ratio Fraction of cache lookups that were hits.\n")
+	fmt.Fprintf(w, "# TYPE commitgen_cache_hit_ratio gauge\n")
+	fmt.Fprintf(w, "commitgen_cache_hit_ratio %g\n", hitRate)
+}
+
+// sortedKeys returns the map keys in a stable order
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeCounter renders a counter family
+func writeCounter(w io.Writer, name, help string, values map[string]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, key := range sortedKeys(values) {
+		if key == "" {
+			fmt.Fprintf(w, "%s %d\n", name, values[key])
+		} else {
+			fmt.Fprintf(w, "%s{%s} %d\n", name, key, values[key])
+		}
+	}
+}
+
+// writeHistogram renders a histogram family
+func writeHistogram(w io.Writer, name, help string, values map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, key := range sortedKeys(values) {
+		h := values[key]
+		var cumulative uint64
+		for i, bound := range latencyBuckets {
+			if h.counts != nil {
+				cumulative += h.counts[i]
+			}
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"%g\"} %d\n", name, key, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.total)
+		fmt.Fprintf(w, "%s_sum{%s} %g\n", name, key, h.sum)
+		fmt.Fprintf(w, "%s_count{%s} %d\n", name, key, h.total)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runNote records, shows, or clears the intent notes for the next commit
+func runNote(args []string) {
+	fs := flag.NewFlagSet("commit-gen note", flag.ExitOnError)
+	clearNotes := fs.Bool("clear", false, "Remove the notes for the next commit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: commit-gen note [-clear] [text...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	repo := gitRepository()
+
+	switch {
+	case *clearNotes:
+		if err := repo.ClearNotes(); err != nil {
+			failErr(err, "Failed to clear the notes")
+		}
+	case fs.NArg() > 0:
+		if err := repo.AddNote(strings.Join(fs.Args(), " ")); err != nil {
+			failErr(err, "Failed to add the note")
+		}
+	default:
+		notes, err := repo.ReadNotes()
+		if err != nil {
+			fatalf("%v", err)
+		}
+		if notes != "" {
+			fmt.Println(notes)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errPickerCancelled is returned when the user leaves the picker without a choice
+var errPickerCancelled = errors.New("selection cancelled")
+
+// pickerMaxRows caps the number of items shown at once
+const pickerMaxRows = 10
+
+// pickItem lets the user fuzzy-filter items and choose one on the terminal,
+// without any external tool like fzf. Each item is shown by its first line,
+// with the rest of the selected item as a preview. It draws on /dev/tty, so
+// it works while stdout is captured, e.g. in git commit -m "$(commit-gen ...)".
+func pickItem(prompt string, items []string) (int, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return -1, fmt.Errorf("no terminal to pick from: %w", err)
+	}
+	defer tty.Close()
+
+	restore, err := rawTerminal(tty)
+	if err != nil {
+		return -1, err
+	}
+	defer restore()
+
+	p := &picker{tty: tty, prompt: prompt, items: items, width: terminalWidth(tty)}
+	p.filter()
+	defer p.clear()
+
+	buf := make([]byte, 64)
+	for {
+		p.render()
+		n, err := tty.Read(buf)
+		if err != nil {
+			return -1, err
+		}
+		// Escape sequences arrive in one read, so a lone ESC is the key itself
+		switch key := string(buf[:n]); key {
+		case "\r", "\n":
+			if len(p.matches) == 0 {
+				continue
+			}
+			return p.matches[p.cursor], nil
+		case "\x1b", "\x03", "\x07": // Esc, Ctrl-C, Ctrl-G
+			return -1, errPickerCancelled
+		case "\x1b[A", "\x1bOA", "\x10", "\x0b": // Up, Ctrl-P, Ctrl-K
+			p.move(-1)
+		case "\x1b[B", "\x1bOB", "\x0e": // Down, Ctrl-N
+			p.move(1)
+		case "\x7f", "\x08": // Backspace
+			if p.query != "" {
+				_, size := utf8.DecodeLastRuneInString(p.query)
+				p.query = p.query[:len(p.query)-size]
+				p.filter()
+			}
+		case "\x15": // Ctrl-U
+			p.query = ""
+			p.filter()
+		default:
+			if r, _ := utf8.DecodeRuneInString(key); !strings.HasPrefix(key, "\x1b") && unicode.IsPrint(r) {
+				p.query += key
+				p.filter()
+			}
+		}
+	}
+}
+
+// picker is the state of one pickItem session
+type picker struct {
+	tty     *os.File
+	prompt  string
+	items   []string
+	query   string
+	matches []int
+	cursor  int
+	width   int
+}
+
+// filter ranks the items matching the query, best first
+func (p *picker) filter() {
+	type scored struct{ index, score int }
+	var ranked []scored
+	for i, item := range p.items {
+		if score, ok := fuzzyScore(p.query, item); ok {
+			ranked = append(ranked, scored{i, score})
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b scored) int { return b.score - a.score })
+
+	p.matches = p.matches[:0]
+	for _, r := range ranked {
+		p.matches = append(p.matches, r.index)
+	}
+	p.cursor = 0
+}
+
+// move moves the selection, wrapping around
+func (p *picker) move(delta int) {
+	if len(p.matches) > 0 {
+		p.cursor = (p.cursor + delta + len(p.matches)) % len(p.matches)
+	}
+}
+
+// render redraws the picker; the cursor is kept on its first line, so
+// erasing to the end of the screen removes the previous render
+func (p *picker) render() {
+	var b strings.Builder
+	b.WriteString("\r\x1b[J")
+
+	lines := []string{fmt.Sprintf("%s %s", p.prompt, p.query)}
+	first := max(0, p.cursor-pickerMaxRows+1)
+	for row, index := range p.matches[first:min(len(p.matches), first+pickerMaxRows)] {
+		marker := "  "
+		if first+row == p.cursor {
+			marker = "> "
+		}
+		title, _, _ := strings.Cut(p.items[index], "\n")
+		lines = append(lines, marker+title)
+	}
+	lines = append(lines, fmt.Sprintf("  %d/%d", len(p.matches), len(p.items)))
+	if len(p.matches) > 0 {
+		if _, preview, ok := strings.Cut(p.items[p.matches[p.cursor]], "\n"); ok {
+			for _, line := range strings.Split(strings.Trim(preview, "\n"), "\n") {
+				lines = append(lines, "  │ "+line)
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(truncateWidth(line, p.width))
+	}
+	// Leave the cursor at the end of the query line
+	if len(lines) > 1 {
+		fmt.Fprintf(&b, "\x1b[%dA", len(lines)-1)
+	}
+	fmt.Fprintf(&b, "\r\x1b[%dC", utf8.RuneCountInString(lines[0]))
+	p.tty.WriteString(b.String())
+}
+
+// clear erases the picker when it closes
+func (p *picker) clear() {
+	p.tty.WriteString("\r\x1b[J")
+}
+
+// fuzzyScore reports whether the query's characters appear in order in
+// text, case-insensitively, scoring consecutive and early matches higher
+func fuzzyScore(query, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+	q := []rune(strings.ToLower(query))
+	score, qi, last := 0, 0, -2
+	for i, r := range []rune(strings.ToLower(text)) {
+		if qi == len(q) {
+			break
+		}
+		if r != q[qi] {
+			continue
+		}
+		score += 10
+		if i == last+1 {
+			score += 15
+		}
+		if i == 0 {
+			score += 5
+		}
+		last = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score - last/4, true
+}
+
+// truncateWidth cuts line to width runes so it never wraps
+func truncateWidth(line string, width int) string {
+	if width <= 1 || utf8.RuneCountInString(line) < width {
+		return line
+	}
+	return string([]rune(line)[:width-2]) + "…"
+}
+
+// rawTerminal switches tty to raw mode and returns the function restoring it
+func rawTerminal(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read terminal settings: %w", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("failed to set raw mode: %w", err)
+	}
+	return func() { stty(tty, strings.TrimSpace(saved)) }, nil
+}
+
+// terminalWidth returns the column count of tty, defaulting to 80
+func terminalWidth(tty *os.File) int {
+	size, err := stty(tty, "size")
+	if fields := strings.Fields(size); err == nil && len(fields) == 2 {
+		if cols, err := strconv.Atoi(fields[1]); err == nil && cols > 0 {
+			return cols
+		}
+	}
+	return 80
+}
+
+// stty runs stty on tty
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	output, err := cmd.Output()
+	return string(output), err
+}
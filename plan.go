@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runPlan proposes a series of commits for all uncommitted changes as a
+// YAML plan; "plan apply" makes the commits of a (possibly edited) plan
+func runPlan(args []string) {
+	if len(args) > 0 && args[0] == "apply" {
+		runPlanApply(args[1:])
+		return
+	}
+
+	fs := flag.NewFlagSet("commit-gen plan", flag.ExitOnError)
+	output := fs.String("o", "", "Write the plan to this file instead of stdout")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	plan, err := commitGen.Plan(context.Background())
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges("No uncommitted changes to plan.")
+	}
+	if err != nil {
+		failErr(err, "Failed to plan commits")
+	}
+
+	text := formatPlan(plan)
+	if *output == "" {
+		fmt.Print(text)
+		return
+	}
+	if err := atomicfile.WriteFile(*output, []byte(text), 0o644); err != nil {
+		fatalf("Failed to write plan: %v", err)
+	}
+	warnf("Planned %d commits in %s, review it and run: commit-gen plan apply %s", len(plan.Commits), *output, *output)
+}
+
+// runPlanApply makes the commits of a plan in order, stopping at the first failure
+func runPlanApply(args []string) {
+	fs := flag.NewFlagSet("commit-gen plan apply", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Print the commits instead of making them")
+	yes := fs.Bool("yes", false, "Make the commits without asking first")
+	fs.Parse(args)
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "" || path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to read plan: %v", err))
+	}
+	plan, err := parsePlan(string(data))
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Invalid plan: %v", err))
+	}
+
+	// Applying needs git only, not a provider
+	repo := gitRepository()
+	if !*dryRun {
+		var commands []generator.Command
+		for _, commit := range plan.Commits {
+			commands = append(commands, repo.CommitFilesCommands(commit.Message, commit.Files)...)
+		}
+		requireConfirmation(commands, *yes)
+	}
+	for i, commit := range plan.Commits {
+		header, _, _ := strings.Cut(commit.Message, "\n")
+		if *dryRun {
+			fmt.Printf("[%d/%d] %s\n", i+1, len(plan.Commits), header)
+			for _, path := range commit.Files {
+				fmt.Printf("    %s\n", path)
+			}
+			continue
+		}
+		if err := repo.CommitFiles(commit.Message, commit.Files); err != nil {
+			failErr(err, fmt.Sprintf("Commit %d of %d (%s) failed, %d applied", i+1, len(plan.Commits), header, i))
+		}
+		log.Printf("[%d/%d] %s", i+1, len(plan.Commits), header)
+	}
+}
+
+// planPlainPath matches paths that need no quoting in YAML
+var planPlainPath = regexp.MustCompile(`^[A-Za-z0-9_.@+/][A-Za-z0-9_.@+/-]*$`)
+
+// formatPlan writes plan as YAML, with each message as a literal block so
+// it is easy to edit
+func formatPlan(plan *generator.Plan) string {
+	var b strings.Builder
+	b.WriteString("# Commit plan by commit-gen. Edit, reorder, or move files between\n")
+	b.WriteString("# commits, then run: commit-gen plan apply <this file>\n")
+	b.WriteString("commits:\n")
+	for _, commit := range plan.Commits {
+		b.WriteString("  - message: |\n")
+		for _, line := range strings.Split(commit.Message, "\n") {
+			if line == "" {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("      " + line + "\n")
+			}
+		}
+		b.WriteString("    files:\n")
+		for _, path := range commit.Files {
+			if !planPlainPath.MatchString(path) {
+				path = strconv.Quote(path)
+			}
+			b.WriteString("      - " + path + "\n")
+		}
+	}
+	return b.String()
+}
+
+// parsePlan reads the YAML written by formatPlan, after any hand edits. It
+// understands that shape only: a commits list of message and files keys,
+// with messages as literal blocks or single-line scalars.
+func parsePlan(text string) (*generator.Plan, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	plan := &generator.Plan{}
+	var commit *generator.PlannedCommit
+	inCommits, inFiles := false, false
+	itemIndent := -1
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		lineNo := i + 1
+
+		if indent == 0 && !(inCommits && strings.HasPrefix(trimmed, "-")) {
+			if trimmed != "commits:" {
+				return nil, fmt.Errorf("line %d: expected \"commits:\"", lineNo)
+			}
+			inCommits = true
+			continue
+		}
+		if !inCommits {
+			return nil, fmt.Errorf("line %d: expected \"commits:\"", lineNo)
+		}
+
+		if itemIndent < 0 && strings.HasPrefix(trimmed, "-") {
+			itemIndent = indent
+		}
+		if indent == itemIndent && strings.HasPrefix(trimmed, "-") {
+			// A new commit; its first key follows the dash
+			plan.Commits = append(plan.Commits, generator.PlannedCommit{})
+			commit = &plan.Commits[len(plan.Commits)-1]
+			inFiles = false
+			trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			indent += len(line[indent:]) - len(strings.TrimLeft(line[indent+1:], " "))
+			if trimmed == "" {
+				continue
+			}
+		} else if commit == nil || indent <= itemIndent {
+			return nil, fmt.Errorf("line %d: expected a commit starting with \"- \"", lineNo)
+		}
+
+		if inFiles && strings.HasPrefix(trimmed, "- ") {
+			path, err := yamlScalar(strings.TrimPrefix(trimmed, "- "))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			commit.Files = append(commit.Files, path)
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"message:\" or \"files:\"", lineNo)
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "message":
+			inFiles = false
+			if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+				var block []string
+				block, i = yamlBlock(lines, i+1, indent)
+				if strings.HasPrefix(value, ">") {
+					commit.Message = strings.Join(block, " ")
+				} else {
+					commit.Message = strings.Join(block, "\n")
+				}
+			} else {
+				message, err := yamlScalar(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				commit.Message = message
+			}
+			commit.Message = strings.TrimSpace(commit.Message)
+		case "files":
+			if value != "" && value != "[]" {
+				return nil, fmt.Errorf("line %d: list the files on their own lines, each starting with \"- \"", lineNo)
+			}
+			inFiles = true
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q", lineNo, key)
+		}
+	}
+
+	if len(plan.Commits) == 0 {
+		return nil, errors.New("no commits")
+	}
+	for i, commit := range plan.Commits {
+		if commit.Message == "" {
+			return nil, fmt.Errorf("commit %d has no message", i+1)
+		}
+		if len(commit.Files) == 0 {
+			return nil, fmt.Errorf("commit %d has no files", i+1)
+		}
+	}
+	return plan, nil
+}
+
+// yamlBlock collects the lines of a block scalar starting at lines[start],
+// indented deeper than keyIndent, and returns them dedented along with the
+// index of the last line consumed
+func yamlBlock(lines []string, start, keyIndent int) ([]string, int) {
+	blockIndent := -1
+	var block []string
+	end := start - 1
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			block = append(block, "")
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if indent <= keyIndent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = indent
+		}
+		block = append(block, line[min(indent, blockIndent):])
+		end = i
+	}
+	// Blank lines after the block belong to what follows
+	return block[:max(0, end-start+1)], end
+}
+
+// yamlScalar decodes a single-line plain, single-quoted, or double-quoted
+// scalar, which may be followed by a comment
+func yamlScalar(value string) (string, error) {
+	var scalar, rest string
+	switch {
+	case strings.HasPrefix(value, `"`):
+		quoted, err := strconv.QuotedPrefix(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted string %s", value)
+		}
+		scalar, _ = strconv.Unquote(quoted)
+		rest = value[len(quoted):]
+	case strings.HasPrefix(value, "'"):
+		// Inside single quotes, '' is an escaped quote
+		end := 1
+		for {
+			next := strings.IndexByte(value[end:], '\'')
+			if next < 0 {
+				return "", fmt.Errorf("invalid quoted string %s", value)
+			}
+			end += next + 1
+			if !strings.HasPrefix(value[end:], "'") {
+				break
+			}
+			end++
+		}
+		scalar = strings.ReplaceAll(value[1:end-1], "''", "'")
+		rest = value[end:]
+	default:
+		// A plain scalar ends at a comment
+		scalar, _, _ = strings.Cut(value, " #")
+		return strings.TrimSpace(scalar), nil
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after quoted string", rest)
+	}
+	return scalar, nil
+}
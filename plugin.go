@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// pluginPrefix starts the name of every plugin executable: "commit-gen
+// jira" runs commitgen-jira from PATH, like git and kubectl plugins
+const pluginPrefix = "commitgen-"
+
+// pluginProtocolVersion is the version of pluginInput; it changes only
+// when a field changes meaning or goes away
+const pluginProtocolVersion = 1
+
+// pluginInput is the JSON a plugin reads on stdin
+type pluginInput struct {
+	Version int `json:"version"`
+	// Args are the arguments after the plugin name
+	Args []string `json:"args"`
+	// Config holds the settings of the merged config files, keyed as in
+	// the files
+	Config map[string]any `json:"config"`
+	// GitInfo is the pending change and recent history, null when nothing
+	// is staged or the directory is not a repository
+	GitInfo *generator.GitInfo `json:"git_info"`
+}
+
+// notPlugins are executables with the plugin prefix that commit-gen ships
+// and that speak another protocol
+var notPlugins = []string{"nvim"}
+
+// findPlugin returns the path of the plugin executable for a subcommand
+func findPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, `/\`) || slices.Contains(notPlugins, name) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	return path, err == nil
+}
+
+// runPlugin runs a plugin with the config and pending change on stdin and
+// exits with its exit code
+func runPlugin(path string, args []string) {
+	cfg, err := config.Load("")
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to load config: %v", err))
+	}
+	values, err := cfg.Values()
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+
+	input := pluginInput{Version: pluginProtocolVersion, Args: args, Config: values}
+	if input.Args == nil {
+		input.Args = []string{}
+	}
+	// Plugins that do not need the change must still run without one
+	if vcs, err := generator.NewVCS(cfg.VCS, ""); err == nil {
+		input.GitInfo, _ = vcs.ChangeContext(&generator.ContextOptions{})
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		fatalf("Failed to encode the plugin input: %v", err)
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = strings.NewReader(string(data))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "COMMITGEN_PLUGIN_VERSION="+fmt.Sprint(pluginProtocolVersion))
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		fatalf("Failed to run %s: %v", filepath.Base(path), err)
+	}
+}
+
+// runPlugins lists the plugins found on PATH, one name per line
+func runPlugins(args []string) {
+	if len(args) > 0 {
+		fail(exitcode.Usage, "Usage: commit-gen plugins")
+	}
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), pluginPrefix)
+			if !ok || name == "" || slices.Contains(names, name) {
+				continue
+			}
+			// Only what LookPath would run counts, e.g. not a shadowed
+			// or non-executable file
+			if path, found := findPlugin(name); found && filepath.Dir(path) == dir {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+}
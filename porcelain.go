@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// porcelainV1 is the first porcelain format. A format never changes once
+// released: v1 may gain keys, which parsers must skip, but a key never
+// changes meaning and the layout stays the same. Anything else is a new
+// version.
+const porcelainV1 = "v1"
+
+// porcelain is the machine format requested with -porcelain, "" for the
+// human output
+var porcelain string
+
+// porcelainFlag is -porcelain, which takes an optional version: a bare
+// -porcelain is -porcelain=v1, so scripts that pin the version keep working
+// when a later one becomes the default
+type porcelainFlag struct{}
+
+func (porcelainFlag) String() string   { return porcelain }
+func (porcelainFlag) IsBoolFlag() bool { return true }
+
+func (porcelainFlag) Set(value string) error {
+	switch value {
+	case "true", porcelainV1:
+		porcelain = porcelainV1
+	case "false":
+		porcelain = ""
+	default:
+		return fmt.Errorf("unknown porcelain format %q, use %s", value, porcelainV1)
+	}
+	return nil
+}
+
+// writePorcelain writes result in the v1 format: a "porcelain v1" line,
+// then "key value" lines, then an empty line and the message to the end
+func writePorcelain(w io.Writer, result *generator.Result) {
+	fmt.Fprintf(w, "porcelain %s\n", porcelainV1)
+	fmt.Fprintf(w, "model %s\n", porcelainValue(result.Model))
+	fmt.Fprintf(w, "tokens %d %d\n", result.Tokens.PromptTokens, result.Tokens.OutputTokens)
+	fmt.Fprintf(w, "cached %t\n", result.Cached)
+	if result.FinishReason != "" {
+		fmt.Fprintf(w, "finish %s\n", result.FinishReason)
+	}
+	if result.Truncated() {
+		fmt.Fprintln(w, "warning truncated")
+	}
+	if result.DuplicateOf != "" {
+		fmt.Fprintf(w, "warning duplicate %s\n", porcelainValue(result.DuplicateOf))
+	}
+	for _, claim := range result.UnsupportedClaims {
+		fmt.Fprintf(w, "warning claim %s\n", porcelainValue(claim))
+	}
+	fmt.Fprintf(w, "\n%s\n", result.Message)
+}
+
+// porcelainValue folds a value onto one line, so that it cannot end the
+// header early
+func porcelainValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// writePorcelainError writes the one line a failure leaves on stderr:
+// "error", the exitcode name, and the message
+func writePorcelainError(code int, message string) {
+	fmt.Fprintf(os.Stderr, "error %s %s\n", exitcode.Name(code), porcelainValue(message))
+}
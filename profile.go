@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/atomicfile"
+	"github.com/nguyenanhhao221/commit-gen/internal/config"
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+)
+
+// runExportProfile writes the user-wide config and the files it points to
+// as a profile to carry to another machine
+func runExportProfile(args []string) {
+	fs := flag.NewFlagSet("commit-gen export-profile", flag.ExitOnError)
+	output := fs.String("o", "", "Write the profile to this file instead of stdout")
+	fs.Parse(args)
+
+	profile, err := config.ExportProfile()
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		fatalf("Failed to encode profile: %v", err)
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := atomicfile.WriteFile(*output, data, 0o644); err != nil {
+		fatalf("Failed to write profile: %v", err)
+	}
+	warnf("Exported the profile to %s", *output)
+}
+
+// runImportProfile installs a profile written by export-profile as the
+// user-wide config
+func runImportProfile(args []string) {
+	fs := flag.NewFlagSet("commit-gen import-profile", flag.ExitOnError)
+	force := fs.Bool("force", false, "Replace an existing user-wide config")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fail(exitcode.Usage, "Usage: commit-gen import-profile [-force] <profile.json | ->")
+	}
+
+	var data []byte
+	var err error
+	if fs.Arg(0) == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fatalf("Failed to read profile: %v", err)
+	}
+	var profile config.Profile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		fail(exitcode.Config, "Invalid profile: "+err.Error())
+	}
+
+	path, err := config.ImportProfile(&profile, *force)
+	if err != nil {
+		fail(exitcode.Config, err.Error())
+	}
+	warnf("Imported the profile to %s", path)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// Script formats of the reword command
+const (
+	rewordRebase     = "rebase"
+	rewordFilterRepo = "filter-repo"
+)
+
+// runReword generates a new message for every commit of a range and
+// prints a script that puts them in place. It never rewrites anything
+// itself; the user reads the script and runs it:
+//
+//	commit-gen reword main..HEAD > todo
+//	git -c sequence.editor="cp todo" rebase -i main
+func runReword(args []string) {
+	fs := flag.NewFlagSet("commit-gen reword", flag.ExitOnError)
+	format := fs.String("format", rewordRebase, "Script to print: rebase (a git rebase -i todo list) or filter-repo")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fail(exitcode.Usage, "Usage: commit-gen reword [flags] <rev-range>, e.g. main..HEAD")
+	}
+	revRange := fs.Arg(0)
+	base, head, isRange := strings.Cut(revRange, "..")
+	if !isRange {
+		// A single revision is the base of the current branch
+		revRange, head = base+"..HEAD", "HEAD"
+	}
+	switch *format {
+	case rewordRebase:
+		if strings.HasPrefix(head, ".") || !sameCommit(cmp.Or(head, "HEAD"), "HEAD") {
+			fail(exitcode.Usage, "A rebase todo needs a range ending at HEAD, like main..HEAD; use -format filter-repo for other branches")
+		}
+		if hasMerges(revRange) {
+			fail(exitcode.Usage, fmt.Sprintf("%s contains merges, which a rebase todo would flatten; use -format filter-repo", revRange))
+		}
+	case rewordFilterRepo:
+	default:
+		fail(exitcode.Usage, fmt.Sprintf("Unknown format %q, use rebase or filter-repo", *format))
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	ctx, stop := interruptible()
+	rewords, err := commitGen.RewordRange(ctx, revRange)
+	stop()
+	if err != nil {
+		failErr(err, "Failed to reword the commits")
+	}
+	if len(rewords) == 0 {
+		fail(exitcode.NoChanges, fmt.Sprintf("No commits to reword in %s", revRange))
+	}
+
+	fmt.Fprintf(os.Stderr, "WARNING: running this script rewrites the history of %s.\n", revRange)
+	fmt.Fprintln(os.Stderr, "Review every message before running it, and never rewrite commits others have pulled.")
+	if *format == rewordFilterRepo {
+		fmt.Print(generator.FilterRepoScript(revRange, rewords))
+		return
+	}
+	fmt.Print(generator.RebaseTodo(base, rewords))
+}
+
+// sameCommit reports whether two revisions name the same commit
+func sameCommit(a, b string) bool {
+	repo := generator.NewGitRepository("")
+	x, errA := repo.ResolveCommit(a)
+	y, errB := repo.ResolveCommit(b)
+	return errA == nil && errB == nil && x == y
+}
+
+// hasMerges reports whether revRange contains merge commits
+func hasMerges(revRange string) bool {
+	commits, err := generator.NewGitRepository("").GetCommits("--merges", revRange)
+	return err == nil && len(commits) > 0
+}
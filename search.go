@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runSearch finds the commits whose messages best match a question by meaning
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("commit-gen search", flag.ExitOnError)
+	limit := fs.Int("n", 5, "Number of commits to show")
+	embeddingModel := fs.String("embedding-model", "", "Embedding model (default: the provider's embedding model)")
+	asJSON := fs.Bool("json", false, "Print the matches as JSON on stdout and errors as JSON on stderr")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fail(exitcode.Usage, `Usage: commit-gen search "when did we change retry backoff"`)
+	}
+	if *limit < 1 {
+		fail(exitcode.Usage, "-n must be at least 1")
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+	if *embeddingModel != "" {
+		opts.EmbeddingModel = *embeddingModel
+	}
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	results, err := commitGen.Search(context.Background(), query, *limit)
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges("No commits to search.")
+	}
+	if err != nil {
+		failErr(err, "Failed to search commits")
+	}
+
+	if *asJSON {
+		printResponse(results)
+		return
+	}
+	for _, result := range results {
+		subject, _, _ := strings.Cut(result.Message, "\n")
+		fmt.Printf("%s %s %.2f  %s (%s)\n",
+			result.Hash[:min(len(result.Hash), 10)], result.Date.Format("2006-01-02"), result.Score, subject, result.AuthorName)
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// maxRequestBytes bounds the JSON body of a /v1 request. It leaves room
+// for large staged diffs, which the generator trims to its own limit.
+const maxRequestBytes = 10 << 20
+
+// generateRequest is the body of POST /v1/generate
+type generateRequest struct {
+	Diff    string `json:"diff"`
+	History string `json:"history,omitempty"`
+	// Hint is the user's summary of the change
+	Hint string `json:"hint,omitempty"`
+	// Optional per-request overrides
+	Model        string   `json:"model,omitempty"`
+	Temperature  *float32 `json:"temperature,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	Short        *bool    `json:"short,omitempty"`
+	// Type and Scope pin those parts of the header
+	Type  string `json:"type,omitempty"`
+	Scope string `json:"scope,omitempty"`
+}
+
+// generateResponse is the reply of POST /v1/generate
+type generateResponse struct {
+	Message           string                   `json:"message,omitempty"`
+	Parsed            *generator.CommitMessage `json:"parsed,omitempty"`
+	Model             string                   `json:"model,omitempty"`
+	Tokens            *generator.Usage         `json:"tokens,omitempty"`
+	LatencyMS         int64                    `json:"latency_ms,omitempty"`
+	Cached            bool                     `json:"cached,omitempty"`
+	FinishReason      string                   `json:"finish_reason,omitempty"`
+	UnsupportedClaims []string                 `json:"unsupported_claims,omitempty"`
+	DuplicateOf       string                   `json:"duplicate_of,omitempty"`
+	Grounding         *generator.Grounding     `json:"grounding,omitempty"`
+	Error             string                   `json:"error,omitempty"`
+}
+
+// newGenerateResponse reports a successful generation
+func newGenerateResponse(result *generator.Result) *generateResponse {
+	return &generateResponse{
+		Message:           result.String(),
+		Parsed:            &result.Message,
+		Model:             result.Model,
+		Tokens:            &result.Tokens,
+		LatencyMS:         result.Latency.Milliseconds(),
+		Cached:            result.Cached,
+		FinishReason:      result.FinishReason,
+		UnsupportedClaims: result.UnsupportedClaims,
+		DuplicateOf:       result.DuplicateOf,
+		Grounding:         result.Grounding,
+	}
+}
+
+// completeRequest is the body of POST /v1/complete
+type completeRequest struct {
+	Diff    string `json:"diff"`
+	History string `json:"history,omitempty"`
+	// Prefix is the start of the message the author already typed
+	Prefix string `json:"prefix"`
+}
+
+// completeResponse is the reply of POST /v1/complete; Continuation is the
+// text to show after the prefix, and empty when there is no suggestion
+type completeResponse struct {
+	Continuation string `json:"continuation"`
+	*generateResponse
+}
+
+// runServe runs commit-gen as a long-lived HTTP daemon
+func runServe(args []string) {
+	fs := flag.NewFlagSet("commit-gen serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:7878", "Address to listen on")
+	shortCommit := fs.Bool("short", false, "Generate short commit titles")
+	cacheSize := fs.Int("cache-size", 256, "Number of generated messages to cache in memory (0 disables)")
+	tenantsPath := fs.String("tenants", "", "TOML file of user tokens and team quotas; requires a token for /v1 endpoints")
+	auditLog := fs.String("audit-log", "", "Append a JSON line per /v1 request to this file (requires -tenants)")
+	auditPrompts := fs.Bool("audit-prompts", false, "Write full prompts to the audit log instead of their SHA-256")
+	warmUp := fs.Bool("warm-up", false, "Send a one-token request at startup, e.g. to load a local model")
+	githubAPI := fs.String("github-api", "https://api.github.com", "GitHub API URL for the pull request webhook and Slack command (GitHub Enterprise: https://HOST/api/v3)")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	var tenants *tenantStore
+	if *tenantsPath != "" {
+		var err error
+		if tenants, err = loadTenants(*tenantsPath, *auditLog, *auditPrompts); err != nil {
+			fail(exitcode.Config, err.Error())
+		}
+	} else if *auditLog != "" {
+		fail(exitcode.Usage, "-audit-log requires -tenants")
+	}
+	// With a webhook secret, pull requests get a summary comment
+	token, secret := os.Getenv("GITHUB_TOKEN"), os.Getenv("GITHUB_WEBHOOK_SECRET")
+	if secret != "" && token == "" {
+		fail(exitcode.Config, "GITHUB_WEBHOOK_SECRET is set but GITHUB_TOKEN is not; the webhook needs it to comment")
+	}
+	// With a signing secret, the /commitgen Slack command is answered
+	slackSecret := os.Getenv("SLACK_SIGNING_SECRET")
+
+	metrics := newServeMetrics()
+	opts := loadOptions("")
+	opts.IsShortCommit = *shortCommit
+	opts.CacheSize = *cacheSize
+	opts.Observer = metrics
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fatalf("Failed to initialize commit generator: %v", err)
+	}
+	defer commitGen.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep checking the primary provider so we can fail back automatically
+	commitGen.StartHealthProbe(ctx)
+	go warmUpProvider(ctx, commitGen, *warmUp)
+
+	mux := newServeMux(commitGen, metrics, tenants)
+	var github *githubClient
+	if token != "" {
+		github = &githubClient{
+			apiURL: strings.TrimRight(*githubAPI, "/"),
+			token:  token,
+			client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	// GitHub and Slack sign their requests, so tenant tokens do not apply
+	if secret != "" {
+		bot := &githubBot{githubClient: github, commitGen: commitGen, secret: []byte(secret)}
+		mux.Handle("POST "+githubWebhookPath, metrics.instrument(githubWebhookPath, bot.ServeHTTP))
+		log.Printf("Commenting on pull requests delivered to %s", githubWebhookPath)
+	}
+	if slackSecret != "" {
+		command := &slackCommand{
+			commitGen: commitGen,
+			github:    github,
+			secret:    []byte(slackSecret),
+			client:    &http.Client{Timeout: 30 * time.Second},
+		}
+		mux.Handle("POST "+slackCommandPath, metrics.instrument(slackCommandPath, command.ServeHTTP))
+		log.Printf("Answering Slack slash commands at %s", slackCommandPath)
+	}
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("commit-gen listening on %s", *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf("Server failed: %v", err)
+	}
+}
+
+// warmUpProvider connects to the provider in the background, so that the
+// first request does not wait for the handshakes; failing only means the
+// first request connects itself
+func warmUpProvider(ctx context.Context, commitGen *generator.CommitGen, generate bool) {
+	start := time.Now()
+	if err := commitGen.WarmUp(ctx, generate); err != nil {
+		if !errors.Is(err, generator.ErrDisabled) {
+			log.Printf("Warm-up failed: %v", err)
+		}
+		return
+	}
+	log.Printf("Provider warmed up in %s", time.Since(start).Round(time.Millisecond))
+}
+
+// newServeMux wires the daemon HTTP endpoints; with tenants, the /v1
+// endpoints require a user token and count against the team quota
+func newServeMux(commitGen *generator.CommitGen, metrics *serveMetrics, tenants *tenantStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	protect := func(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+		if tenants != nil {
+			next = tenants.protect(endpoint, next)
+		}
+		return metrics.instrument(endpoint, next)
+	}
+
+	mux.HandleFunc("POST /v1/generate", protect("/v1/generate", func(w http.ResponseWriter, r *http.Request) {
+		var req generateRequest
+		if status, err := decodeBody(w, r, &req); err != nil {
+			writeJSON(w, status, &generateResponse{Error: err.Error()})
+			return
+		}
+		if req.Diff == "" {
+			writeJSON(w, http.StatusBadRequest, &generateResponse{Error: "diff is required"})
+			return
+		}
+
+		gitInfo := &generator.GitInfo{
+			StagedDiff:    req.Diff,
+			RecentCommits: req.History,
+			HasHistory:    req.History != "",
+			Hint:          req.Hint,
+		}
+		var subjectPrefix string
+		if req.Type != "" {
+			subjectPrefix = generator.SubjectPrefix(req.Type, req.Scope)
+		}
+		result, err := commitGen.GenerateWithConfig(r.Context(), gitInfo, &generator.GenConfig{
+			Model:         req.Model,
+			Temperature:   req.Temperature,
+			SystemPrompt:  req.SystemPrompt,
+			IsShortCommit: req.Short,
+			SubjectPrefix: subjectPrefix,
+			Scope:         req.Scope,
+		})
+		if err != nil {
+			reportCall(r.Context(), req.Diff, req.Model, generator.Usage{})
+			status := http.StatusBadGateway
+			if errors.Is(err, generator.ErrDisabled) {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, &generateResponse{Error: err.Error()})
+			return
+		}
+		reportCall(r.Context(), req.Diff, result.Model, result.Tokens)
+		writeJSON(w, http.StatusOK, newGenerateResponse(result))
+	}))
+
+	// Editors call this as the author types, to show the rest of the
+	// message as ghost text
+	mux.HandleFunc("POST /v1/complete", protect("/v1/complete", func(w http.ResponseWriter, r *http.Request) {
+		var req completeRequest
+		if status, err := decodeBody(w, r, &req); err != nil {
+			writeJSON(w, status, &generateResponse{Error: err.Error()})
+			return
+		}
+		if req.Diff == "" {
+			writeJSON(w, http.StatusBadRequest, &generateResponse{Error: "diff is required"})
+			return
+		}
+
+		continuation, err := commitGen.Continue(r.Context(), &generator.GitInfo{
+			StagedDiff:    req.Diff,
+			RecentCommits: req.History,
+			HasHistory:    req.History != "",
+		}, req.Prefix)
+		if err != nil {
+			reportCall(r.Context(), req.Diff, "", generator.Usage{})
+			status := http.StatusBadGateway
+			if errors.Is(err, generator.ErrDisabled) {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, &generateResponse{Error: err.Error()})
+			return
+		}
+		reportCall(r.Context(), req.Diff, continuation.Result.Model, continuation.Result.Tokens)
+		writeJSON(w, http.StatusOK, &completeResponse{
+			Continuation:     continuation.Text,
+			generateResponse: newGenerateResponse(continuation.Result),
+		})
+	}))
+
+	// The relay runs prompts prepared by CLIs using -provider relay, so that
+	// only the relay needs provider keys
+	mux.HandleFunc("POST "+generator.RelayPath, protect(generator.RelayPath, func(w http.ResponseWriter, r *http.Request) {
+		var req generator.RelayRequest
+		if status, err := decodeBody(w, r, &req); err != nil {
+			writeJSON(w, status, &generator.RelayResponse{Error: err.Error()})
+			return
+		}
+		if req.Prompt == "" {
+			writeJSON(w, http.StatusBadRequest, &generator.RelayResponse{Error: "prompt is required"})
+			return
+		}
+
+		resp, err := commitGen.Complete(r.Context(), &generator.TextRequest{
+			Model:           req.Model,
+			SystemPrompt:    req.SystemPrompt,
+			Prompt:          req.Prompt,
+			Temperature:     req.Temperature,
+			Seed:            req.Seed,
+			ThinkingBudget:  req.ThinkingBudget,
+			MaxOutputTokens: req.MaxOutputTokens,
+		})
+		if err != nil {
+			reportCall(r.Context(), req.SystemPrompt+"\n"+req.Prompt, req.Model, generator.Usage{})
+			writeJSON(w, relayStatus(err), &generator.RelayResponse{Error: err.Error()})
+			return
+		}
+		reportCall(r.Context(), req.SystemPrompt+"\n"+req.Prompt, resp.Model, resp.Usage)
+		writeJSON(w, http.StatusOK, &generator.RelayResponse{
+			Text:         resp.Text,
+			Model:        resp.Model,
+			Usage:        resp.Usage,
+			FinishReason: resp.FinishReason,
+			BlockReason:  resp.BlockReason,
+		})
+	}))
+
+	// Opted-in CLIs report what became of their messages; only the
+	// aggregate counts are kept, in the metrics
+	mux.HandleFunc("POST "+generator.FeedbackPath, protect(generator.FeedbackPath, func(w http.ResponseWriter, r *http.Request) {
+		var batch generator.FeedbackBatch
+		if status, err := decodeBody(w, r, &batch); err != nil {
+			writeJSON(w, status, &generator.RelayResponse{Error: err.Error()})
+			return
+		}
+		for _, signal := range batch.Signals {
+			switch signal.Signal {
+			case generator.FeedbackAccept, generator.FeedbackEdit, generator.FeedbackReject:
+			default:
+				writeJSON(w, http.StatusBadRequest, &generator.RelayResponse{Error: "unknown signal " + strconv.Quote(signal.Signal)})
+				return
+			}
+		}
+		metrics.Feedback(batch.Signals)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.HandleFunc("GET /healthz", metrics.instrument("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, commitGen.ProviderStatus())
+	}))
+
+	mux.Handle("GET /metrics", metrics)
+
+	return mux
+}
+
+// relayStatus passes the upstream provider's status through, so that relay
+// clients can tell an exhausted quota from an outage. A rejected provider key
+// is the relay's problem, not the client's, so it is a bad gateway.
+func relayStatus(err error) int {
+	if errors.Is(err, generator.ErrDisabled) {
+		return http.StatusServiceUnavailable
+	}
+	var providerErr *generator.ProviderError
+	switch {
+	case errors.As(err, &providerErr) && (providerErr.StatusCode == http.StatusUnauthorized || providerErr.StatusCode == http.StatusForbidden):
+		// The relay's own key was rejected; 401 and 403 tell clients that
+		// their token was
+		return http.StatusBadGateway
+	case errors.As(err, &providerErr) && providerErr.StatusCode >= 400:
+		return providerErr.StatusCode
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// decodeBody decodes the JSON body of r into v. A body over
+// maxRequestBytes is refused with 413, any other error with 400.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) (int, error) {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit)
+	case err != nil:
+		return http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return http.StatusOK, nil
+}
+
+// writeJSON encodes v as the response body with the given status
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
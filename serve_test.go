@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+func TestRelayStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"rejected provider key", &generator.ProviderError{StatusCode: http.StatusUnauthorized}, http.StatusBadGateway},
+		{"forbidden provider key", &generator.ProviderError{StatusCode: http.StatusForbidden}, http.StatusBadGateway},
+		{"quota", &generator.ProviderError{StatusCode: http.StatusTooManyRequests}, http.StatusTooManyRequests},
+		{"bad request", fmt.Errorf("wrapped: %w", &generator.ProviderError{StatusCode: http.StatusBadRequest}), http.StatusBadRequest},
+		{"timeout", context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"disabled", generator.ErrDisabled, http.StatusServiceUnavailable},
+		{"other", fmt.Errorf("connection refused"), http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relayStatus(tt.err); got != tt.want {
+				t.Errorf("relayStatus(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	diff := strings.Repeat(`+x\n`, maxRequestBytes/4)
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"valid", `{"diff": "+x"}`, http.StatusOK},
+		{"invalid", `{"diff":`, http.StatusBadRequest},
+		{"too large", `{"diff": "` + diff + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req generateRequest
+			r := httptest.NewRequest(http.MethodPost, "/v1/generate", strings.NewReader(tt.body))
+			if got, err := decodeBody(httptest.NewRecorder(), r, &req); got != tt.want {
+				t.Errorf("decodeBody() = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+const (
+	// slackCommandPath receives Slack slash command invocations
+	slackCommandPath = "/slack/commands"
+	// maxSlackRequestBytes bounds a slash command request
+	maxSlackRequestBytes = 1 << 20
+	// maxSlackClockSkew is how old a signed request may be, against replays
+	maxSlackClockSkew = 5 * time.Minute
+	// slackCommandTimeout bounds the work on one command
+	slackCommandTimeout = 2 * time.Minute
+)
+
+// slackUsage is the help text of the slash command
+const slackUsage = "Usage: `/commitgen owner/repo branch` for a squash commit message, " +
+	"`/commitgen summary owner/repo branch` for a branch summary, or `/commitgen` followed by a pasted diff"
+
+// slackCommand answers the /commitgen slash command with a commit message
+// or a summary for a branch on GitHub or a pasted diff
+type slackCommand struct {
+	commitGen *generator.CommitGen
+	// github is nil without GitHub credentials, leaving only pasted diffs
+	github *githubClient
+	secret []byte
+	client *http.Client
+}
+
+// ServeHTTP acknowledges the command right away, since Slack gives up
+// after 3 seconds, and posts the answer to the channel when it is ready
+func (s *slackCommand) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxSlackRequestBytes))
+	if err != nil {
+		http.Error(w, "failed to read the request", http.StatusBadRequest)
+		return
+	}
+	if !s.validSignature(payload, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(payload))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+
+	// Slack escapes &, <, and > in the text
+	text := strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">").Replace(form.Get("text"))
+	run, err := s.parse(strings.TrimSpace(text))
+	if err != nil {
+		writeJSON(w, http.StatusOK, &slackResponse{ResponseType: "ephemeral", Text: err.Error()})
+		return
+	}
+
+	responseURL := form.Get("response_url")
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), slackCommandTimeout)
+		defer cancel()
+		answer, err := run(ctx)
+		reply := &slackResponse{ResponseType: "in_channel", Text: answer}
+		if err != nil {
+			log.Printf("Slack command %q: %v", form.Get("text"), err)
+			reply = &slackResponse{ResponseType: "ephemeral", Text: "commit-gen failed: " + err.Error()}
+		}
+		if err := s.respond(ctx, responseURL, reply); err != nil {
+			log.Printf("Slack command %q: failed to respond: %v", form.Get("text"), err)
+		}
+	}()
+	writeJSON(w, http.StatusOK, &slackResponse{ResponseType: "ephemeral", Text: "Working on it..."})
+}
+
+// slackResponse is a slash command response
+type slackResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// parse turns the command text into the work to do, or explains the usage
+func (s *slackCommand) parse(text string) (func(context.Context) (string, error), error) {
+	// A pasted diff, possibly in a code block
+	if diff := strings.Trim(text, "`\n"); strings.HasPrefix(diff, "diff --git") || strings.HasPrefix(diff, "--- ") {
+		return func(ctx context.Context) (string, error) {
+			result, err := s.commitGen.GenerateWithConfig(ctx, &generator.GitInfo{StagedDiff: diff + "\n"}, nil)
+			if err != nil {
+				return "", err
+			}
+			return slackCodeBlock(result.String()), nil
+		}, nil
+	}
+
+	fields := strings.Fields(text)
+	summary := len(fields) > 0 && fields[0] == "summary"
+	if summary {
+		fields = fields[1:]
+	}
+	if len(fields) != 2 || strings.Count(fields[0], "/") != 1 {
+		return nil, errors.New(slackUsage)
+	}
+	if s.github == nil {
+		return nil, errors.New("commit-gen has no GitHub credentials here, paste a diff instead")
+	}
+	repo, branch := fields[0], fields[1]
+
+	return func(ctx context.Context) (string, error) {
+		pr, err := s.github.compareBranch(ctx, repo, branch)
+		if err != nil {
+			return "", err
+		}
+		if !summary {
+			result, err := s.commitGen.GenerateWithConfig(ctx, &generator.GitInfo{StagedDiff: pr.Diff, Hint: branch}, nil)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Squash commit for *%s* `%s`:\n%s", repo, branch, slackCodeBlock(result.String())), nil
+		}
+		prSummary, err := s.commitGen.SummarizePullRequest(ctx, pr)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("*%s* `%s` (%s):\n%s\n\nSquash commit:\n%s", repo, branch,
+			plural(len(pr.Commits), "commit"), prSummary.Summary.Text, slackCodeBlock(prSummary.SquashMessage.String())), nil
+	}, nil
+}
+
+// slackCodeBlock formats text as a Slack code block
+func slackCodeBlock(text string) string {
+	return "```\n" + text + "\n```"
+}
+
+// validSignature checks Slack's v0 signature of the request, rejecting
+// stale ones so that a captured request cannot be replayed
+func (s *slackCommand) validSignature(payload []byte, timestamp, signature string) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > maxSlackClockSkew {
+		return false
+	}
+	digest, err := hex.DecodeString(strings.TrimPrefix(signature, "v0="))
+	if err != nil || !strings.HasPrefix(signature, "v0=") {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(payload)
+	return hmac.Equal(digest, mac.Sum(nil))
+}
+
+// respond posts reply to the response URL of a command
+func (s *slackCommand) respond(ctx context.Context, responseURL string, reply *slackResponse) error {
+	body, err := json.Marshal(reply)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Slack returned %s: %s", resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runStats prints how the generated messages fared once committed and,
+// with -history, what types and scopes the history is made of
+func runStats(args []string) {
+	fs := flag.NewFlagSet("commit-gen stats", flag.ExitOnError)
+	count := fs.Int("n", 500, "Number of recent commits to look at")
+	history := fs.Bool("history", false, "Also summarize the types and scopes of every commit")
+	asJSON := fs.Bool("json", false, "Print the statistics as JSON")
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	// The statistics come from git and the cache, no provider is needed
+	stats, err := gitRepository().Stats(*count, *history)
+	if err != nil {
+		fatalf("Failed to read the statistics: %v", err)
+	}
+	if *asJSON {
+		printResponse(stats)
+		return
+	}
+
+	gen := stats.Generations
+	fmt.Printf("Generated messages: %d, committed in the last %s: %d\n", gen.Generated, plural(stats.Commits, "commit"), gen.Committed)
+	if gen.Committed > 0 {
+		edited := gen.Committed - gen.Unchanged - gen.TypeChanged - gen.ScopeChanged
+		for _, row := range []struct {
+			label string
+			n     int
+		}{
+			{"unchanged", gen.Unchanged},
+			{"type changed", gen.TypeChanged},
+			{"scope changed", gen.ScopeChanged},
+			{"subject edited", edited},
+		} {
+			fmt.Printf("  %-16s %4d (%.0f%%)\n", row.label, row.n, 100*float64(row.n)/float64(gen.Committed))
+		}
+		fmt.Println()
+		printTermComparison("TYPE", gen.Suggested.Types, gen.Accepted.Types)
+		if len(gen.Suggested.Scopes)+len(gen.Accepted.Scopes) > 0 {
+			fmt.Println()
+			printTermComparison("SCOPE", gen.Suggested.Scopes, gen.Accepted.Scopes)
+		}
+	}
+
+	if stats.History == nil {
+		return
+	}
+	h := stats.History
+	fmt.Printf("\nHistory: %s, %.0f%% Conventional Commits\n", plural(h.Messages, "commit"), percent(h.Conventional, h.Messages))
+	printTermShares("TYPE", h.Types, h.Messages)
+	if len(h.Scopes) > 0 {
+		fmt.Println()
+		printTermShares("SCOPE", h.Scopes, h.Messages)
+	}
+}
+
+// printTermComparison prints a table of how often each type or scope was
+// suggested and how often it was committed
+func printTermComparison(heading string, suggested, accepted []generator.TermCount) {
+	counts := make(map[string][2]int)
+	var names []string
+	for i, terms := range [][]generator.TermCount{suggested, accepted} {
+		for _, t := range terms {
+			c, seen := counts[t.Name]
+			if !seen {
+				names = append(names, t.Name)
+			}
+			c[i] = t.Count
+			counts[t.Name] = c
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tSUGGESTED\tCOMMITTED\n", heading)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", name, counts[name][0], counts[name][1])
+	}
+	w.Flush()
+}
+
+// printTermShares prints a table of types or scopes with the share of the
+// total commits using each
+func printTermShares(heading string, terms []generator.TermCount, total int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tCOMMITS\tSHARE\n", heading)
+	for _, t := range terms {
+		fmt.Fprintf(w, "%s\t%d\t%.0f%%\n", t.Name, t.Count, percent(t.Count, total))
+	}
+	w.Flush()
+}
+
+// percent is n as a percentage of total
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(total)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runPushSummary summarizes the commits about to be pushed for a team chat
+func runPushSummary(args []string) {
+	fs := flag.NewFlagSet("commit-gen push-summary", flag.ExitOnError)
+	base := fs.String("base", "@{upstream}", "Revision the push is compared to")
+	asJSON := fs.Bool("json", false, "Print the summary as JSON on stdout and errors as JSON on stderr")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	summary, err := commitGen.PushSummary(context.Background(), *base)
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges(fmt.Sprintf("Nothing to push: HEAD has no commits that are not in %s.", *base))
+	}
+	if err != nil {
+		failErr(err, "Failed to summarize the push")
+	}
+	printSummary(summary, *asJSON)
+}
+
+// runStandup summarizes the user's recent commits across repositories
+func runStandup(args []string) {
+	fs := flag.NewFlagSet("commit-gen standup", flag.ExitOnError)
+	since := fs.String("since", "", "Git date to start from, e.g. yesterday (default: the start of the last working day)")
+	author := fs.String("author", generator.StandupMe, "Whose commits to summarize, like git log --author; \"me\" is your user.email")
+	var repos listFlag
+	fs.Var(&repos, "repo", "Repository to include (repeatable; default: repos in the config, or the current one)")
+	asJSON := fs.Bool("json", false, "Print the summary as JSON on stdout and errors as JSON on stderr")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+	jsonErrors = jsonErrors || *asJSON
+
+	if *since == "" {
+		*since = lastWorkday(time.Now()).Format("2006-01-02 15:04:05")
+	}
+
+	opts := loadOptions("")
+	providers.apply(opts)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	summary, err := commitGen.Standup(context.Background(), &generator.StandupOptions{
+		Repos:  repos,
+		Since:  *since,
+		Author: *author,
+	})
+	if errors.Is(err, generator.ErrNoChanges) {
+		failNoChanges(fmt.Sprintf("No commits since %s.", *since))
+	}
+	if err != nil {
+		failErr(err, "Failed to summarize commits")
+	}
+	printSummary(summary, *asJSON)
+}
+
+// lastWorkday returns the start of the working day before now, so that a
+// Monday standup covers Friday
+func lastWorkday(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// printSummary prints a summary as text, or as JSON with -json
+func printSummary(summary *generator.Summary, asJSON bool) {
+	if asJSON {
+		printResponse(summary)
+		return
+	}
+	fmt.Println(summary.Text)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// tenantsFile is the -tenants file of commit-gen serve
+type tenantsFile struct {
+	Users []tenantUser          `toml:"user"`
+	Teams map[string]*teamQuota `toml:"team"`
+}
+
+// tenantUser is one API token holder; only the SHA-256 of the token is stored
+type tenantUser struct {
+	Name        string `toml:"name"`
+	Team        string `toml:"team"`
+	TokenSHA256 string `toml:"token_sha256"`
+}
+
+// teamQuota caps a team's daily usage (0 means unlimited)
+type teamQuota struct {
+	DailyRequests int `toml:"daily_requests"`
+	DailyTokens   int `toml:"daily_tokens"`
+}
+
+// teamUsage is a team's usage on day
+type teamUsage struct {
+	day      string
+	requests int
+	tokens   int
+}
+
+// tenantStore authenticates requests with per-user tokens, enforces per-team
+// quotas, and writes the audit log. Usage is kept in memory and resets at
+// midnight UTC or on restart.
+type tenantStore struct {
+	users map[string]*tenantUser // by token hash
+	teams map[string]*teamQuota
+	// logPrompts writes full prompts to the audit log instead of hashes
+	logPrompts bool
+
+	mu    sync.Mutex
+	usage map[string]*teamUsage
+	log   *json.Encoder
+}
+
+// loadTenants reads the tenants file and opens the audit log, if any
+func loadTenants(path, auditPath string, logPrompts bool) (*tenantStore, error) {
+	var file tenantsFile
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	t := &tenantStore{
+		users:      make(map[string]*tenantUser, len(file.Users)),
+		teams:      file.Teams,
+		logPrompts: logPrompts,
+		usage:      make(map[string]*teamUsage),
+	}
+	for i := range file.Users {
+		user := &file.Users[i]
+		hash := strings.ToLower(strings.TrimSpace(user.TokenSHA256))
+		if user.Name == "" || len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("tenants file: user %q needs a name and a hex token_sha256", user.Name)
+		}
+		t.users[hash] = user
+	}
+
+	if auditPath != "" {
+		f, err := os.OpenFile(auditPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		t.log = json.NewEncoder(f)
+	}
+	return t, nil
+}
+
+// authenticate returns the user holding the request's bearer token
+func (t *tenantStore) authenticate(r *http.Request) *tenantUser {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+	for known, user := range t.users {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(hash)) == 1 {
+			return user
+		}
+	}
+	return nil
+}
+
+// admit counts a request against the team quota, refusing it when the
+// team's daily requests or tokens are used up
+func (t *tenantStore) admit(team string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage := t.teamUsage(team)
+	quota := t.teams[team]
+	if quota != nil && quota.DailyRequests > 0 && usage.requests >= quota.DailyRequests {
+		return fmt.Errorf("team %s used its %d daily requests", team, quota.DailyRequests)
+	}
+	if quota != nil && quota.DailyTokens > 0 && usage.tokens >= quota.DailyTokens {
+		return fmt.Errorf("team %s used its %d daily tokens", team, quota.DailyTokens)
+	}
+	usage.requests++
+	return nil
+}
+
+// charge adds the tokens a request consumed to its team
+func (t *tenantStore) charge(team string, tokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.teamUsage(team).tokens += tokens
+}
+
+// teamUsage returns today's usage of team; t.mu must be held
+func (t *tenantStore) teamUsage(team string) *teamUsage {
+	day := time.Now().UTC().Format(time.DateOnly)
+	usage := t.usage[team]
+	if usage == nil || usage.day != day {
+		usage = &teamUsage{day: day}
+		t.usage[team] = usage
+	}
+	return usage
+}
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user"`
+	Team         string    `json:"team,omitempty"`
+	Endpoint     string    `json:"endpoint"`
+	Status       int       `json:"status"`
+	Model        string    `json:"model,omitempty"`
+	PromptSHA256 string    `json:"prompt_sha256,omitempty"`
+	Prompt       string    `json:"prompt,omitempty"`
+	PromptTokens int       `json:"prompt_tokens,omitempty"`
+	OutputTokens int       `json:"output_tokens,omitempty"`
+}
+
+// tenantCall collects what a handler reports about a request for
+// quotas and the audit log
+type tenantCall struct {
+	model  string
+	prompt string
+	usage  generator.Usage
+}
+
+type tenantCallKey struct{}
+
+// reportCall records the prompt, model, and usage of the request, if it
+// passed through the tenants middleware
+func reportCall(ctx context.Context, prompt, model string, usage generator.Usage) {
+	if call, ok := ctx.Value(tenantCallKey{}).(*tenantCall); ok {
+		call.prompt, call.model, call.usage = prompt, model, usage
+	}
+}
+
+// protect requires a known user token and team quota for next, then
+// charges the tokens used and writes the audit log entry
+func (t *tenantStore) protect(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := t.authenticate(r)
+		if user == nil {
+			writeJSON(w, http.StatusUnauthorized, &generator.RelayResponse{Error: "missing or unknown API token"})
+			return
+		}
+		if err := t.admit(user.Team); err != nil {
+			t.audit(user, endpoint, http.StatusTooManyRequests, &tenantCall{})
+			writeJSON(w, http.StatusTooManyRequests, &generator.RelayResponse{Error: err.Error()})
+			return
+		}
+
+		call := &tenantCall{}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(context.WithValue(r.Context(), tenantCallKey{}, call)))
+
+		t.charge(user.Team, call.usage.TotalTokens)
+		t.audit(user, endpoint, rec.status, call)
+	}
+}
+
+// audit writes one audit log entry; prompts are logged as hashes unless
+// full prompts were requested
+func (t *tenantStore) audit(user *tenantUser, endpoint string, status int, call *tenantCall) {
+	if t.log == nil {
+		return
+	}
+	entry := &auditEntry{
+		Time:         time.Now().UTC(),
+		User:         user.Name,
+		Team:         user.Team,
+		Endpoint:     endpoint,
+		Status:       status,
+		Model:        call.model,
+		PromptTokens: call.usage.PromptTokens,
+		OutputTokens: call.usage.OutputTokens,
+	}
+	if call.prompt != "" {
+		sum := sha256.Sum256([]byte(call.prompt))
+		entry.PromptSHA256 = hex.EncodeToString(sum[:])
+		if t.logPrompts {
+			entry.Prompt = call.prompt
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.log.Encode(entry); err != nil {
+		warnf("Warning: failed to write audit log: %v", err)
+	}
+}
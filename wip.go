@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nguyenanhhao221/commit-gen/internal/exitcode"
+	"github.com/nguyenanhhao221/commit-gen/internal/generator"
+)
+
+// runWIP writes a terse checkpoint message for the staged changes without
+// a model, and with -commit commits them:
+//
+//	git commit -m "$(commit-gen wip)"
+//	commit-gen wip -commit -m "halfway through the parser"
+func runWIP(args []string) {
+	fs := flag.NewFlagSet("commit-gen wip", flag.ExitOnError)
+	note := fs.String("m", "", "Subject after the prefix, instead of a summary of the staged files")
+	prefix := fs.String("prefix", "", "Subject prefix (default \"WIP: \")")
+	commit := fs.Bool("commit", false, "Commit the staged changes with the message")
+	yes := fs.Bool("yes", false, "Commit without asking for confirmation")
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	setIfNotEmpty(&opts.WIPPrefix, *prefix)
+	repo := gitRepository()
+	message, err := repo.WIPMessage(opts.WIPPrefix, *note)
+	if err != nil {
+		failErr(err, "Failed to describe the staged changes")
+	}
+	if !*commit {
+		fmt.Println(message)
+		return
+	}
+
+	requireConfirmation([]generator.Command{repo.CommitCommand(message.String())}, *yes)
+	if err := repo.Commit(message.String()); err != nil {
+		failErr(err, "Failed to commit")
+	}
+	fmt.Println(message)
+}
+
+// runRewordWIP replaces the messages of the checkpoint commits on the
+// current branch with generated ones before the branch is pushed. The
+// commits are recreated with their trees and authors, so everything after
+// the first WIP commit gets a new hash; the old ones stay in the reflog.
+func runRewordWIP(args []string) {
+	fs := flag.NewFlagSet("commit-gen reword-wip", flag.ExitOnError)
+	base := fs.String("base", "@{upstream}", "Revision the WIP commits are on top of")
+	prefix := fs.String("prefix", "", "Subject prefix of the WIP commits (default \"WIP: \")")
+	dryRun := fs.Bool("dry-run", false, "Print the new messages without rewriting the commits")
+	yes := fs.Bool("yes", false, "Rewrite without asking for confirmation")
+	providers := registerProviderFlags(fs)
+	fs.Parse(args)
+
+	opts := loadOptions("")
+	providers.apply(opts)
+	setIfNotEmpty(&opts.WIPPrefix, *prefix)
+
+	commitGen, err := generator.New(opts)
+	if err != nil {
+		fail(exitcode.Config, fmt.Sprintf("Failed to initialize commit generator: %v", err))
+	}
+	defer commitGen.Close()
+
+	ctx, stop := interruptible()
+	rewords, err := commitGen.RewordWIP(ctx, *base, opts.WIPPrefix)
+	stop()
+	if err != nil {
+		failErr(err, "Failed to reword the WIP commits")
+	}
+	if len(rewords) == 0 {
+		fail(exitcode.NoChanges, fmt.Sprintf("No WIP commits in %s..HEAD", *base))
+	}
+
+	for _, reword := range rewords {
+		subject := generator.ParseCommitMessage(reword.Commit.Message).Header
+		fmt.Fprintf(os.Stderr, "%.12s %s\n  -> %s\n", reword.Commit.Hash, subject, reword.Result.Message.Header)
+	}
+	if *dryRun {
+		for _, reword := range rewords {
+			fmt.Printf("%.12s\n%s\n\n", reword.Commit.Hash, reword.Result)
+		}
+		return
+	}
+
+	command, err := commitGen.RewriteCommand(*base, rewords)
+	if err != nil {
+		failErr(err, "Failed to rewrite the WIP commits")
+	}
+	fmt.Fprintln(os.Stderr, "This rewrites the history of the current branch; the old commits stay in the reflog.")
+	requireConfirmation([]generator.Command{command}, *yes)
+	if err := commitGen.Rewrite(command); err != nil {
+		failErr(err, "Failed to rewrite the WIP commits")
+	}
+	fmt.Fprintf(os.Stderr, "Reworded %d WIP commits\n", len(rewords))
+}